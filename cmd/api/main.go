@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"flag"
 	"os"
 	"os/signal"
 	"syscall"
@@ -10,10 +11,20 @@ import (
 	"github.com/araesf/ledgertime/internal/api"
 	"github.com/araesf/ledgertime/internal/config"
 	"github.com/araesf/ledgertime/internal/db"
+	"github.com/araesf/ledgertime/internal/fraud"
+	"github.com/araesf/ledgertime/internal/kafka"
+	"github.com/araesf/ledgertime/internal/ledger"
+	"github.com/araesf/ledgertime/internal/memory"
+	"github.com/araesf/ledgertime/internal/vault"
 	"github.com/araesf/ledgertime/pkg/logger"
 )
 
 func main() {
+	storage := flag.String("storage", "postgres", "storage backend: postgres or memory")
+	migrate := flag.Bool("migrate", false, "apply pending database migrations before starting")
+	relayOutbox := flag.Bool("relay", false, "publish the outbox to Kafka from this process (the consumer does by default)")
+	flag.Parse()
+
 	// Initialize logger
 	log := logger.NewLogger()
 	log.Info("Starting Ledgertime API server")
@@ -24,15 +35,64 @@ func main() {
 		log.Fatal("Failed to load configuration", "error", err)
 	}
 
-	// Connect to database
-	database, err := db.Connect(cfg.Database, log)
+	// Open the store
+	var store ledger.Store
+	switch *storage {
+	case "postgres":
+		database, err := db.Connect(cfg.Database, log)
+		if err != nil {
+			log.Fatal("Failed to connect to database", "error", err)
+		}
+		defer database.Close()
+		if *migrate {
+			if _, err := database.MigrateUp(context.Background()); err != nil {
+				log.Fatal("Failed to migrate database", "error", err)
+			}
+		}
+		store = database
+	case "memory":
+		log.Info("Using in-memory storage, data is lost on exit")
+		store = memory.NewStore()
+	default:
+		log.Fatal("Unknown storage backend", "storage", *storage)
+	}
+
+	// Initialize transaction processor
+	rates, err := ledger.LoadRates(cfg.Ledger)
+	if err != nil {
+		log.Fatal("Failed to load FX rates", "error", err)
+	}
+	fraudEngine := fraud.NewEngine(cfg.Fraud, store)
+	processor, err := ledger.NewProcessor(cfg.Processor, rates, ledger.NewFraudProcessor(fraudEngine, store, log))
+	if err != nil {
+		log.Fatal("Failed to create transaction processor", "error", err)
+	}
+
+	// Load the card vault key
+	cardVault, err := vault.LoadKeyfile(cfg.Vault.KeyFile)
 	if err != nil {
-		log.Fatal("Failed to connect to database", "error", err)
+		log.Fatal("Failed to load card vault", "error", err)
+	}
+
+	// Initialize ledger service
+	ledgerService, err := ledger.NewService(cfg.Ledger, store, processor, cardVault, log)
+	if err != nil {
+		log.Fatal("Failed to create ledger service", "error", err)
+	}
+
+	// Expire authorizations, reviews and cards in the background
+	workers, stopWorkers := context.WithCancel(context.Background())
+	ledgerService.StartSweepers(workers)
+
+	// Publish ledger events from the outbox
+	var relay *kafka.Relay
+	if *relayOutbox {
+		relay = kafka.NewRelay(cfg.Kafka, store, log)
+		go relay.Run(workers)
 	}
-	defer database.Close()
 
 	// Initialize API server
-	server := api.NewServer(cfg, database, log)
+	server := api.NewServer(cfg, store, ledgerService, log)
 
 	// Start server in a goroutine
 	go func() {
@@ -48,6 +108,12 @@ func main() {
 	<-quit
 
 	log.Info("Shutting down server...")
+	stopWorkers()
+	if relay != nil {
+		if err := relay.Close(); err != nil {
+			log.Error("Error closing outbox relay", "error", err)
+		}
+	}
 
 	// Create a deadline for server shutdown
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
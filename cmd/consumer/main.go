@@ -2,18 +2,24 @@ package main
 
 import (
 	"context"
+	"flag"
 	"os"
 	"os/signal"
 	"syscall"
 
 	"github.com/araesf/ledgertime/internal/config"
 	"github.com/araesf/ledgertime/internal/db"
+	"github.com/araesf/ledgertime/internal/fraud"
 	"github.com/araesf/ledgertime/internal/kafka"
 	"github.com/araesf/ledgertime/internal/ledger"
+	"github.com/araesf/ledgertime/internal/vault"
 	"github.com/araesf/ledgertime/pkg/logger"
 )
 
 func main() {
+	migrate := flag.Bool("migrate", false, "apply pending database migrations before starting")
+	flag.Parse()
+
 	// Initialize logger
 	log := logger.NewLogger()
 	log.Info("Starting Ledgertime Kafka consumer")
@@ -31,8 +37,34 @@ func main() {
 	}
 	defer database.Close()
 
+	if *migrate {
+		if _, err := database.MigrateUp(context.Background()); err != nil {
+			log.Fatal("Failed to migrate database", "error", err)
+		}
+	}
+
+	// Initialize transaction processor
+	rates, err := ledger.LoadRates(cfg.Ledger)
+	if err != nil {
+		log.Fatal("Failed to load FX rates", "error", err)
+	}
+	fraudEngine := fraud.NewEngine(cfg.Fraud, database)
+	processor, err := ledger.NewProcessor(cfg.Processor, rates, ledger.NewFraudProcessor(fraudEngine, database, log))
+	if err != nil {
+		log.Fatal("Failed to create transaction processor", "error", err)
+	}
+
+	// Load the card vault key
+	cardVault, err := vault.LoadKeyfile(cfg.Vault.KeyFile)
+	if err != nil {
+		log.Fatal("Failed to load card vault", "error", err)
+	}
+
 	// Initialize ledger service
-	ledgerService := ledger.NewService(database, log)
+	ledgerService, err := ledger.NewService(cfg.Ledger, database, processor, cardVault, log)
+	if err != nil {
+		log.Fatal("Failed to create ledger service", "error", err)
+	}
 
 	// Initialize Kafka consumer
 	consumer, err := kafka.NewConsumer(cfg.Kafka, ledgerService, log)
@@ -49,6 +81,13 @@ func main() {
 		}
 	}()
 
+	// Expire authorizations, reviews and cards in the background
+	ledgerService.StartSweepers(ctx)
+
+	// Publish ledger events from the outbox
+	relay := kafka.NewRelay(cfg.Kafka, database, log)
+	go relay.Run(ctx)
+
 	log.Info("Consumer started successfully")
 
 	// Wait for interrupt signal to gracefully shutdown
@@ -63,5 +102,9 @@ func main() {
 		log.Error("Error closing consumer", "error", err)
 	}
 
+	if err := relay.Close(); err != nil {
+		log.Error("Error closing outbox relay", "error", err)
+	}
+
 	log.Info("Consumer exited properly")
 }
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/araesf/ledgertime/internal/config"
+	"github.com/araesf/ledgertime/internal/db"
+	"github.com/araesf/ledgertime/internal/vault"
+	"github.com/araesf/ledgertime/pkg/logger"
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] up|down|status|baseline|tokenize-cards\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	steps := flag.Int("steps", 1, "number of migrations to roll back with down")
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	command := flag.Arg(0)
+
+	// Initialize logger
+	log := logger.NewLogger()
+
+	// Load configuration
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatal("Failed to load configuration", "error", err)
+	}
+
+	// Connect to database
+	database, err := db.Connect(cfg.Database, log)
+	if err != nil {
+		log.Fatal("Failed to connect to database", "error", err)
+	}
+	defer database.Close()
+
+	ctx := context.Background()
+	switch command {
+	case "up":
+		if _, err := database.MigrateUp(ctx); err != nil {
+			log.Fatal("Failed to migrate database", "error", err)
+		}
+	case "down":
+		if _, err := database.MigrateDown(ctx, *steps); err != nil {
+			log.Fatal("Failed to roll back database", "error", err)
+		}
+	case "status":
+		statuses, err := database.GetMigrationStatus(ctx)
+		if err != nil {
+			log.Fatal("Failed to get migration status", "error", err)
+		}
+		for _, status := range statuses {
+			if status.AppliedAt == nil {
+				log.Info("Migration pending", "version", status.Version, "name", status.Name)
+			} else {
+				log.Info("Migration applied", "version", status.Version, "name", status.Name, "applied_at", status.AppliedAt)
+			}
+		}
+	case "baseline":
+		// Databases set up from the old schema.sql are adopted before migrating them up
+		if err := database.Baseline(ctx); err != nil {
+			log.Fatal("Failed to adopt database", "error", err)
+		}
+	case "tokenize-cards":
+		cardVault, err := vault.LoadKeyfile(cfg.Vault.KeyFile)
+		if err != nil {
+			log.Fatal("Failed to load card vault", "error", err)
+		}
+		if _, err := database.TokenizeCards(ctx, cardVault); err != nil {
+			log.Fatal("Failed to tokenize cards", "error", err)
+		}
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+}
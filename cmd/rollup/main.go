@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"flag"
+
+	"github.com/araesf/ledgertime/internal/config"
+	"github.com/araesf/ledgertime/internal/db"
+	"github.com/araesf/ledgertime/pkg/logger"
+)
+
+func main() {
+	userID := flag.String("user", "", "rebuild only this user's rollups (default every user)")
+	flag.Parse()
+
+	// Initialize logger
+	log := logger.NewLogger()
+	log.Info("Rebuilding spending rollups", "user_id", *userID)
+
+	// Load configuration
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatal("Failed to load configuration", "error", err)
+	}
+
+	// Connect to database
+	database, err := db.Connect(cfg.Database, log)
+	if err != nil {
+		log.Fatal("Failed to connect to database", "error", err)
+	}
+	defer database.Close()
+
+	if *userID != "" {
+		if _, err := database.GetUser(context.Background(), *userID); err != nil {
+			log.Fatal("Failed to rebuild spending rollups", "error", err)
+		}
+	}
+
+	// Replace the rollups with totals recomputed from the transactions
+	rows, err := database.RebuildSpendingRollups(context.Background(), *userID)
+	if err != nil {
+		log.Fatal("Failed to rebuild spending rollups", "error", err)
+	}
+
+	log.Info("Spending rollups rebuilt", "rows", rows)
+}
@@ -3,41 +3,45 @@ package api
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 	"time"
 
-	"github.com/gorilla/mux"
-	"github.com/google/uuid"
+	"github.com/araesf/ledgertime/internal/cardnet"
 	"github.com/araesf/ledgertime/internal/config"
-	"github.com/araesf/ledgertime/internal/db"
 	"github.com/araesf/ledgertime/internal/ledger"
+	"github.com/araesf/ledgertime/internal/merchant"
 	"github.com/araesf/ledgertime/internal/models"
+	"github.com/araesf/ledgertime/internal/money"
+	"github.com/araesf/ledgertime/internal/vault"
 	"github.com/araesf/ledgertime/pkg/logger"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
 )
 
 // Server represents the HTTP server
 type Server struct {
-	router        *mux.Router
-	server        *http.Server
-	db            *db.DB
-	ledgerService *ledger.Service
-	logger        *logger.Logger
+	router         *mux.Router
+	server         *http.Server
+	store          ledger.Store
+	ledgerService  *ledger.Service
+	requestTimeout time.Duration
+	logger         *logger.Logger
 }
 
 // NewServer creates a new HTTP server
-func NewServer(cfg *config.Config, database *db.DB, log *logger.Logger) *Server {
-	ledgerService := ledger.NewService(database, log)
-	
+func NewServer(cfg *config.Config, store ledger.Store, ledgerService *ledger.Service, log *logger.Logger) *Server {
 	s := &Server{
-		router:        mux.NewRouter(),
-		db:            database,
-		ledgerService: ledgerService,
-		logger:        log,
+		router:         mux.NewRouter(),
+		store:          store,
+		ledgerService:  ledgerService,
+		requestTimeout: cfg.Server.RequestTimeout,
+		logger:         log,
 	}
 
 	s.setupRoutes()
-	
+
 	s.server = &http.Server{
 		Addr:         cfg.Server.Host + ":" + cfg.Server.Port,
 		Handler:      s.router,
@@ -51,21 +55,78 @@ func NewServer(cfg *config.Config, database *db.DB, log *logger.Logger) *Server
 
 // setupRoutes configures all HTTP routes
 func (s *Server) setupRoutes() {
+	s.router.Use(s.withRequestTimeout)
+
 	// Health check
 	s.router.HandleFunc("/health", s.healthCheck).Methods("GET")
-	
+
 	// User routes
 	s.router.HandleFunc("/users", s.createUser).Methods("POST")
 	s.router.HandleFunc("/users/{id}", s.getUser).Methods("GET")
-	
+	s.router.HandleFunc("/users/{id}/balance", s.getUserBalance).Methods("GET")
+	s.router.HandleFunc("/users/{id}/overdraft", s.setOverdraftLimit).Methods("PUT")
+	s.router.HandleFunc("/users/{id}/limits", s.getUserLimits).Methods("GET")
+	s.router.HandleFunc("/users/{id}/limits", s.setUserLimits).Methods("PUT")
+
 	// Card routes
 	s.router.HandleFunc("/cards", s.createCard).Methods("POST")
-	s.router.HandleFunc("/cards/{cardNumber}", s.getCard).Methods("GET")
-	
+	s.router.HandleFunc("/cards/{token}", s.getCard).Methods("GET")
+	s.router.HandleFunc("/cards/{id}/limits", s.getCardLimits).Methods("GET")
+	s.router.HandleFunc("/cards/{id}/limits", s.setCardLimits).Methods("PUT")
+	s.router.HandleFunc("/cards/{id}/freeze", s.freezeCard).Methods("POST")
+	s.router.HandleFunc("/cards/{id}/unfreeze", s.unfreezeCard).Methods("POST")
+	s.router.HandleFunc("/cards/{id}/report", s.reportCard).Methods("POST")
+	s.router.HandleFunc("/cards/{id}/close", s.closeCard).Methods("POST")
+	s.router.HandleFunc("/cards/{id}/replace", s.replaceCard).Methods("POST")
+	s.router.HandleFunc("/cards/{id}/history", s.getCardHistory).Methods("GET")
+	s.router.HandleFunc("/cards/{id}/transactions", s.getCardTransactions).Methods("GET")
+
 	// Transaction routes
 	s.router.HandleFunc("/transactions", s.createTransaction).Methods("POST")
+	s.router.HandleFunc("/authorizations", s.createAuthorization).Methods("POST")
+	s.router.HandleFunc("/transactions/{id}/capture", s.captureTransaction).Methods("POST")
+	s.router.HandleFunc("/transactions/{id}/increment", s.incrementAuthorization).Methods("POST")
+	s.router.HandleFunc("/transactions/{id}/void", s.voidTransaction).Methods("POST")
+	s.router.HandleFunc("/transactions/{id}/refunds", s.createRefund).Methods("POST")
+	s.router.HandleFunc("/transactions/{id}/refunds", s.getRefunds).Methods("GET")
+	s.router.HandleFunc("/transactions/{id}/reversal", s.reverseTransaction).Methods("POST")
+	s.router.HandleFunc("/transactions/{id}/history", s.getTransactionHistory).Methods("GET")
 	s.router.HandleFunc("/users/{id}/transactions", s.getUserTransactions).Methods("GET")
 	s.router.HandleFunc("/users/{id}/summary", s.getUserSummary).Methods("GET")
+	s.router.HandleFunc("/users/{id}/spending", s.getUserSpending).Methods("GET")
+
+	// Review queue routes
+	s.router.HandleFunc("/reviews", s.listReviews).Methods("GET")
+	s.router.HandleFunc("/reviews/{id}", s.getReview).Methods("GET")
+	s.router.HandleFunc("/reviews/{id}/claim", s.claimReview).Methods("POST")
+	s.router.HandleFunc("/reviews/{id}/approve", s.approveReview).Methods("POST")
+	s.router.HandleFunc("/reviews/{id}/decline", s.declineReview).Methods("POST")
+
+	// Merchant routes
+	s.router.HandleFunc("/merchants", s.listMerchants).Methods("GET")
+	s.router.HandleFunc("/merchants/{id}", s.getMerchant).Methods("GET")
+	s.router.HandleFunc("/merchants/{id}", s.updateMerchant).Methods("PUT")
+	s.router.HandleFunc("/mccs", s.listMCCs).Methods("GET")
+	s.router.HandleFunc("/mccs/{code}", s.getMCC).Methods("GET")
+
+	// Category routes
+	s.router.HandleFunc("/users/{id}/categories", s.listCategories).Methods("GET")
+	s.router.HandleFunc("/users/{id}/categories", s.createCategory).Methods("POST")
+	s.router.HandleFunc("/categories/{id}", s.deleteCategory).Methods("DELETE")
+	s.router.HandleFunc("/users/{id}/category-rules", s.listCategoryRules).Methods("GET")
+	s.router.HandleFunc("/users/{id}/category-rules", s.createCategoryRule).Methods("POST")
+	s.router.HandleFunc("/users/{id}/category-rules/apply", s.applyCategoryRules).Methods("POST")
+	s.router.HandleFunc("/category-rules/{id}", s.updateCategoryRule).Methods("PUT")
+	s.router.HandleFunc("/category-rules/{id}", s.deleteCategoryRule).Methods("DELETE")
+	s.router.HandleFunc("/transactions/{id}/category", s.recategorizeTransaction).Methods("PUT")
+
+	// Budget routes
+	s.router.HandleFunc("/users/{id}/budgets", s.listBudgets).Methods("GET")
+	s.router.HandleFunc("/users/{id}/budgets", s.createBudget).Methods("POST")
+	s.router.HandleFunc("/users/{id}/budget-alerts", s.listBudgetAlerts).Methods("GET")
+	s.router.HandleFunc("/budgets/{id}", s.getBudget).Methods("GET")
+	s.router.HandleFunc("/budgets/{id}", s.updateBudget).Methods("PUT")
+	s.router.HandleFunc("/budgets/{id}", s.deleteBudget).Methods("DELETE")
 }
 
 // Start starts the HTTP server
@@ -116,7 +177,7 @@ func (s *Server) createUser(w http.ResponseWriter, r *http.Request) {
 		UpdatedAt: now,
 	}
 
-	if err := s.db.CreateUser(user); err != nil {
+	if err := s.store.CreateUser(r.Context(), user); err != nil {
 		s.logger.Error("Failed to create user", "error", err)
 		s.writeError(w, http.StatusInternalServerError, "Failed to create user")
 		return
@@ -130,22 +191,121 @@ func (s *Server) getUser(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	userID := vars["id"]
 
-	user, err := s.db.GetUser(userID)
+	user, err := s.store.GetUser(r.Context(), userID)
 	if err != nil {
 		s.logger.Error("Failed to get user", "error", err, "user_id", userID)
-		s.writeError(w, http.StatusNotFound, "User not found")
+		s.writeLedgerError(w, err, "Failed to get user")
 		return
 	}
 
 	s.writeJSON(w, http.StatusOK, user)
 }
 
+// Get user balance endpoint
+func (s *Server) getUserBalance(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	userID := vars["id"]
+
+	balance, err := s.ledgerService.GetUserBalance(r.Context(), userID, r.URL.Query().Get("currency"))
+	if err != nil {
+		s.logger.Error("Failed to get user balance", "error", err, "user_id", userID)
+		s.writeLedgerError(w, err, "Failed to get balance")
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, balance)
+}
+
+// Set overdraft limit endpoint
+func (s *Server) setOverdraftLimit(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	userID := vars["id"]
+
+	var req struct {
+		OverdraftLimit int64  `json:"overdraft_limit"`
+		Currency       string `json:"currency"` // defaults to the base currency
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if req.OverdraftLimit < 0 {
+		s.writeError(w, http.StatusBadRequest, "Overdraft limit cannot be negative")
+		return
+	}
+
+	if _, err := s.store.GetUser(r.Context(), userID); err != nil {
+		s.logger.Error("Failed to get user", "error", err, "user_id", userID)
+		s.writeLedgerError(w, err, "Failed to get user")
+		return
+	}
+
+	balance, err := s.ledgerService.SetOverdraftLimit(r.Context(), userID, req.Currency, req.OverdraftLimit)
+	if err != nil {
+		s.logger.Error("Failed to set overdraft limit", "error", err, "user_id", userID)
+		s.writeError(w, http.StatusInternalServerError, "Failed to set overdraft limit")
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, balance)
+}
+
+// Get user limits endpoint
+func (s *Server) getUserLimits(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	userID := vars["id"]
+
+	limits, err := s.ledgerService.GetUserLimits(r.Context(), userID)
+	if err != nil {
+		s.logger.Error("Failed to get user limits", "error", err, "user_id", userID)
+		s.writeLedgerError(w, err, "Failed to get user limits")
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, limits)
+}
+
+// Set user limits endpoint
+func (s *Server) setUserLimits(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	userID := vars["id"]
+
+	var limits models.SpendingLimits
+	if err := json.NewDecoder(r.Body).Decode(&limits); err != nil {
+		s.writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := ledger.ValidateSpendingLimits(&limits); err != nil {
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if _, err := s.store.GetUser(r.Context(), userID); err != nil {
+		s.logger.Error("Failed to get user", "error", err, "user_id", userID)
+		s.writeLedgerError(w, err, "Failed to get user")
+		return
+	}
+
+	updated, err := s.ledgerService.SetUserLimits(r.Context(), userID, &limits)
+	if err != nil {
+		s.logger.Error("Failed to set user limits", "error", err, "user_id", userID)
+		s.writeLedgerError(w, err, "Failed to set user limits")
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, updated)
+}
+
 // Create card endpoint
 func (s *Server) createCard(w http.ResponseWriter, r *http.Request) {
 	var req struct {
 		UserID     string `json:"user_id"`
 		CardNumber string `json:"card_number"`
-		CardType   string `json:"card_type"`
+		CardType   string `json:"card_type"` // optional, derived from the card number
+		Currency   string `json:"currency"`  // optional ISO 4217 billing currency
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -153,23 +313,22 @@ func (s *Server) createCard(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if req.UserID == "" || req.CardNumber == "" || req.CardType == "" {
-		s.writeError(w, http.StatusBadRequest, "All fields are required")
+	if req.UserID == "" || req.CardNumber == "" {
+		s.writeError(w, http.StatusBadRequest, "User ID and card number are required")
 		return
 	}
 
-	card := &models.Card{
-		ID:         uuid.New().String(),
-		UserID:     req.UserID,
-		CardNumber: req.CardNumber,
-		CardType:   req.CardType,
-		IsActive:   true,
-		CreatedAt:  time.Now(),
+	if req.Currency != "" {
+		if _, err := money.LookupCurrency(req.Currency); err != nil {
+			s.writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
 	}
 
-	if err := s.db.CreateCard(card); err != nil {
+	card, err := s.ledgerService.RegisterCard(r.Context(), req.UserID, req.CardNumber, req.CardType, req.Currency)
+	if err != nil {
 		s.logger.Error("Failed to create card", "error", err)
-		s.writeError(w, http.StatusInternalServerError, "Failed to create card")
+		s.writeLedgerError(w, err, "Failed to create card")
 		return
 	}
 
@@ -179,64 +338,183 @@ func (s *Server) createCard(w http.ResponseWriter, r *http.Request) {
 // Get card endpoint
 func (s *Server) getCard(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
-	cardNumber := vars["cardNumber"]
+	token := vars["token"]
 
-	card, err := s.db.GetCardByNumber(cardNumber)
+	card, err := s.ledgerService.GetCardByToken(r.Context(), token)
 	if err != nil {
-		s.logger.Error("Failed to get card", "error", err, "card_number", cardNumber)
-		s.writeError(w, http.StatusNotFound, "Card not found")
+		s.logger.Error("Failed to get card", "error", err, "card_token", token)
+		s.writeLedgerError(w, err, "Failed to get card")
 		return
 	}
 
 	s.writeJSON(w, http.StatusOK, card)
 }
 
-// Create transaction endpoint
-func (s *Server) createTransaction(w http.ResponseWriter, r *http.Request) {
-	var payload models.CardPayload
+// cardStatusRequest is the optional body of the card lifecycle endpoints
+type cardStatusRequest struct {
+	Status     string `json:"status"`      // lost or stolen, for reports
+	CardNumber string `json:"card_number"` // new card number, for replacements
+	Reason     string `json:"reason"`
+}
 
-	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
-		s.writeError(w, http.StatusBadRequest, "Invalid request body")
+// decodeCardStatusRequest reads an optional card lifecycle request, writing a 400 response if it is invalid
+func (s *Server) decodeCardStatusRequest(w http.ResponseWriter, r *http.Request) (*cardStatusRequest, bool) {
+	var req cardStatusRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			s.writeError(w, http.StatusBadRequest, "Invalid request body")
+			return nil, false
+		}
+	}
+	return &req, true
+}
+
+// Freeze card endpoint
+func (s *Server) freezeCard(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	cardID := vars["id"]
+
+	req, ok := s.decodeCardStatusRequest(w, r)
+	if !ok {
 		return
 	}
 
-	transaction, err := s.ledgerService.ProcessCardPayload(payload)
+	card, err := s.ledgerService.FreezeCard(r.Context(), cardID, req.Reason)
 	if err != nil {
-		s.logger.Error("Failed to process transaction", "error", err)
-		s.writeError(w, http.StatusInternalServerError, "Failed to process transaction")
+		s.logger.Error("Failed to freeze card", "error", err, "card_id", cardID)
+		s.writeLedgerError(w, err, "Failed to freeze card")
 		return
 	}
 
-	s.writeJSON(w, http.StatusCreated, transaction)
+	s.writeJSON(w, http.StatusOK, card)
 }
 
-// Get user transactions endpoint
-func (s *Server) getUserTransactions(w http.ResponseWriter, r *http.Request) {
+// Unfreeze card endpoint
+func (s *Server) unfreezeCard(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
-	userID := vars["id"]
+	cardID := vars["id"]
 
-	// Parse query parameters
-	limitStr := r.URL.Query().Get("limit")
-	offsetStr := r.URL.Query().Get("offset")
+	req, ok := s.decodeCardStatusRequest(w, r)
+	if !ok {
+		return
+	}
+
+	card, err := s.ledgerService.UnfreezeCard(r.Context(), cardID, req.Reason)
+	if err != nil {
+		s.logger.Error("Failed to unfreeze card", "error", err, "card_id", cardID)
+		s.writeLedgerError(w, err, "Failed to unfreeze card")
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, card)
+}
+
+// Report lost or stolen card endpoint
+func (s *Server) reportCard(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	cardID := vars["id"]
+
+	req, ok := s.decodeCardStatusRequest(w, r)
+	if !ok {
+		return
+	}
+
+	if req.Status != models.CardStatusLost && req.Status != models.CardStatusStolen {
+		s.writeError(w, http.StatusBadRequest, "Status must be lost or stolen")
+		return
+	}
+
+	card, err := s.ledgerService.ReportCard(r.Context(), cardID, req.Status, req.Reason)
+	if err != nil {
+		s.logger.Error("Failed to report card", "error", err, "card_id", cardID)
+		s.writeLedgerError(w, err, "Failed to report card")
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, card)
+}
+
+// Close card endpoint
+func (s *Server) closeCard(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	cardID := vars["id"]
+
+	req, ok := s.decodeCardStatusRequest(w, r)
+	if !ok {
+		return
+	}
+
+	card, err := s.ledgerService.CloseCard(r.Context(), cardID, req.Reason)
+	if err != nil {
+		s.logger.Error("Failed to close card", "error", err, "card_id", cardID)
+		s.writeLedgerError(w, err, "Failed to close card")
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, card)
+}
+
+// Replace card endpoint
+func (s *Server) replaceCard(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	cardID := vars["id"]
+
+	req, ok := s.decodeCardStatusRequest(w, r)
+	if !ok {
+		return
+	}
+
+	if req.CardNumber == "" {
+		s.writeError(w, http.StatusBadRequest, "Card number is required")
+		return
+	}
+
+	card, err := s.ledgerService.ReplaceCard(r.Context(), cardID, req.CardNumber, req.Reason)
+	if err != nil {
+		s.logger.Error("Failed to replace card", "error", err, "card_id", cardID)
+		s.writeLedgerError(w, err, "Failed to replace card")
+		return
+	}
+
+	s.writeJSON(w, http.StatusCreated, card)
+}
+
+// Get card status history endpoint
+func (s *Server) getCardHistory(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	cardID := vars["id"]
+
+	history, err := s.ledgerService.GetCardHistory(r.Context(), cardID)
+	if err != nil {
+		s.logger.Error("Failed to get card history", "error", err, "card_id", cardID)
+		s.writeLedgerError(w, err, "Failed to get card history")
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"history": history,
+	})
+}
+
+// Get card transactions endpoint
+func (s *Server) getCardTransactions(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	cardID := vars["id"]
 
 	limit := 10 // default
-	if limitStr != "" {
-		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 {
-			limit = l
-		}
+	if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l > 0 {
+		limit = l
 	}
 
 	offset := 0 // default
-	if offsetStr != "" {
-		if o, err := strconv.Atoi(offsetStr); err == nil && o >= 0 {
-			offset = o
-		}
+	if o, err := strconv.Atoi(r.URL.Query().Get("offset")); err == nil && o >= 0 {
+		offset = o
 	}
 
-	transactions, err := s.ledgerService.GetUserTransactions(userID, limit, offset)
+	transactions, err := s.ledgerService.GetCardTransactions(r.Context(), cardID, limit, offset)
 	if err != nil {
-		s.logger.Error("Failed to get user transactions", "error", err, "user_id", userID)
-		s.writeError(w, http.StatusInternalServerError, "Failed to get transactions")
+		s.logger.Error("Failed to get card transactions", "error", err, "card_id", cardID)
+		s.writeLedgerError(w, err, "Failed to get transactions")
 		return
 	}
 
@@ -247,28 +525,980 @@ func (s *Server) getUserTransactions(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// Get user summary endpoint
-func (s *Server) getUserSummary(w http.ResponseWriter, r *http.Request) {
+// Get card limits endpoint
+func (s *Server) getCardLimits(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
-	userID := vars["id"]
+	cardID := vars["id"]
 
-	summary, err := s.ledgerService.GetUserSummary(userID)
+	limits, err := s.ledgerService.GetCardLimits(r.Context(), cardID)
 	if err != nil {
-		s.logger.Error("Failed to get user summary", "error", err, "user_id", userID)
-		s.writeError(w, http.StatusInternalServerError, "Failed to get summary")
+		s.logger.Error("Failed to get card limits", "error", err, "card_id", cardID)
+		s.writeLedgerError(w, err, "Failed to get card limits")
 		return
 	}
 
-	s.writeJSON(w, http.StatusOK, summary)
+	s.writeJSON(w, http.StatusOK, limits)
 }
 
-// Helper methods
-func (s *Server) writeJSON(w http.ResponseWriter, status int, data interface{}) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(status)
-	json.NewEncoder(w).Encode(data)
+// Set card limits endpoint
+func (s *Server) setCardLimits(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	cardID := vars["id"]
+
+	var limits models.SpendingLimits
+	if err := json.NewDecoder(r.Body).Decode(&limits); err != nil {
+		s.writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := ledger.ValidateSpendingLimits(&limits); err != nil {
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	updated, err := s.ledgerService.SetCardLimits(r.Context(), cardID, &limits)
+	if err != nil {
+		s.logger.Error("Failed to set card limits", "error", err, "card_id", cardID)
+		s.writeLedgerError(w, err, "Failed to set card limits")
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, updated)
 }
 
-func (s *Server) writeError(w http.ResponseWriter, status int, message string) {
-	s.writeJSON(w, status, map[string]string{"error": message})
+// Create transaction endpoint
+func (s *Server) createTransaction(w http.ResponseWriter, r *http.Request) {
+	var payload models.CardPayload
+
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		s.writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	key := r.Header.Get("Idempotency-Key")
+	transaction, replayed, err := s.ledgerService.ProcessCardPayloadIdempotent(r.Context(), key, payload)
+	if err != nil {
+		s.logger.Error("Failed to process transaction", "error", err)
+		s.writeLedgerError(w, err, "Failed to process transaction")
+		return
+	}
+
+	if replayed {
+		w.Header().Set("Idempotent-Replayed", "true")
+	}
+	s.writeJSON(w, http.StatusCreated, transaction)
+}
+
+// Create authorization endpoint
+func (s *Server) createAuthorization(w http.ResponseWriter, r *http.Request) {
+	var payload models.CardPayload
+
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		s.writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	transaction, err := s.ledgerService.Authorize(r.Context(), payload)
+	if err != nil {
+		s.logger.Error("Failed to authorize transaction", "error", err)
+		s.writeLedgerError(w, err, "Failed to authorize transaction")
+		return
+	}
+
+	s.writeJSON(w, http.StatusCreated, transaction)
+}
+
+// Capture authorization endpoint
+func (s *Server) captureTransaction(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	transactionID := vars["id"]
+
+	var req struct {
+		Amount int64 `json:"amount"` // zero captures the full authorized amount
+	}
+
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			s.writeError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+	}
+
+	transaction, err := s.ledgerService.Capture(r.Context(), transactionID, req.Amount)
+	if err != nil {
+		s.logger.Error("Failed to capture transaction", "error", err, "transaction_id", transactionID)
+		s.writeLedgerError(w, err, "Failed to capture transaction")
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, transaction)
+}
+
+// Incremental authorization endpoint
+func (s *Server) incrementAuthorization(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	transactionID := vars["id"]
+
+	var req struct {
+		Amount int64 `json:"amount"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if req.Amount <= 0 {
+		s.writeError(w, http.StatusBadRequest, "Amount must be positive")
+		return
+	}
+
+	transaction, err := s.ledgerService.IncrementAuthorization(r.Context(), transactionID, req.Amount)
+	if err != nil {
+		s.logger.Error("Failed to increment authorization", "error", err, "transaction_id", transactionID)
+		s.writeLedgerError(w, err, "Failed to increment authorization")
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, transaction)
+}
+
+// Void authorization endpoint
+func (s *Server) voidTransaction(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	transactionID := vars["id"]
+
+	transaction, err := s.ledgerService.Void(r.Context(), transactionID)
+	if err != nil {
+		s.logger.Error("Failed to void transaction", "error", err, "transaction_id", transactionID)
+		s.writeLedgerError(w, err, "Failed to void transaction")
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, transaction)
+}
+
+// Create refund endpoint
+func (s *Server) createRefund(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	transactionID := vars["id"]
+
+	var req struct {
+		Amount int64  `json:"amount"` // zero refunds the remaining amount
+		Reason string `json:"reason"`
+	}
+
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			s.writeError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+	}
+
+	if req.Amount < 0 {
+		s.writeError(w, http.StatusBadRequest, "Amount cannot be negative")
+		return
+	}
+
+	refund, err := s.ledgerService.Refund(r.Context(), transactionID, req.Amount, req.Reason)
+	if err != nil {
+		s.logger.Error("Failed to refund transaction", "error", err, "transaction_id", transactionID)
+		s.writeLedgerError(w, err, "Failed to refund transaction")
+		return
+	}
+
+	s.writeJSON(w, http.StatusCreated, refund)
+}
+
+// Get refunds endpoint
+func (s *Server) getRefunds(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	transactionID := vars["id"]
+
+	refunds, err := s.ledgerService.GetRefunds(r.Context(), transactionID)
+	if err != nil {
+		s.logger.Error("Failed to get refunds", "error", err, "transaction_id", transactionID)
+		s.writeLedgerError(w, err, "Failed to get refunds")
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"refunds": refunds,
+	})
+}
+
+// Reverse transaction endpoint
+func (s *Server) reverseTransaction(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	transactionID := vars["id"]
+
+	var req struct {
+		Reason string `json:"reason"`
+	}
+
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			s.writeError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+	}
+
+	reversal, err := s.ledgerService.Reverse(r.Context(), transactionID, req.Reason)
+	if err != nil {
+		s.logger.Error("Failed to reverse transaction", "error", err, "transaction_id", transactionID)
+		s.writeLedgerError(w, err, "Failed to reverse transaction")
+		return
+	}
+
+	s.writeJSON(w, http.StatusCreated, reversal)
+}
+
+// Get transaction status history endpoint
+func (s *Server) getTransactionHistory(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	transactionID := vars["id"]
+
+	history, err := s.ledgerService.GetTransactionHistory(r.Context(), transactionID)
+	if err != nil {
+		s.logger.Error("Failed to get transaction history", "error", err, "transaction_id", transactionID)
+		s.writeLedgerError(w, err, "Failed to get transaction history")
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"history": history,
+	})
+}
+
+// Get user transactions endpoint
+func (s *Server) getUserTransactions(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	userID := vars["id"]
+
+	// Parse query parameters
+	limitStr := r.URL.Query().Get("limit")
+	offsetStr := r.URL.Query().Get("offset")
+
+	limit := 10 // default
+	if limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 {
+			limit = l
+		}
+	}
+
+	offset := 0 // default
+	if offsetStr != "" {
+		if o, err := strconv.Atoi(offsetStr); err == nil && o >= 0 {
+			offset = o
+		}
+	}
+
+	transactions, err := s.ledgerService.GetUserTransactions(r.Context(), userID, limit, offset)
+	if err != nil {
+		s.logger.Error("Failed to get user transactions", "error", err, "user_id", userID)
+		s.writeError(w, http.StatusInternalServerError, "Failed to get transactions")
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"transactions": transactions,
+		"limit":        limit,
+		"offset":       offset,
+	})
+}
+
+// Get user summary endpoint
+func (s *Server) getUserSummary(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	userID := vars["id"]
+
+	params := r.URL.Query()
+	query := ledger.SummaryQuery{
+		UserID:   userID,
+		Currency: params.Get("currency"),
+	}
+
+	// Both dates are inclusive
+	from, to, err := ledger.ParseDateRange(params.Get("from"), params.Get("to"))
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	query.From, query.To = from, to
+
+	if top := params.Get("top"); top != "" {
+		n, err := strconv.Atoi(top)
+		if err != nil {
+			s.writeError(w, http.StatusBadRequest, "Top must be a number")
+			return
+		}
+		query.TopMerchants = n
+	}
+
+	if err := ledger.ValidateSummaryQuery(&query); err != nil {
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	summary, err := s.ledgerService.GetUserSummary(r.Context(), &query)
+	if err != nil {
+		s.logger.Error("Failed to get user summary", "error", err, "user_id", userID)
+		s.writeLedgerError(w, err, "Failed to get summary")
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, summary)
+}
+
+// Get user spending endpoint
+func (s *Server) getUserSpending(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	userID := vars["id"]
+
+	params := r.URL.Query()
+	query := models.SpendingQuery{
+		UserID:   userID,
+		Interval: params.Get("interval"),
+		GroupBy:  params.Get("group_by"),
+		Currency: params.Get("currency"),
+	}
+
+	// Both dates are inclusive
+	from, to, err := ledger.ParseDateRange(params.Get("from"), params.Get("to"))
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	query.From, query.To = from, to
+
+	if err := ledger.ValidateSpendingQuery(&query); err != nil {
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	series, err := s.ledgerService.GetSpending(r.Context(), &query)
+	if err != nil {
+		s.logger.Error("Failed to get user spending", "error", err, "user_id", userID)
+		s.writeLedgerError(w, err, "Failed to get spending")
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, series)
+}
+
+// List reviews endpoint
+func (s *Server) listReviews(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+
+	limit := 50 // default
+	if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l > 0 {
+		limit = l
+	}
+
+	offset := 0 // default
+	if o, err := strconv.Atoi(r.URL.Query().Get("offset")); err == nil && o >= 0 {
+		offset = o
+	}
+
+	reviews, err := s.ledgerService.ListReviews(r.Context(), status, limit, offset)
+	if err != nil {
+		s.logger.Error("Failed to list reviews", "error", err)
+		s.writeError(w, http.StatusInternalServerError, "Failed to list reviews")
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"reviews": reviews,
+		"limit":   limit,
+		"offset":  offset,
+	})
+}
+
+// Get review endpoint
+func (s *Server) getReview(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	reviewID := vars["id"]
+
+	review, err := s.ledgerService.GetReview(r.Context(), reviewID)
+	if err != nil {
+		s.logger.Error("Failed to get review", "error", err, "review_id", reviewID)
+		s.writeLedgerError(w, err, "Failed to get review")
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, review)
+}
+
+// reviewRequest is the body of the review claim, approve and decline endpoints
+type reviewRequest struct {
+	Analyst string `json:"analyst"`
+	Notes   string `json:"notes"`
+}
+
+// decodeReviewRequest reads a review request, writing a 400 response if it is invalid
+func (s *Server) decodeReviewRequest(w http.ResponseWriter, r *http.Request) (*reviewRequest, bool) {
+	var req reviewRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeError(w, http.StatusBadRequest, "Invalid request body")
+		return nil, false
+	}
+
+	if req.Analyst == "" {
+		s.writeError(w, http.StatusBadRequest, "Analyst is required")
+		return nil, false
+	}
+
+	return &req, true
+}
+
+// Claim review endpoint
+func (s *Server) claimReview(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	reviewID := vars["id"]
+
+	req, ok := s.decodeReviewRequest(w, r)
+	if !ok {
+		return
+	}
+
+	review, err := s.ledgerService.ClaimReview(r.Context(), reviewID, req.Analyst)
+	if err != nil {
+		s.logger.Error("Failed to claim review", "error", err, "review_id", reviewID)
+		s.writeLedgerError(w, err, "Failed to claim review")
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, review)
+}
+
+// Approve review endpoint
+func (s *Server) approveReview(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	reviewID := vars["id"]
+
+	req, ok := s.decodeReviewRequest(w, r)
+	if !ok {
+		return
+	}
+
+	review, err := s.ledgerService.ApproveReview(r.Context(), reviewID, req.Analyst, req.Notes)
+	if err != nil {
+		s.logger.Error("Failed to approve review", "error", err, "review_id", reviewID)
+		s.writeLedgerError(w, err, "Failed to approve review")
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, review)
+}
+
+// Decline review endpoint
+func (s *Server) declineReview(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	reviewID := vars["id"]
+
+	req, ok := s.decodeReviewRequest(w, r)
+	if !ok {
+		return
+	}
+
+	review, err := s.ledgerService.DeclineReview(r.Context(), reviewID, req.Analyst, req.Notes)
+	if err != nil {
+		s.logger.Error("Failed to decline review", "error", err, "review_id", reviewID)
+		s.writeLedgerError(w, err, "Failed to decline review")
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, review)
+}
+
+// List merchants endpoint
+func (s *Server) listMerchants(w http.ResponseWriter, r *http.Request) {
+	limit := 50 // default
+	if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l > 0 {
+		limit = l
+	}
+
+	offset := 0 // default
+	if o, err := strconv.Atoi(r.URL.Query().Get("offset")); err == nil && o >= 0 {
+		offset = o
+	}
+
+	merchants, err := s.ledgerService.ListMerchants(r.Context(), limit, offset)
+	if err != nil {
+		s.logger.Error("Failed to list merchants", "error", err)
+		s.writeError(w, http.StatusInternalServerError, "Failed to list merchants")
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"merchants": merchants,
+		"limit":     limit,
+		"offset":    offset,
+	})
+}
+
+// Get merchant endpoint
+func (s *Server) getMerchant(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	merchantID := vars["id"]
+
+	found, err := s.ledgerService.GetMerchant(r.Context(), merchantID)
+	if err != nil {
+		s.logger.Error("Failed to get merchant", "error", err, "merchant_id", merchantID)
+		s.writeLedgerError(w, err, "Failed to get merchant")
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, found)
+}
+
+// Update merchant endpoint
+func (s *Server) updateMerchant(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	merchantID := vars["id"]
+
+	var req models.Merchant
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := ledger.ValidateMerchant(&req); err != nil {
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	updated, err := s.ledgerService.UpdateMerchant(r.Context(), merchantID, &req)
+	if err != nil {
+		s.logger.Error("Failed to update merchant", "error", err, "merchant_id", merchantID)
+		s.writeLedgerError(w, err, "Failed to update merchant")
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, updated)
+}
+
+// List merchant category codes endpoint
+func (s *Server) listMCCs(w http.ResponseWriter, r *http.Request) {
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"mccs": merchant.MCCs(),
+	})
+}
+
+// Get merchant category code endpoint
+func (s *Server) getMCC(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	mcc, err := merchant.LookupMCC(vars["code"])
+	if err != nil {
+		s.writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, mcc)
+}
+
+// List categories endpoint
+func (s *Server) listCategories(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	userID := vars["id"]
+
+	categories, err := s.ledgerService.ListCategories(r.Context(), userID)
+	if err != nil {
+		s.logger.Error("Failed to list categories", "error", err, "user_id", userID)
+		s.writeLedgerError(w, err, "Failed to list categories")
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"categories": categories,
+	})
+}
+
+// Create category endpoint
+func (s *Server) createCategory(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	userID := vars["id"]
+
+	var req models.Category
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := ledger.ValidateCategory(&req); err != nil {
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	category, err := s.ledgerService.CreateCategory(r.Context(), userID, &req)
+	if err != nil {
+		s.logger.Error("Failed to create category", "error", err, "user_id", userID)
+		s.writeLedgerError(w, err, "Failed to create category")
+		return
+	}
+
+	s.writeJSON(w, http.StatusCreated, category)
+}
+
+// Delete category endpoint
+func (s *Server) deleteCategory(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	categoryID := vars["id"]
+
+	if err := s.ledgerService.DeleteCategory(r.Context(), categoryID); err != nil {
+		s.logger.Error("Failed to delete category", "error", err, "category_id", categoryID)
+		s.writeLedgerError(w, err, "Failed to delete category")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// List category rules endpoint
+func (s *Server) listCategoryRules(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	userID := vars["id"]
+
+	rules, err := s.ledgerService.ListCategoryRules(r.Context(), userID)
+	if err != nil {
+		s.logger.Error("Failed to list category rules", "error", err, "user_id", userID)
+		s.writeLedgerError(w, err, "Failed to list category rules")
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"rules": rules,
+	})
+}
+
+// Create category rule endpoint
+func (s *Server) createCategoryRule(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	userID := vars["id"]
+
+	var req models.CategoryRule
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := ledger.ValidateCategoryRule(&req); err != nil {
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	rule, err := s.ledgerService.CreateCategoryRule(r.Context(), userID, &req)
+	if err != nil {
+		s.logger.Error("Failed to create category rule", "error", err, "user_id", userID)
+		s.writeLedgerError(w, err, "Failed to create category rule")
+		return
+	}
+
+	s.writeJSON(w, http.StatusCreated, rule)
+}
+
+// Update category rule endpoint
+func (s *Server) updateCategoryRule(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	ruleID := vars["id"]
+
+	var req models.CategoryRule
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := ledger.ValidateCategoryRule(&req); err != nil {
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	rule, err := s.ledgerService.UpdateCategoryRule(r.Context(), ruleID, &req)
+	if err != nil {
+		s.logger.Error("Failed to update category rule", "error", err, "rule_id", ruleID)
+		s.writeLedgerError(w, err, "Failed to update category rule")
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, rule)
+}
+
+// Delete category rule endpoint
+func (s *Server) deleteCategoryRule(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	ruleID := vars["id"]
+
+	if err := s.ledgerService.DeleteCategoryRule(r.Context(), ruleID); err != nil {
+		s.logger.Error("Failed to delete category rule", "error", err, "rule_id", ruleID)
+		s.writeLedgerError(w, err, "Failed to delete category rule")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Apply category rules to past transactions endpoint
+func (s *Server) applyCategoryRules(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	userID := vars["id"]
+
+	recategorized, err := s.ledgerService.ApplyCategoryRules(r.Context(), userID)
+	if err != nil {
+		s.logger.Error("Failed to apply category rules", "error", err, "user_id", userID)
+		s.writeLedgerError(w, err, "Failed to apply category rules")
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"user_id":       userID,
+		"recategorized": recategorized,
+	})
+}
+
+// Recategorize transaction endpoint
+func (s *Server) recategorizeTransaction(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	transactionID := vars["id"]
+
+	var req struct {
+		Category string `json:"category"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if req.Category == "" {
+		s.writeError(w, http.StatusBadRequest, "Category is required")
+		return
+	}
+
+	transaction, err := s.ledgerService.RecategorizeTransaction(r.Context(), transactionID, req.Category)
+	if err != nil {
+		s.logger.Error("Failed to recategorize transaction", "error", err, "transaction_id", transactionID)
+		s.writeLedgerError(w, err, "Failed to recategorize transaction")
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, transaction)
+}
+
+// budgetDate reads the optional date query parameter selecting the budget period, defaulting to now
+func (s *Server) budgetDate(w http.ResponseWriter, r *http.Request) (time.Time, bool) {
+	dateStr := r.URL.Query().Get("date")
+	if dateStr == "" {
+		return time.Now(), true
+	}
+
+	date, err := time.Parse("2006-01-02", dateStr)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, "Date must be formatted as YYYY-MM-DD")
+		return time.Time{}, false
+	}
+	return date, true
+}
+
+// List budgets endpoint
+func (s *Server) listBudgets(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	userID := vars["id"]
+
+	date, ok := s.budgetDate(w, r)
+	if !ok {
+		return
+	}
+
+	budgets, err := s.ledgerService.ListBudgets(r.Context(), userID, date)
+	if err != nil {
+		s.logger.Error("Failed to list budgets", "error", err, "user_id", userID)
+		s.writeLedgerError(w, err, "Failed to list budgets")
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"budgets": budgets,
+	})
+}
+
+// Create budget endpoint
+func (s *Server) createBudget(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	userID := vars["id"]
+
+	var req models.Budget
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := ledger.ValidateBudget(&req); err != nil {
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	budget, err := s.ledgerService.CreateBudget(r.Context(), userID, &req)
+	if err != nil {
+		s.logger.Error("Failed to create budget", "error", err, "user_id", userID)
+		s.writeLedgerError(w, err, "Failed to create budget")
+		return
+	}
+
+	s.writeJSON(w, http.StatusCreated, budget)
+}
+
+// Get budget endpoint
+func (s *Server) getBudget(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	budgetID := vars["id"]
+
+	date, ok := s.budgetDate(w, r)
+	if !ok {
+		return
+	}
+
+	progress, err := s.ledgerService.GetBudget(r.Context(), budgetID, date)
+	if err != nil {
+		s.logger.Error("Failed to get budget", "error", err, "budget_id", budgetID)
+		s.writeLedgerError(w, err, "Failed to get budget")
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, progress)
+}
+
+// Update budget endpoint
+func (s *Server) updateBudget(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	budgetID := vars["id"]
+
+	var req models.Budget
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if req.Amount <= 0 {
+		s.writeError(w, http.StatusBadRequest, "Amount must be positive")
+		return
+	}
+
+	budget, err := s.ledgerService.UpdateBudget(r.Context(), budgetID, &req)
+	if err != nil {
+		s.logger.Error("Failed to update budget", "error", err, "budget_id", budgetID)
+		s.writeLedgerError(w, err, "Failed to update budget")
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, budget)
+}
+
+// Delete budget endpoint
+func (s *Server) deleteBudget(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	budgetID := vars["id"]
+
+	if err := s.ledgerService.DeleteBudget(r.Context(), budgetID); err != nil {
+		s.logger.Error("Failed to delete budget", "error", err, "budget_id", budgetID)
+		s.writeLedgerError(w, err, "Failed to delete budget")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// List budget alerts endpoint
+func (s *Server) listBudgetAlerts(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	userID := vars["id"]
+
+	limit := 50 // default
+	if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l > 0 {
+		limit = l
+	}
+
+	offset := 0 // default
+	if o, err := strconv.Atoi(r.URL.Query().Get("offset")); err == nil && o >= 0 {
+		offset = o
+	}
+
+	alerts, err := s.ledgerService.ListBudgetAlerts(r.Context(), userID, limit, offset)
+	if err != nil {
+		s.logger.Error("Failed to list budget alerts", "error", err, "user_id", userID)
+		s.writeLedgerError(w, err, "Failed to list budget alerts")
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"alerts": alerts,
+		"limit":  limit,
+		"offset": offset,
+	})
+}
+
+// Helper methods
+func (s *Server) writeJSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(data)
+}
+
+func (s *Server) writeError(w http.ResponseWriter, status int, message string) {
+	s.writeJSON(w, status, map[string]string{"error": message})
+}
+
+// writeLedgerError maps ledger and storage errors to HTTP status codes
+func (s *Server) writeLedgerError(w http.ResponseWriter, err error, message string) {
+	var validation *cardnet.ValidationError
+	switch {
+	case errors.As(err, &validation):
+		s.writeJSON(w, http.StatusBadRequest, map[string]string{
+			"error": validation.Error(),
+			"field": validation.Field,
+			"code":  validation.Code,
+		})
+	case errors.Is(err, models.ErrNotFound):
+		s.writeError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, vault.ErrInvalidCardNumber):
+		s.writeError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, ledger.ErrInvalidTransactionState), errors.Is(err, ledger.ErrIllegalTransition),
+		errors.Is(err, models.ErrHoldNotActive), errors.Is(err, models.ErrStatusConflict),
+		errors.Is(err, models.ErrReviewClosed), errors.Is(err, models.ErrReviewClaimed),
+		errors.Is(err, ledger.ErrIllegalCardTransition), errors.Is(err, models.ErrCardStatusConflict),
+		errors.Is(err, ledger.ErrAuthorizationExpired):
+		s.writeError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, ledger.ErrCaptureExceedsAuthorization), errors.Is(err, models.ErrRefundLimitExceeded):
+		s.writeError(w, http.StatusUnprocessableEntity, err.Error())
+	case errors.Is(err, ledger.ErrIdempotencyInProgress), errors.Is(err, models.ErrCategoryExists),
+		errors.Is(err, models.ErrCategoryInUse), errors.Is(err, models.ErrBudgetExists):
+		s.writeError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, ledger.ErrIdempotencyKeyReused):
+		s.writeError(w, http.StatusUnprocessableEntity, err.Error())
+	case errors.Is(err, money.ErrNoRate), errors.Is(err, ledger.ErrUnknownCategory):
+		s.writeError(w, http.StatusUnprocessableEntity, err.Error())
+	case errors.Is(err, models.ErrInsufficientFunds):
+		s.writeError(w, http.StatusPaymentRequired, "Insufficient funds")
+	case errors.Is(err, ledger.ErrIncrementDeclined):
+		s.writeError(w, http.StatusPaymentRequired, err.Error())
+	case errors.Is(err, context.DeadlineExceeded):
+		s.writeError(w, http.StatusGatewayTimeout, "Request timed out")
+	default:
+		s.writeError(w, http.StatusInternalServerError, message)
+	}
+}
+
+// withRequestTimeout gives each request the configured deadline. Handlers pass the request's
+// context down to the ledger and database, so the deadline or a client disconnect cancels
+// their queries.
+func (s *Server) withRequestTimeout(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.requestTimeout <= 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), s.requestTimeout)
+		defer cancel()
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
 }
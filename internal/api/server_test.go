@@ -0,0 +1,255 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/araesf/ledgertime/internal/config"
+	"github.com/araesf/ledgertime/internal/ledger"
+	"github.com/araesf/ledgertime/internal/memory"
+	"github.com/araesf/ledgertime/internal/models"
+	"github.com/araesf/ledgertime/internal/vault"
+	"github.com/araesf/ledgertime/pkg/logger"
+)
+
+func newTestServer(t *testing.T) *Server {
+	t.Helper()
+
+	cardVault, err := vault.New("test", make([]byte, 32))
+	if err != nil {
+		t.Fatalf("failed to create vault: %v", err)
+	}
+
+	cfg := &config.Config{
+		Server: config.ServerConfig{RequestTimeout: 5 * time.Second},
+		Ledger: config.LedgerConfig{
+			BaseCurrency:          "USD",
+			BINRangesFile:         "../../data/bin_ranges.json",
+			DefaultOverdraftLimit: 100000,
+			AuthorizationTTL:      time.Hour,
+			ReviewTimeout:         time.Hour,
+			CardValidity:          365 * 24 * time.Hour,
+		},
+	}
+
+	log := logger.NewLoggerWithLevel("error")
+	store := memory.NewStore()
+	ledgerService, err := ledger.NewService(cfg.Ledger, store, &ledger.LimitProcessor{}, cardVault, log)
+	if err != nil {
+		t.Fatalf("failed to create service: %v", err)
+	}
+	return NewServer(cfg, store, ledgerService, log)
+}
+
+// do sends a request to the server, checks its status and decodes the response into out
+func do(t *testing.T, s *Server, method, path string, body interface{}, wantStatus int, out interface{}) {
+	t.Helper()
+
+	var payload bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&payload).Encode(body); err != nil {
+			t.Fatalf("failed to encode request: %v", err)
+		}
+	}
+
+	req := httptest.NewRequest(method, path, &payload)
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	s.router.ServeHTTP(rec, req)
+
+	if rec.Code != wantStatus {
+		t.Fatalf("%s %s = %d %s, want %d", method, path, rec.Code, strings.TrimSpace(rec.Body.String()), wantStatus)
+	}
+	if out != nil {
+		if err := json.Unmarshal(rec.Body.Bytes(), out); err != nil {
+			t.Fatalf("failed to decode %s %s response: %v", method, path, err)
+		}
+	}
+}
+
+// newTestCard creates a user with a card through the API
+func newTestCard(t *testing.T, s *Server) *models.Card {
+	t.Helper()
+
+	var user models.User
+	do(t, s, "POST", "/users", map[string]string{"name": "Test", "email": "test@example.com"}, http.StatusCreated, &user)
+
+	var card models.Card
+	do(t, s, "POST", "/cards", map[string]string{"user_id": user.ID, "card_number": "4012 8888 8888 1881"}, http.StatusCreated, &card)
+	if card.Token == "" || card.CardNumber != "**** 1881" {
+		t.Fatalf("card = token %q, number %q; want a token and a masked number", card.Token, card.CardNumber)
+	}
+	return &card
+}
+
+func payment(card *models.Card, amount int64, merchant string) models.CardPayload {
+	return models.CardPayload{
+		CardToken:    card.Token,
+		Amount:       amount,
+		Currency:     "USD",
+		MerchantName: merchant,
+		Timestamp:    time.Now().UTC().Format(time.RFC3339),
+	}
+}
+
+func TestIdempotentPayment(t *testing.T) {
+	s := newTestServer(t)
+	card := newTestCard(t, s)
+
+	send := func(amount int64, wantStatus int) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(payment(card, amount, "Bakery"))
+		req := httptest.NewRequest("POST", "/transactions", bytes.NewReader(body))
+		req.Header.Set("Idempotency-Key", "key-1")
+		rec := httptest.NewRecorder()
+		s.router.ServeHTTP(rec, req)
+		if rec.Code != wantStatus {
+			t.Fatalf("POST /transactions = %d %s, want %d", rec.Code, strings.TrimSpace(rec.Body.String()), wantStatus)
+		}
+		return rec
+	}
+
+	var first, second models.Transaction
+	rec := send(1200, http.StatusCreated)
+	if rec.Header().Get("Idempotent-Replayed") != "" {
+		t.Error("first request was marked as replayed")
+	}
+	json.Unmarshal(rec.Body.Bytes(), &first)
+
+	rec = send(1200, http.StatusCreated)
+	if rec.Header().Get("Idempotent-Replayed") != "true" {
+		t.Error("retried request was not marked as replayed")
+	}
+	json.Unmarshal(rec.Body.Bytes(), &second)
+	if first.ID == "" || second.ID != first.ID {
+		t.Errorf("replay returned transaction %q, want %q", second.ID, first.ID)
+	}
+
+	send(9900, http.StatusUnprocessableEntity)
+}
+
+func TestCardValidationErrors(t *testing.T) {
+	s := newTestServer(t)
+
+	var user models.User
+	do(t, s, "POST", "/users", map[string]string{"name": "Test", "email": "test@example.com"}, http.StatusCreated, &user)
+
+	var validation struct {
+		Field string `json:"field"`
+		Code  string `json:"code"`
+	}
+	do(t, s, "POST", "/cards", map[string]string{"user_id": user.ID, "card_number": "4012888888881882"}, http.StatusBadRequest, &validation)
+	if validation.Field != "card_number" || validation.Code != "checksum_failed" {
+		t.Errorf("validation error = %s/%s, want card_number/checksum_failed", validation.Field, validation.Code)
+	}
+
+	do(t, s, "GET", "/cards/tok_missing", nil, http.StatusNotFound, nil)
+}
+
+func TestMissingUserIsNotFound(t *testing.T) {
+	s := newTestServer(t)
+
+	do(t, s, "GET", "/users/missing", nil, http.StatusNotFound, nil)
+	do(t, s, "GET", "/users/missing/balance", nil, http.StatusNotFound, nil)
+	do(t, s, "PUT", "/users/missing/limits", map[string]int64{"daily": 1000}, http.StatusNotFound, nil)
+	do(t, s, "GET", "/users/missing/budgets", nil, http.StatusNotFound, nil)
+}
+
+func TestInvalidLimitsAreRejected(t *testing.T) {
+	s := newTestServer(t)
+	card := newTestCard(t, s)
+
+	var limits models.SpendingLimits
+	do(t, s, "PUT", "/cards/"+card.ID+"/limits", map[string]interface{}{
+		"daily":              5000,
+		"blocked_categories": []string{" Gambling "},
+	}, http.StatusOK, &limits)
+	if limits.Daily != 5000 || len(limits.BlockedCategories) != 1 || limits.BlockedCategories[0] != "gambling" {
+		t.Errorf("card limits = %+v, want a daily limit of 5000 blocking gambling", limits)
+	}
+
+	invalid := []map[string]interface{}{
+		{"daily": -1},
+		{"single_transaction": -100},
+		{"category_caps": []map[string]interface{}{{"category": " ", "amount": 1000}}},
+		{"category_caps": []map[string]interface{}{{"category": "dining", "amount": -1}}},
+	}
+	for _, body := range invalid {
+		do(t, s, "PUT", "/cards/"+card.ID+"/limits", body, http.StatusBadRequest, nil)
+		do(t, s, "PUT", "/users/"+card.UserID+"/limits", body, http.StatusBadRequest, nil)
+	}
+
+	// Rejected requests leave the stored limits alone
+	do(t, s, "GET", "/cards/"+card.ID+"/limits", nil, http.StatusOK, &limits)
+	if limits.Daily != 5000 {
+		t.Errorf("card daily limit = %d after rejected updates, want 5000", limits.Daily)
+	}
+	do(t, s, "GET", "/users/"+card.UserID+"/limits", nil, http.StatusNotFound, nil)
+}
+
+func TestCardNumberIsMasked(t *testing.T) {
+	s := newTestServer(t)
+	card := newTestCard(t, s)
+
+	req := httptest.NewRequest("GET", "/cards/"+card.Token, nil)
+	rec := httptest.NewRecorder()
+	s.router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /cards/%s = %d, want %d", card.Token, rec.Code, http.StatusOK)
+	}
+	if body := rec.Body.String(); strings.Contains(body, "4012888888881881") || !strings.Contains(body, "**** 1881") {
+		t.Errorf("card response %s, want only the masked number", strings.TrimSpace(body))
+	}
+}
+
+func TestWriteLedgerError(t *testing.T) {
+	s := newTestServer(t)
+	wrap := func(err error) error { return fmt.Errorf("failed to do something: %w", err) }
+
+	tests := []struct {
+		err    error
+		status int
+	}{
+		{wrap(models.ErrNotFound), http.StatusNotFound},
+		{wrap(vault.ErrInvalidCardNumber), http.StatusBadRequest},
+		{wrap(ledger.ErrInvalidTransactionState), http.StatusConflict},
+		{wrap(models.ErrReviewClaimed), http.StatusConflict},
+		{wrap(models.ErrReviewClosed), http.StatusConflict},
+		{wrap(ledger.ErrAuthorizationExpired), http.StatusConflict},
+		{wrap(ledger.ErrIdempotencyInProgress), http.StatusConflict},
+		{wrap(models.ErrCategoryInUse), http.StatusConflict},
+		{wrap(models.ErrBudgetExists), http.StatusConflict},
+		{wrap(ledger.ErrCaptureExceedsAuthorization), http.StatusUnprocessableEntity},
+		{wrap(models.ErrRefundLimitExceeded), http.StatusUnprocessableEntity},
+		{wrap(ledger.ErrIdempotencyKeyReused), http.StatusUnprocessableEntity},
+		{wrap(ledger.ErrUnknownCategory), http.StatusUnprocessableEntity},
+		{wrap(models.ErrInsufficientFunds), http.StatusPaymentRequired},
+		{wrap(ledger.ErrIncrementDeclined), http.StatusPaymentRequired},
+		{wrap(context.DeadlineExceeded), http.StatusGatewayTimeout},
+		{errors.New("pq: connection refused"), http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		s.writeLedgerError(rec, tt.err, "Failed to do something")
+		if rec.Code != tt.status {
+			t.Errorf("%v: status = %d, want %d", tt.err, rec.Code, tt.status)
+		}
+		if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+			t.Errorf("%v: Content-Type = %q, want application/json", tt.err, ct)
+		}
+	}
+
+	// Unexpected errors are reported with the handler's message, not the underlying error
+	rec := httptest.NewRecorder()
+	s.writeLedgerError(rec, errors.New("pq: connection refused"), "Failed to do something")
+	if strings.Contains(rec.Body.String(), "pq:") {
+		t.Errorf("internal error leaked: %s", strings.TrimSpace(rec.Body.String()))
+	}
+}
@@ -0,0 +1,109 @@
+package cardnet
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// BINRange maps card numbers whose leading digits fall between Low and High to a network.
+// Low and High have the same number of digits. Narrow ranges may carry issuer details.
+type BINRange struct {
+	Low     string `json:"low"`
+	High    string `json:"high"`
+	Network string `json:"network"`
+	Lengths []int  `json:"lengths"` // Allowed card number lengths
+	Issuer  string `json:"issuer,omitempty"`
+	Country string `json:"country,omitempty"` // ISO 3166-1 alpha-2 issuing country
+	Funding string `json:"funding,omitempty"` // credit, debit or prepaid
+}
+
+func (r *BINRange) matches(number string) bool {
+	if len(number) < len(r.Low) {
+		return false
+	}
+	prefix := number[:len(r.Low)]
+	return prefix >= r.Low && prefix <= r.High
+}
+
+func (r *BINRange) acceptsLength(n int) bool {
+	for _, length := range r.Lengths {
+		if length == n {
+			return true
+		}
+	}
+	return false
+}
+
+// BINTable resolves card numbers to networks and issuers
+type BINTable struct {
+	ranges []BINRange // sorted by prefix length descending, so the most specific range matches first
+}
+
+// NewBINTable builds a table from a list of ranges, validating their bounds
+func NewBINTable(ranges []BINRange) (*BINTable, error) {
+	table := &BINTable{}
+
+	for _, r := range ranges {
+		if r.High == "" {
+			r.High = r.Low
+		}
+		if r.Low == "" || len(r.Low) != len(r.High) || !isDigits(r.Low) || !isDigits(r.High) || r.Low > r.High {
+			return nil, fmt.Errorf("invalid BIN range: %q-%q", r.Low, r.High)
+		}
+		if r.Network == "" {
+			return nil, fmt.Errorf("BIN range %s-%s has no network", r.Low, r.High)
+		}
+		if len(r.Lengths) == 0 {
+			return nil, fmt.Errorf("BIN range %s-%s has no card lengths", r.Low, r.High)
+		}
+
+		r.Network = strings.ToLower(r.Network)
+		r.Country = strings.ToUpper(r.Country)
+		table.ranges = append(table.ranges, r)
+	}
+
+	sort.SliceStable(table.ranges, func(i, j int) bool {
+		return len(table.ranges[i].Low) > len(table.ranges[j].Low)
+	})
+
+	return table, nil
+}
+
+// LoadBINTable reads a JSON BIN file of the form {"ranges": [{"low", "high", "network", "lengths", ...}]}
+func LoadBINTable(path string) (*BINTable, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read BIN file: %w", err)
+	}
+
+	var file struct {
+		Ranges []BINRange `json:"ranges"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse BIN file: %w", err)
+	}
+
+	return NewBINTable(file.Ranges)
+}
+
+// Lookup returns the most specific range containing a card number, or nil if none does
+func (t *BINTable) Lookup(number string) *BINRange {
+	for i := range t.ranges {
+		if t.ranges[i].matches(number) {
+			return &t.ranges[i]
+		}
+	}
+	return nil
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,144 @@
+package cardnet
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/araesf/ledgertime/internal/vault"
+)
+
+// Card networks
+const (
+	NetworkVisa       = "visa"
+	NetworkMastercard = "mastercard"
+	NetworkAmex       = "amex"
+	NetworkDiscover   = "discover"
+	NetworkDiners     = "diners"
+	NetworkJCB        = "jcb"
+	NetworkUnionPay   = "unionpay"
+	NetworkMaestro    = "maestro"
+)
+
+// Validation error codes
+const (
+	CodeInvalidFormat   = "invalid_format"
+	CodeInvalidLength   = "invalid_length"
+	CodeChecksumFailed  = "checksum_failed"
+	CodeUnknownNetwork  = "unknown_network"
+	CodeNetworkMismatch = "network_mismatch"
+)
+
+// Card numbers are between 12 and 19 digits under ISO/IEC 7812
+const (
+	minLength = 12
+	maxLength = 19
+)
+
+// ValidationError describes why a card number or type was rejected
+type ValidationError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("invalid %s: %s", e.Field, e.Message)
+}
+
+// Extensions exposes the error's field and code to GraphQL clients
+func (e *ValidationError) Extensions() map[string]interface{} {
+	return map[string]interface{}{
+		"field": e.Field,
+		"code":  e.Code,
+	}
+}
+
+// Card is a validated card number with the network and issuer derived from its BIN
+type Card struct {
+	Number        string // Digits only
+	Network       string
+	Issuer        string
+	IssuerCountry string
+	Funding       string // credit, debit or prepaid, when known
+}
+
+// Luhn reports whether a string of digits passes the Luhn (mod 10) checksum
+func Luhn(digits string) bool {
+	sum := 0
+	double := false
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := int(digits[i] - '0')
+		if d < 0 || d > 9 {
+			return false
+		}
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+// Validate normalizes a card number, checks its length and checksum, and derives its network
+// from the BIN table. A non-empty cardType must match the derived network; cards whose BIN is
+// not in the table are only accepted when the caller names their network.
+func (t *BINTable) Validate(cardNumber, cardType string) (*Card, error) {
+	number, err := vault.Normalize(cardNumber)
+	if err != nil {
+		if errors.Is(err, vault.ErrInvalidCardNumber) {
+			return nil, &ValidationError{Field: "card_number", Code: CodeInvalidFormat, Message: "card number must contain only digits, spaces and dashes"}
+		}
+		return nil, err
+	}
+
+	if len(number) < minLength || len(number) > maxLength {
+		return nil, &ValidationError{
+			Field:   "card_number",
+			Code:    CodeInvalidLength,
+			Message: fmt.Sprintf("card number must have %d to %d digits, got: %d", minLength, maxLength, len(number)),
+		}
+	}
+
+	if !Luhn(number) {
+		return nil, &ValidationError{Field: "card_number", Code: CodeChecksumFailed, Message: "card number fails the Luhn checksum"}
+	}
+
+	cardType = strings.ToLower(strings.TrimSpace(cardType))
+
+	r := t.Lookup(number)
+	if r == nil {
+		if cardType == "" {
+			return nil, &ValidationError{Field: "card_number", Code: CodeUnknownNetwork, Message: "card network cannot be determined from the card number"}
+		}
+		return &Card{Number: number, Network: cardType}, nil
+	}
+
+	if !r.acceptsLength(len(number)) {
+		return nil, &ValidationError{
+			Field:   "card_number",
+			Code:    CodeInvalidLength,
+			Message: fmt.Sprintf("%s card numbers cannot have %d digits", r.Network, len(number)),
+		}
+	}
+
+	if cardType != "" && cardType != r.Network {
+		return nil, &ValidationError{
+			Field:   "card_type",
+			Code:    CodeNetworkMismatch,
+			Message: fmt.Sprintf("card number belongs to %s, not %s", r.Network, cardType),
+		}
+	}
+
+	return &Card{
+		Number:        number,
+		Network:       r.Network,
+		Issuer:        r.Issuer,
+		IssuerCountry: r.Country,
+		Funding:       r.Funding,
+	}, nil
+}
@@ -0,0 +1,92 @@
+package cardnet
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestLuhn(t *testing.T) {
+	tests := map[string]bool{
+		"4012888888881881": true,
+		"4111111111111111": true,
+		"5555555555554444": true,
+		"378282246310005":  true,
+		"0":                true,
+		"4012888888881882": false,
+		"4111111111111112": false,
+		"4111-1111":        false,
+		"":                 true,
+	}
+
+	for digits, want := range tests {
+		if got := Luhn(digits); got != want {
+			t.Errorf("Luhn(%q) = %v, want %v", digits, got, want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	table, err := NewBINTable([]BINRange{
+		{Low: "4", High: "4", Network: "VISA", Lengths: []int{13, 16, 19}},
+		{Low: "51", High: "55", Network: "mastercard", Lengths: []int{16}},
+		{Low: "34", Network: "amex", Lengths: []int{15}},
+		{Low: "401288", Network: "visa", Lengths: []int{16}, Issuer: "Test Bank", Country: "us", Funding: "credit"},
+	})
+	if err != nil {
+		t.Fatalf("failed to build BIN table: %v", err)
+	}
+
+	tests := []struct {
+		number, cardType string
+		network          string // Empty when the number is rejected
+		code             string
+	}{
+		{"4111 1111 1111 1111", "", NetworkVisa, ""},
+		{"4111-1111-1111-1111", "visa", NetworkVisa, ""},
+		{"5555555555554444", "", NetworkMastercard, ""},
+		{"341111111111111", "AMEX", NetworkAmex, ""},
+		{"6011111111111117", "discover", NetworkDiscover, ""},
+		{"4111 1111 1111 111a", "", "", CodeInvalidFormat},
+		{"41111111111", "", "", CodeInvalidLength},
+		{"41111111111111111111", "", "", CodeInvalidLength},
+		{"4111111111111112", "", "", CodeChecksumFailed},
+		{"6011111111111117", "", "", CodeUnknownNetwork},
+		{"5555555555554444", "visa", "", CodeNetworkMismatch},
+		{"55555555555544440", "", "", CodeInvalidLength},
+	}
+
+	for _, tt := range tests {
+		card, err := table.Validate(tt.number, tt.cardType)
+		if tt.network != "" {
+			if err != nil {
+				t.Errorf("Validate(%q, %q) = %v, want network %s", tt.number, tt.cardType, err, tt.network)
+			} else if card.Network != tt.network {
+				t.Errorf("Validate(%q, %q) network = %s, want %s", tt.number, tt.cardType, card.Network, tt.network)
+			}
+			continue
+		}
+
+		var validationErr *ValidationError
+		if !errors.As(err, &validationErr) || validationErr.Code != tt.code {
+			t.Errorf("Validate(%q, %q) = %v, want code %s", tt.number, tt.cardType, err, tt.code)
+		}
+	}
+}
+
+func TestValidateIssuer(t *testing.T) {
+	table, err := NewBINTable([]BINRange{
+		{Low: "4", Network: "visa", Lengths: []int{16}},
+		{Low: "401288", Network: "visa", Lengths: []int{16}, Issuer: "Test Bank", Country: "us", Funding: "credit"},
+	})
+	if err != nil {
+		t.Fatalf("failed to build BIN table: %v", err)
+	}
+
+	card, err := table.Validate("4012 8888 8888 1881", "")
+	if err != nil {
+		t.Fatalf("Validate() = %v", err)
+	}
+	if card.Number != "4012888888881881" || card.Issuer != "Test Bank" || card.IssuerCountry != "US" || card.Funding != "credit" {
+		t.Errorf("Validate() = %+v, want the most specific range's issuer", card)
+	}
+}
@@ -4,15 +4,20 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 )
 
 // Config holds all configuration for the application
 type Config struct {
-	Server   ServerConfig   `json:"server"`
-	Database DatabaseConfig `json:"database"`
-	Kafka    KafkaConfig    `json:"kafka"`
-	Logger   LoggerConfig   `json:"logger"`
+	Server    ServerConfig    `json:"server"`
+	Database  DatabaseConfig  `json:"database"`
+	Kafka     KafkaConfig     `json:"kafka"`
+	Ledger    LedgerConfig    `json:"ledger"`
+	Processor ProcessorConfig `json:"processor"`
+	Fraud     FraudConfig     `json:"fraud"`
+	Vault     VaultConfig     `json:"vault"`
+	Logger    LoggerConfig    `json:"logger"`
 }
 
 // ServerConfig holds HTTP server configuration
@@ -22,6 +27,9 @@ type ServerConfig struct {
 	ReadTimeout  time.Duration `json:"read_timeout"`
 	WriteTimeout time.Duration `json:"write_timeout"`
 	IdleTimeout  time.Duration `json:"idle_timeout"`
+	// RequestTimeout bounds the work done for one REST or GraphQL request, database queries
+	// included. Zero leaves requests without a deadline.
+	RequestTimeout time.Duration `json:"request_timeout"`
 }
 
 // DatabaseConfig holds database configuration
@@ -34,6 +42,11 @@ type DatabaseConfig struct {
 	SSLMode      string `json:"ssl_mode"`
 	MaxOpenConns int    `json:"max_open_conns"`
 	MaxIdleConns int    `json:"max_idle_conns"`
+	// IsolationLevel of units of work: read_committed, repeatable_read or serializable
+	IsolationLevel string `json:"isolation_level"`
+	// MaxTxRetries is how many times a unit of work is retried after a serialization failure
+	// or deadlock
+	MaxTxRetries int `json:"max_tx_retries"`
 }
 
 // KafkaConfig holds Kafka configuration
@@ -42,6 +55,51 @@ type KafkaConfig struct {
 	TransactionsTopic string   `json:"transactions_topic"`
 	ConsumerGroup     string   `json:"consumer_group"`
 	BatchSize         int      `json:"batch_size"`
+
+	// Topics the outbox relay publishes ledger events to, per aggregate
+	TransactionEventsTopic string        `json:"transaction_events_topic"`
+	CardEventsTopic        string        `json:"card_events_topic"`
+	OutboxPollInterval     time.Duration `json:"outbox_poll_interval"` // How often the relay looks for unsent events
+}
+
+// LedgerConfig holds ledger configuration
+type LedgerConfig struct {
+	BaseCurrency          string        `json:"base_currency"` // ISO 4217 currency of new cards and default wallets
+	FXRatesFile           string        `json:"fx_rates_file"`
+	BINRangesFile         string        `json:"bin_ranges_file"`         // Card number ranges used to derive card networks and issuers
+	DefaultOverdraftLimit int64         `json:"default_overdraft_limit"` // In minor units
+	AuthorizationTTL      time.Duration `json:"authorization_ttl"`
+	SweepInterval         time.Duration `json:"sweep_interval"`
+	ReviewTimeout         time.Duration `json:"review_timeout"` // Reviews left undecided this long are auto-declined
+	CardValidity          time.Duration `json:"card_validity"`  // How long new and replacement cards are valid
+}
+
+// ProcessorConfig holds transaction processor configuration
+type ProcessorConfig struct {
+	Chain                []string      `json:"chain"`      // Processors to run in order: fraud, limit, simulator, issuer
+	MaxAmount            int64         `json:"max_amount"` // Single transaction limit for the limit processor, in minor units of Currency
+	Currency             string        `json:"currency"`   // Currency of MaxAmount; transactions in other currencies are converted
+	SimulatorLatency     time.Duration `json:"simulator_latency"`
+	SimulatorFailureRate float64       `json:"simulator_failure_rate"`
+	SimulatorSeed        int64         `json:"simulator_seed"`
+}
+
+// FraudConfig holds fraud rule configuration. A zero window or threshold disables its rule.
+type FraudConfig struct {
+	ReviewScore       int           `json:"review_score"`  // Score at which a transaction needs manual review
+	DeclineScore      int           `json:"decline_score"` // Score at which a transaction is declined
+	VelocityWindow    time.Duration `json:"velocity_window"`
+	VelocityMaxCount  int           `json:"velocity_max_count"`
+	VelocityMaxAmount int64         `json:"velocity_max_amount"` // In minor units
+	NewMerchantAmount int64         `json:"new_merchant_amount"` // First purchases at a merchant at or above this are flagged
+	BlockedCategories []string      `json:"blocked_categories"`
+	TravelWindow      time.Duration `json:"travel_window"` // Minimum time between transactions in different countries
+	DuplicateWindow   time.Duration `json:"duplicate_window"`
+}
+
+// VaultConfig holds card tokenization vault configuration
+type VaultConfig struct {
+	KeyFile string `json:"key_file"` // JSON keyfile with the vault's master key; required
 }
 
 // LoggerConfig holds logging configuration
@@ -54,27 +112,66 @@ type LoggerConfig struct {
 func Load() (*Config, error) {
 	cfg := &Config{
 		Server: ServerConfig{
-			Port:         getEnv("SERVER_PORT", "8080"),
-			Host:         getEnv("SERVER_HOST", "0.0.0.0"),
-			ReadTimeout:  getDurationEnv("SERVER_READ_TIMEOUT", 15*time.Second),
-			WriteTimeout: getDurationEnv("SERVER_WRITE_TIMEOUT", 15*time.Second),
-			IdleTimeout:  getDurationEnv("SERVER_IDLE_TIMEOUT", 60*time.Second),
+			Port:           getEnv("SERVER_PORT", "8080"),
+			Host:           getEnv("SERVER_HOST", "0.0.0.0"),
+			ReadTimeout:    getDurationEnv("SERVER_READ_TIMEOUT", 15*time.Second),
+			WriteTimeout:   getDurationEnv("SERVER_WRITE_TIMEOUT", 15*time.Second),
+			IdleTimeout:    getDurationEnv("SERVER_IDLE_TIMEOUT", 60*time.Second),
+			RequestTimeout: getDurationEnv("SERVER_REQUEST_TIMEOUT", 10*time.Second),
 		},
 		Database: DatabaseConfig{
-			Host:         getEnv("DB_HOST", "localhost"),
-			Port:         getIntEnv("DB_PORT", 5432),
-			User:         getEnv("DB_USER", "postgres"),
-			Password:     getEnv("DB_PASSWORD", "password"),
-			Database:     getEnv("DB_NAME", "ledgertime"),
-			SSLMode:      getEnv("DB_SSL_MODE", "disable"),
-			MaxOpenConns: getIntEnv("DB_MAX_OPEN_CONNS", 25),
-			MaxIdleConns: getIntEnv("DB_MAX_IDLE_CONNS", 25),
+			Host:           getEnv("DB_HOST", "localhost"),
+			Port:           getIntEnv("DB_PORT", 5432),
+			User:           getEnv("DB_USER", "postgres"),
+			Password:       getEnv("DB_PASSWORD", "password"),
+			Database:       getEnv("DB_NAME", "ledgertime"),
+			SSLMode:        getEnv("DB_SSL_MODE", "disable"),
+			MaxOpenConns:   getIntEnv("DB_MAX_OPEN_CONNS", 25),
+			MaxIdleConns:   getIntEnv("DB_MAX_IDLE_CONNS", 25),
+			IsolationLevel: getEnv("DB_ISOLATION_LEVEL", "read_committed"),
+			MaxTxRetries:   getIntEnv("DB_MAX_TX_RETRIES", 3),
 		},
 		Kafka: KafkaConfig{
 			Brokers:           getSliceEnv("KAFKA_BROKERS", []string{"localhost:9092"}),
 			TransactionsTopic: getEnv("KAFKA_TRANSACTIONS_TOPIC", "card-transactions"),
 			ConsumerGroup:     getEnv("KAFKA_CONSUMER_GROUP", "ledger-consumer"),
 			BatchSize:         getIntEnv("KAFKA_BATCH_SIZE", 100),
+
+			TransactionEventsTopic: getEnv("KAFKA_TRANSACTION_EVENTS_TOPIC", "ledger-transaction-events"),
+			CardEventsTopic:        getEnv("KAFKA_CARD_EVENTS_TOPIC", "ledger-card-events"),
+			OutboxPollInterval:     getDurationEnv("KAFKA_OUTBOX_POLL_INTERVAL", time.Second),
+		},
+		Ledger: LedgerConfig{
+			BaseCurrency:          getEnv("LEDGER_BASE_CURRENCY", "USD"),
+			FXRatesFile:           getEnv("LEDGER_FX_RATES_FILE", "data/fx_rates.json"),
+			BINRangesFile:         getEnv("LEDGER_BIN_RANGES_FILE", "data/bin_ranges.json"),
+			DefaultOverdraftLimit: getInt64Env("LEDGER_DEFAULT_OVERDRAFT_LIMIT", 100000),
+			AuthorizationTTL:      getDurationEnv("LEDGER_AUTHORIZATION_TTL", 7*24*time.Hour),
+			SweepInterval:         getDurationEnv("LEDGER_SWEEP_INTERVAL", time.Minute),
+			ReviewTimeout:         getDurationEnv("LEDGER_REVIEW_TIMEOUT", 24*time.Hour),
+			CardValidity:          getDurationEnv("LEDGER_CARD_VALIDITY", 3*365*24*time.Hour),
+		},
+		Processor: ProcessorConfig{
+			Chain:                getSliceEnv("PROCESSOR_CHAIN", []string{"fraud", "limit"}),
+			MaxAmount:            getInt64Env("PROCESSOR_MAX_AMOUNT", 100000),
+			Currency:             getEnv("PROCESSOR_CURRENCY", getEnv("LEDGER_BASE_CURRENCY", "USD")),
+			SimulatorLatency:     getDurationEnv("PROCESSOR_SIMULATOR_LATENCY", 100*time.Millisecond),
+			SimulatorFailureRate: getFloatEnv("PROCESSOR_SIMULATOR_FAILURE_RATE", 0.05),
+			SimulatorSeed:        getInt64Env("PROCESSOR_SIMULATOR_SEED", 1),
+		},
+		Fraud: FraudConfig{
+			ReviewScore:       getIntEnv("FRAUD_REVIEW_SCORE", 50),
+			DeclineScore:      getIntEnv("FRAUD_DECLINE_SCORE", 90),
+			VelocityWindow:    getDurationEnv("FRAUD_VELOCITY_WINDOW", time.Hour),
+			VelocityMaxCount:  getIntEnv("FRAUD_VELOCITY_MAX_COUNT", 10),
+			VelocityMaxAmount: getInt64Env("FRAUD_VELOCITY_MAX_AMOUNT", 500000),
+			NewMerchantAmount: getInt64Env("FRAUD_NEW_MERCHANT_AMOUNT", 50000),
+			BlockedCategories: getSliceEnv("FRAUD_BLOCKED_CATEGORIES", nil),
+			TravelWindow:      getDurationEnv("FRAUD_TRAVEL_WINDOW", 2*time.Hour),
+			DuplicateWindow:   getDurationEnv("FRAUD_DUPLICATE_WINDOW", 5*time.Minute),
+		},
+		Vault: VaultConfig{
+			KeyFile: getEnv("VAULT_KEYFILE", ""),
 		},
 		Logger: LoggerConfig{
 			Level:  getEnv("LOG_LEVEL", "info"),
@@ -82,9 +179,22 @@ func Load() (*Config, error) {
 		},
 	}
 
+	if err := cfg.Ledger.Validate(); err != nil {
+		return nil, err
+	}
+
 	return cfg, nil
 }
 
+// Validate rejects ledger settings that contradict each other
+func (c *LedgerConfig) Validate() error {
+	// A review holds the transaction's funds, so it must be decided before an authorization would lapse
+	if c.ReviewTimeout > c.AuthorizationTTL {
+		return fmt.Errorf("review timeout %s exceeds authorization TTL %s", c.ReviewTimeout, c.AuthorizationTTL)
+	}
+	return nil
+}
+
 // GetDSN returns the database connection string
 func (d *DatabaseConfig) GetDSN() string {
 	return fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
@@ -108,6 +218,24 @@ func getIntEnv(key string, defaultValue int) int {
 	return defaultValue
 }
 
+func getInt64Env(key string, defaultValue int64) int64 {
+	if value := os.Getenv(key); value != "" {
+		if intValue, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return intValue
+		}
+	}
+	return defaultValue
+}
+
+func getFloatEnv(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if floatValue, err := strconv.ParseFloat(value, 64); err == nil {
+			return floatValue
+		}
+	}
+	return defaultValue
+}
+
 func getDurationEnv(key string, defaultValue time.Duration) time.Duration {
 	if value := os.Getenv(key); value != "" {
 		if duration, err := time.ParseDuration(value); err == nil {
@@ -120,7 +248,13 @@ func getDurationEnv(key string, defaultValue time.Duration) time.Duration {
 func getSliceEnv(key string, defaultValue []string) []string {
 	if value := os.Getenv(key); value != "" {
 		// Simple comma-separated parsing
-		return []string{value}
+		var values []string
+		for _, v := range strings.Split(value, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				values = append(values, v)
+			}
+		}
+		return values
 	}
 	return defaultValue
 }
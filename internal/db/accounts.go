@@ -0,0 +1,141 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/araesf/ledgertime/internal/models"
+	"github.com/google/uuid"
+)
+
+const accountColumns = `id, user_id, account_type, name, currency, ledger_balance, held_amount, overdraft_limit, created_at, updated_at`
+
+// Account operations
+func (db *DB) EnsureUserAccount(ctx context.Context, userID, currency string, overdraftLimit int64) (*models.Account, error) {
+	query := `
+		INSERT INTO accounts (id, user_id, account_type, name, currency, overdraft_limit, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $7)
+		ON CONFLICT (user_id, account_type, currency) WHERE user_id IS NOT NULL DO NOTHING`
+
+	name := fmt.Sprintf("User wallet (%s)", currency)
+	_, err := db.conn(ctx).ExecContext(ctx, query, uuid.New().String(), userID, models.AccountTypeUserWallet, name, currency, overdraftLimit, time.Now())
+	if err != nil {
+		db.logger.Error("Failed to create user account", "error", err, "user_id", userID)
+		return nil, fmt.Errorf("failed to create user account: %w", err)
+	}
+
+	query = `SELECT ` + accountColumns + ` FROM accounts WHERE user_id = $1 AND account_type = $2 AND currency = $3`
+
+	return scanAccount(db.conn(ctx).QueryRowContext(ctx, query, userID, models.AccountTypeUserWallet, currency))
+}
+
+// GetUserAccount returns a user's wallet in a currency without opening it
+func (db *DB) GetUserAccount(ctx context.Context, userID, currency string) (*models.Account, error) {
+	query := `SELECT ` + accountColumns + ` FROM accounts WHERE user_id = $1 AND account_type = $2 AND currency = $3`
+
+	account, err := scanAccount(db.conn(ctx).QueryRowContext(ctx, query, userID, models.AccountTypeUserWallet, currency))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("account not found: user %s, %s: %w", userID, currency, models.ErrNotFound)
+	}
+	return account, err
+}
+
+// EnsureSystemAccount returns the shared account of a type in a currency, opening it if needed
+func (db *DB) EnsureSystemAccount(ctx context.Context, accountType, currency string) (*models.Account, error) {
+	query := `
+		INSERT INTO accounts (id, user_id, account_type, name, currency, created_at, updated_at)
+		VALUES ($1, NULL, $2, $3, $4, $5, $5)
+		ON CONFLICT (account_type, currency) WHERE user_id IS NULL DO NOTHING`
+
+	name := fmt.Sprintf("System %s (%s)", accountType, currency)
+	_, err := db.conn(ctx).ExecContext(ctx, query, uuid.New().String(), accountType, name, currency, time.Now())
+	if err != nil {
+		db.logger.Error("Failed to create system account", "error", err, "account_type", accountType)
+		return nil, fmt.Errorf("failed to create system account: %w", err)
+	}
+
+	query = `SELECT ` + accountColumns + ` FROM accounts WHERE user_id IS NULL AND account_type = $1 AND currency = $2`
+
+	return scanAccount(db.conn(ctx).QueryRowContext(ctx, query, accountType, currency))
+}
+
+func (db *DB) SetOverdraftLimit(ctx context.Context, accountID string, limit int64) error {
+	query := `UPDATE accounts SET overdraft_limit = $2 WHERE id = $1`
+
+	result, err := db.conn(ctx).ExecContext(ctx, query, accountID, limit)
+	if err != nil {
+		return fmt.Errorf("failed to set overdraft limit: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("account not found: %s: %w", accountID, models.ErrNotFound)
+	}
+
+	db.logger.Info("Overdraft limit updated", "account_id", accountID, "limit", limit)
+	return nil
+}
+
+func scanAccount(row *sql.Row) (*models.Account, error) {
+	account := &models.Account{}
+	var userID sql.NullString
+	err := row.Scan(
+		&account.ID, &userID, &account.AccountType, &account.Name, &account.Currency,
+		&account.LedgerBalance, &account.HeldAmount, &account.OverdraftLimit,
+		&account.CreatedAt, &account.UpdatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to get account: %w", err)
+	}
+	account.UserID = userID.String
+	return account, nil
+}
+
+// Posting operations
+func (db *DB) CreatePostings(ctx context.Context, postings []*models.Posting) error {
+	tx, err := db.begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	insert := `
+		INSERT INTO postings (id, transaction_id, account_id, direction, amount, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)`
+
+	// Wallet debits may not push available balance past the overdraft limit
+	update := `
+		UPDATE accounts SET ledger_balance = ledger_balance + $2
+		WHERE id = $1
+		  AND ($2 >= 0 OR account_type <> 'user_wallet'
+		       OR ledger_balance - held_amount + overdraft_limit + $2 >= 0)`
+
+	for _, p := range postings {
+		if _, err := tx.ExecContext(ctx, insert, p.ID, p.TransactionID, p.AccountID, p.Direction, p.Amount, p.CreatedAt); err != nil {
+			db.logger.Error("Failed to create posting", "error", err, "transaction_id", p.TransactionID)
+			return fmt.Errorf("failed to create posting: %w", err)
+		}
+
+		delta := p.Amount
+		if p.Direction == models.PostingDirectionDebit {
+			delta = -delta
+		}
+
+		result, err := tx.ExecContext(ctx, update, p.AccountID, delta)
+		if err != nil {
+			return fmt.Errorf("failed to update account balance: %w", err)
+		}
+		if n, _ := result.RowsAffected(); n == 0 {
+			return fmt.Errorf("%w: account %s", models.ErrInsufficientFunds, p.AccountID)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit postings: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,94 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/araesf/ledgertime/internal/models"
+)
+
+// spendingGroupColumns maps spending groups to the rollup column they group by
+var spendingGroupColumns = map[string]string{
+	"":                           `''`,
+	models.SpendingGroupCategory: `category`,
+	models.SpendingGroupMerchant: `merchant_name`,
+	models.SpendingGroupCard:     `card_id`,
+}
+
+// GetSpending sums a user's net spend per interval and group between the UTC dates from and
+// to, ordered by interval and group. Intervals are keyed by their UTC start.
+func (db *DB) GetSpending(ctx context.Context, userID, currency, interval, groupBy string, from, to time.Time) ([]models.SpendingRow, error) {
+	group, ok := spendingGroupColumns[groupBy]
+	if !ok {
+		return nil, fmt.Errorf("unknown spending group: %s", groupBy)
+	}
+
+	query := `
+		SELECT date_trunc($1, day::timestamp) AS period_start, ` + group + ` AS grp, SUM(amount), SUM(count)
+		FROM spending_rollups
+		WHERE user_id = $2 AND currency = $3 AND day >= $4 AND day < $5
+		GROUP BY period_start, grp
+		HAVING SUM(amount) <> 0 OR SUM(count) <> 0
+		ORDER BY period_start, grp`
+
+	rows, err := db.conn(ctx).QueryContext(ctx, query, interval, userID, currency, rollupDay(from), rollupDay(to))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get spending: %w", err)
+	}
+	defer rows.Close()
+
+	var spending []models.SpendingRow
+	for rows.Next() {
+		var row models.SpendingRow
+		if err := rows.Scan(&row.PeriodStart, &row.Group, &row.Amount, &row.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan spending: %w", err)
+		}
+		row.PeriodStart = utcDate(row.PeriodStart)
+		spending = append(spending, row)
+	}
+
+	return spending, nil
+}
+
+// GetSpendingTotals sums a user's net spend per group between the UTC dates from and to,
+// largest first. A zero from or to leaves that end of the range open and a zero limit returns
+// every group.
+func (db *DB) GetSpendingTotals(ctx context.Context, userID, currency, groupBy string, from, to time.Time, limit int) ([]models.SpendingTotal, error) {
+	group, ok := spendingGroupColumns[groupBy]
+	if !ok {
+		return nil, fmt.Errorf("unknown spending group: %s", groupBy)
+	}
+
+	query := `
+		SELECT ` + group + ` AS grp, SUM(amount) AS total, SUM(count)
+		FROM spending_rollups
+		WHERE user_id = $1 AND currency = $2
+			AND ($3::date IS NULL OR day >= $3) AND ($4::date IS NULL OR day < $4)
+		GROUP BY grp
+		HAVING SUM(amount) <> 0 OR SUM(count) <> 0
+		ORDER BY total DESC, grp
+		LIMIT NULLIF($5, 0)`
+
+	rows, err := db.conn(ctx).QueryContext(ctx, query, userID, currency, nullRollupDay(from), nullRollupDay(to), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get spending totals: %w", err)
+	}
+	defer rows.Close()
+
+	var totals []models.SpendingTotal
+	for rows.Next() {
+		var total models.SpendingTotal
+		if err := rows.Scan(&total.Group, &total.Amount, &total.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan spending total: %w", err)
+		}
+		totals = append(totals, total)
+	}
+
+	return totals, nil
+}
+
+// utcDate reads the date of a truncated timestamp without time zone as UTC midnight
+func utcDate(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
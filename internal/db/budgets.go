@@ -0,0 +1,209 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/araesf/ledgertime/internal/models"
+)
+
+// Budget operations
+const budgetColumns = `id, user_id, category, period, amount, currency, rollover, created_at, updated_at`
+
+func scanBudget(row rowScanner) (*models.Budget, error) {
+	budget := &models.Budget{}
+	err := row.Scan(
+		&budget.ID, &budget.UserID, &budget.Category, &budget.Period, &budget.Amount, &budget.Currency,
+		&budget.Rollover, &budget.CreatedAt, &budget.UpdatedAt,
+	)
+	return budget, err
+}
+
+func (db *DB) CreateBudget(ctx context.Context, budget *models.Budget) error {
+	query := `
+		INSERT INTO budgets (` + budgetColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (user_id, category, period) DO NOTHING`
+
+	result, err := db.conn(ctx).ExecContext(ctx, query,
+		budget.ID, budget.UserID, budget.Category, budget.Period, budget.Amount, budget.Currency,
+		budget.Rollover, budget.CreatedAt, budget.UpdatedAt,
+	)
+	if err != nil {
+		db.logger.Error("Failed to create budget", "error", err, "user_id", budget.UserID)
+		return fmt.Errorf("failed to create budget: %w", err)
+	}
+	if created, _ := result.RowsAffected(); created == 0 {
+		return fmt.Errorf("%w: %s %s", models.ErrBudgetExists, budget.Period, budget.Category)
+	}
+
+	db.logger.Info("Budget created", "budget_id", budget.ID, "user_id", budget.UserID, "category", budget.Category)
+	return nil
+}
+
+func (db *DB) GetBudget(ctx context.Context, id string) (*models.Budget, error) {
+	query := `SELECT ` + budgetColumns + ` FROM budgets WHERE id = $1`
+
+	budget, err := scanBudget(db.conn(ctx).QueryRowContext(ctx, query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("budget %w: %s", models.ErrNotFound, id)
+		}
+		return nil, fmt.Errorf("failed to get budget: %w", err)
+	}
+
+	return budget, nil
+}
+
+// GetBudgets lists a user's budgets by category and period
+func (db *DB) GetBudgets(ctx context.Context, userID string) ([]*models.Budget, error) {
+	query := `SELECT ` + budgetColumns + ` FROM budgets WHERE user_id = $1 ORDER BY category, period`
+
+	rows, err := db.conn(ctx).QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get budgets: %w", err)
+	}
+	defer rows.Close()
+
+	var budgets []*models.Budget
+	for rows.Next() {
+		budget, err := scanBudget(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan budget: %w", err)
+		}
+		budgets = append(budgets, budget)
+	}
+
+	return budgets, nil
+}
+
+// UpdateBudget saves a budget's amount, currency and rollover. The category and period are fixed.
+func (db *DB) UpdateBudget(ctx context.Context, budget *models.Budget) error {
+	query := `
+		UPDATE budgets
+		SET amount = $2, currency = $3, rollover = $4
+		WHERE id = $1
+		RETURNING updated_at`
+
+	err := db.conn(ctx).QueryRowContext(ctx, query, budget.ID, budget.Amount, budget.Currency, budget.Rollover).Scan(&budget.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("budget %w: %s", models.ErrNotFound, budget.ID)
+		}
+		db.logger.Error("Failed to update budget", "error", err, "budget_id", budget.ID)
+		return fmt.Errorf("failed to update budget: %w", err)
+	}
+
+	db.logger.Info("Budget updated", "budget_id", budget.ID)
+	return nil
+}
+
+func (db *DB) DeleteBudget(ctx context.Context, id string) error {
+	result, err := db.conn(ctx).ExecContext(ctx, `DELETE FROM budgets WHERE id = $1`, id)
+	if err != nil {
+		db.logger.Error("Failed to delete budget", "error", err, "budget_id", id)
+		return fmt.Errorf("failed to delete budget: %w", err)
+	}
+	if deleted, _ := result.RowsAffected(); deleted == 0 {
+		return fmt.Errorf("budget %w: %s", models.ErrNotFound, id)
+	}
+
+	db.logger.Info("Budget deleted", "budget_id", id)
+	return nil
+}
+
+// budgetTruncUnit maps budget periods to date_trunc units. Postgres weeks start on Monday.
+var budgetTruncUnit = map[string]string{
+	models.BudgetPeriodWeekly:  "week",
+	models.BudgetPeriodMonthly: "month",
+}
+
+// GetBudgetSpending sums a budget's net spend per period from the UTC date since up to until,
+// keyed by the UTC start of each period
+func (db *DB) GetBudgetSpending(ctx context.Context, budget *models.Budget, since, until time.Time) (map[time.Time]int64, error) {
+	unit, ok := budgetTruncUnit[budget.Period]
+	if !ok {
+		return nil, fmt.Errorf("unknown budget period: %s", budget.Period)
+	}
+
+	query := `
+		SELECT date_trunc($1, day::timestamp) AS period_start, SUM(amount)
+		FROM spending_rollups
+		WHERE user_id = $2 AND category = $3 AND currency = $4 AND day >= $5 AND day < $6
+		GROUP BY period_start`
+
+	rows, err := db.conn(ctx).QueryContext(ctx, query, unit, budget.UserID, budget.Category, budget.Currency, rollupDay(since), rollupDay(until))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get budget spending: %w", err)
+	}
+	defer rows.Close()
+
+	spending := make(map[time.Time]int64)
+	for rows.Next() {
+		var start time.Time
+		var spent int64
+		if err := rows.Scan(&start, &spent); err != nil {
+			return nil, fmt.Errorf("failed to scan budget spending: %w", err)
+		}
+		spending[utcDate(start)] = spent
+	}
+
+	return spending, nil
+}
+
+// Budget alert operations
+const budgetAlertColumns = `id, budget_id, user_id, category, threshold, period_start, spent, available, transaction_id, created_at`
+
+// CreateBudgetAlert records an alert unless its budget already alerted the threshold this
+// period. Reports whether the alert was created.
+func (db *DB) CreateBudgetAlert(ctx context.Context, alert *models.BudgetAlert) (bool, error) {
+	query := `
+		INSERT INTO budget_alerts (` + budgetAlertColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		ON CONFLICT (budget_id, period_start, threshold) DO NOTHING`
+
+	result, err := db.conn(ctx).ExecContext(ctx, query,
+		alert.ID, alert.BudgetID, alert.UserID, alert.Category, alert.Threshold, alert.PeriodStart,
+		alert.Spent, alert.Available, alert.TransactionID, alert.CreatedAt,
+	)
+	if err != nil {
+		db.logger.Error("Failed to create budget alert", "error", err, "budget_id", alert.BudgetID)
+		return false, fmt.Errorf("failed to create budget alert: %w", err)
+	}
+
+	created, _ := result.RowsAffected()
+	return created == 1, nil
+}
+
+// GetBudgetAlerts lists a user's budget alerts, newest first
+func (db *DB) GetBudgetAlerts(ctx context.Context, userID string, limit, offset int) ([]*models.BudgetAlert, error) {
+	query := `
+		SELECT ` + budgetAlertColumns + `
+		FROM budget_alerts
+		WHERE user_id = $1
+		ORDER BY created_at DESC, threshold DESC
+		LIMIT $2 OFFSET $3`
+
+	rows, err := db.conn(ctx).QueryContext(ctx, query, userID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get budget alerts: %w", err)
+	}
+	defer rows.Close()
+
+	var alerts []*models.BudgetAlert
+	for rows.Next() {
+		alert := &models.BudgetAlert{}
+		err := rows.Scan(
+			&alert.ID, &alert.BudgetID, &alert.UserID, &alert.Category, &alert.Threshold, &alert.PeriodStart,
+			&alert.Spent, &alert.Available, &alert.TransactionID, &alert.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan budget alert: %w", err)
+		}
+		alerts = append(alerts, alert)
+	}
+
+	return alerts, nil
+}
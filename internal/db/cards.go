@@ -0,0 +1,186 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/araesf/ledgertime/internal/models"
+)
+
+// cardLineage selects the IDs of a card ($1) and every card it replaced, directly or indirectly
+const cardLineage = `
+	WITH RECURSIVE lineage (id, replaces_card_id) AS (
+		SELECT id, replaces_card_id FROM cards WHERE id = $1
+		UNION ALL
+		SELECT c.id, c.replaces_card_id FROM cards c JOIN lineage l ON c.id = l.replaces_card_id
+	)
+	SELECT id FROM lineage`
+
+// transitionCard moves a card from one status to another and records the change
+func transitionCard(ctx context.Context, tx querier, card *models.Card, change *models.CardStatusChange) error {
+	query := `
+		UPDATE cards SET status = $2, is_active = $3, updated_at = $4
+		WHERE id = $1 AND status = $5`
+
+	result, err := tx.ExecContext(ctx, query, card.ID, change.ToStatus, change.ToStatus == models.CardStatusActive, change.CreatedAt, change.FromStatus)
+	if err != nil {
+		return fmt.Errorf("failed to update card status: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("%w: card %s is no longer %s", models.ErrCardStatusConflict, card.ID, change.FromStatus)
+	}
+
+	query = `
+		INSERT INTO card_status_history (id, card_id, from_status, to_status, actor, reason, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`
+
+	_, err = tx.ExecContext(ctx, query,
+		change.ID, change.CardID, change.FromStatus, change.ToStatus,
+		change.Actor, nullString(change.Reason), change.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record card status change: %w", err)
+	}
+
+	return nil
+}
+
+// TransitionCard atomically changes a card's status and records it in the card status history
+func (db *DB) TransitionCard(ctx context.Context, card *models.Card, change *models.CardStatusChange) error {
+	tx, err := db.begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := transitionCard(ctx, tx, card, change); err != nil {
+		db.logger.Error("Failed to transition card", "error", err, "card_id", card.ID)
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit card status change: %w", err)
+	}
+
+	db.logger.Info("Card status changed", "card_id", card.ID, "from", change.FromStatus, "to", change.ToStatus, "actor", change.Actor)
+	return nil
+}
+
+// ReplaceCard moves the old card to its final status, issues the replacement and copies the old
+// card's spending limits to it, all in one transaction
+func (db *DB) ReplaceCard(ctx context.Context, old, replacement *models.Card, change *models.CardStatusChange) error {
+	tx, err := db.begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := transitionCard(ctx, tx, old, change); err != nil {
+		db.logger.Error("Failed to transition card", "error", err, "card_id", old.ID)
+		return err
+	}
+
+	if err := insertCard(ctx, tx, replacement); err != nil {
+		db.logger.Error("Failed to create replacement card", "error", err, "card_id", old.ID)
+		return fmt.Errorf("failed to create replacement card: %w", err)
+	}
+
+	query := `
+		INSERT INTO spending_limits (scope, scope_id, currency, single_transaction_limit, daily_limit, weekly_limit,
+			monthly_limit, blocked_categories, category_caps, updated_at)
+		SELECT scope, $2, currency, single_transaction_limit, daily_limit, weekly_limit,
+			monthly_limit, blocked_categories, category_caps, $3
+		FROM spending_limits
+		WHERE scope = 'card' AND scope_id = $1`
+
+	if _, err := tx.ExecContext(ctx, query, old.ID, replacement.ID, replacement.CreatedAt); err != nil {
+		return fmt.Errorf("failed to copy spending limits: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit card replacement: %w", err)
+	}
+
+	db.logger.Info("Card replaced", "card_id", old.ID, "replacement_card_id", replacement.ID)
+	return nil
+}
+
+func (db *DB) GetCardStatusHistory(ctx context.Context, cardID string) ([]*models.CardStatusChange, error) {
+	query := `
+		SELECT id, card_id, from_status, to_status, actor, COALESCE(reason, ''), created_at
+		FROM card_status_history
+		WHERE card_id = $1
+		ORDER BY created_at, id`
+
+	rows, err := db.conn(ctx).QueryContext(ctx, query, cardID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get card status history: %w", err)
+	}
+	defer rows.Close()
+
+	var history []*models.CardStatusChange
+	for rows.Next() {
+		c := &models.CardStatusChange{}
+		if err := rows.Scan(&c.ID, &c.CardID, &c.FromStatus, &c.ToStatus, &c.Actor, &c.Reason, &c.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan card status change: %w", err)
+		}
+		history = append(history, c)
+	}
+
+	return history, nil
+}
+
+// GetExpiredCards returns active or frozen cards whose expiry has passed
+func (db *DB) GetExpiredCards(ctx context.Context, now time.Time, limit int) ([]*models.Card, error) {
+	query := `
+		SELECT ` + cardColumns + `
+		FROM cards
+		WHERE status IN ('active', 'frozen') AND expires_at <= $1
+		ORDER BY expires_at
+		LIMIT $2`
+
+	rows, err := db.conn(ctx).QueryContext(ctx, query, now, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get expired cards: %w", err)
+	}
+	defer rows.Close()
+
+	var cards []*models.Card
+	for rows.Next() {
+		card, err := scanCard(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan card: %w", err)
+		}
+		cards = append(cards, card)
+	}
+
+	return cards, nil
+}
+
+// GetCardTransactions returns the transactions of a card and the cards it replaced, newest first
+func (db *DB) GetCardTransactions(ctx context.Context, cardID string, limit, offset int) ([]*models.Transaction, error) {
+	query := `
+		SELECT ` + transactionColumns + `
+		FROM transactions
+		WHERE card_id IN (` + cardLineage + `)
+		ORDER BY timestamp DESC
+		LIMIT $2 OFFSET $3`
+
+	rows, err := db.conn(ctx).QueryContext(ctx, query, cardID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get card transactions: %w", err)
+	}
+	defer rows.Close()
+
+	var transactions []*models.Transaction
+	for rows.Next() {
+		tx, err := scanTransaction(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan transaction: %w", err)
+		}
+		transactions = append(transactions, tx)
+	}
+
+	return transactions, nil
+}
@@ -0,0 +1,274 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/araesf/ledgertime/internal/models"
+)
+
+// Category operations
+const categoryColumns = `id, user_id, name, created_at`
+
+func scanCategory(row rowScanner) (*models.Category, error) {
+	category := &models.Category{}
+	err := row.Scan(&category.ID, &category.UserID, &category.Name, &category.CreatedAt)
+	return category, err
+}
+
+func (db *DB) CreateCategory(ctx context.Context, category *models.Category) error {
+	query := `
+		INSERT INTO categories (` + categoryColumns + `)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id, name) DO NOTHING`
+
+	result, err := db.conn(ctx).ExecContext(ctx, query, category.ID, category.UserID, category.Name, category.CreatedAt)
+	if err != nil {
+		db.logger.Error("Failed to create category", "error", err, "user_id", category.UserID)
+		return fmt.Errorf("failed to create category: %w", err)
+	}
+	if created, _ := result.RowsAffected(); created == 0 {
+		return fmt.Errorf("%w: %s", models.ErrCategoryExists, category.Name)
+	}
+
+	db.logger.Info("Category created", "category_id", category.ID, "user_id", category.UserID, "name", category.Name)
+	return nil
+}
+
+func (db *DB) GetCategory(ctx context.Context, id string) (*models.Category, error) {
+	query := `SELECT ` + categoryColumns + ` FROM categories WHERE id = $1`
+
+	category, err := scanCategory(db.conn(ctx).QueryRowContext(ctx, query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("category %w: %s", models.ErrNotFound, id)
+		}
+		return nil, fmt.Errorf("failed to get category: %w", err)
+	}
+
+	return category, nil
+}
+
+// GetCategories lists a user's own categories by name
+func (db *DB) GetCategories(ctx context.Context, userID string) ([]*models.Category, error) {
+	query := `SELECT ` + categoryColumns + ` FROM categories WHERE user_id = $1 ORDER BY name`
+
+	rows, err := db.conn(ctx).QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get categories: %w", err)
+	}
+	defer rows.Close()
+
+	var categories []*models.Category
+	for rows.Next() {
+		category, err := scanCategory(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan category: %w", err)
+		}
+		categories = append(categories, category)
+	}
+
+	return categories, nil
+}
+
+// DeleteCategory removes a category unless one of its user's rules assigns it. Transactions
+// keep the category name they were given.
+func (db *DB) DeleteCategory(ctx context.Context, id string) error {
+	query := `
+		DELETE FROM categories c
+		WHERE c.id = $1 AND NOT EXISTS (
+			SELECT 1 FROM category_rules r WHERE r.user_id = c.user_id AND r.category = c.name
+		)`
+
+	result, err := db.conn(ctx).ExecContext(ctx, query, id)
+	if err != nil {
+		db.logger.Error("Failed to delete category", "error", err, "category_id", id)
+		return fmt.Errorf("failed to delete category: %w", err)
+	}
+	if deleted, _ := result.RowsAffected(); deleted == 0 {
+		category, err := db.GetCategory(ctx, id)
+		if err != nil {
+			return err
+		}
+		return fmt.Errorf("%w: %s", models.ErrCategoryInUse, category.Name)
+	}
+
+	db.logger.Info("Category deleted", "category_id", id)
+	return nil
+}
+
+// Category rule operations
+const categoryRuleColumns = `id, user_id, field, operator, value, category, priority, created_at, updated_at`
+
+func scanCategoryRule(row rowScanner) (*models.CategoryRule, error) {
+	rule := &models.CategoryRule{}
+	err := row.Scan(
+		&rule.ID, &rule.UserID, &rule.Field, &rule.Operator, &rule.Value, &rule.Category, &rule.Priority,
+		&rule.CreatedAt, &rule.UpdatedAt,
+	)
+	return rule, err
+}
+
+func (db *DB) CreateCategoryRule(ctx context.Context, rule *models.CategoryRule) error {
+	query := `
+		INSERT INTO category_rules (` + categoryRuleColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
+
+	_, err := db.conn(ctx).ExecContext(ctx, query,
+		rule.ID, rule.UserID, rule.Field, rule.Operator, rule.Value, rule.Category, rule.Priority,
+		rule.CreatedAt, rule.UpdatedAt,
+	)
+	if err != nil {
+		db.logger.Error("Failed to create category rule", "error", err, "user_id", rule.UserID)
+		return fmt.Errorf("failed to create category rule: %w", err)
+	}
+
+	db.logger.Info("Category rule created", "rule_id", rule.ID, "user_id", rule.UserID, "category", rule.Category)
+	return nil
+}
+
+func (db *DB) GetCategoryRule(ctx context.Context, id string) (*models.CategoryRule, error) {
+	query := `SELECT ` + categoryRuleColumns + ` FROM category_rules WHERE id = $1`
+
+	rule, err := scanCategoryRule(db.conn(ctx).QueryRowContext(ctx, query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("category rule %w: %s", models.ErrNotFound, id)
+		}
+		return nil, fmt.Errorf("failed to get category rule: %w", err)
+	}
+
+	return rule, nil
+}
+
+// GetCategoryRules lists a user's rules in evaluation order
+func (db *DB) GetCategoryRules(ctx context.Context, userID string) ([]*models.CategoryRule, error) {
+	query := `
+		SELECT ` + categoryRuleColumns + `
+		FROM category_rules
+		WHERE user_id = $1
+		ORDER BY priority, created_at, id`
+
+	rows, err := db.conn(ctx).QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get category rules: %w", err)
+	}
+	defer rows.Close()
+
+	var rules []*models.CategoryRule
+	for rows.Next() {
+		rule, err := scanCategoryRule(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan category rule: %w", err)
+		}
+		rules = append(rules, rule)
+	}
+
+	return rules, nil
+}
+
+// UpdateCategoryRule saves a rule's condition, category and priority
+func (db *DB) UpdateCategoryRule(ctx context.Context, rule *models.CategoryRule) error {
+	query := `
+		UPDATE category_rules
+		SET field = $2, operator = $3, value = $4, category = $5, priority = $6
+		WHERE id = $1
+		RETURNING updated_at`
+
+	err := db.conn(ctx).QueryRowContext(ctx, query, rule.ID, rule.Field, rule.Operator, rule.Value, rule.Category, rule.Priority).
+		Scan(&rule.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("category rule %w: %s", models.ErrNotFound, rule.ID)
+		}
+		db.logger.Error("Failed to update category rule", "error", err, "rule_id", rule.ID)
+		return fmt.Errorf("failed to update category rule: %w", err)
+	}
+
+	db.logger.Info("Category rule updated", "rule_id", rule.ID)
+	return nil
+}
+
+func (db *DB) DeleteCategoryRule(ctx context.Context, id string) error {
+	result, err := db.conn(ctx).ExecContext(ctx, `DELETE FROM category_rules WHERE id = $1`, id)
+	if err != nil {
+		db.logger.Error("Failed to delete category rule", "error", err, "rule_id", id)
+		return fmt.Errorf("failed to delete category rule: %w", err)
+	}
+	if deleted, _ := result.RowsAffected(); deleted == 0 {
+		return fmt.Errorf("category rule %w: %s", models.ErrNotFound, id)
+	}
+
+	db.logger.Info("Category rule deleted", "rule_id", id)
+	return nil
+}
+
+// SetTransactionCategory recategorizes a transaction together with its refunds and reversals,
+// moving their net spend to the new category's rollups
+func (db *DB) SetTransactionCategory(ctx context.Context, id, category, source string) error {
+	sqlTx, err := db.begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer sqlTx.Rollback()
+
+	if err := lockTransactions(ctx, sqlTx, rollupTransactionFamily, id); err != nil {
+		return err
+	}
+	if err := rollupTransactions(ctx, sqlTx, rollupTransactionFamily, -1, id); err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE transactions
+		SET category = $2, category_source = $3
+		WHERE ` + rollupTransactionFamily
+
+	result, err := sqlTx.ExecContext(ctx, query, id, category, source)
+	if err != nil {
+		db.logger.Error("Failed to set transaction category", "error", err, "transaction_id", id)
+		return fmt.Errorf("failed to set transaction category: %w", err)
+	}
+	if updated, _ := result.RowsAffected(); updated == 0 {
+		return fmt.Errorf("transaction %w: %s", models.ErrNotFound, id)
+	}
+
+	if err := rollupTransactions(ctx, sqlTx, rollupTransactionFamily, 1, id); err != nil {
+		return err
+	}
+
+	if err := sqlTx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction category: %w", err)
+	}
+
+	return nil
+}
+
+// GetRuleCategorizableTransactions pages through a user's payments whose category was not set
+// by the user, in ID order starting after afterID
+func (db *DB) GetRuleCategorizableTransactions(ctx context.Context, userID, afterID string, limit int) ([]*models.Transaction, error) {
+	query := `
+		SELECT ` + transactionColumns + `
+		FROM transactions
+		WHERE user_id = $1 AND type = 'payment' AND category_source <> 'user' AND id > $2
+		ORDER BY id
+		LIMIT $3`
+
+	rows, err := db.conn(ctx).QueryContext(ctx, query, userID, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transactions: %w", err)
+	}
+	defer rows.Close()
+
+	var transactions []*models.Transaction
+	for rows.Next() {
+		tx, err := scanTransaction(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan transaction: %w", err)
+		}
+		transactions = append(transactions, tx)
+	}
+
+	return transactions, nil
+}
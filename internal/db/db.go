@@ -1,24 +1,36 @@
 package db
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"time"
 
 	"github.com/araesf/ledgertime/internal/config"
+	"github.com/araesf/ledgertime/internal/ledger"
 	"github.com/araesf/ledgertime/internal/models"
+	"github.com/araesf/ledgertime/internal/vault"
 	"github.com/araesf/ledgertime/pkg/logger"
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
+var _ ledger.Store = (*DB)(nil)
+
 // DB wraps the database connection with additional methods
 type DB struct {
 	*sql.DB
-	logger *logger.Logger
+	isolation    sql.IsolationLevel // Of units of work
+	maxTxRetries int
+	logger       *logger.Logger
 }
 
 // Connect creates a new database connection
 func Connect(cfg config.DatabaseConfig, log *logger.Logger) (*DB, error) {
+	isolation, ok := isolationLevels[cfg.IsolationLevel]
+	if !ok {
+		return nil, fmt.Errorf("unknown isolation level: %q", cfg.IsolationLevel)
+	}
+
 	db, err := sql.Open("postgres", cfg.GetDSN())
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
@@ -37,18 +49,20 @@ func Connect(cfg config.DatabaseConfig, log *logger.Logger) (*DB, error) {
 	log.Info("Database connection established")
 
 	return &DB{
-		DB:     db,
-		logger: log,
+		DB:           db,
+		isolation:    isolation,
+		maxTxRetries: cfg.MaxTxRetries,
+		logger:       log,
 	}, nil
 }
 
 // User operations
-func (db *DB) CreateUser(user *models.User) error {
+func (db *DB) CreateUser(ctx context.Context, user *models.User) error {
 	query := `
 		INSERT INTO users (id, name, email, created_at, updated_at)
 		VALUES ($1, $2, $3, $4, $5)`
 
-	_, err := db.Exec(query, user.ID, user.Name, user.Email, user.CreatedAt, user.UpdatedAt)
+	_, err := db.conn(ctx).ExecContext(ctx, query, user.ID, user.Name, user.Email, user.CreatedAt, user.UpdatedAt)
 	if err != nil {
 		db.logger.Error("Failed to create user", "error", err, "user_id", user.ID)
 		return fmt.Errorf("failed to create user: %w", err)
@@ -58,18 +72,18 @@ func (db *DB) CreateUser(user *models.User) error {
 	return nil
 }
 
-func (db *DB) GetUser(id string) (*models.User, error) {
+func (db *DB) GetUser(ctx context.Context, id string) (*models.User, error) {
 	query := `
 		SELECT id, name, email, created_at, updated_at
 		FROM users WHERE id = $1`
 
 	user := &models.User{}
-	err := db.QueryRow(query, id).Scan(
+	err := db.conn(ctx).QueryRowContext(ctx, query, id).Scan(
 		&user.ID, &user.Name, &user.Email, &user.CreatedAt, &user.UpdatedAt,
 	)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("user not found: %s", id)
+			return nil, fmt.Errorf("user not found: %s: %w", id, models.ErrNotFound)
 		}
 		return nil, fmt.Errorf("failed to get user: %w", err)
 	}
@@ -78,13 +92,38 @@ func (db *DB) GetUser(id string) (*models.User, error) {
 }
 
 // Card operations
-func (db *DB) CreateCard(card *models.Card) error {
+const cardColumns = `id, user_id, token, last4, pan_hash, pan_key_id, pan_data_key, pan_ciphertext, card_type, issuer, issuer_country, funding, currency, status, is_active, expires_at, replaces_card_id, created_at, updated_at`
+
+func scanCard(row rowScanner) (*models.Card, error) {
+	card := &models.Card{}
+	var replacesCardID, issuer, issuerCountry, funding sql.NullString
+	err := row.Scan(
+		&card.ID, &card.UserID, &card.Token, &card.Last4, &card.PANHash, &card.PANKeyID, &card.PANDataKey, &card.PANCiphertext,
+		&card.CardType, &issuer, &issuerCountry, &funding, &card.Currency, &card.Status, &card.IsActive, &card.ExpiresAt,
+		&replacesCardID, &card.CreatedAt, &card.UpdatedAt,
+	)
+	card.ReplacesCardID = replacesCardID.String
+	card.Issuer, card.IssuerCountry, card.Funding = issuer.String, issuerCountry.String, funding.String
+	card.CardNumber = vault.MaskLast4(card.Last4)
+	return card, err
+}
+
+// insertCard creates a card through either the pool or a transaction
+func insertCard(ctx context.Context, exec querier, card *models.Card) error {
 	query := `
-		INSERT INTO cards (id, user_id, card_number, card_type, is_active, created_at)
-		VALUES ($1, $2, $3, $4, $5, $6)`
+		INSERT INTO cards (` + cardColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19)`
 
-	_, err := db.Exec(query, card.ID, card.UserID, card.CardNumber, card.CardType, card.IsActive, card.CreatedAt)
-	if err != nil {
+	_, err := exec.ExecContext(ctx, query,
+		card.ID, card.UserID, card.Token, card.Last4, card.PANHash, card.PANKeyID, card.PANDataKey, card.PANCiphertext,
+		card.CardType, nullString(card.Issuer), nullString(card.IssuerCountry), nullString(card.Funding), card.Currency, card.Status, card.IsActive, card.ExpiresAt,
+		nullString(card.ReplacesCardID), card.CreatedAt, card.UpdatedAt,
+	)
+	return err
+}
+
+func (db *DB) CreateCard(ctx context.Context, card *models.Card) error {
+	if err := insertCard(ctx, db.conn(ctx), card); err != nil {
 		db.logger.Error("Failed to create card", "error", err, "card_id", card.ID)
 		return fmt.Errorf("failed to create card: %w", err)
 	}
@@ -93,18 +132,43 @@ func (db *DB) CreateCard(card *models.Card) error {
 	return nil
 }
 
-func (db *DB) GetCardByNumber(cardNumber string) (*models.Card, error) {
-	query := `
-		SELECT id, user_id, card_number, card_type, is_active, created_at
-		FROM cards WHERE card_number = $1 AND is_active = true`
+// GetCardByPANHash returns the card whose number has a lookup hash, regardless of its status
+func (db *DB) GetCardByPANHash(ctx context.Context, panHash string) (*models.Card, error) {
+	query := `SELECT ` + cardColumns + ` FROM cards WHERE pan_hash = $1`
 
-	card := &models.Card{}
-	err := db.QueryRow(query, cardNumber).Scan(
-		&card.ID, &card.UserID, &card.CardNumber, &card.CardType, &card.IsActive, &card.CreatedAt,
-	)
+	card, err := scanCard(db.conn(ctx).QueryRowContext(ctx, query, panHash))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("card %w", models.ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to get card: %w", err)
+	}
+
+	return card, nil
+}
+
+// GetCardByToken returns the card with a token regardless of its status
+func (db *DB) GetCardByToken(ctx context.Context, token string) (*models.Card, error) {
+	query := `SELECT ` + cardColumns + ` FROM cards WHERE token = $1`
+
+	card, err := scanCard(db.conn(ctx).QueryRowContext(ctx, query, token))
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("card not found: %s", cardNumber)
+			return nil, fmt.Errorf("card %w: %s", models.ErrNotFound, token)
+		}
+		return nil, fmt.Errorf("failed to get card: %w", err)
+	}
+
+	return card, nil
+}
+
+func (db *DB) GetCard(ctx context.Context, id string) (*models.Card, error) {
+	query := `SELECT ` + cardColumns + ` FROM cards WHERE id = $1`
+
+	card, err := scanCard(db.conn(ctx).QueryRowContext(ctx, query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("card %w: %s", models.ErrNotFound, id)
 		}
 		return nil, fmt.Errorf("failed to get card: %w", err)
 	}
@@ -113,16 +177,54 @@ func (db *DB) GetCardByNumber(cardNumber string) (*models.Card, error) {
 }
 
 // Transaction operations
-func (db *DB) CreateTransaction(tx *models.Transaction) error {
-	query := `
-		INSERT INTO transactions (id, user_id, card_id, amount, merchant_name, category, description, status, timestamp, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`
+const transactionColumns = `id, user_id, card_id, parent_transaction_id, type, amount, authorized_amount, currency, original_amount, original_currency, fx_rate, merchant_id, merchant_name, merchant_descriptor, mcc, merchant_city, merchant_country, category, category_source, description, status, decline_reason, fraud_score, fraud_decision, fraud_reasons, timestamp, created_at, updated_at`
 
-	_, err := db.Exec(query,
-		tx.ID, tx.UserID, tx.CardID, tx.Amount, tx.MerchantName,
-		tx.Category, tx.Description, tx.Status, tx.Timestamp,
-		tx.CreatedAt, tx.UpdatedAt,
+// rowScanner is implemented by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanTransaction(row rowScanner) (*models.Transaction, error) {
+	tx := &models.Transaction{}
+	var parentID, fxRate, merchantID, declineReason, fraudDecision sql.NullString
+	err := row.Scan(
+		&tx.ID, &tx.UserID, &tx.CardID, &parentID, &tx.Type, &tx.Amount, &tx.AuthorizedAmount,
+		&tx.Currency, &tx.OriginalAmount, &tx.OriginalCurrency, &fxRate, &merchantID, &tx.MerchantName,
+		&tx.MerchantDescriptor, &tx.MCC, &tx.MerchantCity, &tx.MerchantCountry,
+		&tx.Category, &tx.CategorySource, &tx.Description, &tx.Status, &declineReason, &tx.FraudScore, &fraudDecision, pq.Array(&tx.FraudReasons), &tx.Timestamp,
+		&tx.CreatedAt, &tx.UpdatedAt,
 	)
+	tx.ParentTransactionID = parentID.String
+	tx.FXRate = fxRate.String
+	tx.MerchantID = merchantID.String
+	tx.DeclineReason = declineReason.String
+	tx.FraudDecision = fraudDecision.String
+	return tx, err
+}
+
+// nullString stores empty strings as NULL
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+// insertTransactionQuery inserts every column in transactionColumns, in order
+const insertTransactionQuery = `
+	INSERT INTO transactions (` + transactionColumns + `)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24, $25, $26, $27, $28)`
+
+// transactionValues returns the arguments for insertTransactionQuery
+func transactionValues(tx *models.Transaction) []interface{} {
+	return []interface{}{
+		tx.ID, tx.UserID, tx.CardID, nullString(tx.ParentTransactionID), tx.Type, tx.Amount, tx.AuthorizedAmount,
+		tx.Currency, tx.OriginalAmount, tx.OriginalCurrency, nullString(tx.FXRate), nullString(tx.MerchantID), tx.MerchantName,
+		tx.MerchantDescriptor, tx.MCC, tx.MerchantCity, tx.MerchantCountry,
+		tx.Category, tx.CategorySource, tx.Description, tx.Status, nullString(tx.DeclineReason), tx.FraudScore, nullString(tx.FraudDecision), pq.Array(tx.FraudReasons), tx.Timestamp,
+		tx.CreatedAt, tx.UpdatedAt,
+	}
+}
+
+func (db *DB) CreateTransaction(ctx context.Context, tx *models.Transaction) error {
+	_, err := db.conn(ctx).ExecContext(ctx, insertTransactionQuery, transactionValues(tx)...)
 	if err != nil {
 		db.logger.Error("Failed to create transaction", "error", err, "transaction_id", tx.ID)
 		return fmt.Errorf("failed to create transaction: %w", err)
@@ -132,15 +234,29 @@ func (db *DB) CreateTransaction(tx *models.Transaction) error {
 	return nil
 }
 
-func (db *DB) GetTransactionsByUser(userID string, limit, offset int) ([]*models.Transaction, error) {
+func (db *DB) GetTransaction(ctx context.Context, id string) (*models.Transaction, error) {
+	query := `SELECT ` + transactionColumns + ` FROM transactions WHERE id = $1`
+
+	tx, err := scanTransaction(db.conn(ctx).QueryRowContext(ctx, query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("transaction %w: %s", models.ErrNotFound, id)
+		}
+		return nil, fmt.Errorf("failed to get transaction: %w", err)
+	}
+
+	return tx, nil
+}
+
+func (db *DB) GetTransactionsByUser(ctx context.Context, userID string, limit, offset int) ([]*models.Transaction, error) {
 	query := `
-		SELECT id, user_id, card_id, amount, merchant_name, category, description, status, timestamp, created_at, updated_at
+		SELECT ` + transactionColumns + `
 		FROM transactions 
 		WHERE user_id = $1 
 		ORDER BY timestamp DESC 
 		LIMIT $2 OFFSET $3`
 
-	rows, err := db.Query(query, userID, limit, offset)
+	rows, err := db.conn(ctx).QueryContext(ctx, query, userID, limit, offset)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get transactions: %w", err)
 	}
@@ -148,12 +264,7 @@ func (db *DB) GetTransactionsByUser(userID string, limit, offset int) ([]*models
 
 	var transactions []*models.Transaction
 	for rows.Next() {
-		tx := &models.Transaction{}
-		err := rows.Scan(
-			&tx.ID, &tx.UserID, &tx.CardID, &tx.Amount, &tx.MerchantName,
-			&tx.Category, &tx.Description, &tx.Status, &tx.Timestamp,
-			&tx.CreatedAt, &tx.UpdatedAt,
-		)
+		tx, err := scanTransaction(rows)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan transaction: %w", err)
 		}
@@ -162,31 +273,3 @@ func (db *DB) GetTransactionsByUser(userID string, limit, offset int) ([]*models
 
 	return transactions, nil
 }
-
-func (db *DB) GetTransactionSummary(userID string) (*models.TransactionSummary, error) {
-	query := `
-		SELECT 
-			user_id,
-			COALESCE(SUM(amount), 0) as total_amount,
-			COUNT(*) as total_count,
-			COALESCE(AVG(amount), 0) as avg_amount,
-			COALESCE(MODE() WITHIN GROUP (ORDER BY category), '') as top_category,
-			COALESCE(MODE() WITHIN GROUP (ORDER BY merchant_name), '') as top_merchant
-		FROM transactions 
-		WHERE user_id = $1 AND status = 'completed'
-		GROUP BY user_id`
-
-	summary := &models.TransactionSummary{}
-	err := db.QueryRow(query, userID).Scan(
-		&summary.UserID, &summary.TotalAmount, &summary.TotalCount,
-		&summary.AvgAmount, &summary.TopCategory, &summary.TopMerchant,
-	)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return &models.TransactionSummary{UserID: userID}, nil
-		}
-		return nil, fmt.Errorf("failed to get transaction summary: %w", err)
-	}
-
-	return summary, nil
-}
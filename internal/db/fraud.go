@@ -0,0 +1,75 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/araesf/ledgertime/internal/models"
+	"github.com/lib/pq"
+)
+
+// GetCardTransactionsSince returns the payments of a card and the cards it replaced with a
+// timestamp at or after since, newest first
+func (db *DB) GetCardTransactionsSince(ctx context.Context, cardID string, since time.Time) ([]*models.Transaction, error) {
+	query := `
+		SELECT ` + transactionColumns + `
+		FROM transactions
+		WHERE card_id IN (` + cardLineage + `) AND type = 'payment' AND timestamp >= $2
+		ORDER BY timestamp DESC`
+
+	rows, err := db.conn(ctx).QueryContext(ctx, query, cardID, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get card transactions: %w", err)
+	}
+	defer rows.Close()
+
+	var transactions []*models.Transaction
+	for rows.Next() {
+		tx, err := scanTransaction(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan transaction: %w", err)
+		}
+		transactions = append(transactions, tx)
+	}
+
+	return transactions, nil
+}
+
+// HasCardUsedMerchant reports whether the card, or a card it replaced, has an accepted payment
+// to the merchant before the given time
+func (db *DB) HasCardUsedMerchant(ctx context.Context, cardID, merchantName string, before time.Time) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM transactions
+			WHERE card_id IN (` + cardLineage + `) AND merchant_name = $2 AND type = 'payment'
+				AND status <> 'declined' AND timestamp < $3
+		)`
+
+	var used bool
+	if err := db.conn(ctx).QueryRowContext(ctx, query, cardID, merchantName, before).Scan(&used); err != nil {
+		return false, fmt.Errorf("failed to check merchant history: %w", err)
+	}
+
+	return used, nil
+}
+
+// SetTransactionFraud stores the fraud screening result of a transaction
+func (db *DB) SetTransactionFraud(ctx context.Context, id string, score int, decision string, reasons []string) error {
+	query := `
+		UPDATE transactions
+		SET fraud_score = $2, fraud_decision = $3, fraud_reasons = $4, updated_at = NOW()
+		WHERE id = $1`
+
+	result, err := db.conn(ctx).ExecContext(ctx, query, id, score, decision, pq.Array(reasons))
+	if err != nil {
+		db.logger.Error("Failed to store fraud result", "error", err, "transaction_id", id)
+		return fmt.Errorf("failed to store fraud result: %w", err)
+	}
+
+	if rows, err := result.RowsAffected(); err == nil && rows == 0 {
+		return fmt.Errorf("transaction %w: %s", models.ErrNotFound, id)
+	}
+
+	return nil
+}
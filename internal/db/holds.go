@@ -0,0 +1,182 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/araesf/ledgertime/internal/models"
+)
+
+const holdColumns = `id, transaction_id, account_id, amount, status, expires_at, created_at, updated_at`
+
+func scanHold(row rowScanner) (*models.Hold, error) {
+	hold := &models.Hold{}
+	err := row.Scan(
+		&hold.ID, &hold.TransactionID, &hold.AccountID, &hold.Amount,
+		&hold.Status, &hold.ExpiresAt, &hold.CreatedAt, &hold.UpdatedAt,
+	)
+	return hold, err
+}
+
+// reserveFunds adjusts the held amount on an account, refusing increases that exceed the overdraft limit
+func reserveFunds(ctx context.Context, tx querier, accountID string, delta int64) error {
+	query := `
+		UPDATE accounts SET held_amount = held_amount + $2
+		WHERE id = $1
+		  AND ($2 <= 0 OR account_type <> 'user_wallet'
+		       OR ledger_balance - held_amount + overdraft_limit - $2 >= 0)`
+
+	result, err := tx.ExecContext(ctx, query, accountID, delta)
+	if err != nil {
+		return fmt.Errorf("failed to update held amount: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("%w: account %s", models.ErrInsufficientFunds, accountID)
+	}
+	return nil
+}
+
+// Hold operations
+func (db *DB) CreateHold(ctx context.Context, hold *models.Hold) error {
+	tx, err := db.begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := reserveFunds(ctx, tx, hold.AccountID, hold.Amount); err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO holds (` + holdColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+
+	_, err = tx.ExecContext(ctx, query,
+		hold.ID, hold.TransactionID, hold.AccountID, hold.Amount,
+		hold.Status, hold.ExpiresAt, hold.CreatedAt, hold.UpdatedAt,
+	)
+	if err != nil {
+		db.logger.Error("Failed to create hold", "error", err, "transaction_id", hold.TransactionID)
+		return fmt.Errorf("failed to create hold: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit hold: %w", err)
+	}
+
+	db.logger.Info("Hold created", "hold_id", hold.ID, "transaction_id", hold.TransactionID, "amount", hold.Amount)
+	return nil
+}
+
+func (db *DB) GetActiveHoldByTransaction(ctx context.Context, transactionID string) (*models.Hold, error) {
+	query := `SELECT ` + holdColumns + ` FROM holds WHERE transaction_id = $1 AND status = 'active'`
+
+	hold, err := scanHold(db.conn(ctx).QueryRowContext(ctx, query, transactionID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("active hold %w for transaction: %s", models.ErrNotFound, transactionID)
+		}
+		return nil, fmt.Errorf("failed to get hold: %w", err)
+	}
+
+	return hold, nil
+}
+
+// IncreaseHold raises an active hold by delta and pushes out its expiry
+func (db *DB) IncreaseHold(ctx context.Context, holdID string, delta int64, expiresAt time.Time) error {
+	tx, err := db.begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var accountID string
+	query := `SELECT account_id FROM holds WHERE id = $1 AND status = 'active' FOR UPDATE`
+	if err := tx.QueryRowContext(ctx, query, holdID).Scan(&accountID); err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("%w: %s", models.ErrHoldNotActive, holdID)
+		}
+		return fmt.Errorf("failed to lock hold: %w", err)
+	}
+
+	if err := reserveFunds(ctx, tx, accountID, delta); err != nil {
+		return err
+	}
+
+	query = `UPDATE holds SET amount = amount + $2, expires_at = $3 WHERE id = $1`
+	if _, err := tx.ExecContext(ctx, query, holdID, delta, expiresAt); err != nil {
+		return fmt.Errorf("failed to increase hold: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit hold: %w", err)
+	}
+
+	db.logger.Info("Hold increased", "hold_id", holdID, "delta", delta)
+	return nil
+}
+
+// ReleaseHold returns the held funds to the account and closes the hold with the given status
+func (db *DB) ReleaseHold(ctx context.Context, holdID, status string) error {
+	tx, err := db.begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var accountID string
+	var amount int64
+	query := `SELECT account_id, amount FROM holds WHERE id = $1 AND status = 'active' FOR UPDATE`
+	if err := tx.QueryRowContext(ctx, query, holdID).Scan(&accountID, &amount); err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("%w: %s", models.ErrHoldNotActive, holdID)
+		}
+		return fmt.Errorf("failed to lock hold: %w", err)
+	}
+
+	if err := reserveFunds(ctx, tx, accountID, -amount); err != nil {
+		return err
+	}
+
+	query = `UPDATE holds SET status = $2 WHERE id = $1`
+	if _, err := tx.ExecContext(ctx, query, holdID, status); err != nil {
+		return fmt.Errorf("failed to release hold: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit hold: %w", err)
+	}
+
+	db.logger.Info("Hold released", "hold_id", holdID, "status", status, "amount", amount)
+	return nil
+}
+
+// GetExpiredHolds returns active holds whose expiry has passed
+func (db *DB) GetExpiredHolds(ctx context.Context, now time.Time, limit int) ([]*models.Hold, error) {
+	query := `
+		SELECT ` + holdColumns + `
+		FROM holds
+		WHERE status = 'active' AND expires_at <= $1
+		ORDER BY expires_at
+		LIMIT $2`
+
+	rows, err := db.conn(ctx).QueryContext(ctx, query, now, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get expired holds: %w", err)
+	}
+	defer rows.Close()
+
+	var holds []*models.Hold
+	for rows.Next() {
+		hold, err := scanHold(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan hold: %w", err)
+		}
+		holds = append(holds, hold)
+	}
+
+	return holds, nil
+}
@@ -0,0 +1,79 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/araesf/ledgertime/internal/models"
+)
+
+const idempotencyColumns = `key, operation, request_hash, status, transaction_id, response, locked_at, created_at, completed_at`
+
+func scanIdempotencyRecord(row rowScanner) (*models.IdempotencyRecord, error) {
+	rec := &models.IdempotencyRecord{}
+	var transactionID sql.NullString
+	var response []byte
+	var completedAt sql.NullTime
+	err := row.Scan(
+		&rec.Key, &rec.Operation, &rec.RequestHash, &rec.Status, &transactionID,
+		&response, &rec.LockedAt, &rec.CreatedAt, &completedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	rec.TransactionID = transactionID.String
+	rec.Response = response
+	if completedAt.Valid {
+		rec.CompletedAt = &completedAt.Time
+	}
+	return rec, nil
+}
+
+// ClaimIdempotencyKey reserves a key for processing until the unit of work ctx runs in ends.
+// It returns claimed=true when the caller now owns the key. Otherwise it returns the existing
+// record, locked, after waiting for any unit of work that holds the key to end.
+func (db *DB) ClaimIdempotencyKey(ctx context.Context, rec *models.IdempotencyRecord) (*models.IdempotencyRecord, bool, error) {
+	if _, ok := ctx.Value(unitOfWorkKey{}).(*unitOfWork); !ok {
+		return nil, false, fmt.Errorf("failed to claim idempotency key: %w", models.ErrNotInUnitOfWork)
+	}
+
+	query := `
+		INSERT INTO idempotency_keys (key, operation, request_hash, status, locked_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $5)
+		ON CONFLICT (operation, key) DO NOTHING`
+
+	result, err := db.conn(ctx).ExecContext(ctx, query, rec.Key, rec.Operation, rec.RequestHash, models.IdempotencyStatusInProgress, rec.LockedAt)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to claim idempotency key: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 1 {
+		return rec, true, nil
+	}
+
+	query = `SELECT ` + idempotencyColumns + ` FROM idempotency_keys WHERE operation = $1 AND key = $2 FOR UPDATE`
+
+	existing, err := scanIdempotencyRecord(db.conn(ctx).QueryRowContext(ctx, query, rec.Operation, rec.Key))
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get idempotency key: %w", err)
+	}
+
+	return existing, false, nil
+}
+
+// CompleteIdempotencyKey stores the response for a claimed key
+func (db *DB) CompleteIdempotencyKey(ctx context.Context, operation, key, transactionID string, response []byte) error {
+	query := `
+		UPDATE idempotency_keys
+		SET status = 'completed', transaction_id = $3, response = $4, completed_at = $5
+		WHERE operation = $1 AND key = $2`
+
+	if _, err := db.conn(ctx).ExecContext(ctx, query, operation, key, nullString(transactionID), response, time.Now()); err != nil {
+		db.logger.Error("Failed to complete idempotency key", "error", err, "key", key)
+		return fmt.Errorf("failed to complete idempotency key: %w", err)
+	}
+
+	return nil
+}
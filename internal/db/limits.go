@@ -0,0 +1,125 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/araesf/ledgertime/internal/models"
+	"github.com/lib/pq"
+)
+
+// Spending limit operations
+func (db *DB) GetSpendingLimits(ctx context.Context, scope, scopeID string) (*models.SpendingLimits, error) {
+	return db.getSpendingLimits(ctx, scope, scopeID, "")
+}
+
+// LockSpendingLimits loads the limits of a card or user FOR UPDATE, so payments checked against
+// them wait for each other and sum the windowed spend the previous payment committed
+func (db *DB) LockSpendingLimits(ctx context.Context, scope, scopeID string) (*models.SpendingLimits, error) {
+	if _, ok := ctx.Value(unitOfWorkKey{}).(*unitOfWork); !ok {
+		return nil, fmt.Errorf("failed to lock spending limits: %w", models.ErrNotInUnitOfWork)
+	}
+	return db.getSpendingLimits(ctx, scope, scopeID, " FOR UPDATE")
+}
+
+func (db *DB) getSpendingLimits(ctx context.Context, scope, scopeID, lock string) (*models.SpendingLimits, error) {
+	query := `
+		SELECT scope, scope_id, currency, single_transaction_limit, daily_limit, weekly_limit, monthly_limit,
+			blocked_categories, category_caps, updated_at
+		FROM spending_limits WHERE scope = $1 AND scope_id = $2` + lock
+
+	limits := &models.SpendingLimits{}
+	var caps []byte
+	err := db.conn(ctx).QueryRowContext(ctx, query, scope, scopeID).Scan(
+		&limits.Scope, &limits.ScopeID, &limits.Currency, &limits.SingleTransaction, &limits.Daily,
+		&limits.Weekly, &limits.Monthly, pq.Array(&limits.BlockedCategories), &caps, &limits.UpdatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("%s spending limits %w: %s", scope, models.ErrNotFound, scopeID)
+		}
+		return nil, fmt.Errorf("failed to get spending limits: %w", err)
+	}
+
+	if err := json.Unmarshal(caps, &limits.CategoryCaps); err != nil {
+		return nil, fmt.Errorf("failed to decode category caps: %w", err)
+	}
+
+	return limits, nil
+}
+
+// SetSpendingLimits creates or replaces the limits of a card or user
+func (db *DB) SetSpendingLimits(ctx context.Context, limits *models.SpendingLimits) error {
+	caps, err := json.Marshal(limits.CategoryCaps)
+	if err != nil {
+		return fmt.Errorf("failed to encode category caps: %w", err)
+	}
+
+	query := `
+		INSERT INTO spending_limits (scope, scope_id, currency, single_transaction_limit, daily_limit, weekly_limit,
+			monthly_limit, blocked_categories, category_caps, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		ON CONFLICT (scope, scope_id) DO UPDATE SET
+			currency = EXCLUDED.currency,
+			single_transaction_limit = EXCLUDED.single_transaction_limit,
+			daily_limit = EXCLUDED.daily_limit,
+			weekly_limit = EXCLUDED.weekly_limit,
+			monthly_limit = EXCLUDED.monthly_limit,
+			blocked_categories = EXCLUDED.blocked_categories,
+			category_caps = EXCLUDED.category_caps,
+			updated_at = EXCLUDED.updated_at`
+
+	_, err = db.conn(ctx).ExecContext(ctx, query,
+		limits.Scope, limits.ScopeID, limits.Currency, limits.SingleTransaction, limits.Daily, limits.Weekly,
+		limits.Monthly, pq.Array(limits.BlockedCategories), caps, limits.UpdatedAt,
+	)
+	if err != nil {
+		db.logger.Error("Failed to set spending limits", "error", err, "scope", limits.Scope, "scope_id", limits.ScopeID)
+		return fmt.Errorf("failed to set spending limits: %w", err)
+	}
+
+	db.logger.Info("Spending limits set", "scope", limits.Scope, "scope_id", limits.ScopeID)
+	return nil
+}
+
+// GetSpendingSince sums the accepted payments of a card or user per currency with a timestamp at
+// or after since, excluding one transaction. An empty category sums all categories.
+func (db *DB) GetSpendingSince(ctx context.Context, scope, scopeID, category string, since time.Time, excludeID string) (map[string]int64, error) {
+	owner := `card_id IN (` + cardLineage + `)` // Replacement cards inherit their predecessors' spend
+	if scope == models.LimitScopeUser {
+		owner = `user_id = $1`
+	}
+
+	query := `
+		SELECT currency, SUM(amount)
+		FROM transactions
+		WHERE ` + owner + ` AND timestamp >= $2 AND id <> $3
+			AND type = 'payment'
+			AND status IN ('in_review', 'authorized', 'settled', 'partially_refunded')
+			AND ($4 = '' OR LOWER(category) = LOWER($4))
+		GROUP BY currency`
+
+	rows, err := db.conn(ctx).QueryContext(ctx, query, scopeID, since, excludeID, category)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get spending: %w", err)
+	}
+	defer rows.Close()
+
+	spent := make(map[string]int64)
+	for rows.Next() {
+		var currency string
+		var amount int64
+		if err := rows.Scan(&currency, &amount); err != nil {
+			return nil, fmt.Errorf("failed to scan spending: %w", err)
+		}
+		spent[currency] = amount
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get spending: %w", err)
+	}
+
+	return spent, nil
+}
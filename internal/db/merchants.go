@@ -0,0 +1,118 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/araesf/ledgertime/internal/models"
+)
+
+// Merchant operations
+const merchantColumns = `id, name, normalized_name, mcc, city, country, logo_url, created_at, updated_at`
+
+func scanMerchant(row rowScanner) (*models.Merchant, error) {
+	merchant := &models.Merchant{}
+	var mcc, city, country, logoURL sql.NullString
+	err := row.Scan(
+		&merchant.ID, &merchant.Name, &merchant.NormalizedName, &mcc, &city, &country, &logoURL,
+		&merchant.CreatedAt, &merchant.UpdatedAt,
+	)
+	merchant.MCC = mcc.String
+	merchant.City = city.String
+	merchant.Country = country.String
+	merchant.LogoURL = logoURL.String
+	return merchant, err
+}
+
+// FindOrCreateMerchant returns the merchant with the given merchant's normalized name, creating
+// it first if none exists. Concurrent callers for the same name all get the same merchant.
+func (db *DB) FindOrCreateMerchant(ctx context.Context, merchant *models.Merchant) (*models.Merchant, error) {
+	insert := `
+		INSERT INTO merchants (` + merchantColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (normalized_name) DO NOTHING`
+
+	result, err := db.conn(ctx).ExecContext(ctx, insert,
+		merchant.ID, merchant.Name, merchant.NormalizedName, nullString(merchant.MCC), nullString(merchant.City),
+		nullString(merchant.Country), nullString(merchant.LogoURL), merchant.CreatedAt, merchant.UpdatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create merchant: %w", err)
+	}
+	if created, _ := result.RowsAffected(); created == 1 {
+		db.logger.Info("Merchant created", "merchant_id", merchant.ID, "name", merchant.Name)
+		return merchant, nil
+	}
+
+	query := `SELECT ` + merchantColumns + ` FROM merchants WHERE normalized_name = $1`
+	existing, err := scanMerchant(db.conn(ctx).QueryRowContext(ctx, query, merchant.NormalizedName))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get merchant: %w", err)
+	}
+	return existing, nil
+}
+
+func (db *DB) GetMerchant(ctx context.Context, id string) (*models.Merchant, error) {
+	query := `SELECT ` + merchantColumns + ` FROM merchants WHERE id = $1`
+
+	merchant, err := scanMerchant(db.conn(ctx).QueryRowContext(ctx, query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("merchant %w: %s", models.ErrNotFound, id)
+		}
+		return nil, fmt.Errorf("failed to get merchant: %w", err)
+	}
+
+	return merchant, nil
+}
+
+// GetMerchants lists merchants by name
+func (db *DB) GetMerchants(ctx context.Context, limit, offset int) ([]*models.Merchant, error) {
+	query := `
+		SELECT ` + merchantColumns + `
+		FROM merchants
+		ORDER BY name, id
+		LIMIT $1 OFFSET $2`
+
+	rows, err := db.conn(ctx).QueryContext(ctx, query, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get merchants: %w", err)
+	}
+	defer rows.Close()
+
+	var merchants []*models.Merchant
+	for rows.Next() {
+		merchant, err := scanMerchant(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan merchant: %w", err)
+		}
+		merchants = append(merchants, merchant)
+	}
+
+	return merchants, nil
+}
+
+// UpdateMerchant saves a merchant's display details. The normalized name is fixed at creation.
+func (db *DB) UpdateMerchant(ctx context.Context, merchant *models.Merchant) error {
+	query := `
+		UPDATE merchants
+		SET name = $2, mcc = $3, city = $4, country = $5, logo_url = $6
+		WHERE id = $1
+		RETURNING updated_at`
+
+	err := db.conn(ctx).QueryRowContext(ctx, query,
+		merchant.ID, merchant.Name, nullString(merchant.MCC), nullString(merchant.City),
+		nullString(merchant.Country), nullString(merchant.LogoURL),
+	).Scan(&merchant.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("merchant %w: %s", models.ErrNotFound, merchant.ID)
+		}
+		db.logger.Error("Failed to update merchant", "error", err, "merchant_id", merchant.ID)
+		return fmt.Errorf("failed to update merchant: %w", err)
+	}
+
+	db.logger.Info("Merchant updated", "merchant_id", merchant.ID)
+	return nil
+}
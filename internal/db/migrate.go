@@ -0,0 +1,566 @@
+package db
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/araesf/ledgertime/internal/vault"
+)
+
+var (
+	// ErrChecksumMismatch is returned when an applied migration's file has changed since it ran
+	ErrChecksumMismatch = errors.New("migration checksum mismatch")
+
+	// ErrUnknownMigration is returned when the database has a migration this build doesn't have,
+	// usually because a newer build migrated it
+	ErrUnknownMigration = errors.New("unknown migration")
+
+	// ErrUntrackedSchema is returned when migrating a database that already has tables but no
+	// recorded migrations, such as one set up from the old schema.sql. Those are adopted with Baseline.
+	ErrUntrackedSchema = errors.New("database has tables but no recorded migrations")
+
+	// ErrSchemaMismatch is returned when adopting a database whose tables don't match the baseline
+	ErrSchemaMismatch = errors.New("database does not match the baseline schema")
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// migrationFile matches migration file names: <version>_<name>.up.sql and <version>_<name>.down.sql
+var migrationFile = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// migrationLockID is the key of the advisory lock held while migrating, so instances starting
+// together don't apply the same migration twice
+const migrationLockID = 4_715_530_198
+
+// Migrations the Go side of the migrator knows about
+const (
+	baselineVersion        = 1  // the old schema.sql, which Baseline records as applied
+	cardTokensVersion      = 13 // adds token columns; TokenizeCards fills them in
+	dropCardNumbersVersion = 14 // drops plaintext card numbers once TokenizeCards has run
+)
+
+// baselineColumns are the tables and columns of the old schema.sql
+var baselineColumns = map[string][]string{
+	"users":        {"id", "name", "email", "created_at", "updated_at"},
+	"cards":        {"id", "user_id", "card_number", "card_type", "is_active", "created_at"},
+	"transactions": {"id", "user_id", "card_id", "amount", "merchant_name", "category", "description", "status", "timestamp", "created_at", "updated_at"},
+}
+
+// Migration is one versioned change to the schema
+type Migration struct {
+	Version  int64
+	Name     string
+	Up       string
+	Down     string
+	Checksum string // SHA-256 of the up script, recorded when it is applied
+}
+
+// MigrationStatus reports whether a migration has been applied
+type MigrationStatus struct {
+	Version   int64
+	Name      string
+	AppliedAt *time.Time // Nil while pending
+}
+
+// appliedMigration is a row of schema_migrations
+type appliedMigration struct {
+	version   int64
+	name      string
+	checksum  string
+	appliedAt time.Time
+}
+
+// Migrations returns the migrations embedded in the binary, oldest first. Every migration
+// needs both an up and a down script.
+func Migrations() ([]*Migration, error) {
+	files, err := fs.ReadDir(migrationFiles, "migrations")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, file := range files {
+		match := migrationFile.FindStringSubmatch(file.Name())
+		if match == nil {
+			return nil, fmt.Errorf("invalid migration file name: %s", file.Name())
+		}
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version: %s", file.Name())
+		}
+
+		script, err := migrationFiles.ReadFile("migrations/" + file.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", file.Name(), err)
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		}
+		if m.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has two names: %s and %s", version, m.Name, match[2])
+		}
+
+		if match[3] == "up" {
+			m.Up = string(script)
+			sum := sha256.Sum256(script)
+			m.Checksum = hex.EncodeToString(sum[:])
+		} else {
+			m.Down = string(script)
+		}
+	}
+
+	migrations := make([]*Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s needs both an up and a down script", m.Version, m.Name)
+		}
+		migrations = append(migrations, m)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+// MigrateUp applies every pending migration in order, each in its own transaction, and returns
+// how many were applied. It refuses to run if an applied migration has changed or is unknown,
+// or if the database has tables that no migration created.
+func (db *DB) MigrateUp(ctx context.Context) (int, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return 0, err
+	}
+
+	applied := 0
+	err = db.withMigrationLock(ctx, func(conn *sql.Conn) error {
+		done, err := verifyMigrations(ctx, conn, migrations)
+		if err != nil {
+			return err
+		}
+		if len(done) == 0 {
+			if err := checkEmptySchema(ctx, conn); err != nil {
+				return err
+			}
+		}
+
+		for _, m := range migrations {
+			if _, ok := done[m.Version]; ok {
+				continue
+			}
+
+			db.logger.Info("Applying migration", "version", m.Version, "name", m.Name)
+			insert := `
+				INSERT INTO schema_migrations (version, name, checksum, applied_at)
+				VALUES ($1, $2, $3, $4)`
+			if err := runMigration(ctx, conn, m, m.Up, insert, m.Version, m.Name, m.Checksum, time.Now()); err != nil {
+				return err
+			}
+			applied++
+		}
+		return nil
+	})
+	if err != nil {
+		return applied, err
+	}
+
+	db.logger.Info("Database migrated", "applied", applied)
+	return applied, nil
+}
+
+// MigrateDown rolls back the given number of most recently applied migrations, newest first,
+// and returns how many were rolled back
+func (db *DB) MigrateDown(ctx context.Context, steps int) (int, error) {
+	if steps <= 0 {
+		return 0, fmt.Errorf("steps must be positive, got: %d", steps)
+	}
+
+	migrations, err := Migrations()
+	if err != nil {
+		return 0, err
+	}
+
+	rolledBack := 0
+	err = db.withMigrationLock(ctx, func(conn *sql.Conn) error {
+		done, err := verifyMigrations(ctx, conn, migrations)
+		if err != nil {
+			return err
+		}
+
+		for i := len(migrations) - 1; i >= 0 && rolledBack < steps; i-- {
+			m := migrations[i]
+			if _, ok := done[m.Version]; !ok {
+				continue
+			}
+
+			db.logger.Info("Rolling back migration", "version", m.Version, "name", m.Name)
+			remove := `DELETE FROM schema_migrations WHERE version = $1`
+			if err := runMigration(ctx, conn, m, m.Down, remove, m.Version); err != nil {
+				return err
+			}
+			rolledBack++
+		}
+		return nil
+	})
+	if err != nil {
+		return rolledBack, err
+	}
+
+	db.logger.Info("Database rolled back", "rolled_back", rolledBack)
+	return rolledBack, nil
+}
+
+// Baseline adopts a database set up from the old schema.sql by recording the baseline migration
+// as applied without running it, so MigrateUp can take it from there. It refuses databases
+// that already have recorded migrations or whose tables don't match the baseline.
+func (db *DB) Baseline(ctx context.Context) error {
+	migrations, err := Migrations()
+	if err != nil {
+		return err
+	}
+	baseline := migrations[0]
+	if baseline.Version != baselineVersion {
+		return fmt.Errorf("first migration is %d_%s, not the baseline", baseline.Version, baseline.Name)
+	}
+
+	err = db.withMigrationLock(ctx, func(conn *sql.Conn) error {
+		done, err := verifyMigrations(ctx, conn, migrations)
+		if err != nil {
+			return err
+		}
+		if len(done) > 0 {
+			return fmt.Errorf("database already has %d recorded migrations", len(done))
+		}
+
+		columns, err := schemaColumns(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if err := matchBaseline(columns); err != nil {
+			return err
+		}
+
+		query := `
+			INSERT INTO schema_migrations (version, name, checksum, applied_at)
+			VALUES ($1, $2, $3, $4)`
+		if _, err := conn.ExecContext(ctx, query, baseline.Version, baseline.Name, baseline.Checksum, time.Now()); err != nil {
+			return fmt.Errorf("failed to record migration %d_%s: %w", baseline.Version, baseline.Name, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	db.logger.Info("Database adopted at the baseline", "version", baseline.Version, "name", baseline.Name)
+	return nil
+}
+
+// TokenizeCards encrypts the plaintext card numbers of cards from before the card token
+// migration under the vault and clears them, and returns how many cards it tokenized. It runs
+// between that migration and the one dropping plaintext numbers, which refuses to run before it.
+func (db *DB) TokenizeCards(ctx context.Context, v *vault.Vault) (int, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return 0, err
+	}
+
+	tokenized := 0
+	err = db.withMigrationLock(ctx, func(conn *sql.Conn) error {
+		done, err := verifyMigrations(ctx, conn, migrations)
+		if err != nil {
+			return err
+		}
+		if _, ok := done[dropCardNumbersVersion]; ok {
+			return nil // Every card is already tokenized
+		}
+		if _, ok := done[cardTokensVersion]; !ok {
+			return fmt.Errorf("migration %d must be applied before tokenizing cards", cardTokensVersion)
+		}
+
+		tokenized, err = tokenizeCards(ctx, conn, v)
+		return err
+	})
+	if err != nil {
+		return tokenized, err
+	}
+
+	db.logger.Info("Cards tokenized", "tokenized", tokenized)
+	return tokenized, nil
+}
+
+// tokenizeCards tokenizes every card still holding a plaintext number in one transaction
+func tokenizeCards(ctx context.Context, conn *sql.Conn, v *vault.Vault) (int, error) {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `SELECT id, card_number FROM cards WHERE token IS NULL FOR UPDATE`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get untokenized cards: %w", err)
+	}
+	numbers := make(map[string]string)
+	for rows.Next() {
+		var id, number string
+		if err := rows.Scan(&id, &number); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan card: %w", err)
+		}
+		numbers[id] = number
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to get untokenized cards: %w", err)
+	}
+
+	query := `
+		UPDATE cards
+		SET token = $2, last4 = $3, pan_hash = $4, pan_key_id = $5, pan_data_key = $6, pan_ciphertext = $7,
+			card_number = NULL
+		WHERE id = $1`
+
+	for id, number := range numbers {
+		normalized, err := vault.Normalize(number)
+		if err != nil {
+			return 0, fmt.Errorf("failed to tokenize card %s: %w", id, err)
+		}
+		token, err := vault.NewToken()
+		if err != nil {
+			return 0, err
+		}
+		sealed, err := v.Seal(normalized, token)
+		if err != nil {
+			return 0, fmt.Errorf("failed to encrypt card number of card %s: %w", id, err)
+		}
+
+		_, err = tx.ExecContext(ctx, query, id, token, vault.Last4(normalized), v.LookupHash(normalized),
+			sealed.KeyID, sealed.DataKey, sealed.Ciphertext)
+		if err != nil {
+			return 0, fmt.Errorf("failed to tokenize card %s: %w", id, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit tokenized cards: %w", err)
+	}
+	return len(numbers), nil
+}
+
+// GetMigrationStatus lists every migration, oldest first, with when it was applied
+func (db *DB) GetMigrationStatus(ctx context.Context) ([]*MigrationStatus, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+
+	var statuses []*MigrationStatus
+	err = db.withMigrationLock(ctx, func(conn *sql.Conn) error {
+		done, err := verifyMigrations(ctx, conn, migrations)
+		if err != nil {
+			return err
+		}
+
+		for _, m := range migrations {
+			status := &MigrationStatus{Version: m.Version, Name: m.Name}
+			if a, ok := done[m.Version]; ok {
+				status.AppliedAt = &a.appliedAt
+			}
+			statuses = append(statuses, status)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return statuses, nil
+}
+
+// withMigrationLock runs fn on one connection holding the migration lock, creating the
+// schema_migrations table first if needed. Advisory locks belong to a session, so the lock,
+// fn and the unlock must all use the same connection.
+func (db *DB) withMigrationLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get connection: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, migrationLockID); err != nil {
+		return fmt.Errorf("failed to acquire migration lock: %w", err)
+	}
+	defer func() {
+		// Unlock even if ctx is done, so the connection doesn't go back to the pool still locked
+		if _, err := conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, migrationLockID); err != nil {
+			db.logger.Error("Failed to release migration lock", "error", err)
+		}
+	}()
+
+	query := `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version BIGINT PRIMARY KEY,
+			name VARCHAR(255) NOT NULL,
+			checksum VARCHAR(64) NOT NULL,
+			applied_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+		)`
+	if _, err := conn.ExecContext(ctx, query); err != nil {
+		return fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+
+	return fn(conn)
+}
+
+// verifyMigrations loads the applied migrations and checks each is one of migrations, unchanged
+func verifyMigrations(ctx context.Context, conn *sql.Conn, migrations []*Migration) (map[int64]*appliedMigration, error) {
+	query := `SELECT version, name, checksum, applied_at FROM schema_migrations ORDER BY version`
+
+	rows, err := conn.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get applied migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int64]*appliedMigration)
+	for rows.Next() {
+		a := &appliedMigration{}
+		if err := rows.Scan(&a.version, &a.name, &a.checksum, &a.appliedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan applied migration: %w", err)
+		}
+		applied[a.version] = a
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get applied migrations: %w", err)
+	}
+
+	known := make(map[int64]*Migration, len(migrations))
+	for _, m := range migrations {
+		known[m.Version] = m
+	}
+	for _, a := range applied {
+		m, ok := known[a.version]
+		if !ok {
+			return nil, fmt.Errorf("%w: %d_%s", ErrUnknownMigration, a.version, a.name)
+		}
+		if m.Checksum != a.checksum {
+			return nil, fmt.Errorf("%w: %d_%s", ErrChecksumMismatch, m.Version, m.Name)
+		}
+	}
+
+	return applied, nil
+}
+
+// checkEmptySchema fails with ErrUntrackedSchema if the current schema has any table besides
+// schema_migrations. The first migration assumes an empty database, and running it over tables
+// from elsewhere would leave them half matching it; databases that already match it are
+// adopted with Baseline instead.
+func checkEmptySchema(ctx context.Context, conn *sql.Conn) error {
+	query := `
+		SELECT table_name FROM information_schema.tables
+		WHERE table_schema = current_schema() AND table_name <> 'schema_migrations'
+		ORDER BY table_name
+		LIMIT 1`
+
+	var table string
+	err := conn.QueryRowContext(ctx, query).Scan(&table)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to check for existing tables: %w", err)
+	}
+	return fmt.Errorf("%w: found table %s", ErrUntrackedSchema, table)
+}
+
+// schemaColumns lists the columns of every table in the current schema besides schema_migrations
+func schemaColumns(ctx context.Context, conn *sql.Conn) (map[string][]string, error) {
+	query := `
+		SELECT table_name, column_name FROM information_schema.columns
+		WHERE table_schema = current_schema() AND table_name <> 'schema_migrations'
+		ORDER BY table_name, ordinal_position`
+
+	rows, err := conn.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get existing columns: %w", err)
+	}
+	defer rows.Close()
+
+	columns := make(map[string][]string)
+	for rows.Next() {
+		var table, column string
+		if err := rows.Scan(&table, &column); err != nil {
+			return nil, fmt.Errorf("failed to scan column: %w", err)
+		}
+		columns[table] = append(columns[table], column)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get existing columns: %w", err)
+	}
+
+	return columns, nil
+}
+
+// matchBaseline fails with ErrSchemaMismatch unless columns has exactly the baseline's tables,
+// each with exactly the baseline's columns
+func matchBaseline(columns map[string][]string) error {
+	for table := range columns {
+		if _, ok := baselineColumns[table]; !ok {
+			return fmt.Errorf("%w: unexpected table %s", ErrSchemaMismatch, table)
+		}
+	}
+
+	for table, want := range baselineColumns {
+		got, ok := columns[table]
+		if !ok {
+			return fmt.Errorf("%w: missing table %s", ErrSchemaMismatch, table)
+		}
+
+		sortedGot := append([]string(nil), got...)
+		sortedWant := append([]string(nil), want...)
+		sort.Strings(sortedGot)
+		sort.Strings(sortedWant)
+		if strings.Join(sortedGot, ",") != strings.Join(sortedWant, ",") {
+			return fmt.Errorf("%w: table %s has columns %s, want %s",
+				ErrSchemaMismatch, table, strings.Join(got, ", "), strings.Join(want, ", "))
+		}
+	}
+
+	return nil
+}
+
+// runMigration runs a migration script and records the result in schema_migrations in one transaction
+func runMigration(ctx context.Context, conn *sql.Conn, m *Migration, script, record string, args ...interface{}) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Without arguments the script runs as a simple query, which may hold several statements
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return fmt.Errorf("failed to run migration %d_%s: %w", m.Version, m.Name, err)
+	}
+	if _, err := tx.ExecContext(ctx, record, args...); err != nil {
+		return fmt.Errorf("failed to record migration %d_%s: %w", m.Version, m.Name, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit migration %d_%s: %w", m.Version, m.Name, err)
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+package db
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestMigrations(t *testing.T) {
+	migrations, err := Migrations()
+	if err != nil {
+		t.Fatalf("Migrations() = %v", err)
+	}
+
+	want := []struct {
+		version int64
+		name    string
+	}{
+		{1, "baseline"},
+		{2, "accounts_and_postings"},
+		{3, "account_balances"},
+		{4, "authorizations"},
+		{5, "refunds"},
+		{6, "status_history"},
+		{7, "idempotency_keys"},
+		{8, "currencies"},
+		{9, "fraud"},
+		{10, "review_queue"},
+		{11, "spending_limits"},
+		{12, "card_lifecycle"},
+		{13, "card_tokens"},
+		{14, "drop_card_numbers"},
+		{15, "card_bins"},
+		{16, "merchants"},
+		{17, "categories"},
+		{18, "budgets"},
+		{19, "spending_rollups"},
+		{20, "outbox"},
+	}
+	if len(migrations) != len(want) {
+		t.Fatalf("got %d migrations, want %d", len(migrations), len(want))
+	}
+
+	for i, m := range migrations {
+		if m.Version != want[i].version || m.Name != want[i].name {
+			t.Errorf("migration %d = %d_%s, want %d_%s", i, m.Version, m.Name, want[i].version, want[i].name)
+		}
+		if strings.TrimSpace(m.Up) == "" || strings.TrimSpace(m.Down) == "" {
+			t.Errorf("migration %d_%s has an empty script", m.Version, m.Name)
+		}
+
+		sum := sha256.Sum256([]byte(m.Up))
+		if m.Checksum != hex.EncodeToString(sum[:]) {
+			t.Errorf("migration %d_%s checksum = %s, want the SHA-256 of its up script", m.Version, m.Name, m.Checksum)
+		}
+	}
+}
+
+func TestMigrationVersionsMatchGoSteps(t *testing.T) {
+	migrations, err := Migrations()
+	if err != nil {
+		t.Fatalf("Migrations() = %v", err)
+	}
+
+	names := make(map[int64]string)
+	for _, m := range migrations {
+		names[m.Version] = m.Name
+	}
+	for version, name := range map[int64]string{
+		baselineVersion:        "baseline",
+		cardTokensVersion:      "card_tokens",
+		dropCardNumbersVersion: "drop_card_numbers",
+	} {
+		if names[version] != name {
+			t.Errorf("migration %d = %q, want %q", version, names[version], name)
+		}
+	}
+}
+
+func TestMatchBaseline(t *testing.T) {
+	baseline := func() map[string][]string {
+		columns := make(map[string][]string)
+		for table, cols := range baselineColumns {
+			// Reversed, since column order doesn't matter
+			for i := len(cols) - 1; i >= 0; i-- {
+				columns[table] = append(columns[table], cols[i])
+			}
+		}
+		return columns
+	}
+
+	if err := matchBaseline(baseline()); err != nil {
+		t.Errorf("matchBaseline(baseline) = %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		change func(map[string][]string)
+	}{
+		{"extra table", func(c map[string][]string) { c["accounts"] = []string{"id"} }},
+		{"missing table", func(c map[string][]string) { delete(c, "transactions") }},
+		{"extra column", func(c map[string][]string) { c["cards"] = append(c["cards"], "currency") }},
+		{"missing column", func(c map[string][]string) { c["users"] = c["users"][1:] }},
+		{"empty schema", func(c map[string][]string) {
+			for table := range c {
+				delete(c, table)
+			}
+		}},
+	}
+
+	for _, tt := range tests {
+		columns := baseline()
+		tt.change(columns)
+		if err := matchBaseline(columns); !errors.Is(err, ErrSchemaMismatch) {
+			t.Errorf("%s: matchBaseline() = %v, want ErrSchemaMismatch", tt.name, err)
+		}
+	}
+}
+
+func TestMigrationFileNames(t *testing.T) {
+	tests := []struct {
+		name      string
+		version   string
+		migration string
+		direction string
+	}{
+		{"0001_initial_schema.up.sql", "0001", "initial_schema", "up"},
+		{"0002_outbox.down.sql", "0002", "outbox", "down"},
+		{"12_add_index.up.sql", "12", "add_index", "up"},
+		{"0001_initial_schema.sql", "", "", ""},
+		{"0001_initial-schema.up.sql", "", "", ""},
+		{"initial_schema.up.sql", "", "", ""},
+		{"0001_initial_schema.sideways.sql", "", "", ""},
+		{"0001_initial_schema.up.sql.bak", "", "", ""},
+	}
+
+	for _, tt := range tests {
+		match := migrationFile.FindStringSubmatch(tt.name)
+		if tt.version == "" {
+			if match != nil {
+				t.Errorf("%s matched as a migration file", tt.name)
+			}
+			continue
+		}
+		if match == nil {
+			t.Errorf("%s did not match as a migration file", tt.name)
+			continue
+		}
+		if match[1] != tt.version || match[2] != tt.migration || match[3] != tt.direction {
+			t.Errorf("%s parsed as %s_%s.%s", tt.name, match[1], match[2], match[3])
+		}
+	}
+}
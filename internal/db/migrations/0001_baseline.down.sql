@@ -0,0 +1,8 @@
+-- Drops the baseline schema, and with it every row in the ledger
+
+-- Dropping the tables drops their triggers
+DROP TABLE IF EXISTS transactions;
+DROP TABLE IF EXISTS cards;
+DROP TABLE IF EXISTS users;
+
+DROP FUNCTION IF EXISTS update_updated_at_column();
@@ -1,5 +1,6 @@
--- Database schema for Ledgertime
--- PostgreSQL schema for a modern financial ledger system
+-- Baseline schema for Ledgertime, as created by the former schema.sql
+-- PostgreSQL schema for a modern financial ledger system. Databases set up from schema.sql
+-- already match it and are adopted with `migrate baseline` instead of running it.
 
 -- Users table
 CREATE TABLE IF NOT EXISTS users (
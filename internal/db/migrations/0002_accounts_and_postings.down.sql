@@ -0,0 +1,3 @@
+-- Drops the accounts and every posting booked to them
+DROP TABLE IF EXISTS postings;
+DROP TABLE IF EXISTS accounts;
@@ -0,0 +1,56 @@
+-- Double-entry accounts and postings beneath the ledger. Payments completed before this
+-- migration are booked the way the ledger books them from now on: a debit of the card
+-- holder's wallet and a credit of merchant clearing.
+
+-- Accounts table (double-entry ledger accounts)
+CREATE TABLE accounts (
+    id VARCHAR(36) PRIMARY KEY,
+    user_id VARCHAR(36) REFERENCES users(id) ON DELETE CASCADE, -- NULL for system accounts
+    account_type VARCHAR(50) NOT NULL, -- user_wallet, merchant_clearing, fee_income, suspense
+    name VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    CHECK (account_type IN ('user_wallet', 'merchant_clearing', 'fee_income', 'suspense')),
+    CHECK ((account_type = 'user_wallet') = (user_id IS NOT NULL))
+);
+
+-- Postings table (debit/credit legs of every ledger transaction)
+CREATE TABLE postings (
+    id VARCHAR(36) PRIMARY KEY,
+    transaction_id VARCHAR(36) NOT NULL REFERENCES transactions(id) ON DELETE CASCADE,
+    account_id VARCHAR(36) NOT NULL REFERENCES accounts(id),
+    direction VARCHAR(6) NOT NULL, -- debit, credit
+    amount BIGINT NOT NULL, -- Amount in cents
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    CHECK (amount > 0),
+    CHECK (direction IN ('debit', 'credit'))
+);
+
+-- System accounts shared by all users
+INSERT INTO accounts (id, user_id, account_type, name) VALUES
+    ('00000000-0000-0000-0000-000000000001', NULL, 'merchant_clearing', 'Merchant clearing'),
+    ('00000000-0000-0000-0000-000000000002', NULL, 'fee_income', 'Fee income'),
+    ('00000000-0000-0000-0000-000000000003', NULL, 'suspense', 'Suspense');
+
+CREATE UNIQUE INDEX idx_accounts_user_type ON accounts(user_id, account_type) WHERE user_id IS NOT NULL;
+CREATE UNIQUE INDEX idx_accounts_system_type ON accounts(account_type) WHERE user_id IS NULL;
+
+CREATE INDEX idx_postings_transaction_id ON postings(transaction_id);
+CREATE INDEX idx_postings_account_id ON postings(account_id);
+
+-- Wallets of the users with completed payments
+INSERT INTO accounts (id, user_id, account_type, name)
+SELECT gen_random_uuid()::text, id, 'user_wallet', 'User wallet (USD)'
+FROM users
+WHERE id IN (SELECT user_id FROM transactions WHERE status = 'completed');
+
+-- Both legs of every completed payment, dated when it completed
+INSERT INTO postings (id, transaction_id, account_id, direction, amount, created_at)
+SELECT gen_random_uuid()::text, t.id, a.id, 'debit', t.amount, t.updated_at
+FROM transactions t
+JOIN accounts a ON a.user_id = t.user_id AND a.account_type = 'user_wallet'
+WHERE t.status = 'completed';
+
+INSERT INTO postings (id, transaction_id, account_id, direction, amount, created_at)
+SELECT gen_random_uuid()::text, id, '00000000-0000-0000-0000-000000000001', 'credit', amount, updated_at
+FROM transactions
+WHERE status = 'completed';
@@ -0,0 +1,8 @@
+-- Drops the balances; they can be recomputed from the postings
+DROP TRIGGER IF EXISTS update_accounts_updated_at ON accounts;
+
+ALTER TABLE accounts
+    DROP COLUMN ledger_balance,
+    DROP COLUMN held_amount,
+    DROP COLUMN overdraft_limit,
+    DROP COLUMN updated_at;
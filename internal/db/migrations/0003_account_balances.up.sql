@@ -0,0 +1,21 @@
+-- Real-time account balances and overdraft limits, starting from the postings booked so far
+
+ALTER TABLE accounts
+    ADD COLUMN ledger_balance BIGINT NOT NULL DEFAULT 0, -- credits minus debits, in cents
+    ADD COLUMN held_amount BIGINT NOT NULL DEFAULT 0, -- pending holds, in cents
+    ADD COLUMN overdraft_limit BIGINT NOT NULL DEFAULT 0, -- how far available balance may go below zero
+    ADD COLUMN updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    ADD CONSTRAINT accounts_held_amount_check CHECK (held_amount >= 0),
+    ADD CONSTRAINT accounts_overdraft_limit_check CHECK (overdraft_limit >= 0);
+
+UPDATE accounts a
+SET ledger_balance = p.balance
+FROM (
+    SELECT account_id, SUM(CASE WHEN direction = 'credit' THEN amount ELSE -amount END) AS balance
+    FROM postings
+    GROUP BY account_id
+) p
+WHERE a.id = p.account_id;
+
+CREATE TRIGGER update_accounts_updated_at BEFORE UPDATE ON accounts
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
@@ -0,0 +1,9 @@
+-- Drops the holds. Fails while transactions are authorized, voided or expired, which the
+-- previous schema has no status for.
+DROP TABLE IF EXISTS holds;
+
+ALTER TABLE transactions DROP CONSTRAINT transactions_status_check;
+ALTER TABLE transactions ADD CONSTRAINT transactions_status_check
+    CHECK (status IN ('pending', 'completed', 'failed'));
+
+ALTER TABLE transactions DROP COLUMN authorized_amount;
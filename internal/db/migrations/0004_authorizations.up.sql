@@ -0,0 +1,28 @@
+-- Two-phase authorizations: held amounts and the holds that reserve them
+
+ALTER TABLE transactions
+    ADD COLUMN authorized_amount BIGINT NOT NULL DEFAULT 0; -- Amount held by the authorization, in cents
+
+ALTER TABLE transactions DROP CONSTRAINT transactions_status_check;
+ALTER TABLE transactions ADD CONSTRAINT transactions_status_check
+    CHECK (status IN ('pending', 'authorized', 'completed', 'failed', 'voided', 'expired'));
+
+-- Holds table (funds reserved by card authorizations)
+CREATE TABLE holds (
+    id VARCHAR(36) PRIMARY KEY,
+    transaction_id VARCHAR(36) NOT NULL REFERENCES transactions(id) ON DELETE CASCADE,
+    account_id VARCHAR(36) NOT NULL REFERENCES accounts(id),
+    amount BIGINT NOT NULL, -- Amount in cents
+    status VARCHAR(20) NOT NULL DEFAULT 'active', -- active, captured, released, expired
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    CHECK (amount > 0),
+    CHECK (status IN ('active', 'captured', 'released', 'expired'))
+);
+
+CREATE INDEX idx_holds_transaction_id ON holds(transaction_id);
+CREATE INDEX idx_holds_active_expiry ON holds(expires_at) WHERE status = 'active';
+
+CREATE TRIGGER update_holds_updated_at BEFORE UPDATE ON holds
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
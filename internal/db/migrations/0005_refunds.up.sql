@@ -0,0 +1,13 @@
+-- Refunds and reversals, linked to the payment they return money for
+
+ALTER TABLE transactions
+    ADD COLUMN parent_transaction_id VARCHAR(36) REFERENCES transactions(id), -- original transaction for refunds and reversals
+    ADD COLUMN type VARCHAR(20) NOT NULL DEFAULT 'payment', -- payment, refund, reversal
+    ADD CONSTRAINT transactions_type_check CHECK (type IN ('payment', 'refund', 'reversal')),
+    ADD CONSTRAINT transactions_parent_check CHECK ((type = 'payment') = (parent_transaction_id IS NULL));
+
+ALTER TABLE transactions DROP CONSTRAINT transactions_status_check;
+ALTER TABLE transactions ADD CONSTRAINT transactions_status_check
+    CHECK (status IN ('pending', 'authorized', 'completed', 'failed', 'voided', 'expired', 'reversed', 'partially_refunded', 'refunded'));
+
+CREATE INDEX idx_transactions_parent ON transactions(parent_transaction_id) WHERE parent_transaction_id IS NOT NULL;
@@ -0,0 +1,12 @@
+-- Drops the status history and renames settled and declined back to completed and failed
+DROP TABLE IF EXISTS transaction_status_history;
+
+ALTER TABLE transactions DROP CONSTRAINT transactions_status_check;
+
+ALTER TABLE transactions DISABLE TRIGGER update_transactions_updated_at;
+UPDATE transactions SET status = 'completed' WHERE status = 'settled';
+UPDATE transactions SET status = 'failed' WHERE status = 'declined';
+ALTER TABLE transactions ENABLE TRIGGER update_transactions_updated_at;
+
+ALTER TABLE transactions ADD CONSTRAINT transactions_status_check
+    CHECK (status IN ('pending', 'authorized', 'completed', 'failed', 'voided', 'expired', 'reversed', 'partially_refunded', 'refunded'));
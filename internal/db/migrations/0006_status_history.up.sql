@@ -0,0 +1,26 @@
+-- Transaction state machine: completed and failed become settled and declined, and every
+-- transition from now on is recorded
+
+ALTER TABLE transactions DROP CONSTRAINT transactions_status_check;
+
+-- Renaming a status is not an update of the transaction, so updated_at is left alone
+ALTER TABLE transactions DISABLE TRIGGER update_transactions_updated_at;
+UPDATE transactions SET status = 'settled' WHERE status = 'completed';
+UPDATE transactions SET status = 'declined' WHERE status = 'failed';
+ALTER TABLE transactions ENABLE TRIGGER update_transactions_updated_at;
+
+ALTER TABLE transactions ADD CONSTRAINT transactions_status_check
+    CHECK (status IN ('pending', 'authorized', 'settled', 'declined', 'voided', 'expired', 'reversed', 'partially_refunded', 'refunded'));
+
+-- Transaction status history (every persisted state machine transition)
+CREATE TABLE transaction_status_history (
+    id VARCHAR(36) PRIMARY KEY,
+    transaction_id VARCHAR(36) NOT NULL REFERENCES transactions(id) ON DELETE CASCADE,
+    from_status VARCHAR(20) NOT NULL,
+    to_status VARCHAR(20) NOT NULL,
+    actor VARCHAR(100) NOT NULL, -- component or operation that caused the change
+    reason TEXT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_status_history_transaction ON transaction_status_history(transaction_id, created_at);
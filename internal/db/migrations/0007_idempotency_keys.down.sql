@@ -0,0 +1,2 @@
+-- Drops the idempotency keys, so retries after this are processed again
+DROP TABLE IF EXISTS idempotency_keys;
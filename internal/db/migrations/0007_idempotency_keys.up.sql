@@ -0,0 +1,14 @@
+-- Idempotency keys (stored responses for retried requests and redelivered messages)
+CREATE TABLE idempotency_keys (
+    key VARCHAR(255) NOT NULL,
+    operation VARCHAR(50) NOT NULL, -- payment, authorization
+    request_hash CHAR(64) NOT NULL, -- SHA-256 of the request fingerprint
+    status VARCHAR(20) NOT NULL DEFAULT 'in_progress', -- in_progress, completed
+    transaction_id VARCHAR(36) REFERENCES transactions(id) ON DELETE SET NULL,
+    response JSONB,
+    locked_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    completed_at TIMESTAMP WITH TIME ZONE,
+    PRIMARY KEY (operation, key),
+    CHECK (status IN ('in_progress', 'completed'))
+);
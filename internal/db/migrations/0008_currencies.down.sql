@@ -0,0 +1,15 @@
+-- Drops the currencies. Fails while a user or system account is open in more than one currency.
+DROP INDEX idx_accounts_user_type;
+DROP INDEX idx_accounts_system_type;
+CREATE UNIQUE INDEX idx_accounts_user_type ON accounts(user_id, account_type) WHERE user_id IS NOT NULL;
+CREATE UNIQUE INDEX idx_accounts_system_type ON accounts(account_type) WHERE user_id IS NULL;
+
+ALTER TABLE accounts DROP COLUMN currency;
+
+ALTER TABLE transactions
+    DROP COLUMN currency,
+    DROP COLUMN original_amount,
+    DROP COLUMN original_currency,
+    DROP COLUMN fx_rate;
+
+ALTER TABLE cards DROP COLUMN currency;
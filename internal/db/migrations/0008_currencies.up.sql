@@ -0,0 +1,28 @@
+-- Multi-currency amounts. Everything before this migration was in US dollars, so existing
+-- cards, accounts and transactions are USD and each transaction's original amount is its amount.
+
+ALTER TABLE cards
+    ADD COLUMN currency CHAR(3) NOT NULL DEFAULT 'USD'; -- ISO 4217 billing currency
+
+ALTER TABLE transactions
+    ADD COLUMN currency CHAR(3) NOT NULL DEFAULT 'USD', -- ISO 4217 billing currency of amount
+    ADD COLUMN original_amount BIGINT, -- Amount in minor units of the original currency
+    ADD COLUMN original_currency CHAR(3), -- ISO 4217 currency the merchant charged in
+    ADD COLUMN fx_rate NUMERIC(20, 10); -- original -> billing rate, NULL when no conversion was needed
+
+ALTER TABLE transactions DISABLE TRIGGER update_transactions_updated_at;
+UPDATE transactions SET original_amount = amount, original_currency = currency;
+ALTER TABLE transactions ENABLE TRIGGER update_transactions_updated_at;
+
+ALTER TABLE transactions
+    ALTER COLUMN original_amount SET NOT NULL,
+    ALTER COLUMN original_currency SET NOT NULL;
+
+ALTER TABLE accounts
+    ADD COLUMN currency CHAR(3) NOT NULL DEFAULT 'USD'; -- ISO 4217
+
+-- One wallet per user and currency, and one system account per type and currency
+DROP INDEX idx_accounts_user_type;
+DROP INDEX idx_accounts_system_type;
+CREATE UNIQUE INDEX idx_accounts_user_type ON accounts(user_id, account_type, currency) WHERE user_id IS NOT NULL;
+CREATE UNIQUE INDEX idx_accounts_system_type ON accounts(account_type, currency) WHERE user_id IS NULL;
@@ -0,0 +1,8 @@
+-- Drops the fraud screening results
+DROP INDEX IF EXISTS idx_transactions_card_timestamp;
+
+ALTER TABLE transactions
+    DROP COLUMN merchant_country,
+    DROP COLUMN fraud_score,
+    DROP COLUMN fraud_decision,
+    DROP COLUMN fraud_reasons;
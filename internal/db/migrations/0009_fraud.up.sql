@@ -0,0 +1,10 @@
+-- Fraud screening results and the merchant country the travel rule compares
+
+ALTER TABLE transactions
+    ADD COLUMN merchant_country VARCHAR(2) NOT NULL DEFAULT '', -- ISO 3166-1 alpha-2, empty when unknown
+    ADD COLUMN fraud_score INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN fraud_decision VARCHAR(20), -- approve, review, decline; NULL when not screened
+    ADD COLUMN fraud_reasons TEXT[],
+    ADD CONSTRAINT transactions_fraud_decision_check CHECK (fraud_decision IN ('approve', 'review', 'decline'));
+
+CREATE INDEX idx_transactions_card_timestamp ON transactions(card_id, timestamp DESC);
@@ -0,0 +1,7 @@
+-- Drops the review queue. Fails while transactions are in review, which the previous schema
+-- has no status for.
+DROP TABLE IF EXISTS review_queue;
+
+ALTER TABLE transactions DROP CONSTRAINT transactions_status_check;
+ALTER TABLE transactions ADD CONSTRAINT transactions_status_check
+    CHECK (status IN ('pending', 'authorized', 'settled', 'declined', 'voided', 'expired', 'reversed', 'partially_refunded', 'refunded'));
@@ -0,0 +1,30 @@
+-- Manual review queue for transactions flagged by fraud checks
+
+ALTER TABLE transactions DROP CONSTRAINT transactions_status_check;
+ALTER TABLE transactions ADD CONSTRAINT transactions_status_check
+    CHECK (status IN ('pending', 'in_review', 'authorized', 'settled', 'declined', 'voided', 'expired', 'reversed', 'partially_refunded', 'refunded'));
+
+-- Review queue (transactions parked for manual review)
+CREATE TABLE review_queue (
+    id VARCHAR(36) PRIMARY KEY,
+    transaction_id VARCHAR(36) NOT NULL UNIQUE REFERENCES transactions(id) ON DELETE CASCADE,
+    intent VARCHAR(20) NOT NULL, -- payment, authorization
+    status VARCHAR(20) NOT NULL DEFAULT 'pending', -- pending, claimed, approved, declined, expired
+    reason TEXT NOT NULL,
+    claimed_by VARCHAR(255),
+    claimed_at TIMESTAMP WITH TIME ZONE,
+    decided_by VARCHAR(255),
+    decided_at TIMESTAMP WITH TIME ZONE,
+    notes TEXT,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    CHECK (intent IN ('payment', 'authorization')),
+    CHECK (status IN ('pending', 'claimed', 'approved', 'declined', 'expired'))
+);
+
+CREATE INDEX idx_review_queue_status ON review_queue(status, created_at);
+CREATE INDEX idx_review_queue_open_expiry ON review_queue(expires_at) WHERE status IN ('pending', 'claimed');
+
+CREATE TRIGGER update_review_queue_updated_at BEFORE UPDATE ON review_queue
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
@@ -0,0 +1,4 @@
+-- Drops the spending limits and decline reasons
+DROP TABLE IF EXISTS spending_limits;
+
+ALTER TABLE transactions DROP COLUMN decline_reason;
@@ -0,0 +1,21 @@
+-- Per-card and per-user spending limits, and why a transaction was declined
+
+ALTER TABLE transactions
+    ADD COLUMN decline_reason TEXT; -- why the transaction was declined, NULL otherwise
+
+-- Spending limits per card or user (amounts in minor units, 0 = no limit)
+CREATE TABLE spending_limits (
+    scope VARCHAR(10) NOT NULL, -- card, user
+    scope_id VARCHAR(36) NOT NULL, -- card or user ID
+    currency CHAR(3) NOT NULL,
+    single_transaction_limit BIGINT NOT NULL DEFAULT 0,
+    daily_limit BIGINT NOT NULL DEFAULT 0, -- rolling 24 hours
+    weekly_limit BIGINT NOT NULL DEFAULT 0, -- rolling 7 days
+    monthly_limit BIGINT NOT NULL DEFAULT 0, -- rolling 30 days
+    blocked_categories TEXT[] NOT NULL DEFAULT '{}',
+    category_caps JSONB NOT NULL DEFAULT '[]', -- [{"category": "...", "amount": ...}] per rolling 30 days
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (scope, scope_id),
+    CHECK (scope IN ('card', 'user')),
+    CHECK (single_transaction_limit >= 0 AND daily_limit >= 0 AND weekly_limit >= 0 AND monthly_limit >= 0)
+);
@@ -0,0 +1,11 @@
+-- Drops card statuses, their history, expiry and replacement links. is_active keeps whether
+-- each card is usable.
+DROP TRIGGER IF EXISTS update_cards_updated_at ON cards;
+DROP TABLE IF EXISTS card_status_history;
+
+-- Dropping the columns drops their constraints and indexes
+ALTER TABLE cards
+    DROP COLUMN status,
+    DROP COLUMN expires_at,
+    DROP COLUMN replaces_card_id,
+    DROP COLUMN updated_at;
@@ -0,0 +1,36 @@
+-- Card lifecycle: statuses with their history, expiry and replacement cards. Cards
+-- deactivated before this migration become frozen, so they can still be reactivated or
+-- closed, and existing cards expire 1095 days after they were issued, the default validity.
+
+ALTER TABLE cards
+    ADD COLUMN status VARCHAR(20) NOT NULL DEFAULT 'active', -- active, frozen, lost, stolen, closed, expired
+    ADD COLUMN expires_at TIMESTAMP WITH TIME ZONE,
+    ADD COLUMN replaces_card_id VARCHAR(36) REFERENCES cards(id), -- card this one was issued to replace
+    ADD COLUMN updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW();
+
+UPDATE cards
+SET status = CASE WHEN is_active THEN 'active' ELSE 'frozen' END,
+    expires_at = created_at + INTERVAL '1095 days';
+
+ALTER TABLE cards
+    ALTER COLUMN expires_at SET NOT NULL,
+    ADD CONSTRAINT cards_status_check CHECK (status IN ('active', 'frozen', 'lost', 'stolen', 'closed', 'expired')),
+    ADD CONSTRAINT cards_is_active_check CHECK (is_active = (status = 'active'));
+
+-- Card status history (freezes, closures, replacements and expiry)
+CREATE TABLE card_status_history (
+    id VARCHAR(36) PRIMARY KEY,
+    card_id VARCHAR(36) NOT NULL REFERENCES cards(id) ON DELETE CASCADE,
+    from_status VARCHAR(20) NOT NULL,
+    to_status VARCHAR(20) NOT NULL,
+    actor VARCHAR(100) NOT NULL,
+    reason TEXT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_cards_replaces ON cards(replaces_card_id) WHERE replaces_card_id IS NOT NULL;
+CREATE INDEX idx_cards_open_expiry ON cards(expires_at) WHERE status IN ('active', 'frozen');
+CREATE INDEX idx_card_status_history_card ON card_status_history(card_id, created_at);
+
+CREATE TRIGGER update_cards_updated_at BEFORE UPDATE ON cards
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
@@ -0,0 +1,10 @@
+-- Drops the tokens and encrypted card numbers. Fails once any card has been tokenized, since
+-- its plaintext number is gone.
+ALTER TABLE cards
+    ALTER COLUMN card_number SET NOT NULL,
+    DROP COLUMN token,
+    DROP COLUMN last4,
+    DROP COLUMN pan_hash,
+    DROP COLUMN pan_key_id,
+    DROP COLUMN pan_data_key,
+    DROP COLUMN pan_ciphertext;
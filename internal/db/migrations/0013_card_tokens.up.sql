@@ -0,0 +1,12 @@
+-- Card tokens, lookup hashes and encrypted card numbers. Encrypting needs the vault key, which
+-- SQL doesn't have, so cards from before this migration keep their plaintext number until
+-- `migrate tokenize-cards` encrypts it. The next migration refuses to run until then.
+
+ALTER TABLE cards
+    ADD COLUMN token VARCHAR(36), -- opaque reference used in API paths, Kafka keys and logs
+    ADD COLUMN last4 CHAR(4), -- for masked display
+    ADD COLUMN pan_hash CHAR(64), -- keyed lookup hash of the card number
+    ADD COLUMN pan_key_id VARCHAR(64), -- vault key that wrapped pan_data_key
+    ADD COLUMN pan_data_key BYTEA, -- per-card data key, encrypted under the vault key
+    ADD COLUMN pan_ciphertext BYTEA, -- card number, encrypted under the data key
+    ALTER COLUMN card_number DROP NOT NULL; -- NULL once tokenized
@@ -0,0 +1,14 @@
+-- Restores the card_number column, empty: decrypting the numbers needs the vault key
+ALTER TABLE cards
+    ADD COLUMN card_number VARCHAR(19), -- Support for various card number formats
+    ADD CONSTRAINT cards_card_number_key UNIQUE (card_number),
+    DROP CONSTRAINT cards_token_key,
+    DROP CONSTRAINT cards_pan_hash_key,
+    ALTER COLUMN token DROP NOT NULL,
+    ALTER COLUMN last4 DROP NOT NULL,
+    ALTER COLUMN pan_hash DROP NOT NULL,
+    ALTER COLUMN pan_key_id DROP NOT NULL,
+    ALTER COLUMN pan_data_key DROP NOT NULL,
+    ALTER COLUMN pan_ciphertext DROP NOT NULL;
+
+CREATE INDEX idx_cards_card_number ON cards(card_number);
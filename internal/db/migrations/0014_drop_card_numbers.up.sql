@@ -0,0 +1,21 @@
+-- Drops the plaintext card numbers once every card is tokenized
+
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM cards WHERE token IS NULL) THEN
+        RAISE EXCEPTION 'cards with plaintext card numbers remain; run "migrate tokenize-cards" first';
+    END IF;
+END
+$$;
+
+-- Dropping card_number drops its unique constraint and index
+ALTER TABLE cards
+    ALTER COLUMN token SET NOT NULL,
+    ALTER COLUMN last4 SET NOT NULL,
+    ALTER COLUMN pan_hash SET NOT NULL,
+    ALTER COLUMN pan_key_id SET NOT NULL,
+    ALTER COLUMN pan_data_key SET NOT NULL,
+    ALTER COLUMN pan_ciphertext SET NOT NULL,
+    ADD CONSTRAINT cards_token_key UNIQUE (token),
+    ADD CONSTRAINT cards_pan_hash_key UNIQUE (pan_hash),
+    DROP COLUMN card_number;
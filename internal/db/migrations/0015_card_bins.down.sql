@@ -0,0 +1,5 @@
+-- Drops the issuer details
+ALTER TABLE cards
+    DROP COLUMN issuer,
+    DROP COLUMN issuer_country,
+    DROP COLUMN funding;
@@ -0,0 +1,5 @@
+-- Issuer details derived from the BIN table; NULL for cards registered before it
+ALTER TABLE cards
+    ADD COLUMN issuer VARCHAR(255), -- from the BIN table, when known
+    ADD COLUMN issuer_country VARCHAR(2),
+    ADD COLUMN funding VARCHAR(10); -- credit, debit or prepaid
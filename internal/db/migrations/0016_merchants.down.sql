@@ -0,0 +1,8 @@
+-- Drops the merchants and the descriptors matched to them
+ALTER TABLE transactions
+    DROP COLUMN merchant_id,
+    DROP COLUMN merchant_descriptor,
+    DROP COLUMN mcc,
+    DROP COLUMN merchant_city;
+
+DROP TABLE IF EXISTS merchants;
@@ -0,0 +1,32 @@
+-- Canonical merchants and the raw descriptors matched to them. Transactions from before this
+-- migration keep their merchant name, which is also their descriptor.
+
+-- Merchants (canonical merchants that raw card descriptors are normalized to)
+CREATE TABLE merchants (
+    id VARCHAR(36) PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    normalized_name VARCHAR(255) NOT NULL,
+    mcc VARCHAR(4), -- ISO 18245 merchant category code
+    city VARCHAR(100),
+    country VARCHAR(2), -- ISO 3166-1 alpha-2
+    logo_url TEXT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    UNIQUE(normalized_name)
+);
+
+ALTER TABLE transactions
+    ADD COLUMN merchant_id VARCHAR(36) REFERENCES merchants(id), -- canonical merchant the descriptor was matched to
+    ADD COLUMN merchant_descriptor VARCHAR(255) NOT NULL DEFAULT '', -- raw descriptor sent by the network
+    ADD COLUMN mcc VARCHAR(4) NOT NULL DEFAULT '', -- ISO 18245 merchant category code, empty when unknown
+    ADD COLUMN merchant_city VARCHAR(100) NOT NULL DEFAULT '';
+
+ALTER TABLE transactions DISABLE TRIGGER update_transactions_updated_at;
+UPDATE transactions SET merchant_descriptor = merchant_name;
+ALTER TABLE transactions ENABLE TRIGGER update_transactions_updated_at;
+
+CREATE INDEX idx_transactions_merchant_id ON transactions(merchant_id) WHERE merchant_id IS NOT NULL;
+CREATE INDEX idx_transactions_mcc ON transactions(mcc);
+
+CREATE TRIGGER update_merchants_updated_at BEFORE UPDATE ON merchants
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
@@ -0,0 +1,5 @@
+-- Drops the user-defined categories and rules. Transactions keep the categories they were given.
+ALTER TABLE transactions DROP COLUMN category_source;
+
+DROP TABLE IF EXISTS category_rules;
+DROP TABLE IF EXISTS categories;
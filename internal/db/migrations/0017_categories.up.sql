@@ -0,0 +1,37 @@
+-- User-defined categories and auto-categorization rules. Transactions from before this
+-- migration were categorized by their payload.
+
+-- User-defined spending categories (built-in categories are not stored)
+CREATE TABLE categories (
+    id VARCHAR(36) PRIMARY KEY,
+    user_id VARCHAR(36) NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL, -- lowercase, as stored on transactions
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    UNIQUE(user_id, name)
+);
+
+-- Auto-categorization rules, evaluated in priority order (lowest first, first match wins)
+CREATE TABLE category_rules (
+    id VARCHAR(36) PRIMARY KEY,
+    user_id VARCHAR(36) NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    field VARCHAR(20) NOT NULL, -- merchant, mcc, merchant_city, merchant_country
+    operator VARCHAR(20) NOT NULL, -- contains, equals, starts_with
+    value VARCHAR(255) NOT NULL,
+    category VARCHAR(100) NOT NULL,
+    priority INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    CHECK (field IN ('merchant', 'mcc', 'merchant_city', 'merchant_country')),
+    CHECK (operator IN ('contains', 'equals', 'starts_with'))
+);
+
+-- Existing rows get 'payload', new ones default to 'mcc'
+ALTER TABLE transactions
+    ADD COLUMN category_source VARCHAR(10) NOT NULL DEFAULT 'payload', -- mcc, payload, rule, user
+    ADD CONSTRAINT transactions_category_source_check CHECK (category_source IN ('mcc', 'payload', 'rule', 'user'));
+ALTER TABLE transactions ALTER COLUMN category_source SET DEFAULT 'mcc';
+
+CREATE INDEX idx_category_rules_user ON category_rules(user_id, priority, created_at);
+
+CREATE TRIGGER update_category_rules_updated_at BEFORE UPDATE ON category_rules
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
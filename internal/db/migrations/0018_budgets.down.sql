@@ -0,0 +1,3 @@
+-- Drops the budgets and their alerts
+DROP TABLE IF EXISTS budget_alerts;
+DROP TABLE IF EXISTS budgets;
@@ -0,0 +1,35 @@
+-- Budgets per user, category and calendar period (amounts in minor units)
+CREATE TABLE budgets (
+    id VARCHAR(36) PRIMARY KEY,
+    user_id VARCHAR(36) NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    category VARCHAR(100) NOT NULL,
+    period VARCHAR(10) NOT NULL, -- weekly (from Monday), monthly; in UTC
+    amount BIGINT NOT NULL,
+    currency CHAR(3) NOT NULL,
+    rollover BOOLEAN NOT NULL DEFAULT false, -- unspent amounts carry into the next period
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    UNIQUE(user_id, category, period),
+    CHECK (amount > 0),
+    CHECK (period IN ('weekly', 'monthly'))
+);
+
+-- Budget threshold alerts (one per budget, period and threshold)
+CREATE TABLE budget_alerts (
+    id VARCHAR(36) PRIMARY KEY,
+    budget_id VARCHAR(36) NOT NULL REFERENCES budgets(id) ON DELETE CASCADE,
+    user_id VARCHAR(36) NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    category VARCHAR(100) NOT NULL,
+    threshold INTEGER NOT NULL, -- percent of the available amount: 50, 80, 100
+    period_start TIMESTAMP WITH TIME ZONE NOT NULL,
+    spent BIGINT NOT NULL,
+    available BIGINT NOT NULL,
+    transaction_id VARCHAR(36) NOT NULL REFERENCES transactions(id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    UNIQUE(budget_id, period_start, threshold)
+);
+
+CREATE INDEX idx_budget_alerts_user ON budget_alerts(user_id, created_at DESC);
+
+CREATE TRIGGER update_budgets_updated_at BEFORE UPDATE ON budgets
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
@@ -0,0 +1,2 @@
+-- Drops the spending rollups; they can be rebuilt from the transactions
+DROP TABLE IF EXISTS spending_rollups;
@@ -0,0 +1,23 @@
+-- Daily net spend per user, currency, card, category and merchant, maintained with every
+-- transaction status change and recategorization (see db/rollups.go). The rollups start out
+-- built from the existing transactions, the same way RebuildSpendingRollups builds them.
+CREATE TABLE spending_rollups (
+    user_id VARCHAR(36) NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    day DATE NOT NULL, -- UTC date of the transaction timestamp
+    currency CHAR(3) NOT NULL,
+    card_id VARCHAR(36) NOT NULL REFERENCES cards(id) ON DELETE CASCADE,
+    category VARCHAR(100) NOT NULL, -- lowercased
+    merchant_name VARCHAR(255) NOT NULL,
+    amount BIGINT NOT NULL DEFAULT 0, -- accepted payments less settled refunds, in cents
+    count INTEGER NOT NULL DEFAULT 0, -- accepted payments
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, day, currency, card_id, category, merchant_name)
+);
+
+INSERT INTO spending_rollups (user_id, day, currency, card_id, category, merchant_name, amount, count)
+SELECT user_id, (timestamp AT TIME ZONE 'UTC')::date, currency, card_id, LOWER(category), merchant_name,
+    SUM(CASE WHEN type = 'payment' THEN amount ELSE -amount END), COUNT(*) FILTER (WHERE type = 'payment')
+FROM transactions
+WHERE (type = 'payment' AND status IN ('authorized', 'settled', 'partially_refunded', 'refunded'))
+    OR (type = 'refund' AND status = 'settled')
+GROUP BY 1, 2, 3, 4, 5, 6;
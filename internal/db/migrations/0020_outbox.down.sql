@@ -0,0 +1,2 @@
+-- Drops the outbox, and with it every event not yet published
+DROP TABLE IF EXISTS outbox;
@@ -0,0 +1,19 @@
+-- Outbox of domain events, saved in the transaction of the change they announce and published
+-- to Kafka by the outbox relay. Events of one aggregate are saved while its row is locked, so
+-- their sequence follows their commit order.
+CREATE TABLE outbox (
+    sequence BIGSERIAL PRIMARY KEY,
+    id VARCHAR(36) UNIQUE NOT NULL,
+    aggregate_type VARCHAR(50) NOT NULL, -- transaction, card
+    aggregate_id VARCHAR(36) NOT NULL,
+    event_type VARCHAR(100) NOT NULL,
+    payload JSONB NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0, -- failed publish attempts
+    last_error TEXT,
+    next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    sent_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX idx_outbox_unsent ON outbox(sequence) WHERE sent_at IS NULL;
+CREATE INDEX idx_outbox_unsent_aggregate ON outbox(aggregate_type, aggregate_id, sequence) WHERE sent_at IS NULL;
@@ -0,0 +1,134 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/araesf/ledgertime/internal/models"
+)
+
+// outboxLockID is the key of the advisory lock held by the relay publishing the outbox, so
+// two relays never publish events of one aggregate out of order
+const outboxLockID = 4_715_530_199
+
+const outboxColumns = `sequence, id, aggregate_type, aggregate_id, event_type, payload, attempts, last_error, next_attempt_at, created_at, sent_at`
+
+func scanOutboxEvent(row rowScanner) (*models.OutboxEvent, error) {
+	event := &models.OutboxEvent{}
+	var payload []byte
+	var lastError sql.NullString
+	var sentAt sql.NullTime
+	err := row.Scan(
+		&event.Sequence, &event.ID, &event.AggregateType, &event.AggregateID, &event.EventType,
+		&payload, &event.Attempts, &lastError, &event.NextAttemptAt, &event.CreatedAt, &sentAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	event.Payload = payload
+	event.LastError = lastError.String
+	if sentAt.Valid {
+		event.SentAt = &sentAt.Time
+	}
+	return event, nil
+}
+
+// CreateOutboxEvent saves an event for the relay to publish, assigning its sequence
+func (db *DB) CreateOutboxEvent(ctx context.Context, event *models.OutboxEvent) error {
+	query := `
+		INSERT INTO outbox (id, aggregate_type, aggregate_id, event_type, payload, next_attempt_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING sequence`
+
+	err := db.conn(ctx).QueryRowContext(ctx, query,
+		event.ID, event.AggregateType, event.AggregateID, event.EventType, []byte(event.Payload),
+		event.NextAttemptAt, event.CreatedAt,
+	).Scan(&event.Sequence)
+	if err != nil {
+		db.logger.Error("Failed to create outbox event", "error", err, "event_type", event.EventType, "aggregate_id", event.AggregateID)
+		return fmt.Errorf("failed to create outbox event: %w", err)
+	}
+
+	return nil
+}
+
+// LockOutbox takes the outbox until the unit of work ctx runs in ends, reporting false without
+// waiting when another relay holds it
+func (db *DB) LockOutbox(ctx context.Context) (bool, error) {
+	if _, ok := ctx.Value(unitOfWorkKey{}).(*unitOfWork); !ok {
+		return false, fmt.Errorf("failed to lock outbox: %w", models.ErrNotInUnitOfWork)
+	}
+
+	var locked bool
+	if err := db.conn(ctx).QueryRowContext(ctx, `SELECT pg_try_advisory_xact_lock($1)`, outboxLockID).Scan(&locked); err != nil {
+		return false, fmt.Errorf("failed to lock outbox: %w", err)
+	}
+	return locked, nil
+}
+
+// GetPendingOutboxEvents returns unsent events due by now in sequence order. Events queued
+// behind an earlier event of their aggregate that is waiting to be retried are left out.
+func (db *DB) GetPendingOutboxEvents(ctx context.Context, now time.Time, limit int) ([]*models.OutboxEvent, error) {
+	query := `
+		SELECT ` + outboxColumns + `
+		FROM outbox o
+		WHERE sent_at IS NULL AND next_attempt_at <= $1
+		  AND NOT EXISTS (
+			SELECT 1 FROM outbox earlier
+			WHERE earlier.aggregate_type = o.aggregate_type AND earlier.aggregate_id = o.aggregate_id
+			  AND earlier.sent_at IS NULL AND earlier.sequence < o.sequence AND earlier.next_attempt_at > $1
+		  )
+		ORDER BY sequence
+		LIMIT $2`
+
+	rows, err := db.conn(ctx).QueryContext(ctx, query, now, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pending outbox events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []*models.OutboxEvent
+	for rows.Next() {
+		event, err := scanOutboxEvent(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan outbox event: %w", err)
+		}
+		events = append(events, event)
+	}
+
+	return events, rows.Err()
+}
+
+// MarkOutboxEventSent records that an event was published
+func (db *DB) MarkOutboxEventSent(ctx context.Context, id string, sentAt time.Time) error {
+	query := `UPDATE outbox SET sent_at = $2 WHERE id = $1 AND sent_at IS NULL`
+
+	result, err := db.conn(ctx).ExecContext(ctx, query, id, sentAt)
+	if err != nil {
+		return fmt.Errorf("failed to mark outbox event sent: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("unsent outbox event %w: %s", models.ErrNotFound, id)
+	}
+	return nil
+}
+
+// MarkOutboxEventFailed records a failed publish attempt and when to try again
+func (db *DB) MarkOutboxEventFailed(ctx context.Context, id, lastError string, nextAttemptAt time.Time) error {
+	query := `
+		UPDATE outbox
+		SET attempts = attempts + 1, last_error = $2, next_attempt_at = $3
+		WHERE id = $1 AND sent_at IS NULL`
+
+	result, err := db.conn(ctx).ExecContext(ctx, query, id, lastError, nextAttemptAt)
+	if err != nil {
+		return fmt.Errorf("failed to mark outbox event failed: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("unsent outbox event %w: %s", models.ErrNotFound, id)
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/araesf/ledgertime/internal/models"
+)
+
+// CreateLinkedTransaction saves a refund or reversal, ensuring the total linked to
+// the original transaction never exceeds its amount
+func (db *DB) CreateLinkedTransaction(ctx context.Context, tx *models.Transaction) error {
+	sqlTx, err := db.begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer sqlTx.Rollback()
+
+	// Lock the original so concurrent refunds are serialized
+	var originalAmount int64
+	query := `SELECT amount FROM transactions WHERE id = $1 FOR UPDATE`
+	if err := sqlTx.QueryRowContext(ctx, query, tx.ParentTransactionID).Scan(&originalAmount); err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("transaction %w: %s", models.ErrNotFound, tx.ParentTransactionID)
+		}
+		return fmt.Errorf("failed to lock original transaction: %w", err)
+	}
+
+	var linked int64
+	query = `
+		SELECT COALESCE(SUM(amount), 0)
+		FROM transactions
+		WHERE parent_transaction_id = $1 AND status <> 'declined'`
+	if err := sqlTx.QueryRowContext(ctx, query, tx.ParentTransactionID).Scan(&linked); err != nil {
+		return fmt.Errorf("failed to sum linked transactions: %w", err)
+	}
+
+	if linked+tx.Amount > originalAmount {
+		return fmt.Errorf("%w: original %d, already refunded %d, requested %d",
+			models.ErrRefundLimitExceeded, originalAmount, linked, tx.Amount)
+	}
+
+	_, err = sqlTx.ExecContext(ctx, insertTransactionQuery, transactionValues(tx)...)
+	if err != nil {
+		db.logger.Error("Failed to create linked transaction", "error", err, "transaction_id", tx.ID)
+		return fmt.Errorf("failed to create linked transaction: %w", err)
+	}
+
+	if err := sqlTx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit linked transaction: %w", err)
+	}
+
+	db.logger.Info("Linked transaction created", "transaction_id", tx.ID, "parent_transaction_id", tx.ParentTransactionID, "type", tx.Type, "amount", tx.Amount)
+	return nil
+}
+
+// GetLinkedTransactions returns the refunds and reversals of a transaction, oldest first
+func (db *DB) GetLinkedTransactions(ctx context.Context, parentID string) ([]*models.Transaction, error) {
+	query := `
+		SELECT ` + transactionColumns + `
+		FROM transactions
+		WHERE parent_transaction_id = $1
+		ORDER BY created_at`
+
+	rows, err := db.conn(ctx).QueryContext(ctx, query, parentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get linked transactions: %w", err)
+	}
+	defer rows.Close()
+
+	var transactions []*models.Transaction
+	for rows.Next() {
+		tx, err := scanTransaction(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan transaction: %w", err)
+		}
+		transactions = append(transactions, tx)
+	}
+
+	return transactions, nil
+}
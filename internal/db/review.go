@@ -0,0 +1,202 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/araesf/ledgertime/internal/models"
+	"github.com/lib/pq"
+)
+
+const reviewColumns = `id, transaction_id, intent, status, reason, claimed_by, claimed_at, decided_by, decided_at, notes, expires_at, created_at, updated_at`
+
+func scanReviewItem(row rowScanner) (*models.ReviewItem, error) {
+	item := &models.ReviewItem{}
+	var claimedBy, decidedBy, notes sql.NullString
+	var claimedAt, decidedAt sql.NullTime
+	err := row.Scan(
+		&item.ID, &item.TransactionID, &item.Intent, &item.Status, &item.Reason,
+		&claimedBy, &claimedAt, &decidedBy, &decidedAt, &notes,
+		&item.ExpiresAt, &item.CreatedAt, &item.UpdatedAt,
+	)
+	item.ClaimedBy, item.DecidedBy, item.Notes = claimedBy.String, decidedBy.String, notes.String
+	if claimedAt.Valid {
+		item.ClaimedAt = &claimedAt.Time
+	}
+	if decidedAt.Valid {
+		item.DecidedAt = &decidedAt.Time
+	}
+	return item, err
+}
+
+func scanReviewItems(rows *sql.Rows) ([]*models.ReviewItem, error) {
+	var items []*models.ReviewItem
+	for rows.Next() {
+		item, err := scanReviewItem(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan review item: %w", err)
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// Review queue operations
+func (db *DB) CreateReviewItem(ctx context.Context, item *models.ReviewItem) error {
+	query := `
+		INSERT INTO review_queue (id, transaction_id, intent, status, reason, expires_at, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+
+	_, err := db.conn(ctx).ExecContext(ctx, query,
+		item.ID, item.TransactionID, item.Intent, item.Status, item.Reason,
+		item.ExpiresAt, item.CreatedAt, item.UpdatedAt,
+	)
+	if err != nil {
+		db.logger.Error("Failed to create review item", "error", err, "transaction_id", item.TransactionID)
+		return fmt.Errorf("failed to create review item: %w", err)
+	}
+
+	db.logger.Info("Transaction queued for review", "review_id", item.ID, "transaction_id", item.TransactionID)
+	return nil
+}
+
+func (db *DB) GetReviewItem(ctx context.Context, id string) (*models.ReviewItem, error) {
+	query := `SELECT ` + reviewColumns + ` FROM review_queue WHERE id = $1`
+
+	item, err := scanReviewItem(db.conn(ctx).QueryRowContext(ctx, query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("review %w: %s", models.ErrNotFound, id)
+		}
+		return nil, fmt.Errorf("failed to get review item: %w", err)
+	}
+
+	return item, nil
+}
+
+// GetReviewItems lists review items with the given status, oldest first. An empty status lists open items.
+func (db *DB) GetReviewItems(ctx context.Context, status string, limit, offset int) ([]*models.ReviewItem, error) {
+	statuses := []string{status}
+	if status == "" {
+		statuses = []string{models.ReviewStatusPending, models.ReviewStatusClaimed}
+	}
+
+	query := `
+		SELECT ` + reviewColumns + `
+		FROM review_queue
+		WHERE status = ANY($1)
+		ORDER BY created_at
+		LIMIT $2 OFFSET $3`
+
+	rows, err := db.conn(ctx).QueryContext(ctx, query, pq.Array(statuses), limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get review items: %w", err)
+	}
+	defer rows.Close()
+
+	return scanReviewItems(rows)
+}
+
+// ClaimReviewItem assigns an open review to an analyst. Claiming an item the analyst already holds succeeds.
+func (db *DB) ClaimReviewItem(ctx context.Context, id, analyst string, now time.Time) (*models.ReviewItem, error) {
+	tx, err := db.begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	item, err := lockOpenReviewItem(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if item.Status == models.ReviewStatusClaimed && item.ClaimedBy != analyst {
+		return nil, fmt.Errorf("%w: %s", models.ErrReviewClaimed, item.ClaimedBy)
+	}
+
+	query := `UPDATE review_queue SET status = $2, claimed_by = $3, claimed_at = $4 WHERE id = $1`
+
+	if _, err := tx.ExecContext(ctx, query, id, models.ReviewStatusClaimed, analyst, now); err != nil {
+		db.logger.Error("Failed to claim review item", "error", err, "review_id", id)
+		return nil, fmt.Errorf("failed to claim review item: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit review claim: %w", err)
+	}
+
+	item.Status, item.ClaimedBy, item.ClaimedAt = models.ReviewStatusClaimed, analyst, &now
+	db.logger.Info("Review item claimed", "review_id", id, "analyst", analyst)
+	return item, nil
+}
+
+// DecideReviewItem closes an open review with a final status. Claimed items can only be decided by
+// their claimant, except when they time out.
+func (db *DB) DecideReviewItem(ctx context.Context, id, status, decidedBy, notes string, now time.Time) (*models.ReviewItem, error) {
+	tx, err := db.begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	item, err := lockOpenReviewItem(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if status != models.ReviewStatusExpired && item.Status == models.ReviewStatusClaimed && item.ClaimedBy != decidedBy {
+		return nil, fmt.Errorf("%w: %s", models.ErrReviewClaimed, item.ClaimedBy)
+	}
+
+	query := `UPDATE review_queue SET status = $2, decided_by = $3, decided_at = $4, notes = $5 WHERE id = $1`
+
+	if _, err := tx.ExecContext(ctx, query, id, status, decidedBy, now, nullString(notes)); err != nil {
+		db.logger.Error("Failed to decide review item", "error", err, "review_id", id)
+		return nil, fmt.Errorf("failed to decide review item: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit review decision: %w", err)
+	}
+
+	item.Status, item.DecidedBy, item.DecidedAt, item.Notes = status, decidedBy, &now, notes
+	db.logger.Info("Review item decided", "review_id", id, "status", status, "decided_by", decidedBy)
+	return item, nil
+}
+
+// lockOpenReviewItem loads a review item FOR UPDATE, failing if it is no longer open
+func lockOpenReviewItem(ctx context.Context, tx querier, id string) (*models.ReviewItem, error) {
+	query := `SELECT ` + reviewColumns + ` FROM review_queue WHERE id = $1 FOR UPDATE`
+
+	item, err := scanReviewItem(tx.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("review %w: %s", models.ErrNotFound, id)
+		}
+		return nil, fmt.Errorf("failed to get review item: %w", err)
+	}
+
+	if item.Status != models.ReviewStatusPending && item.Status != models.ReviewStatusClaimed {
+		return nil, fmt.Errorf("%w: review %s is %s", models.ErrReviewClosed, id, item.Status)
+	}
+
+	return item, nil
+}
+
+// GetExpiredReviewItems returns open review items whose deadline has passed
+func (db *DB) GetExpiredReviewItems(ctx context.Context, now time.Time, limit int) ([]*models.ReviewItem, error) {
+	query := `
+		SELECT ` + reviewColumns + `
+		FROM review_queue
+		WHERE status IN ('pending', 'claimed') AND expires_at <= $1
+		ORDER BY expires_at
+		LIMIT $2`
+
+	rows, err := db.conn(ctx).QueryContext(ctx, query, now, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get expired review items: %w", err)
+	}
+	defer rows.Close()
+
+	return scanReviewItems(rows)
+}
@@ -0,0 +1,109 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Spending rollups hold each day's net spend per user, currency, card, category and merchant
+// so analytics, summaries and budgets never scan a user's full transaction history. They are
+// kept in step inside the same database transaction as every change to a transaction's
+// status, amount or category: the transaction's old contribution is subtracted and its new
+// one added. Transactions are created pending, which contributes nothing.
+
+// Net spend counts accepted payments when made and deducts settled refunds when issued.
+// Payments in review do not count until approved.
+const (
+	netSpendCondition = `((type = 'payment' AND status IN ('authorized', 'settled', 'partially_refunded', 'refunded'))
+		OR (type = 'refund' AND status = 'settled'))`
+	netSpendAmount = `CASE WHEN type = 'payment' THEN amount ELSE -amount END`
+)
+
+// rollupQuery adds the net spend of the transactions matching a condition, multiplied by a
+// sign ($2), to their rollups
+const rollupQuery = `
+	INSERT INTO spending_rollups (user_id, day, currency, card_id, category, merchant_name, amount, count)
+	SELECT user_id, (timestamp AT TIME ZONE 'UTC')::date, currency, card_id, LOWER(category), merchant_name,
+		$2 * SUM(` + netSpendAmount + `), $2 * COUNT(*) FILTER (WHERE type = 'payment')
+	FROM transactions
+	WHERE %s AND ` + netSpendCondition + `
+	GROUP BY 1, 2, 3, 4, 5, 6
+	ON CONFLICT (user_id, day, currency, card_id, category, merchant_name) DO UPDATE
+	SET amount = spending_rollups.amount + EXCLUDED.amount,
+		count = spending_rollups.count + EXCLUDED.count,
+		updated_at = NOW()`
+
+// Rollup conditions selecting the transactions a change touches by its ID, $1
+const (
+	rollupTransaction       = `id = $1`
+	rollupTransactionFamily = `(id = $1 OR parent_transaction_id = $1)`
+)
+
+// rollupTransactions adds (sign 1) or subtracts (sign -1) the net spend of the transactions
+// matching a rollup condition
+func rollupTransactions(ctx context.Context, tx querier, condition string, sign int, id string) error {
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf(rollupQuery, condition), id, sign); err != nil {
+		return fmt.Errorf("failed to update spending rollups: %w", err)
+	}
+	return nil
+}
+
+// lockTransactions locks the transactions matching a rollup condition so their contribution
+// cannot change between subtracting it and adding it back
+func lockTransactions(ctx context.Context, tx querier, condition, id string) error {
+	rows, err := tx.QueryContext(ctx, `SELECT id FROM transactions WHERE `+condition+` FOR UPDATE`, id)
+	if err != nil {
+		return fmt.Errorf("failed to lock transactions: %w", err)
+	}
+	return rows.Close()
+}
+
+// RebuildSpendingRollups recomputes the spending rollups of one user, or every user when
+// userID is empty, from their transactions. Returns how many rollup rows were written.
+func (db *DB) RebuildSpendingRollups(ctx context.Context, userID string) (int64, error) {
+	sqlTx, err := db.begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer sqlTx.Rollback()
+
+	// Concurrent status changes wait until the rebuild commits and then apply their change on
+	// top of it, so none are lost or counted twice
+	if _, err := sqlTx.ExecContext(ctx, `LOCK TABLE spending_rollups IN EXCLUSIVE MODE`); err != nil {
+		return 0, fmt.Errorf("failed to lock spending rollups: %w", err)
+	}
+
+	if _, err := sqlTx.ExecContext(ctx, `DELETE FROM spending_rollups WHERE $1 = '' OR user_id = $1`, userID); err != nil {
+		return 0, fmt.Errorf("failed to clear spending rollups: %w", err)
+	}
+
+	query := fmt.Sprintf(rollupQuery, `($1 = '' OR user_id = $1)`)
+	result, err := sqlTx.ExecContext(ctx, query, userID, 1)
+	if err != nil {
+		db.logger.Error("Failed to rebuild spending rollups", "error", err, "user_id", userID)
+		return 0, fmt.Errorf("failed to rebuild spending rollups: %w", err)
+	}
+	written, _ := result.RowsAffected()
+
+	if err := sqlTx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit spending rollups: %w", err)
+	}
+
+	db.logger.Info("Spending rollups rebuilt", "user_id", userID, "rows", written)
+	return written, nil
+}
+
+// rollupDay formats a UTC midnight as a DATE parameter
+func rollupDay(t time.Time) string {
+	return t.UTC().Format("2006-01-02")
+}
+
+// nullRollupDay stores zero times as NULL
+func nullRollupDay(t time.Time) sql.NullString {
+	if t.IsZero() {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: rollupDay(t), Valid: true}
+}
@@ -0,0 +1,87 @@
+package db
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/araesf/ledgertime/internal/models"
+)
+
+// TransitionTransaction atomically moves a transaction from one status to another,
+// persisting its amounts and decline reason, recording the change in the status history and
+// moving its net spend in the spending rollups
+func (db *DB) TransitionTransaction(ctx context.Context, tx *models.Transaction, change *models.StatusChange) error {
+	sqlTx, err := db.begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer sqlTx.Rollback()
+
+	if err := lockTransactions(ctx, sqlTx, rollupTransaction, tx.ID); err != nil {
+		return err
+	}
+	if err := rollupTransactions(ctx, sqlTx, rollupTransaction, -1, tx.ID); err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE transactions
+		SET amount = $2, authorized_amount = $3, status = $4, decline_reason = $5, updated_at = $6
+		WHERE id = $1 AND status = $7`
+
+	result, err := sqlTx.ExecContext(ctx, query, tx.ID, tx.Amount, tx.AuthorizedAmount, change.ToStatus, nullString(tx.DeclineReason), tx.UpdatedAt, change.FromStatus)
+	if err != nil {
+		db.logger.Error("Failed to update transaction status", "error", err, "transaction_id", tx.ID)
+		return fmt.Errorf("failed to update transaction status: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("%w: transaction %s is no longer %s", models.ErrStatusConflict, tx.ID, change.FromStatus)
+	}
+	if err := rollupTransactions(ctx, sqlTx, rollupTransaction, 1, tx.ID); err != nil {
+		return err
+	}
+
+	query = `
+		INSERT INTO transaction_status_history (id, transaction_id, from_status, to_status, actor, reason, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`
+
+	_, err = sqlTx.ExecContext(ctx, query,
+		change.ID, change.TransactionID, change.FromStatus, change.ToStatus,
+		change.Actor, nullString(change.Reason), change.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record status change: %w", err)
+	}
+
+	if err := sqlTx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit status change: %w", err)
+	}
+
+	db.logger.Info("Transaction status changed", "transaction_id", tx.ID, "from", change.FromStatus, "to", change.ToStatus, "actor", change.Actor)
+	return nil
+}
+
+func (db *DB) GetStatusHistory(ctx context.Context, transactionID string) ([]*models.StatusChange, error) {
+	query := `
+		SELECT id, transaction_id, from_status, to_status, actor, COALESCE(reason, ''), created_at
+		FROM transaction_status_history
+		WHERE transaction_id = $1
+		ORDER BY created_at, id`
+
+	rows, err := db.conn(ctx).QueryContext(ctx, query, transactionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get status history: %w", err)
+	}
+	defer rows.Close()
+
+	var history []*models.StatusChange
+	for rows.Next() {
+		c := &models.StatusChange{}
+		if err := rows.Scan(&c.ID, &c.TransactionID, &c.FromStatus, &c.ToStatus, &c.Actor, &c.Reason, &c.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan status change: %w", err)
+		}
+		history = append(history, c)
+	}
+
+	return history, nil
+}
@@ -0,0 +1,168 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Postgres error codes after which a transaction may succeed if run again from the start
+const (
+	serializationFailure = "40001"
+	deadlockDetected     = "40P01"
+)
+
+// isolationLevels maps the configurable isolation levels to their database/sql values
+var isolationLevels = map[string]sql.IsolationLevel{
+	"read_committed":  sql.LevelReadCommitted,
+	"repeatable_read": sql.LevelRepeatableRead,
+	"serializable":    sql.LevelSerializable,
+}
+
+// querier runs queries through either the pool or a transaction
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+type unitOfWorkKey struct{}
+
+// unitOfWork is the transaction shared by the store calls made inside RunUnitOfWork
+type unitOfWork struct {
+	tx         *sql.Tx
+	savepoints int
+}
+
+// RunUnitOfWork runs fn in one transaction at the configured isolation level. Store calls made
+// with the context fn is given run in that transaction, which commits if fn returns nil and
+// rolls back otherwise. After a serialization failure or deadlock the whole unit is rolled back
+// and fn runs again, up to the configured number of retries. A unit of work started inside
+// another one joins it.
+func (db *DB) RunUnitOfWork(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(unitOfWorkKey{}).(*unitOfWork); ok {
+		return fn(ctx)
+	}
+
+	for attempt := 0; ; attempt++ {
+		err := db.runUnitOfWork(ctx, fn)
+		if err == nil || !retryable(err) || attempt >= db.maxTxRetries {
+			return err
+		}
+
+		db.logger.Info("Retrying unit of work", "attempt", attempt+1, "error", err)
+		select {
+		case <-time.After(retryBackoff(attempt)):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (db *DB) runUnitOfWork(ctx context.Context, fn func(ctx context.Context) error) error {
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{Isolation: db.isolation})
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := fn(context.WithValue(ctx, unitOfWorkKey{}, &unitOfWork{tx: tx})); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// retryable reports whether a unit of work failed on a serialization failure or deadlock
+func retryable(err error) bool {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return false
+	}
+	return pqErr.Code == serializationFailure || pqErr.Code == deadlockDetected
+}
+
+// maxBackoffAttempt is the attempt after which the retry backoff stops growing
+const maxBackoffAttempt = 6
+
+// retryBackoff waits longer after every attempt, with jitter so conflicting units of work
+// don't collide again
+func retryBackoff(attempt int) time.Duration {
+	if attempt > maxBackoffAttempt {
+		attempt = maxBackoffAttempt
+	}
+	backoff := time.Duration(10<<attempt) * time.Millisecond
+	return backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)))
+}
+
+// conn returns the transaction of the unit of work ctx runs in, or the pool outside of one
+func (db *DB) conn(ctx context.Context) querier {
+	if uow, ok := ctx.Value(unitOfWorkKey{}).(*unitOfWork); ok {
+		return uow.tx
+	}
+	return db.DB
+}
+
+// dbTx is the transaction of a single store method. Inside a unit of work it is a savepoint
+// in the unit's transaction, so a failed method undoes only its own writes and the unit can
+// carry on.
+type dbTx struct {
+	*sql.Tx
+	ctx       context.Context
+	savepoint string
+	done      bool
+}
+
+// begin starts the transaction of a store method
+func (db *DB) begin(ctx context.Context) (*dbTx, error) {
+	uow, ok := ctx.Value(unitOfWorkKey{}).(*unitOfWork)
+	if !ok {
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			return nil, err
+		}
+		return &dbTx{Tx: tx}, nil
+	}
+
+	uow.savepoints++
+	savepoint := fmt.Sprintf("store_method_%d", uow.savepoints)
+	if _, err := uow.tx.ExecContext(ctx, "SAVEPOINT "+savepoint); err != nil {
+		return nil, err
+	}
+	return &dbTx{Tx: uow.tx, ctx: ctx, savepoint: savepoint}, nil
+}
+
+// Commit commits the transaction, or releases the savepoint inside a unit of work
+func (t *dbTx) Commit() error {
+	if t.savepoint == "" {
+		return t.Tx.Commit()
+	}
+	if t.done {
+		return sql.ErrTxDone
+	}
+	t.done = true
+	_, err := t.Tx.ExecContext(t.ctx, "RELEASE SAVEPOINT "+t.savepoint)
+	return err
+}
+
+// Rollback rolls back the transaction, or back to the savepoint inside a unit of work. Like
+// sql.Tx, it does nothing after Commit.
+func (t *dbTx) Rollback() error {
+	if t.savepoint == "" {
+		return t.Tx.Rollback()
+	}
+	if t.done {
+		return sql.ErrTxDone
+	}
+	t.done = true
+	_, err := t.Tx.ExecContext(t.ctx, "ROLLBACK TO SAVEPOINT "+t.savepoint)
+	return err
+}
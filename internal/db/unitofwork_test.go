@@ -0,0 +1,296 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/araesf/ledgertime/pkg/logger"
+	"github.com/lib/pq"
+)
+
+// recordingDriver is a database/sql driver that runs nothing and records the statements and
+// transaction calls it receives, one log per data source name
+type recordingDriver struct {
+	mu   sync.Mutex
+	logs map[string]*[]string
+}
+
+var testDriver = &recordingDriver{logs: make(map[string]*[]string)}
+
+func init() {
+	sql.Register("recording", testDriver)
+}
+
+func (d *recordingDriver) Open(name string) (driver.Conn, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	log, ok := d.logs[name]
+	if !ok {
+		log = new([]string)
+		d.logs[name] = log
+	}
+	return &recordingConn{driver: d, log: log}, nil
+}
+
+func (d *recordingDriver) record(log *[]string, statement string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	*log = append(*log, statement)
+}
+
+type recordingConn struct {
+	driver *recordingDriver
+	log    *[]string
+}
+
+func (c *recordingConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, fmt.Errorf("prepared statements are not supported: %s", query)
+}
+
+func (c *recordingConn) Close() error { return nil }
+
+func (c *recordingConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *recordingConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	c.driver.record(c.log, "BEGIN")
+	return &recordingTx{conn: c}, nil
+}
+
+func (c *recordingConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.driver.record(c.log, query)
+	return driver.RowsAffected(0), nil
+}
+
+type recordingTx struct {
+	conn *recordingConn
+}
+
+func (t *recordingTx) Commit() error {
+	t.conn.driver.record(t.conn.log, "COMMIT")
+	return nil
+}
+
+func (t *recordingTx) Rollback() error {
+	t.conn.driver.record(t.conn.log, "ROLLBACK")
+	return nil
+}
+
+// newRecordingDB returns a DB over the recording driver and a function returning the
+// statements it has run so far
+func newRecordingDB(t *testing.T, maxTxRetries int) (*DB, func() []string) {
+	t.Helper()
+	testDriver.mu.Lock()
+	delete(testDriver.logs, t.Name())
+	testDriver.mu.Unlock()
+
+	conn, err := sql.Open("recording", t.Name())
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	conn.SetMaxOpenConns(1)
+	t.Cleanup(func() { conn.Close() })
+
+	db := &DB{
+		DB:           conn,
+		isolation:    sql.LevelSerializable,
+		maxTxRetries: maxTxRetries,
+		logger:       logger.NewLoggerWithLevel("error"),
+	}
+	statements := func() []string {
+		testDriver.mu.Lock()
+		defer testDriver.mu.Unlock()
+		log, ok := testDriver.logs[t.Name()]
+		if !ok {
+			return nil
+		}
+		return append([]string(nil), *log...)
+	}
+	return db, statements
+}
+
+func TestRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"serialization failure", &pq.Error{Code: "40001"}, true},
+		{"deadlock", &pq.Error{Code: "40P01"}, true},
+		{"wrapped serialization failure", fmt.Errorf("failed to post entry: %w", &pq.Error{Code: "40001"}), true},
+		{"unique violation", &pq.Error{Code: "23505"}, false},
+		{"lock timeout", &pq.Error{Code: "55P03"}, false},
+		{"plain error", errors.New("40001"), false},
+		{"no rows", sql.ErrNoRows, false},
+		{"nil", nil, false},
+	}
+
+	for _, tt := range tests {
+		if got := retryable(tt.err); got != tt.want {
+			t.Errorf("%s: retryable() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	for attempt := 0; attempt <= maxBackoffAttempt+2; attempt++ {
+		capped := attempt
+		if capped > maxBackoffAttempt {
+			capped = maxBackoffAttempt
+		}
+		base := time.Duration(10<<capped) * time.Millisecond
+
+		for i := 0; i < 20; i++ {
+			if got := retryBackoff(attempt); got < base/2 || got >= base {
+				t.Errorf("retryBackoff(%d) = %s, want within [%s, %s)", attempt, got, base/2, base)
+			}
+		}
+	}
+}
+
+func TestRunUnitOfWorkRetries(t *testing.T) {
+	tests := []struct {
+		name         string
+		failures     []error // Returned by fn on successive attempts, then nil
+		maxTxRetries int
+		wantAttempts int
+		wantErr      bool
+	}{
+		{"succeeds first time", nil, 3, 1, false},
+		{"retries serialization failures", []error{&pq.Error{Code: "40001"}, &pq.Error{Code: "40001"}}, 3, 3, false},
+		{"retries deadlocks", []error{fmt.Errorf("failed to create hold: %w", &pq.Error{Code: "40P01"})}, 3, 2, false},
+		{"gives up at the retry limit", []error{&pq.Error{Code: "40001"}, &pq.Error{Code: "40P01"}, &pq.Error{Code: "40001"}}, 2, 3, true},
+		{"no retries configured", []error{&pq.Error{Code: "40001"}}, 0, 1, true},
+		{"other errors are not retried", []error{&pq.Error{Code: "23505"}}, 3, 1, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, statements := newRecordingDB(t, tt.maxTxRetries)
+
+			attempts := 0
+			err := db.RunUnitOfWork(context.Background(), func(ctx context.Context) error {
+				attempts++
+				if _, ok := ctx.Value(unitOfWorkKey{}).(*unitOfWork); !ok {
+					t.Error("fn is not running in a unit of work")
+				}
+				if attempts <= len(tt.failures) {
+					return tt.failures[attempts-1]
+				}
+				return nil
+			})
+
+			if attempts != tt.wantAttempts {
+				t.Errorf("fn ran %d times, want %d", attempts, tt.wantAttempts)
+			}
+			if tt.wantErr {
+				if want := tt.failures[tt.wantAttempts-1]; !errors.Is(err, want) {
+					t.Errorf("RunUnitOfWork() = %v, want the last attempt's error %v", err, want)
+				}
+			} else if err != nil {
+				t.Errorf("RunUnitOfWork() = %v", err)
+			}
+
+			// Every failed attempt rolls back its whole transaction before the next begins
+			var want []string
+			for i := 1; i <= tt.wantAttempts; i++ {
+				end := "ROLLBACK"
+				if i == tt.wantAttempts && !tt.wantErr {
+					end = "COMMIT"
+				}
+				want = append(want, "BEGIN", end)
+			}
+			if got := statements(); strings.Join(got, ",") != strings.Join(want, ",") {
+				t.Errorf("ran %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestRunUnitOfWorkStopsRetryingWhenCancelled(t *testing.T) {
+	db, _ := newRecordingDB(t, 5)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	attempts := 0
+	err := db.RunUnitOfWork(ctx, func(ctx context.Context) error {
+		attempts++
+		cancel()
+		return &pq.Error{Code: "40001"}
+	})
+
+	if !errors.Is(err, context.Canceled) || attempts != 1 {
+		t.Errorf("RunUnitOfWork() = %v after %d attempts, want context.Canceled after 1", err, attempts)
+	}
+}
+
+func TestNestedUnitOfWorkJoins(t *testing.T) {
+	db, statements := newRecordingDB(t, 3)
+
+	err := db.RunUnitOfWork(context.Background(), func(ctx context.Context) error {
+		outer := ctx.Value(unitOfWorkKey{}).(*unitOfWork)
+		return db.RunUnitOfWork(ctx, func(ctx context.Context) error {
+			if ctx.Value(unitOfWorkKey{}).(*unitOfWork) != outer {
+				t.Error("inner unit of work has its own transaction")
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		t.Fatalf("RunUnitOfWork() = %v", err)
+	}
+
+	if got := strings.Join(statements(), ","); got != "BEGIN,COMMIT" {
+		t.Errorf("ran %s, want one transaction", got)
+	}
+}
+
+func TestStoreMethodSavepoints(t *testing.T) {
+	db, statements := newRecordingDB(t, 3)
+
+	err := db.RunUnitOfWork(context.Background(), func(ctx context.Context) error {
+		// A failed store method rolls back to its savepoint, leaving the unit usable
+		failed, err := db.begin(ctx)
+		if err != nil {
+			return err
+		}
+		if err := failed.Rollback(); err != nil {
+			return err
+		}
+
+		succeeded, err := db.begin(ctx)
+		if err != nil {
+			return err
+		}
+		if err := succeeded.Commit(); err != nil {
+			return err
+		}
+		// Like sql.Tx, a deferred Rollback after Commit does nothing
+		if err := succeeded.Rollback(); !errors.Is(err, sql.ErrTxDone) {
+			t.Errorf("Rollback() after Commit() = %v, want sql.ErrTxDone", err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("RunUnitOfWork() = %v", err)
+	}
+
+	want := []string{
+		"BEGIN",
+		"SAVEPOINT store_method_1",
+		"ROLLBACK TO SAVEPOINT store_method_1",
+		"SAVEPOINT store_method_2",
+		"RELEASE SAVEPOINT store_method_2",
+		"COMMIT",
+	}
+	if got := statements(); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("ran %v, want %v", got, want)
+	}
+}
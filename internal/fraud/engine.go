@@ -0,0 +1,120 @@
+package fraud
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/araesf/ledgertime/internal/config"
+	"github.com/araesf/ledgertime/internal/models"
+)
+
+// Decision constants
+const (
+	DecisionApprove = "approve"
+	DecisionReview  = "review"
+	DecisionDecline = "decline"
+)
+
+// History gives rules access to a card's earlier transactions
+type History interface {
+	// GetCardTransactionsSince returns the card's payments with a timestamp at or after since
+	GetCardTransactionsSince(ctx context.Context, cardID string, since time.Time) ([]*models.Transaction, error)
+	// HasCardUsedMerchant reports whether the card paid the merchant before the given time
+	HasCardUsedMerchant(ctx context.Context, cardID, merchantName string, before time.Time) (bool, error)
+}
+
+// Result is the outcome of screening a transaction
+type Result struct {
+	Score    int      `json:"score"`
+	Decision string   `json:"decision"`
+	Reasons  []string `json:"reasons"`
+}
+
+// Engine scores transactions against a set of rules
+type Engine struct {
+	rules        []Rule
+	history      History
+	lookback     time.Duration
+	reviewScore  int
+	declineScore int
+}
+
+// NewEngine creates an engine with the rules enabled in the configuration
+func NewEngine(cfg config.FraudConfig, history History) *Engine {
+	var rules []Rule
+	if cfg.VelocityWindow > 0 && (cfg.VelocityMaxCount > 0 || cfg.VelocityMaxAmount > 0) {
+		rules = append(rules, &VelocityRule{Window: cfg.VelocityWindow, MaxCount: cfg.VelocityMaxCount, MaxAmount: cfg.VelocityMaxAmount})
+	}
+	if cfg.NewMerchantAmount > 0 {
+		rules = append(rules, &NewMerchantRule{Amount: cfg.NewMerchantAmount})
+	}
+	if len(cfg.BlockedCategories) > 0 {
+		rules = append(rules, NewCategoryBlockRule(cfg.BlockedCategories))
+	}
+	if cfg.TravelWindow > 0 {
+		rules = append(rules, &ImpossibleTravelRule{Window: cfg.TravelWindow})
+	}
+	if cfg.DuplicateWindow > 0 {
+		rules = append(rules, &DuplicateRule{Window: cfg.DuplicateWindow})
+	}
+
+	return NewEngineWithRules(rules, history, cfg.ReviewScore, cfg.DeclineScore)
+}
+
+// NewEngineWithRules creates an engine with an explicit rule set
+func NewEngineWithRules(rules []Rule, history History, reviewScore, declineScore int) *Engine {
+	var lookback time.Duration
+	for _, rule := range rules {
+		if w := rule.Lookback(); w > lookback {
+			lookback = w
+		}
+	}
+
+	return &Engine{
+		rules:        rules,
+		history:      history,
+		lookback:     lookback,
+		reviewScore:  reviewScore,
+		declineScore: declineScore,
+	}
+}
+
+// Evaluate runs every rule against the transaction and combines their scores into a decision
+func (e *Engine) Evaluate(ctx context.Context, tx *models.Transaction) (*Result, error) {
+	in := &Input{Transaction: tx, History: e.history}
+	if e.lookback > 0 {
+		recent, err := e.history.GetCardTransactionsSince(ctx, tx.CardID, tx.Timestamp.Add(-e.lookback))
+		if err != nil {
+			return nil, fmt.Errorf("failed to load card history: %w", err)
+		}
+		for _, r := range recent {
+			// Declined attempts and the transaction being screened don't count as history
+			if r.ID != tx.ID && r.Status != models.TransactionStatusDeclined {
+				in.Recent = append(in.Recent, r)
+			}
+		}
+	}
+
+	result := &Result{Decision: DecisionApprove, Reasons: []string{}}
+	for _, rule := range e.rules {
+		hit, err := rule.Evaluate(ctx, in)
+		if err != nil {
+			return nil, fmt.Errorf("fraud rule %s failed: %w", rule.Name(), err)
+		}
+		if hit == nil {
+			continue
+		}
+		result.Score += hit.Score
+		result.Reasons = append(result.Reasons, fmt.Sprintf("%s: %s", rule.Name(), hit.Reason))
+	}
+
+	switch {
+	case e.declineScore > 0 && result.Score >= e.declineScore:
+		result.Decision = DecisionDecline
+	case e.reviewScore > 0 && result.Score >= e.reviewScore:
+		result.Decision = DecisionReview
+	}
+
+	return result, nil
+}
@@ -0,0 +1,112 @@
+package fraud
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/araesf/ledgertime/internal/models"
+)
+
+func TestEngineThresholds(t *testing.T) {
+	history := &fakeHistory{
+		transactions: []*models.Transaction{tx("a", 1000, "Shop", "FR", time.Minute)},
+	}
+	engine := NewEngineWithRules([]Rule{
+		&NewMerchantRule{Amount: 50000},            // 30
+		&ImpossibleTravelRule{Window: time.Hour},   // 60
+		NewCategoryBlockRule([]string{"gambling"}), // 100
+	}, history, 50, 100)
+
+	tests := []struct {
+		name     string
+		amount   int64
+		country  string
+		category string
+		score    int
+		decision string
+	}{
+		{"no hits", 1000, "FR", "shopping", 0, DecisionApprove},
+		{"below review", 50000, "FR", "shopping", 30, DecisionApprove},
+		{"at review", 1000, "US", "shopping", 60, DecisionReview},
+		{"scores add up", 50000, "US", "shopping", 90, DecisionReview},
+		{"at decline", 1000, "FR", "gambling", 100, DecisionDecline},
+		{"over decline", 50000, "US", "gambling", 190, DecisionDecline},
+	}
+
+	for _, tt := range tests {
+		in := tx("t", tt.amount, "New Shop", tt.country, 0)
+		in.Category = tt.category
+
+		result, err := engine.Evaluate(context.Background(), in)
+		if err != nil {
+			t.Fatalf("%s: Evaluate() = %v", tt.name, err)
+		}
+		if result.Score != tt.score || result.Decision != tt.decision {
+			t.Errorf("%s: got score %d and %s, want %d and %s (reasons %v)",
+				tt.name, result.Score, result.Decision, tt.score, tt.decision, result.Reasons)
+		}
+		if hits := len(result.Reasons); (hits == 0) != (tt.score == 0) {
+			t.Errorf("%s: got reasons %v for score %d", tt.name, result.Reasons, result.Score)
+		}
+	}
+}
+
+func TestEngineDisabledThresholds(t *testing.T) {
+	engine := NewEngineWithRules([]Rule{NewCategoryBlockRule([]string{"gambling"})}, &fakeHistory{}, 0, 0)
+
+	in := tx("t", 1000, "Shop", "US", 0)
+	in.Category = "gambling"
+	result, err := engine.Evaluate(context.Background(), in)
+	if err != nil {
+		t.Fatalf("Evaluate() = %v", err)
+	}
+	if result.Score != categoryBlockScore || result.Decision != DecisionApprove {
+		t.Errorf("got score %d and %s, want %d and approve with both thresholds off", result.Score, result.Decision, categoryBlockScore)
+	}
+}
+
+func TestEngineHistory(t *testing.T) {
+	declined := tx("declined", 1000, "Shop", "US", time.Minute)
+	declined.Status = models.TransactionStatusDeclined
+	screened := tx("t", 1000, "Shop", "US", 0)
+
+	history := &fakeHistory{
+		transactions: []*models.Transaction{
+			declined,
+			screened, // The transaction being screened is already stored
+			tx("old", 1000, "Shop", "US", 2*time.Hour),
+		},
+	}
+	// Recent history only covers the longest rule window, an hour
+	engine := NewEngineWithRules([]Rule{
+		&DuplicateRule{Window: 5 * time.Minute},
+		&VelocityRule{Window: time.Hour, MaxCount: 1},
+	}, history, 50, 100)
+
+	result, err := engine.Evaluate(context.Background(), screened)
+	if err != nil {
+		t.Fatalf("Evaluate() = %v", err)
+	}
+	if result.Score != 0 || result.Decision != DecisionApprove {
+		t.Errorf("got score %d and %s (%v), want declined attempts, the transaction itself and older history ignored",
+			result.Score, result.Decision, result.Reasons)
+	}
+
+	// An accepted payment a minute ago is a duplicate
+	history.transactions = append(history.transactions, tx("accepted", 1000, "Shop", "US", time.Minute))
+	result, err = engine.Evaluate(context.Background(), screened)
+	if err != nil {
+		t.Fatalf("Evaluate() = %v", err)
+	}
+	if result.Score != duplicateScore+velocityScore || !strings.Contains(strings.Join(result.Reasons, "; "), "duplicate: matches transaction accepted") {
+		t.Errorf("got score %d (%v), want duplicate and velocity hits", result.Score, result.Reasons)
+	}
+
+	history.err = errors.New("store down")
+	if _, err := engine.Evaluate(context.Background(), screened); err == nil {
+		t.Error("Evaluate() ignored a history error")
+	}
+}
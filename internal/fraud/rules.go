@@ -0,0 +1,194 @@
+package fraud
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/araesf/ledgertime/internal/models"
+)
+
+// Rule scores one aspect of a transaction
+type Rule interface {
+	Name() string
+	// Lookback is how far back the rule needs the card's history, zero if it needs none
+	Lookback() time.Duration
+	// Evaluate returns a hit when the rule matches, nil otherwise
+	Evaluate(ctx context.Context, in *Input) (*Hit, error)
+}
+
+// Input is what rules see when screening a transaction
+type Input struct {
+	Transaction *models.Transaction
+	Recent      []*models.Transaction // Card's accepted payments within the engine's lookback
+	History     History
+}
+
+// Hit is a matched rule's contribution to the score
+type Hit struct {
+	Score  int
+	Reason string
+}
+
+// Rule scores
+const (
+	velocityScore         = 40
+	newMerchantScore      = 30
+	categoryBlockScore    = 100
+	impossibleTravelScore = 60
+	duplicateScore        = 50
+)
+
+// within returns the transactions no older than window before the reference time
+func within(txs []*models.Transaction, ref time.Time, window time.Duration) []*models.Transaction {
+	var out []*models.Transaction
+	for _, tx := range txs {
+		if d := ref.Sub(tx.Timestamp); d >= -window && d <= window {
+			out = append(out, tx)
+		}
+	}
+	return out
+}
+
+// VelocityRule flags cards used too often or for too much within a window
+type VelocityRule struct {
+	Window    time.Duration
+	MaxCount  int   // Zero disables the count check
+	MaxAmount int64 // Zero disables the amount check
+}
+
+// Name identifies the rule
+func (r *VelocityRule) Name() string { return "velocity" }
+
+// Lookback covers the velocity window
+func (r *VelocityRule) Lookback() time.Duration { return r.Window }
+
+// Evaluate counts the transaction together with the card's recent ones
+func (r *VelocityRule) Evaluate(ctx context.Context, in *Input) (*Hit, error) {
+	tx := in.Transaction
+	recent := within(in.Recent, tx.Timestamp, r.Window)
+
+	count, total := len(recent)+1, tx.Amount
+	for _, t := range recent {
+		if t.Currency == tx.Currency {
+			total += t.Amount
+		}
+	}
+
+	if r.MaxCount > 0 && count > r.MaxCount {
+		return &Hit{Score: velocityScore, Reason: fmt.Sprintf("%d transactions within %s", count, r.Window)}, nil
+	}
+	if r.MaxAmount > 0 && total > r.MaxAmount {
+		return &Hit{Score: velocityScore, Reason: fmt.Sprintf("%d %s spent within %s", total, tx.Currency, r.Window)}, nil
+	}
+	return nil, nil
+}
+
+// NewMerchantRule flags large first purchases at a merchant the card has never used
+type NewMerchantRule struct {
+	Amount int64 // First purchases at or above this amount are flagged
+}
+
+// Name identifies the rule
+func (r *NewMerchantRule) Name() string { return "new_merchant" }
+
+// Lookback is zero because the rule queries the full history itself
+func (r *NewMerchantRule) Lookback() time.Duration { return 0 }
+
+// Evaluate checks whether the card has paid the merchant before
+func (r *NewMerchantRule) Evaluate(ctx context.Context, in *Input) (*Hit, error) {
+	tx := in.Transaction
+	if tx.Amount < r.Amount {
+		return nil, nil
+	}
+
+	used, err := in.History.HasCardUsedMerchant(ctx, tx.CardID, tx.MerchantName, tx.Timestamp)
+	if err != nil {
+		return nil, err
+	}
+	if used {
+		return nil, nil
+	}
+	return &Hit{Score: newMerchantScore, Reason: fmt.Sprintf("first purchase at %s for %d %s", tx.MerchantName, tx.Amount, tx.Currency)}, nil
+}
+
+// CategoryBlockRule declines categories the issuer doesn't allow
+type CategoryBlockRule struct {
+	blocked map[string]bool
+}
+
+// NewCategoryBlockRule creates a rule blocking the given categories, matched case-insensitively
+func NewCategoryBlockRule(categories []string) *CategoryBlockRule {
+	blocked := make(map[string]bool, len(categories))
+	for _, c := range categories {
+		blocked[strings.ToLower(strings.TrimSpace(c))] = true
+	}
+	return &CategoryBlockRule{blocked: blocked}
+}
+
+// Name identifies the rule
+func (r *CategoryBlockRule) Name() string { return "category_block" }
+
+// Lookback is zero because the rule only looks at the transaction
+func (r *CategoryBlockRule) Lookback() time.Duration { return 0 }
+
+// Evaluate checks the transaction's category
+func (r *CategoryBlockRule) Evaluate(ctx context.Context, in *Input) (*Hit, error) {
+	category := strings.ToLower(in.Transaction.Category)
+	if !r.blocked[category] {
+		return nil, nil
+	}
+	return &Hit{Score: categoryBlockScore, Reason: fmt.Sprintf("category %s is blocked", category)}, nil
+}
+
+// ImpossibleTravelRule flags cards used in two countries closer together in time than travel allows
+type ImpossibleTravelRule struct {
+	Window time.Duration
+}
+
+// Name identifies the rule
+func (r *ImpossibleTravelRule) Name() string { return "impossible_travel" }
+
+// Lookback covers the travel window
+func (r *ImpossibleTravelRule) Lookback() time.Duration { return r.Window }
+
+// Evaluate compares the merchant country with the card's recent transactions
+func (r *ImpossibleTravelRule) Evaluate(ctx context.Context, in *Input) (*Hit, error) {
+	tx := in.Transaction
+	if tx.MerchantCountry == "" {
+		return nil, nil
+	}
+
+	for _, t := range within(in.Recent, tx.Timestamp, r.Window) {
+		if t.MerchantCountry != "" && !strings.EqualFold(t.MerchantCountry, tx.MerchantCountry) {
+			return &Hit{
+				Score:  impossibleTravelScore,
+				Reason: fmt.Sprintf("used in %s and %s within %s", t.MerchantCountry, tx.MerchantCountry, r.Window),
+			}, nil
+		}
+	}
+	return nil, nil
+}
+
+// DuplicateRule flags the same amount charged by the same merchant in quick succession
+type DuplicateRule struct {
+	Window time.Duration
+}
+
+// Name identifies the rule
+func (r *DuplicateRule) Name() string { return "duplicate" }
+
+// Lookback covers the duplicate window
+func (r *DuplicateRule) Lookback() time.Duration { return r.Window }
+
+// Evaluate looks for a recent transaction with the same merchant, amount and currency
+func (r *DuplicateRule) Evaluate(ctx context.Context, in *Input) (*Hit, error) {
+	tx := in.Transaction
+	for _, t := range within(in.Recent, tx.Timestamp, r.Window) {
+		if t.MerchantName == tx.MerchantName && t.OriginalAmount == tx.OriginalAmount && t.OriginalCurrency == tx.OriginalCurrency {
+			return &Hit{Score: duplicateScore, Reason: fmt.Sprintf("matches transaction %s", t.ID)}, nil
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,218 @@
+package fraud
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/araesf/ledgertime/internal/models"
+)
+
+var now = time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC)
+
+// fakeHistory serves a fixed card history
+type fakeHistory struct {
+	transactions []*models.Transaction
+	merchants    map[string]bool // Merchants the card paid before now
+	err          error
+}
+
+func (h *fakeHistory) GetCardTransactionsSince(ctx context.Context, cardID string, since time.Time) ([]*models.Transaction, error) {
+	if h.err != nil {
+		return nil, h.err
+	}
+	var out []*models.Transaction
+	for _, tx := range h.transactions {
+		if tx.CardID == cardID && !tx.Timestamp.Before(since) {
+			out = append(out, tx)
+		}
+	}
+	return out, nil
+}
+
+func (h *fakeHistory) HasCardUsedMerchant(ctx context.Context, cardID, merchantName string, before time.Time) (bool, error) {
+	if h.err != nil {
+		return false, h.err
+	}
+	return h.merchants[merchantName], nil
+}
+
+// tx builds a USD card payment made ago before now
+func tx(id string, amount int64, merchant, country string, ago time.Duration) *models.Transaction {
+	return &models.Transaction{
+		ID:               id,
+		CardID:           "card-1",
+		Amount:           amount,
+		Currency:         "USD",
+		OriginalAmount:   amount,
+		OriginalCurrency: "USD",
+		MerchantName:     merchant,
+		MerchantCountry:  country,
+		Category:         "shopping",
+		Status:           models.TransactionStatusSettled,
+		Timestamp:        now.Add(-ago),
+	}
+}
+
+func TestVelocityRule(t *testing.T) {
+	rule := &VelocityRule{Window: time.Hour, MaxCount: 3, MaxAmount: 10000}
+	eur := tx("eur", 9000, "Cafe", "FR", 10*time.Minute)
+	eur.Currency = "EUR"
+
+	tests := []struct {
+		name   string
+		tx     *models.Transaction
+		recent []*models.Transaction
+		want   string // Reason substring, empty for no hit
+	}{
+		{"first use", tx("t", 1000, "Shop", "US", 0), nil, ""},
+		{"at the count", tx("t", 1000, "Shop", "US", 0),
+			[]*models.Transaction{tx("a", 100, "Shop", "US", time.Minute), tx("b", 100, "Shop", "US", 2*time.Minute)}, ""},
+		{"over the count", tx("t", 1000, "Shop", "US", 0),
+			[]*models.Transaction{tx("a", 100, "Shop", "US", time.Minute), tx("b", 100, "Shop", "US", 2*time.Minute), tx("c", 100, "Shop", "US", 3*time.Minute)},
+			"4 transactions within 1h0m0s"},
+		{"outside the window", tx("t", 1000, "Shop", "US", 0),
+			[]*models.Transaction{tx("a", 100, "Shop", "US", 2*time.Hour), tx("b", 100, "Shop", "US", 2*time.Hour), tx("c", 100, "Shop", "US", 2*time.Hour)}, ""},
+		{"at the amount", tx("t", 4000, "Shop", "US", 0), []*models.Transaction{tx("a", 6000, "Shop", "US", time.Minute)}, ""},
+		{"over the amount", tx("t", 4001, "Shop", "US", 0), []*models.Transaction{tx("a", 6000, "Shop", "US", time.Minute)},
+			"10001 USD spent within 1h0m0s"},
+		{"other currencies not summed", tx("t", 4000, "Shop", "US", 0), []*models.Transaction{eur}, ""},
+	}
+
+	for _, tt := range tests {
+		hit, err := rule.Evaluate(context.Background(), &Input{Transaction: tt.tx, Recent: tt.recent})
+		if err != nil {
+			t.Fatalf("%s: Evaluate() = %v", tt.name, err)
+		}
+		checkHit(t, tt.name, hit, velocityScore, tt.want)
+	}
+}
+
+func TestNewMerchantRule(t *testing.T) {
+	rule := &NewMerchantRule{Amount: 50000}
+	history := &fakeHistory{merchants: map[string]bool{"Known Shop": true}}
+
+	tests := []struct {
+		name string
+		tx   *models.Transaction
+		want string
+	}{
+		{"small first purchase", tx("t", 49999, "New Shop", "US", 0), ""},
+		{"large first purchase", tx("t", 50000, "New Shop", "US", 0), "first purchase at New Shop for 50000 USD"},
+		{"large repeat purchase", tx("t", 90000, "Known Shop", "US", 0), ""},
+	}
+
+	for _, tt := range tests {
+		hit, err := rule.Evaluate(context.Background(), &Input{Transaction: tt.tx, History: history})
+		if err != nil {
+			t.Fatalf("%s: Evaluate() = %v", tt.name, err)
+		}
+		checkHit(t, tt.name, hit, newMerchantScore, tt.want)
+	}
+
+	failing := &fakeHistory{err: errors.New("store down")}
+	if _, err := rule.Evaluate(context.Background(), &Input{Transaction: tx("t", 50000, "New Shop", "US", 0), History: failing}); err == nil {
+		t.Error("Evaluate() ignored a history error")
+	}
+}
+
+func TestCategoryBlockRule(t *testing.T) {
+	rule := NewCategoryBlockRule([]string{" Gambling ", "crypto"})
+
+	tests := []struct {
+		category string
+		want     string
+	}{
+		{"gambling", "category gambling is blocked"},
+		{"GAMBLING", "category gambling is blocked"},
+		{"crypto", "category crypto is blocked"},
+		{"groceries", ""},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		in := tx("t", 1000, "Shop", "US", 0)
+		in.Category = tt.category
+		hit, err := rule.Evaluate(context.Background(), &Input{Transaction: in})
+		if err != nil {
+			t.Fatalf("%q: Evaluate() = %v", tt.category, err)
+		}
+		checkHit(t, tt.category, hit, categoryBlockScore, tt.want)
+	}
+}
+
+func TestImpossibleTravelRule(t *testing.T) {
+	rule := &ImpossibleTravelRule{Window: 2 * time.Hour}
+
+	tests := []struct {
+		name   string
+		tx     *models.Transaction
+		recent []*models.Transaction
+		want   string
+	}{
+		{"same country", tx("t", 1000, "Shop", "US", 0), []*models.Transaction{tx("a", 100, "Shop", "us", time.Hour)}, ""},
+		{"other country within the window", tx("t", 1000, "Shop", "US", 0),
+			[]*models.Transaction{tx("a", 100, "Cafe", "FR", time.Hour)}, "used in FR and US within 2h0m0s"},
+		{"other country outside the window", tx("t", 1000, "Shop", "US", 0),
+			[]*models.Transaction{tx("a", 100, "Cafe", "FR", 3*time.Hour)}, ""},
+		{"unknown country", tx("t", 1000, "Shop", "", 0), []*models.Transaction{tx("a", 100, "Cafe", "FR", time.Hour)}, ""},
+		{"recent unknown country", tx("t", 1000, "Shop", "US", 0), []*models.Transaction{tx("a", 100, "Cafe", "", time.Hour)}, ""},
+	}
+
+	for _, tt := range tests {
+		hit, err := rule.Evaluate(context.Background(), &Input{Transaction: tt.tx, Recent: tt.recent})
+		if err != nil {
+			t.Fatalf("%s: Evaluate() = %v", tt.name, err)
+		}
+		checkHit(t, tt.name, hit, impossibleTravelScore, tt.want)
+	}
+}
+
+func TestDuplicateRule(t *testing.T) {
+	rule := &DuplicateRule{Window: 5 * time.Minute}
+	converted := tx("a", 1000, "Shop", "US", time.Minute)
+	converted.OriginalAmount, converted.OriginalCurrency = 920, "EUR"
+
+	tests := []struct {
+		name   string
+		tx     *models.Transaction
+		recent []*models.Transaction
+		want   string
+	}{
+		{"same merchant and amount", tx("t", 1000, "Shop", "US", 0),
+			[]*models.Transaction{tx("a", 1000, "Shop", "US", time.Minute)}, "matches transaction a"},
+		{"other amount", tx("t", 1000, "Shop", "US", 0), []*models.Transaction{tx("a", 1001, "Shop", "US", time.Minute)}, ""},
+		{"other merchant", tx("t", 1000, "Shop", "US", 0), []*models.Transaction{tx("a", 1000, "Cafe", "US", time.Minute)}, ""},
+		{"outside the window", tx("t", 1000, "Shop", "US", 0), []*models.Transaction{tx("a", 1000, "Shop", "US", 10*time.Minute)}, ""},
+		{"other original currency", tx("t", 1000, "Shop", "US", 0), []*models.Transaction{converted}, ""},
+	}
+
+	for _, tt := range tests {
+		hit, err := rule.Evaluate(context.Background(), &Input{Transaction: tt.tx, Recent: tt.recent})
+		if err != nil {
+			t.Fatalf("%s: Evaluate() = %v", tt.name, err)
+		}
+		checkHit(t, tt.name, hit, duplicateScore, tt.want)
+	}
+}
+
+// checkHit checks a rule's hit against the expected score and reason, or its absence when
+// the reason is empty
+func checkHit(t *testing.T, name string, hit *Hit, score int, reason string) {
+	t.Helper()
+	if reason == "" {
+		if hit != nil {
+			t.Errorf("%s: got hit %+v, want none", name, hit)
+		}
+		return
+	}
+	if hit == nil {
+		t.Errorf("%s: got no hit, want %q", name, reason)
+		return
+	}
+	if hit.Score != score || !strings.Contains(hit.Reason, reason) {
+		t.Errorf("%s: got hit %+v, want score %d and reason %q", name, hit, score, reason)
+	}
+}
@@ -1,8 +1,12 @@
 package graphql
 
 import (
-	"github.com/araesf/ledgertime/internal/db"
+	"errors"
+	"fmt"
+
+	"github.com/araesf/ledgertime/internal/cardnet"
 	"github.com/araesf/ledgertime/internal/ledger"
+	"github.com/araesf/ledgertime/internal/merchant"
 	"github.com/araesf/ledgertime/internal/models"
 	"github.com/araesf/ledgertime/pkg/logger"
 	"github.com/google/uuid"
@@ -11,71 +15,444 @@ import (
 )
 
 type Resolver struct {
-	db            *db.DB
+	store         ledger.Store
 	ledgerService *ledger.Service
 	logger        *logger.Logger
 }
 
-func NewResolver(database *db.DB, ledgerService *ledger.Service, log *logger.Logger) *Resolver {
+func NewResolver(store ledger.Store, ledgerService *ledger.Service, log *logger.Logger) *Resolver {
 	return &Resolver{
-		db:            database,
+		store:         store,
 		ledgerService: ledgerService,
 		logger:        log,
 	}
 }
 
 func (r *Resolver) BuildSchema() (graphql.Schema, error) {
+	// Balance Type
+	balanceType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Balance",
+		Fields: graphql.Fields{
+			"account_id":        &graphql.Field{Type: graphql.String},
+			"currency":          &graphql.Field{Type: graphql.String},
+			"ledger_balance":    &graphql.Field{Type: graphql.Int},
+			"pending_amount":    &graphql.Field{Type: graphql.Int},
+			"available_balance": &graphql.Field{Type: graphql.Int},
+			"overdraft_limit":   &graphql.Field{Type: graphql.Int},
+			"updated_at":        &graphql.Field{Type: graphql.DateTime},
+		},
+	})
+
+	// Spending Types
+	spendingBucketType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "SpendingBucket",
+		Fields: graphql.Fields{
+			"period_start":   &graphql.Field{Type: graphql.DateTime},
+			"group":          &graphql.Field{Type: graphql.String},
+			"amount":         &graphql.Field{Type: graphql.Int},
+			"count":          &graphql.Field{Type: graphql.Int},
+			"previous":       &graphql.Field{Type: graphql.Int},
+			"change":         &graphql.Field{Type: graphql.Int},
+			"change_percent": &graphql.Field{Type: graphql.Float},
+		},
+	})
+
+	spendingSeriesType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "SpendingSeries",
+		Fields: graphql.Fields{
+			"interval": &graphql.Field{Type: graphql.String},
+			"group_by": &graphql.Field{Type: graphql.String},
+			"currency": &graphql.Field{Type: graphql.String},
+			"from":     &graphql.Field{Type: graphql.DateTime},
+			"to":       &graphql.Field{Type: graphql.DateTime},
+			"total":    &graphql.Field{Type: graphql.Int},
+			"buckets":  &graphql.Field{Type: graphql.NewList(spendingBucketType)},
+		},
+	})
+
 	// User Type
 	userType := graphql.NewObject(graphql.ObjectConfig{
 		Name: "User",
 		Fields: graphql.Fields{
-			"id":         &graphql.Field{Type: graphql.String},
-			"name":       &graphql.Field{Type: graphql.String},
-			"email":      &graphql.Field{Type: graphql.String},
+			"id":    &graphql.Field{Type: graphql.String},
+			"name":  &graphql.Field{Type: graphql.String},
+			"email": &graphql.Field{Type: graphql.String},
+			"balance": &graphql.Field{
+				Type: balanceType,
+				Args: graphql.FieldConfigArgument{
+					"currency": &graphql.ArgumentConfig{
+						Type:         graphql.String,
+						DefaultValue: "",
+					},
+				},
+				Resolve: r.userBalanceResolver,
+			},
+			"spending": &graphql.Field{
+				Type: spendingSeriesType,
+				Args: graphql.FieldConfigArgument{
+					"interval": &graphql.ArgumentConfig{
+						Type:         graphql.String,
+						DefaultValue: models.SpendingIntervalMonth,
+					},
+					"group_by": &graphql.ArgumentConfig{
+						Type:         graphql.String,
+						DefaultValue: "",
+					},
+					"from": &graphql.ArgumentConfig{
+						Type:         graphql.String, // YYYY-MM-DD, inclusive
+						DefaultValue: "",
+					},
+					"to": &graphql.ArgumentConfig{
+						Type:         graphql.String, // YYYY-MM-DD, inclusive
+						DefaultValue: "",
+					},
+					"currency": &graphql.ArgumentConfig{
+						Type:         graphql.String,
+						DefaultValue: "",
+					},
+				},
+				Resolve: r.userSpendingResolver,
+			},
 			"created_at": &graphql.Field{Type: graphql.DateTime},
 			"updated_at": &graphql.Field{Type: graphql.DateTime},
 		},
 	})
 
+	// Card Status Change Type
+	cardStatusChangeType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "CardStatusChange",
+		Fields: graphql.Fields{
+			"id":          &graphql.Field{Type: graphql.String},
+			"card_id":     &graphql.Field{Type: graphql.String},
+			"from_status": &graphql.Field{Type: graphql.String},
+			"to_status":   &graphql.Field{Type: graphql.String},
+			"actor":       &graphql.Field{Type: graphql.String},
+			"reason":      &graphql.Field{Type: graphql.String},
+			"created_at":  &graphql.Field{Type: graphql.DateTime},
+		},
+	})
+
 	// Card Type
 	cardType := graphql.NewObject(graphql.ObjectConfig{
 		Name: "Card",
 		Fields: graphql.Fields{
-			"id":          &graphql.Field{Type: graphql.String},
-			"user_id":     &graphql.Field{Type: graphql.String},
-			"card_number": &graphql.Field{Type: graphql.String},
-			"card_type":   &graphql.Field{Type: graphql.String},
-			"is_active":   &graphql.Field{Type: graphql.Boolean},
+			"id":               &graphql.Field{Type: graphql.String},
+			"user_id":          &graphql.Field{Type: graphql.String},
+			"token":            &graphql.Field{Type: graphql.String},
+			"card_number":      &graphql.Field{Type: graphql.String},
+			"last4":            &graphql.Field{Type: graphql.String},
+			"card_type":        &graphql.Field{Type: graphql.String},
+			"issuer":           &graphql.Field{Type: graphql.String},
+			"issuer_country":   &graphql.Field{Type: graphql.String},
+			"funding":          &graphql.Field{Type: graphql.String},
+			"currency":         &graphql.Field{Type: graphql.String},
+			"status":           &graphql.Field{Type: graphql.String},
+			"is_active":        &graphql.Field{Type: graphql.Boolean},
+			"expires_at":       &graphql.Field{Type: graphql.DateTime},
+			"replaces_card_id": &graphql.Field{Type: graphql.String},
+			"created_at":       &graphql.Field{Type: graphql.DateTime},
+			"updated_at":       &graphql.Field{Type: graphql.DateTime},
+			"status_history": &graphql.Field{
+				Type:    graphql.NewList(cardStatusChangeType),
+				Resolve: r.cardHistoryResolver,
+			},
+		},
+	})
+
+	// cardStatusArgs are shared by the card lifecycle mutations
+	cardStatusArgs := graphql.FieldConfigArgument{
+		"card_id": &graphql.ArgumentConfig{
+			Type: graphql.NewNonNull(graphql.String),
+		},
+		"reason": &graphql.ArgumentConfig{
+			Type:         graphql.String,
+			DefaultValue: "",
+		},
+	}
+
+	// Status Change Type
+	statusChangeType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "StatusChange",
+		Fields: graphql.Fields{
+			"from_status": &graphql.Field{Type: graphql.String},
+			"to_status":   &graphql.Field{Type: graphql.String},
+			"actor":       &graphql.Field{Type: graphql.String},
+			"reason":      &graphql.Field{Type: graphql.String},
 			"created_at":  &graphql.Field{Type: graphql.DateTime},
 		},
 	})
 
+	// Merchant Type
+	merchantType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Merchant",
+		Fields: graphql.Fields{
+			"id":              &graphql.Field{Type: graphql.String},
+			"name":            &graphql.Field{Type: graphql.String},
+			"normalized_name": &graphql.Field{Type: graphql.String},
+			"mcc":             &graphql.Field{Type: graphql.String},
+			"city":            &graphql.Field{Type: graphql.String},
+			"country":         &graphql.Field{Type: graphql.String},
+			"logo_url":        &graphql.Field{Type: graphql.String},
+			"created_at":      &graphql.Field{Type: graphql.DateTime},
+			"updated_at":      &graphql.Field{Type: graphql.DateTime},
+		},
+	})
+
+	// MCC Type
+	mccType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "MCC",
+		Fields: graphql.Fields{
+			"code":        &graphql.Field{Type: graphql.String},
+			"description": &graphql.Field{Type: graphql.String},
+			"category":    &graphql.Field{Type: graphql.String},
+		},
+	})
+
+	// Category Type
+	categoryType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Category",
+		Fields: graphql.Fields{
+			"id":         &graphql.Field{Type: graphql.String},
+			"user_id":    &graphql.Field{Type: graphql.String},
+			"name":       &graphql.Field{Type: graphql.String},
+			"built_in":   &graphql.Field{Type: graphql.Boolean},
+			"created_at": &graphql.Field{Type: graphql.DateTime},
+		},
+	})
+
+	// Category Rule Type
+	categoryRuleType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "CategoryRule",
+		Fields: graphql.Fields{
+			"id":         &graphql.Field{Type: graphql.String},
+			"user_id":    &graphql.Field{Type: graphql.String},
+			"field":      &graphql.Field{Type: graphql.String},
+			"operator":   &graphql.Field{Type: graphql.String},
+			"value":      &graphql.Field{Type: graphql.String},
+			"category":   &graphql.Field{Type: graphql.String},
+			"priority":   &graphql.Field{Type: graphql.Int},
+			"created_at": &graphql.Field{Type: graphql.DateTime},
+			"updated_at": &graphql.Field{Type: graphql.DateTime},
+		},
+	})
+
+	// Budget Types
+	budgetType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Budget",
+		Fields: graphql.Fields{
+			"id":         &graphql.Field{Type: graphql.String},
+			"user_id":    &graphql.Field{Type: graphql.String},
+			"category":   &graphql.Field{Type: graphql.String},
+			"period":     &graphql.Field{Type: graphql.String},
+			"amount":     &graphql.Field{Type: graphql.Int},
+			"currency":   &graphql.Field{Type: graphql.String},
+			"rollover":   &graphql.Field{Type: graphql.Boolean},
+			"created_at": &graphql.Field{Type: graphql.DateTime},
+			"updated_at": &graphql.Field{Type: graphql.DateTime},
+		},
+	})
+
+	budgetProgressType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "BudgetProgress",
+		Fields: graphql.Fields{
+			"budget":       &graphql.Field{Type: budgetType},
+			"period_start": &graphql.Field{Type: graphql.DateTime},
+			"period_end":   &graphql.Field{Type: graphql.DateTime},
+			"rolled_over":  &graphql.Field{Type: graphql.Int},
+			"available":    &graphql.Field{Type: graphql.Int},
+			"spent":        &graphql.Field{Type: graphql.Int},
+			"remaining":    &graphql.Field{Type: graphql.Int},
+			"percent_used": &graphql.Field{Type: graphql.Int},
+		},
+	})
+
+	budgetAlertType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "BudgetAlert",
+		Fields: graphql.Fields{
+			"id":             &graphql.Field{Type: graphql.String},
+			"budget_id":      &graphql.Field{Type: graphql.String},
+			"user_id":        &graphql.Field{Type: graphql.String},
+			"category":       &graphql.Field{Type: graphql.String},
+			"threshold":      &graphql.Field{Type: graphql.Int},
+			"period_start":   &graphql.Field{Type: graphql.DateTime},
+			"spent":          &graphql.Field{Type: graphql.Int},
+			"available":      &graphql.Field{Type: graphql.Int},
+			"transaction_id": &graphql.Field{Type: graphql.String},
+			"created_at":     &graphql.Field{Type: graphql.DateTime},
+		},
+	})
+
 	// Transaction Type
 	transactionType := graphql.NewObject(graphql.ObjectConfig{
 		Name: "Transaction",
 		Fields: graphql.Fields{
-			"id":               &graphql.Field{Type: graphql.String},
-			"user_id":          &graphql.Field{Type: graphql.String},
-			"card_id":          &graphql.Field{Type: graphql.String},
-			"amount":           &graphql.Field{Type: graphql.Int},
-			"merchant_name":    &graphql.Field{Type: graphql.String},
-			"merchant_city":    &graphql.Field{Type: graphql.String},
-			"merchant_country": &graphql.Field{Type: graphql.String},
-			"mcc":              &graphql.Field{Type: graphql.String},
-			"auth_code":        &graphql.Field{Type: graphql.String},
-			"status":           &graphql.Field{Type: graphql.String},
-			"timestamp":        &graphql.Field{Type: graphql.DateTime},
+			"id":                    &graphql.Field{Type: graphql.String},
+			"user_id":               &graphql.Field{Type: graphql.String},
+			"card_id":               &graphql.Field{Type: graphql.String},
+			"type":                  &graphql.Field{Type: graphql.String},
+			"parent_transaction_id": &graphql.Field{Type: graphql.String},
+			"amount":                &graphql.Field{Type: graphql.Int},
+			"currency":              &graphql.Field{Type: graphql.String},
+			"original_amount":       &graphql.Field{Type: graphql.Int},
+			"original_currency":     &graphql.Field{Type: graphql.String},
+			"fx_rate":               &graphql.Field{Type: graphql.String},
+			"merchant_id":           &graphql.Field{Type: graphql.String},
+			"merchant_name":         &graphql.Field{Type: graphql.String},
+			"merchant_descriptor":   &graphql.Field{Type: graphql.String},
+			"merchant_city":         &graphql.Field{Type: graphql.String},
+			"merchant_country":      &graphql.Field{Type: graphql.String},
+			"mcc":                   &graphql.Field{Type: graphql.String},
+			"category":              &graphql.Field{Type: graphql.String},
+			"category_source":       &graphql.Field{Type: graphql.String},
+			"auth_code":             &graphql.Field{Type: graphql.String},
+			"status":                &graphql.Field{Type: graphql.String},
+			"decline_reason":        &graphql.Field{Type: graphql.String},
+			"fraud_score":           &graphql.Field{Type: graphql.Int},
+			"fraud_decision":        &graphql.Field{Type: graphql.String},
+			"fraud_reasons":         &graphql.Field{Type: graphql.NewList(graphql.String)},
+			"timestamp":             &graphql.Field{Type: graphql.DateTime},
+			"status_history": &graphql.Field{
+				Type:    graphql.NewList(statusChangeType),
+				Resolve: r.transactionHistoryResolver,
+			},
+			"merchant": &graphql.Field{
+				Type:    merchantType,
+				Resolve: r.transactionMerchantResolver,
+			},
+		},
+	})
+
+	// Spending Limits Type
+	categoryCapType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "CategoryCap",
+		Fields: graphql.Fields{
+			"category": &graphql.Field{Type: graphql.String},
+			"amount":   &graphql.Field{Type: graphql.Int},
+		},
+	})
+
+	spendingLimitsType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "SpendingLimits",
+		Fields: graphql.Fields{
+			"scope":              &graphql.Field{Type: graphql.String},
+			"scope_id":           &graphql.Field{Type: graphql.String},
+			"currency":           &graphql.Field{Type: graphql.String},
+			"single_transaction": &graphql.Field{Type: graphql.Int},
+			"daily":              &graphql.Field{Type: graphql.Int},
+			"weekly":             &graphql.Field{Type: graphql.Int},
+			"monthly":            &graphql.Field{Type: graphql.Int},
+			"blocked_categories": &graphql.Field{Type: graphql.NewList(graphql.String)},
+			"category_caps":      &graphql.Field{Type: graphql.NewList(categoryCapType)},
+			"updated_at":         &graphql.Field{Type: graphql.DateTime},
+		},
+	})
+
+	categoryCapInput := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "CategoryCapInput",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"category": &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+			"amount":   &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.Int)},
+		},
+	})
+
+	// limitArgs builds the arguments of the set limits mutations around the owner's ID argument
+	limitArgs := func(idArg string) graphql.FieldConfigArgument {
+		args := graphql.FieldConfigArgument{
+			idArg: &graphql.ArgumentConfig{
+				Type: graphql.NewNonNull(graphql.String),
+			},
+			"currency": &graphql.ArgumentConfig{
+				Type:         graphql.String,
+				DefaultValue: "",
+			},
+			"blocked_categories": &graphql.ArgumentConfig{
+				Type: graphql.NewList(graphql.String),
+			},
+			"category_caps": &graphql.ArgumentConfig{
+				Type: graphql.NewList(categoryCapInput),
+			},
+		}
+		for _, name := range []string{"single_transaction", "daily", "weekly", "monthly"} {
+			args[name] = &graphql.ArgumentConfig{
+				Type:         graphql.Int,
+				DefaultValue: 0,
+			}
+		}
+		return args
+	}
+
+	// Review Item Type
+	reviewItemType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "ReviewItem",
+		Fields: graphql.Fields{
+			"id":             &graphql.Field{Type: graphql.String},
+			"transaction_id": &graphql.Field{Type: graphql.String},
+			"intent":         &graphql.Field{Type: graphql.String},
+			"status":         &graphql.Field{Type: graphql.String},
+			"reason":         &graphql.Field{Type: graphql.String},
+			"claimed_by":     &graphql.Field{Type: graphql.String},
+			"claimed_at":     &graphql.Field{Type: graphql.DateTime},
+			"decided_by":     &graphql.Field{Type: graphql.String},
+			"decided_at":     &graphql.Field{Type: graphql.DateTime},
+			"notes":          &graphql.Field{Type: graphql.String},
+			"expires_at":     &graphql.Field{Type: graphql.DateTime},
+			"created_at":     &graphql.Field{Type: graphql.DateTime},
+			"transaction": &graphql.Field{
+				Type:    transactionType,
+				Resolve: r.reviewTransactionResolver,
+			},
+		},
+	})
+
+	// reviewDecisionArgs are shared by the claim, approve and decline mutations
+	reviewDecisionArgs := graphql.FieldConfigArgument{
+		"id": &graphql.ArgumentConfig{
+			Type: graphql.NewNonNull(graphql.String),
+		},
+		"analyst": &graphql.ArgumentConfig{
+			Type: graphql.NewNonNull(graphql.String),
+		},
+		"notes": &graphql.ArgumentConfig{
+			Type:         graphql.String,
+			DefaultValue: "",
+		},
+	}
+
+	// Summary Types
+	categorySpendType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "CategorySpend",
+		Fields: graphql.Fields{
+			"category": &graphql.Field{Type: graphql.String},
+			"amount":   &graphql.Field{Type: graphql.Int},
+			"count":    &graphql.Field{Type: graphql.Int},
+			"share":    &graphql.Field{Type: graphql.Float},
+		},
+	})
+
+	merchantSpendType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "MerchantSpend",
+		Fields: graphql.Fields{
+			"merchant_name": &graphql.Field{Type: graphql.String},
+			"amount":        &graphql.Field{Type: graphql.Int},
+			"count":         &graphql.Field{Type: graphql.Int},
+			"share":         &graphql.Field{Type: graphql.Float},
 		},
 	})
 
-	// Summary Type
 	summaryType := graphql.NewObject(graphql.ObjectConfig{
 		Name: "UserSummary",
 		Fields: graphql.Fields{
 			"user_id":            &graphql.Field{Type: graphql.String},
-			"total_transactions": &graphql.Field{Type: graphql.Int},
+			"currency":           &graphql.Field{Type: graphql.String},
+			"from":               &graphql.Field{Type: graphql.DateTime},
+			"to":                 &graphql.Field{Type: graphql.DateTime},
 			"total_amount":       &graphql.Field{Type: graphql.Int},
-			"category_breakdown": &graphql.Field{Type: graphql.NewList(graphql.String)},
+			"total_transactions": &graphql.Field{Type: graphql.Int},
+			"avg_amount":         &graphql.Field{Type: graphql.Int},
+			"top_category":       &graphql.Field{Type: graphql.String},
+			"top_merchant":       &graphql.Field{Type: graphql.String},
+			"category_breakdown": &graphql.Field{Type: graphql.NewList(categorySpendType)},
+			"top_merchants":      &graphql.Field{Type: graphql.NewList(merchantSpendType)},
 		},
 	})
 
@@ -95,7 +472,7 @@ func (r *Resolver) BuildSchema() (graphql.Schema, error) {
 			"card": &graphql.Field{
 				Type: cardType,
 				Args: graphql.FieldConfigArgument{
-					"card_number": &graphql.ArgumentConfig{
+					"token": &graphql.ArgumentConfig{
 						Type: graphql.NewNonNull(graphql.String),
 					},
 				},
@@ -124,9 +501,146 @@ func (r *Resolver) BuildSchema() (graphql.Schema, error) {
 					"user_id": &graphql.ArgumentConfig{
 						Type: graphql.NewNonNull(graphql.String),
 					},
+					"from": &graphql.ArgumentConfig{
+						Type:         graphql.String, // YYYY-MM-DD, inclusive
+						DefaultValue: "",
+					},
+					"to": &graphql.ArgumentConfig{
+						Type:         graphql.String, // YYYY-MM-DD, inclusive
+						DefaultValue: "",
+					},
+					"currency": &graphql.ArgumentConfig{
+						Type:         graphql.String,
+						DefaultValue: "",
+					},
+					"top_merchants": &graphql.ArgumentConfig{
+						Type:         graphql.Int,
+						DefaultValue: 5,
+					},
 				},
 				Resolve: r.getUserSummaryResolver,
 			},
+			"cardLimits": &graphql.Field{
+				Type: spendingLimitsType,
+				Args: graphql.FieldConfigArgument{
+					"card_id": &graphql.ArgumentConfig{
+						Type: graphql.NewNonNull(graphql.String),
+					},
+				},
+				Resolve: r.getCardLimitsResolver,
+			},
+			"userLimits": &graphql.Field{
+				Type: spendingLimitsType,
+				Args: graphql.FieldConfigArgument{
+					"user_id": &graphql.ArgumentConfig{
+						Type: graphql.NewNonNull(graphql.String),
+					},
+				},
+				Resolve: r.getUserLimitsResolver,
+			},
+			"merchants": &graphql.Field{
+				Type: graphql.NewList(merchantType),
+				Args: graphql.FieldConfigArgument{
+					"limit": &graphql.ArgumentConfig{
+						Type:         graphql.Int,
+						DefaultValue: 50,
+					},
+					"offset": &graphql.ArgumentConfig{
+						Type:         graphql.Int,
+						DefaultValue: 0,
+					},
+				},
+				Resolve: r.getMerchantsResolver,
+			},
+			"merchant": &graphql.Field{
+				Type: merchantType,
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{
+						Type: graphql.NewNonNull(graphql.String),
+					},
+				},
+				Resolve: r.getMerchantResolver,
+			},
+			"mcc": &graphql.Field{
+				Type: mccType,
+				Args: graphql.FieldConfigArgument{
+					"code": &graphql.ArgumentConfig{
+						Type: graphql.NewNonNull(graphql.String),
+					},
+				},
+				Resolve: r.getMCCResolver,
+			},
+			"categories": &graphql.Field{
+				Type: graphql.NewList(categoryType),
+				Args: graphql.FieldConfigArgument{
+					"user_id": &graphql.ArgumentConfig{
+						Type: graphql.NewNonNull(graphql.String),
+					},
+				},
+				Resolve: r.getCategoriesResolver,
+			},
+			"categoryRules": &graphql.Field{
+				Type: graphql.NewList(categoryRuleType),
+				Args: graphql.FieldConfigArgument{
+					"user_id": &graphql.ArgumentConfig{
+						Type: graphql.NewNonNull(graphql.String),
+					},
+				},
+				Resolve: r.getCategoryRulesResolver,
+			},
+			"budgets": &graphql.Field{
+				Type: graphql.NewList(budgetProgressType),
+				Args: graphql.FieldConfigArgument{
+					"user_id": &graphql.ArgumentConfig{
+						Type: graphql.NewNonNull(graphql.String),
+					},
+				},
+				Resolve: r.getBudgetsResolver,
+			},
+			"budgetAlerts": &graphql.Field{
+				Type: graphql.NewList(budgetAlertType),
+				Args: graphql.FieldConfigArgument{
+					"user_id": &graphql.ArgumentConfig{
+						Type: graphql.NewNonNull(graphql.String),
+					},
+					"limit": &graphql.ArgumentConfig{
+						Type:         graphql.Int,
+						DefaultValue: 50,
+					},
+					"offset": &graphql.ArgumentConfig{
+						Type:         graphql.Int,
+						DefaultValue: 0,
+					},
+				},
+				Resolve: r.getBudgetAlertsResolver,
+			},
+			"reviews": &graphql.Field{
+				Type: graphql.NewList(reviewItemType),
+				Args: graphql.FieldConfigArgument{
+					"status": &graphql.ArgumentConfig{
+						Type:         graphql.String,
+						DefaultValue: "",
+					},
+					"limit": &graphql.ArgumentConfig{
+						Type:         graphql.Int,
+						DefaultValue: 50,
+					},
+					"offset": &graphql.ArgumentConfig{
+						Type:         graphql.Int,
+						DefaultValue: 0,
+					},
+				},
+				Resolve: r.getReviewsResolver,
+			},
+			"review": &graphql.Field{
+				Type: reviewItemType,
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{
+						Type: graphql.NewNonNull(graphql.String),
+					},
+				},
+				Resolve: r.getReviewResolver,
+			},
 		},
 	})
 
@@ -156,7 +670,12 @@ func (r *Resolver) BuildSchema() (graphql.Schema, error) {
 						Type: graphql.NewNonNull(graphql.String),
 					},
 					"card_type": &graphql.ArgumentConfig{
-						Type: graphql.NewNonNull(graphql.String),
+						Type:         graphql.String,
+						DefaultValue: "",
+					},
+					"currency": &graphql.ArgumentConfig{
+						Type:         graphql.String,
+						DefaultValue: "",
 					},
 				},
 				Resolve: r.createCardResolver,
@@ -170,6 +689,10 @@ func (r *Resolver) BuildSchema() (graphql.Schema, error) {
 					"amount": &graphql.ArgumentConfig{
 						Type: graphql.NewNonNull(graphql.Int),
 					},
+					"currency": &graphql.ArgumentConfig{
+						Type:         graphql.String,
+						DefaultValue: "",
+					},
 					"merchant_name": &graphql.ArgumentConfig{
 						Type: graphql.NewNonNull(graphql.String),
 					},
@@ -182,9 +705,214 @@ func (r *Resolver) BuildSchema() (graphql.Schema, error) {
 					"mcc": &graphql.ArgumentConfig{
 						Type: graphql.String,
 					},
+					"idempotency_key": &graphql.ArgumentConfig{
+						Type:         graphql.String,
+						DefaultValue: "",
+					},
 				},
 				Resolve: r.processTransactionResolver,
 			},
+			"refundTransaction": &graphql.Field{
+				Type: transactionType,
+				Args: graphql.FieldConfigArgument{
+					"transaction_id": &graphql.ArgumentConfig{
+						Type: graphql.NewNonNull(graphql.String),
+					},
+					"amount": &graphql.ArgumentConfig{
+						Type:         graphql.Int,
+						DefaultValue: 0,
+					},
+					"reason": &graphql.ArgumentConfig{
+						Type:         graphql.String,
+						DefaultValue: "",
+					},
+				},
+				Resolve: r.refundTransactionResolver,
+			},
+			"freezeCard": &graphql.Field{
+				Type:    cardType,
+				Args:    cardStatusArgs,
+				Resolve: r.freezeCardResolver,
+			},
+			"unfreezeCard": &graphql.Field{
+				Type:    cardType,
+				Args:    cardStatusArgs,
+				Resolve: r.unfreezeCardResolver,
+			},
+			"closeCard": &graphql.Field{
+				Type:    cardType,
+				Args:    cardStatusArgs,
+				Resolve: r.closeCardResolver,
+			},
+			"reportCard": &graphql.Field{
+				Type: cardType,
+				Args: graphql.FieldConfigArgument{
+					"card_id": &graphql.ArgumentConfig{
+						Type: graphql.NewNonNull(graphql.String),
+					},
+					"status": &graphql.ArgumentConfig{
+						Type: graphql.NewNonNull(graphql.String),
+					},
+					"reason": &graphql.ArgumentConfig{
+						Type:         graphql.String,
+						DefaultValue: "",
+					},
+				},
+				Resolve: r.reportCardResolver,
+			},
+			"replaceCard": &graphql.Field{
+				Type: cardType,
+				Args: graphql.FieldConfigArgument{
+					"card_id": &graphql.ArgumentConfig{
+						Type: graphql.NewNonNull(graphql.String),
+					},
+					"card_number": &graphql.ArgumentConfig{
+						Type: graphql.NewNonNull(graphql.String),
+					},
+					"reason": &graphql.ArgumentConfig{
+						Type:         graphql.String,
+						DefaultValue: "",
+					},
+				},
+				Resolve: r.replaceCardResolver,
+			},
+			"setCardLimits": &graphql.Field{
+				Type:    spendingLimitsType,
+				Args:    limitArgs("card_id"),
+				Resolve: r.setCardLimitsResolver,
+			},
+			"setUserLimits": &graphql.Field{
+				Type:    spendingLimitsType,
+				Args:    limitArgs("user_id"),
+				Resolve: r.setUserLimitsResolver,
+			},
+			"updateMerchant": &graphql.Field{
+				Type: merchantType,
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{
+						Type: graphql.NewNonNull(graphql.String),
+					},
+					"name": &graphql.ArgumentConfig{
+						Type: graphql.NewNonNull(graphql.String),
+					},
+					"mcc": &graphql.ArgumentConfig{
+						Type:         graphql.String,
+						DefaultValue: "",
+					},
+					"city": &graphql.ArgumentConfig{
+						Type:         graphql.String,
+						DefaultValue: "",
+					},
+					"country": &graphql.ArgumentConfig{
+						Type:         graphql.String,
+						DefaultValue: "",
+					},
+					"logo_url": &graphql.ArgumentConfig{
+						Type:         graphql.String,
+						DefaultValue: "",
+					},
+				},
+				Resolve: r.updateMerchantResolver,
+			},
+			"createCategory": &graphql.Field{
+				Type: categoryType,
+				Args: graphql.FieldConfigArgument{
+					"user_id": &graphql.ArgumentConfig{
+						Type: graphql.NewNonNull(graphql.String),
+					},
+					"name": &graphql.ArgumentConfig{
+						Type: graphql.NewNonNull(graphql.String),
+					},
+				},
+				Resolve: r.createCategoryResolver,
+			},
+			"createCategoryRule": &graphql.Field{
+				Type: categoryRuleType,
+				Args: graphql.FieldConfigArgument{
+					"user_id": &graphql.ArgumentConfig{
+						Type: graphql.NewNonNull(graphql.String),
+					},
+					"field": &graphql.ArgumentConfig{
+						Type: graphql.NewNonNull(graphql.String),
+					},
+					"operator": &graphql.ArgumentConfig{
+						Type: graphql.NewNonNull(graphql.String),
+					},
+					"value": &graphql.ArgumentConfig{
+						Type: graphql.NewNonNull(graphql.String),
+					},
+					"category": &graphql.ArgumentConfig{
+						Type: graphql.NewNonNull(graphql.String),
+					},
+					"priority": &graphql.ArgumentConfig{
+						Type:         graphql.Int,
+						DefaultValue: 0,
+					},
+				},
+				Resolve: r.createCategoryRuleResolver,
+			},
+			"applyCategoryRules": &graphql.Field{
+				Type: graphql.Int,
+				Args: graphql.FieldConfigArgument{
+					"user_id": &graphql.ArgumentConfig{
+						Type: graphql.NewNonNull(graphql.String),
+					},
+				},
+				Resolve: r.applyCategoryRulesResolver,
+			},
+			"recategorizeTransaction": &graphql.Field{
+				Type: transactionType,
+				Args: graphql.FieldConfigArgument{
+					"transaction_id": &graphql.ArgumentConfig{
+						Type: graphql.NewNonNull(graphql.String),
+					},
+					"category": &graphql.ArgumentConfig{
+						Type: graphql.NewNonNull(graphql.String),
+					},
+				},
+				Resolve: r.recategorizeTransactionResolver,
+			},
+			"createBudget": &graphql.Field{
+				Type: budgetType,
+				Args: graphql.FieldConfigArgument{
+					"user_id": &graphql.ArgumentConfig{
+						Type: graphql.NewNonNull(graphql.String),
+					},
+					"category": &graphql.ArgumentConfig{
+						Type: graphql.NewNonNull(graphql.String),
+					},
+					"period": &graphql.ArgumentConfig{
+						Type: graphql.NewNonNull(graphql.String),
+					},
+					"amount": &graphql.ArgumentConfig{
+						Type: graphql.NewNonNull(graphql.Int),
+					},
+					"currency": &graphql.ArgumentConfig{
+						Type:         graphql.String,
+						DefaultValue: "",
+					},
+					"rollover": &graphql.ArgumentConfig{
+						Type:         graphql.Boolean,
+						DefaultValue: false,
+					},
+				},
+				Resolve: r.createBudgetResolver,
+			},
+			"claimReview": &graphql.Field{
+				Type:    reviewItemType,
+				Args:    reviewDecisionArgs,
+				Resolve: r.claimReviewResolver,
+			},
+			"approveReview": &graphql.Field{
+				Type:    reviewItemType,
+				Args:    reviewDecisionArgs,
+				Resolve: r.approveReviewResolver,
+			},
+			"declineReview": &graphql.Field{
+				Type:    reviewItemType,
+				Args:    reviewDecisionArgs,
+				Resolve: r.declineReviewResolver,
+			},
 		},
 	})
 
@@ -197,31 +925,150 @@ func (r *Resolver) BuildSchema() (graphql.Schema, error) {
 // Query Resolvers
 func (r *Resolver) getUserResolver(p graphql.ResolveParams) (interface{}, error) {
 	id := p.Args["id"].(string)
-	return r.db.GetUser(id)
+	return r.store.GetUser(p.Context, id)
 }
 
 func (r *Resolver) getCardResolver(p graphql.ResolveParams) (interface{}, error) {
-	cardNumber := p.Args["card_number"].(string)
-	return r.db.GetCardByNumber(cardNumber)
+	token := p.Args["token"].(string)
+	return r.ledgerService.GetCardByToken(p.Context, token)
 }
 
 func (r *Resolver) getTransactionsResolver(p graphql.ResolveParams) (interface{}, error) {
 	userID := p.Args["user_id"].(string)
 	limit := p.Args["limit"].(int)
 	offset := p.Args["offset"].(int)
-	return r.ledgerService.GetUserTransactions(userID, limit, offset)
+	return r.ledgerService.GetUserTransactions(p.Context, userID, limit, offset)
 }
 
 func (r *Resolver) getUserSummaryResolver(p graphql.ResolveParams) (interface{}, error) {
 	userID := p.Args["user_id"].(string)
-	return r.ledgerService.GetUserSummary(userID)
+	query := &ledger.SummaryQuery{
+		UserID:       userID,
+		Currency:     p.Args["currency"].(string),
+		TopMerchants: p.Args["top_merchants"].(int),
+	}
+
+	from, to, err := ledger.ParseDateRange(p.Args["from"].(string), p.Args["to"].(string))
+	if err != nil {
+		return nil, err
+	}
+	query.From, query.To = from, to
+
+	return r.ledgerService.GetUserSummary(p.Context, query)
+}
+
+func (r *Resolver) userBalanceResolver(p graphql.ResolveParams) (interface{}, error) {
+	user := p.Source.(*models.User)
+	currency := p.Args["currency"].(string)
+	return r.ledgerService.GetUserBalance(p.Context, user.ID, currency)
+}
+
+func (r *Resolver) userSpendingResolver(p graphql.ResolveParams) (interface{}, error) {
+	user := p.Source.(*models.User)
+	query := &models.SpendingQuery{
+		UserID:   user.ID,
+		Interval: p.Args["interval"].(string),
+		GroupBy:  p.Args["group_by"].(string),
+		Currency: p.Args["currency"].(string),
+	}
+
+	from, to, err := ledger.ParseDateRange(p.Args["from"].(string), p.Args["to"].(string))
+	if err != nil {
+		return nil, err
+	}
+	query.From, query.To = from, to
+
+	return r.ledgerService.GetSpending(p.Context, query)
+}
+
+func (r *Resolver) transactionHistoryResolver(p graphql.ResolveParams) (interface{}, error) {
+	transaction := p.Source.(*models.Transaction)
+	return r.ledgerService.GetTransactionHistory(p.Context, transaction.ID)
+}
+
+func (r *Resolver) cardHistoryResolver(p graphql.ResolveParams) (interface{}, error) {
+	card := p.Source.(*models.Card)
+	return r.ledgerService.GetCardHistory(p.Context, card.ID)
+}
+
+func (r *Resolver) getCardLimitsResolver(p graphql.ResolveParams) (interface{}, error) {
+	cardID := p.Args["card_id"].(string)
+	return r.ledgerService.GetCardLimits(p.Context, cardID)
+}
+
+func (r *Resolver) getUserLimitsResolver(p graphql.ResolveParams) (interface{}, error) {
+	userID := p.Args["user_id"].(string)
+	return r.ledgerService.GetUserLimits(p.Context, userID)
+}
+
+func (r *Resolver) getMerchantsResolver(p graphql.ResolveParams) (interface{}, error) {
+	limit := p.Args["limit"].(int)
+	offset := p.Args["offset"].(int)
+	return r.ledgerService.ListMerchants(p.Context, limit, offset)
+}
+
+func (r *Resolver) getMerchantResolver(p graphql.ResolveParams) (interface{}, error) {
+	id := p.Args["id"].(string)
+	return r.ledgerService.GetMerchant(p.Context, id)
+}
+
+func (r *Resolver) getMCCResolver(p graphql.ResolveParams) (interface{}, error) {
+	code := p.Args["code"].(string)
+	return merchant.LookupMCC(code)
+}
+
+func (r *Resolver) getCategoriesResolver(p graphql.ResolveParams) (interface{}, error) {
+	userID := p.Args["user_id"].(string)
+	return r.ledgerService.ListCategories(p.Context, userID)
+}
+
+func (r *Resolver) getCategoryRulesResolver(p graphql.ResolveParams) (interface{}, error) {
+	userID := p.Args["user_id"].(string)
+	return r.ledgerService.ListCategoryRules(p.Context, userID)
+}
+
+func (r *Resolver) getBudgetsResolver(p graphql.ResolveParams) (interface{}, error) {
+	userID := p.Args["user_id"].(string)
+	return r.ledgerService.ListBudgets(p.Context, userID, time.Now())
+}
+
+func (r *Resolver) getBudgetAlertsResolver(p graphql.ResolveParams) (interface{}, error) {
+	userID := p.Args["user_id"].(string)
+	limit := p.Args["limit"].(int)
+	offset := p.Args["offset"].(int)
+	return r.ledgerService.ListBudgetAlerts(p.Context, userID, limit, offset)
+}
+
+func (r *Resolver) transactionMerchantResolver(p graphql.ResolveParams) (interface{}, error) {
+	tx := p.Source.(*models.Transaction)
+	if tx.MerchantID == "" {
+		return nil, nil
+	}
+	return r.ledgerService.GetMerchant(p.Context, tx.MerchantID)
+}
+
+func (r *Resolver) getReviewsResolver(p graphql.ResolveParams) (interface{}, error) {
+	status := p.Args["status"].(string)
+	limit := p.Args["limit"].(int)
+	offset := p.Args["offset"].(int)
+	return r.ledgerService.ListReviews(p.Context, status, limit, offset)
+}
+
+func (r *Resolver) getReviewResolver(p graphql.ResolveParams) (interface{}, error) {
+	id := p.Args["id"].(string)
+	return r.ledgerService.GetReview(p.Context, id)
+}
+
+func (r *Resolver) reviewTransactionResolver(p graphql.ResolveParams) (interface{}, error) {
+	item := p.Source.(*models.ReviewItem)
+	return r.store.GetTransaction(p.Context, item.TransactionID)
 }
 
 // Mutation Resolvers
 func (r *Resolver) createUserResolver(p graphql.ResolveParams) (interface{}, error) {
 	name := p.Args["name"].(string)
 	email := p.Args["email"].(string)
-	
+
 	now := time.Now()
 	user := &models.User{
 		ID:        uuid.New().String(),
@@ -230,8 +1077,8 @@ func (r *Resolver) createUserResolver(p graphql.ResolveParams) (interface{}, err
 		CreatedAt: now,
 		UpdatedAt: now,
 	}
-	
-	if err := r.db.CreateUser(user); err != nil {
+
+	if err := r.store.CreateUser(p.Context, user); err != nil {
 		return nil, err
 	}
 	return user, nil
@@ -241,49 +1088,203 @@ func (r *Resolver) createCardResolver(p graphql.ResolveParams) (interface{}, err
 	userID := p.Args["user_id"].(string)
 	cardNumber := p.Args["card_number"].(string)
 	cardType := p.Args["card_type"].(string)
-	
-	card := &models.Card{
-		ID:         uuid.New().String(),
-		UserID:     userID,
-		CardNumber: cardNumber,
-		CardType:   cardType,
-		IsActive:   true,
-		CreatedAt:  time.Now(),
-	}
-	
-	if err := r.db.CreateCard(card); err != nil {
-		return nil, err
-	}
-	return card, nil
+	currency := p.Args["currency"].(string)
+
+	card, err := r.ledgerService.RegisterCard(p.Context, userID, cardNumber, cardType, currency)
+	return card, validationError(err)
 }
 
 func (r *Resolver) processTransactionResolver(p graphql.ResolveParams) (interface{}, error) {
 	payload := models.CardPayload{
 		CardNumber:   p.Args["card_number"].(string),
 		Amount:       int64(p.Args["amount"].(int)),
+		Currency:     p.Args["currency"].(string),
 		MerchantName: p.Args["merchant_name"].(string),
-		Category:     "general", // default category
 		Timestamp:    time.Now().Format(time.RFC3339),
 	}
-	
+
+	if city, ok := p.Args["merchant_city"].(string); ok {
+		payload.MerchantCity = city
+	}
+
+	if country, ok := p.Args["merchant_country"].(string); ok {
+		payload.MerchantCountry = country
+	}
+
+	// The category is derived from the MCC and the user's category rules
 	if mcc, ok := p.Args["mcc"].(string); ok {
-		// Map MCC to category
-		payload.Category = mapMCCToCategory(mcc)
+		payload.MCC = mcc
 	}
-	
-	return r.ledgerService.ProcessCardPayload(payload)
-}
-
-func mapMCCToCategory(mcc string) string {
-	// Simple MCC to category mapping
-	switch mcc {
-	case "5411", "5412":
-		return "groceries"
-	case "5541", "5542":
-		return "gas"
-	case "5812", "5814":
-		return "dining"
-	default:
-		return "general"
+
+	key := p.Args["idempotency_key"].(string)
+	transaction, _, err := r.ledgerService.ProcessCardPayloadIdempotent(p.Context, key, payload)
+	return transaction, err
+}
+
+func (r *Resolver) refundTransactionResolver(p graphql.ResolveParams) (interface{}, error) {
+	transactionID := p.Args["transaction_id"].(string)
+	amount := int64(p.Args["amount"].(int))
+	reason := p.Args["reason"].(string)
+
+	if amount < 0 {
+		return nil, fmt.Errorf("amount cannot be negative")
 	}
-}
\ No newline at end of file
+
+	return r.ledgerService.Refund(p.Context, transactionID, amount, reason)
+}
+
+func (r *Resolver) freezeCardResolver(p graphql.ResolveParams) (interface{}, error) {
+	cardID := p.Args["card_id"].(string)
+	reason := p.Args["reason"].(string)
+	return r.ledgerService.FreezeCard(p.Context, cardID, reason)
+}
+
+func (r *Resolver) unfreezeCardResolver(p graphql.ResolveParams) (interface{}, error) {
+	cardID := p.Args["card_id"].(string)
+	reason := p.Args["reason"].(string)
+	return r.ledgerService.UnfreezeCard(p.Context, cardID, reason)
+}
+
+func (r *Resolver) closeCardResolver(p graphql.ResolveParams) (interface{}, error) {
+	cardID := p.Args["card_id"].(string)
+	reason := p.Args["reason"].(string)
+	return r.ledgerService.CloseCard(p.Context, cardID, reason)
+}
+
+func (r *Resolver) reportCardResolver(p graphql.ResolveParams) (interface{}, error) {
+	cardID := p.Args["card_id"].(string)
+	status := p.Args["status"].(string)
+	reason := p.Args["reason"].(string)
+	return r.ledgerService.ReportCard(p.Context, cardID, status, reason)
+}
+
+func (r *Resolver) replaceCardResolver(p graphql.ResolveParams) (interface{}, error) {
+	cardID := p.Args["card_id"].(string)
+	cardNumber := p.Args["card_number"].(string)
+	reason := p.Args["reason"].(string)
+	card, err := r.ledgerService.ReplaceCard(p.Context, cardID, cardNumber, reason)
+	return card, validationError(err)
+}
+
+// validationError unwraps card validation errors so their field and code reach the
+// client as GraphQL error extensions
+func validationError(err error) error {
+	var validation *cardnet.ValidationError
+	if errors.As(err, &validation) {
+		return validation
+	}
+	return err
+}
+
+func (r *Resolver) setCardLimitsResolver(p graphql.ResolveParams) (interface{}, error) {
+	cardID := p.Args["card_id"].(string)
+	return r.ledgerService.SetCardLimits(p.Context, cardID, limitsFromArgs(p.Args))
+}
+
+func (r *Resolver) setUserLimitsResolver(p graphql.ResolveParams) (interface{}, error) {
+	userID := p.Args["user_id"].(string)
+	return r.ledgerService.SetUserLimits(p.Context, userID, limitsFromArgs(p.Args))
+}
+
+func (r *Resolver) updateMerchantResolver(p graphql.ResolveParams) (interface{}, error) {
+	id := p.Args["id"].(string)
+	return r.ledgerService.UpdateMerchant(p.Context, id, &models.Merchant{
+		Name:    p.Args["name"].(string),
+		MCC:     p.Args["mcc"].(string),
+		City:    p.Args["city"].(string),
+		Country: p.Args["country"].(string),
+		LogoURL: p.Args["logo_url"].(string),
+	})
+}
+
+func (r *Resolver) createCategoryResolver(p graphql.ResolveParams) (interface{}, error) {
+	userID := p.Args["user_id"].(string)
+	return r.ledgerService.CreateCategory(p.Context, userID, &models.Category{
+		Name: p.Args["name"].(string),
+	})
+}
+
+func (r *Resolver) createCategoryRuleResolver(p graphql.ResolveParams) (interface{}, error) {
+	userID := p.Args["user_id"].(string)
+	return r.ledgerService.CreateCategoryRule(p.Context, userID, &models.CategoryRule{
+		Field:    p.Args["field"].(string),
+		Operator: p.Args["operator"].(string),
+		Value:    p.Args["value"].(string),
+		Category: p.Args["category"].(string),
+		Priority: p.Args["priority"].(int),
+	})
+}
+
+func (r *Resolver) applyCategoryRulesResolver(p graphql.ResolveParams) (interface{}, error) {
+	userID := p.Args["user_id"].(string)
+	return r.ledgerService.ApplyCategoryRules(p.Context, userID)
+}
+
+func (r *Resolver) recategorizeTransactionResolver(p graphql.ResolveParams) (interface{}, error) {
+	transactionID := p.Args["transaction_id"].(string)
+	category := p.Args["category"].(string)
+	return r.ledgerService.RecategorizeTransaction(p.Context, transactionID, category)
+}
+
+func (r *Resolver) createBudgetResolver(p graphql.ResolveParams) (interface{}, error) {
+	userID := p.Args["user_id"].(string)
+	return r.ledgerService.CreateBudget(p.Context, userID, &models.Budget{
+		Category: p.Args["category"].(string),
+		Period:   p.Args["period"].(string),
+		Amount:   int64(p.Args["amount"].(int)),
+		Currency: p.Args["currency"].(string),
+		Rollover: p.Args["rollover"].(bool),
+	})
+}
+
+// limitsFromArgs reads the arguments of the set limits mutations
+func limitsFromArgs(args map[string]interface{}) *models.SpendingLimits {
+	limits := &models.SpendingLimits{
+		Currency:          args["currency"].(string),
+		SingleTransaction: int64(args["single_transaction"].(int)),
+		Daily:             int64(args["daily"].(int)),
+		Weekly:            int64(args["weekly"].(int)),
+		Monthly:           int64(args["monthly"].(int)),
+	}
+
+	if categories, ok := args["blocked_categories"].([]interface{}); ok {
+		for _, c := range categories {
+			if category, ok := c.(string); ok {
+				limits.BlockedCategories = append(limits.BlockedCategories, category)
+			}
+		}
+	}
+
+	if caps, ok := args["category_caps"].([]interface{}); ok {
+		for _, c := range caps {
+			if fields, ok := c.(map[string]interface{}); ok {
+				limits.CategoryCaps = append(limits.CategoryCaps, models.CategoryCap{
+					Category: fields["category"].(string),
+					Amount:   int64(fields["amount"].(int)),
+				})
+			}
+		}
+	}
+
+	return limits
+}
+
+func (r *Resolver) claimReviewResolver(p graphql.ResolveParams) (interface{}, error) {
+	id := p.Args["id"].(string)
+	analyst := p.Args["analyst"].(string)
+	return r.ledgerService.ClaimReview(p.Context, id, analyst)
+}
+
+func (r *Resolver) approveReviewResolver(p graphql.ResolveParams) (interface{}, error) {
+	id := p.Args["id"].(string)
+	analyst := p.Args["analyst"].(string)
+	notes := p.Args["notes"].(string)
+	return r.ledgerService.ApproveReview(p.Context, id, analyst, notes)
+}
+
+func (r *Resolver) declineReviewResolver(p graphql.ResolveParams) (interface{}, error) {
+	id := p.Args["id"].(string)
+	analyst := p.Args["analyst"].(string)
+	notes := p.Args["notes"].(string)
+	return r.ledgerService.DeclineReview(p.Context, id, analyst, notes)
+}
@@ -0,0 +1,228 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/araesf/ledgertime/internal/config"
+	"github.com/araesf/ledgertime/internal/ledger"
+	"github.com/araesf/ledgertime/internal/memory"
+	"github.com/araesf/ledgertime/internal/vault"
+	"github.com/araesf/ledgertime/pkg/logger"
+	"github.com/graphql-go/graphql"
+)
+
+const testCardNumber = "4012888888881881"
+
+func newTestSchema(t *testing.T) graphql.Schema {
+	t.Helper()
+
+	cardVault, err := vault.New("test", make([]byte, 32))
+	if err != nil {
+		t.Fatalf("failed to create vault: %v", err)
+	}
+
+	cfg := config.LedgerConfig{
+		BaseCurrency:          "USD",
+		BINRangesFile:         "../../data/bin_ranges.json",
+		DefaultOverdraftLimit: 100000,
+		AuthorizationTTL:      time.Hour,
+		ReviewTimeout:         time.Hour,
+		CardValidity:          365 * 24 * time.Hour,
+	}
+
+	log := logger.NewLoggerWithLevel("error")
+	store := memory.NewStore()
+	ledgerService, err := ledger.NewService(cfg, store, &ledger.LimitProcessor{}, cardVault, log)
+	if err != nil {
+		t.Fatalf("failed to create service: %v", err)
+	}
+
+	schema, err := NewResolver(store, ledgerService, log).BuildSchema()
+	if err != nil {
+		t.Fatalf("failed to build schema: %v", err)
+	}
+	return schema
+}
+
+// run executes a request and decodes its data into out, failing on any error
+func run(t *testing.T, schema graphql.Schema, request string, variables map[string]interface{}, out interface{}) {
+	t.Helper()
+
+	result := runResult(schema, request, variables)
+	if result.HasErrors() {
+		t.Fatalf("request failed: %v", result.Errors)
+	}
+
+	data, err := json.Marshal(result.Data)
+	if err != nil {
+		t.Fatalf("failed to encode result: %v", err)
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		t.Fatalf("failed to decode result: %v", err)
+	}
+}
+
+func runResult(schema graphql.Schema, request string, variables map[string]interface{}) *graphql.Result {
+	return graphql.Do(graphql.Params{
+		Schema:         schema,
+		RequestString:  request,
+		VariableValues: variables,
+		Context:        context.Background(),
+	})
+}
+
+// newTestUser creates a user and returns the user's ID
+func newTestUser(t *testing.T, schema graphql.Schema) string {
+	t.Helper()
+
+	var created struct {
+		CreateUser struct {
+			ID string `json:"id"`
+		} `json:"createUser"`
+	}
+	run(t, schema, `mutation { createUser(name: "Test", email: "test@example.com") { id } }`, nil, &created)
+	return created.CreateUser.ID
+}
+
+type cardResult struct {
+	Token      string `json:"token"`
+	CardNumber string `json:"card_number"`
+	CardType   string `json:"card_type"`
+}
+
+// newTestCard registers the test card for a user
+func newTestCard(t *testing.T, schema graphql.Schema, userID string) cardResult {
+	t.Helper()
+
+	var created struct {
+		CreateCard cardResult `json:"createCard"`
+	}
+	run(t, schema, `mutation($user: String!, $number: String!) {
+		createCard(user_id: $user, card_number: $number) { token card_number card_type }
+	}`, map[string]interface{}{"user": userID, "number": testCardNumber}, &created)
+	return created.CreateCard
+}
+
+type transactionResult struct {
+	ID     string `json:"id"`
+	Amount int    `json:"amount"`
+	Status string `json:"status"`
+}
+
+// processTransaction sends a payment with the given idempotency key
+func processTransaction(schema graphql.Schema, amount int, key string) *graphql.Result {
+	return runResult(schema, `mutation($number: String!, $amount: Int!, $key: String) {
+		processTransaction(card_number: $number, amount: $amount, currency: "USD", merchant_name: "Bakery", idempotency_key: $key) { id amount status }
+	}`, map[string]interface{}{"number": testCardNumber, "amount": amount, "key": key})
+}
+
+func TestCardNumberIsMasked(t *testing.T) {
+	schema := newTestSchema(t)
+	card := newTestCard(t, schema, newTestUser(t, schema))
+	if card.Token == "" || card.CardNumber != "**** 1881" || card.CardType != "visa" {
+		t.Fatalf("created card = %+v, want a token and **** 1881 (visa)", card)
+	}
+
+	var query struct {
+		Card cardResult `json:"card"`
+	}
+	run(t, schema, `query($token: String!) { card(token: $token) { token card_number card_type } }`,
+		map[string]interface{}{"token": card.Token}, &query)
+	if query.Card != card {
+		t.Errorf("queried card = %+v, want %+v", query.Card, card)
+	}
+}
+
+func TestIdempotentMutation(t *testing.T) {
+	schema := newTestSchema(t)
+	newTestCard(t, schema, newTestUser(t, schema))
+
+	id := func(result *graphql.Result) string {
+		t.Helper()
+		if result.HasErrors() {
+			t.Fatalf("processTransaction failed: %v", result.Errors)
+		}
+		return result.Data.(map[string]interface{})["processTransaction"].(map[string]interface{})["id"].(string)
+	}
+
+	first := id(processTransaction(schema, 1200, "key-1"))
+	if retried := id(processTransaction(schema, 1200, "key-1")); retried != first {
+		t.Errorf("retried mutation returned transaction %s, want %s", retried, first)
+	}
+	if other := id(processTransaction(schema, 1200, "")); other == first {
+		t.Error("mutation without a key replayed the keyed one")
+	}
+	if result := processTransaction(schema, 9900, "key-1"); !result.HasErrors() {
+		t.Error("reusing the key for another payment succeeded")
+	}
+}
+
+func TestCardValidationExtensions(t *testing.T) {
+	schema := newTestSchema(t)
+
+	result := runResult(schema, `mutation($user: String!) { createCard(user_id: $user, card_number: "4012888888881882") { id } }`,
+		map[string]interface{}{"user": newTestUser(t, schema)})
+	if len(result.Errors) != 1 {
+		t.Fatalf("got %d errors, want 1", len(result.Errors))
+	}
+	extensions := result.Errors[0].Extensions
+	if extensions["field"] != "card_number" || extensions["code"] != "checksum_failed" {
+		t.Errorf("error extensions = %v, want card_number/checksum_failed", extensions)
+	}
+}
+
+func TestMissingUserIsAnError(t *testing.T) {
+	schema := newTestSchema(t)
+
+	result := runResult(schema, `{ user(id: "missing") { id } }`, nil)
+	if len(result.Errors) != 1 || !strings.Contains(result.Errors[0].Message, "not found") {
+		t.Errorf("errors = %v, want a not found error", result.Errors)
+	}
+}
+
+func TestInvalidLimitsAreRejected(t *testing.T) {
+	schema := newTestSchema(t)
+	userID := newTestUser(t, schema)
+
+	var set struct {
+		SetUserLimits struct {
+			Daily             int      `json:"daily"`
+			BlockedCategories []string `json:"blocked_categories"`
+		} `json:"setUserLimits"`
+	}
+	run(t, schema, `mutation($user: String!) {
+		setUserLimits(user_id: $user, daily: 5000, blocked_categories: [" Gambling "]) { daily blocked_categories }
+	}`, map[string]interface{}{"user": userID}, &set)
+	if set.SetUserLimits.Daily != 5000 || len(set.SetUserLimits.BlockedCategories) != 1 || set.SetUserLimits.BlockedCategories[0] != "gambling" {
+		t.Errorf("user limits = %+v, want a daily limit of 5000 blocking gambling", set.SetUserLimits)
+	}
+
+	for _, args := range []string{
+		`daily: -1`,
+		`single_transaction: -100`,
+		`category_caps: [{category: " ", amount: 1000}]`,
+		`category_caps: [{category: "dining", amount: -1}]`,
+	} {
+		result := runResult(schema, `mutation($user: String!) { setUserLimits(user_id: $user, `+args+`) { daily } }`,
+			map[string]interface{}{"user": userID})
+		if !result.HasErrors() {
+			t.Errorf("setUserLimits(%s) succeeded, want an error", args)
+		}
+	}
+
+	// Rejected mutations leave the stored limits alone
+	var query struct {
+		UserLimits struct {
+			Daily int `json:"daily"`
+		} `json:"userLimits"`
+	}
+	run(t, schema, `query($user: String!) { userLimits(user_id: $user) { daily } }`,
+		map[string]interface{}{"user": userID}, &query)
+	if query.UserLimits.Daily != 5000 {
+		t.Errorf("user daily limit = %d after rejected mutations, want 5000", query.UserLimits.Daily)
+	}
+}
@@ -55,7 +55,7 @@ func (c *Consumer) Start(ctx context.Context) error {
 				continue
 			}
 
-			if err := c.processMessage(message); err != nil {
+			if err := c.processMessage(ctx, message); err != nil {
 				c.logger.Error("Failed to process message", "error", err, "offset", message.Offset)
 			}
 		}
@@ -63,7 +63,7 @@ func (c *Consumer) Start(ctx context.Context) error {
 }
 
 // processMessage processes a single Kafka message
-func (c *Consumer) processMessage(message kafka.Message) error {
+func (c *Consumer) processMessage(ctx context.Context, message kafka.Message) error {
 	c.logger.Info("Processing message", "offset", message.Offset, "partition", message.Partition)
 
 	// Parse the card payload
@@ -72,14 +72,20 @@ func (c *Consumer) processMessage(message kafka.Message) error {
 		return fmt.Errorf("failed to unmarshal message: %w", err)
 	}
 
-	// Process the transaction
-	transaction, err := c.ledgerService.ProcessCardPayload(payload)
+	// Process the transaction at most once per message
+	key := idempotencyKey(message, payload)
+	transaction, replayed, err := c.ledgerService.ProcessKafkaPayloadIdempotent(ctx, key, payload)
 	if err != nil {
 		return fmt.Errorf("failed to process card payload: %w", err)
 	}
 
-	c.logger.Info("Transaction processed successfully", 
-		"transaction_id", transaction.ID, 
+	if replayed {
+		c.logger.Info("Skipped redelivered message", "transaction_id", transaction.ID, "idempotency_key", key)
+		return nil
+	}
+
+	c.logger.Info("Transaction processed successfully",
+		"transaction_id", transaction.ID,
 		"user_id", transaction.UserID,
 		"amount", transaction.Amount,
 	)
@@ -87,6 +93,15 @@ func (c *Consumer) processMessage(message kafka.Message) error {
 	return nil
 }
 
+// idempotencyKey derives a stable key for a message. The network reference identifies the
+// payment across re-publishes; otherwise the message's position in the log is used.
+func idempotencyKey(message kafka.Message, payload models.CardPayload) string {
+	if payload.NetworkReference != "" {
+		return "network:" + payload.NetworkReference
+	}
+	return fmt.Sprintf("kafka:%s:%d:%d", message.Topic, message.Partition, message.Offset)
+}
+
 // Close closes the Kafka consumer
 func (c *Consumer) Close() error {
 	c.logger.Info("Closing Kafka consumer")
@@ -95,12 +110,14 @@ func (c *Consumer) Close() error {
 
 // Producer handles Kafka message production
 type Producer struct {
-	writer *kafka.Writer
-	logger *logger.Logger
+	writer        *kafka.Writer
+	ledgerService *ledger.Service
+	logger        *logger.Logger
 }
 
-// NewProducer creates a new Kafka producer
-func NewProducer(cfg config.KafkaConfig, log *logger.Logger) *Producer {
+// NewProducer creates a new Kafka producer. Card numbers are tokenized through the ledger
+// service before publishing.
+func NewProducer(cfg config.KafkaConfig, ledgerService *ledger.Service, log *logger.Logger) *Producer {
 	writer := &kafka.Writer{
 		Addr:         kafka.TCP(cfg.Brokers...),
 		Topic:        cfg.TransactionsTopic,
@@ -110,20 +127,31 @@ func NewProducer(cfg config.KafkaConfig, log *logger.Logger) *Producer {
 	}
 
 	return &Producer{
-		writer: writer,
-		logger: log,
+		writer:        writer,
+		ledgerService: ledgerService,
+		logger:        log,
 	}
 }
 
-// PublishCardPayload publishes a card payload to Kafka
+// PublishCardPayload publishes a card payload to Kafka, keyed by card token. The card
+// number is replaced by its token so it never reaches the topic.
 func (p *Producer) PublishCardPayload(ctx context.Context, payload models.CardPayload) error {
+	if payload.CardToken == "" {
+		token, err := p.ledgerService.CardToken(ctx, payload.CardNumber)
+		if err != nil {
+			return fmt.Errorf("failed to tokenize card: %w", err)
+		}
+		payload.CardToken = token
+	}
+	payload.CardNumber = ""
+
 	data, err := json.Marshal(payload)
 	if err != nil {
 		return fmt.Errorf("failed to marshal payload: %w", err)
 	}
 
 	message := kafka.Message{
-		Key:   []byte(payload.CardNumber),
+		Key:   []byte(payload.CardToken),
 		Value: data,
 		Time:  time.Now(),
 	}
@@ -133,7 +161,7 @@ func (p *Producer) PublishCardPayload(ctx context.Context, payload models.CardPa
 		return fmt.Errorf("failed to publish message: %w", err)
 	}
 
-	p.logger.Info("Message published", "card_number", payload.CardNumber, "amount", payload.Amount)
+	p.logger.Info("Message published", "card_token", payload.CardToken, "amount", payload.Amount)
 	return nil
 }
 
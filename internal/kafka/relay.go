@@ -0,0 +1,256 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/araesf/ledgertime/internal/config"
+	"github.com/araesf/ledgertime/internal/ledger"
+	"github.com/araesf/ledgertime/internal/models"
+	"github.com/araesf/ledgertime/pkg/logger"
+	"github.com/segmentio/kafka-go"
+)
+
+// Backoff between attempts to publish an event that failed
+const (
+	minRelayBackoff = time.Second
+	maxRelayBackoff = 5 * time.Minute
+)
+
+// Event is the message the relay publishes for an outbox event
+type Event struct {
+	ID            string          `json:"id"`
+	Type          string          `json:"type"`
+	AggregateType string          `json:"aggregate_type"`
+	AggregateID   string          `json:"aggregate_id"`
+	Sequence      int64           `json:"sequence"`
+	OccurredAt    time.Time       `json:"occurred_at"`
+	Data          json.RawMessage `json:"data"`
+}
+
+// messageWriter is the part of a *kafka.Writer the relay uses
+type messageWriter interface {
+	WriteMessages(ctx context.Context, messages ...kafka.Message) error
+	Close() error
+}
+
+// Relay publishes the ledger's outbox to Kafka. Each event goes to the topic of its aggregate,
+// keyed by aggregate ID, so the events of one aggregate share a partition and keep their order.
+// Delivery is at least once: consumers should skip event IDs they have already seen.
+type Relay struct {
+	writer    messageWriter
+	store     ledger.Store
+	topics    map[string]string
+	interval  time.Duration
+	batchSize int
+	logger    *logger.Logger
+}
+
+// NewRelay creates an outbox relay
+func NewRelay(cfg config.KafkaConfig, store ledger.Store, log *logger.Logger) *Relay {
+	writer := &kafka.Writer{
+		Addr:         kafka.TCP(cfg.Brokers...),
+		Balancer:     &kafka.Hash{},
+		RequiredAcks: kafka.RequireAll,
+		BatchSize:    cfg.BatchSize,
+		BatchTimeout: 10 * time.Millisecond,
+	}
+
+	return &Relay{
+		writer: writer,
+		store:  store,
+		topics: map[string]string{
+			models.AggregateTransaction: cfg.TransactionEventsTopic,
+			models.AggregateCard:        cfg.CardEventsTopic,
+		},
+		interval:  cfg.OutboxPollInterval,
+		batchSize: cfg.BatchSize,
+		logger:    log,
+	}
+}
+
+// Run publishes pending events periodically until ctx is cancelled
+func (r *Relay) Run(ctx context.Context) {
+	r.logger.Info("Starting outbox relay", "interval", r.interval)
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			r.logger.Info("Outbox relay stopped")
+			return
+		case <-ticker.C:
+			published, err := r.Publish(ctx)
+			if err != nil {
+				r.logger.Error("Failed to publish outbox", "error", err)
+			}
+			if published > 0 {
+				r.logger.Info("Published outbox events", "count", published)
+			}
+		}
+	}
+}
+
+// Publish sends one batch of pending events and returns how many were published. It does nothing
+// while another relay holds the outbox. Events go out in rounds of at most one per aggregate, so
+// an event that fails holds back the later events of its aggregate until it is retried.
+func (r *Relay) Publish(ctx context.Context) (int, error) {
+	published := 0
+	err := r.store.RunUnitOfWork(ctx, func(ctx context.Context) error {
+		published = 0
+
+		locked, err := r.store.LockOutbox(ctx)
+		if err != nil || !locked {
+			return err
+		}
+
+		events, err := r.store.GetPendingOutboxEvents(ctx, time.Now(), r.batchSize)
+		if err != nil {
+			return err
+		}
+
+		queues := aggregateQueues(events)
+		for len(queues) > 0 {
+			round := make([]*models.OutboxEvent, len(queues))
+			for i, queue := range queues {
+				round[i] = queue[0]
+			}
+
+			failures, err := r.write(ctx, round)
+			if err != nil {
+				return err
+			}
+
+			var remaining [][]*models.OutboxEvent
+			for i, event := range round {
+				if failures[i] != nil {
+					r.logger.Error("Failed to publish event", "error", failures[i], "event_id", event.ID, "attempts", event.Attempts+1)
+					retryAt := time.Now().Add(relayBackoff(event.Attempts))
+					if err := r.store.MarkOutboxEventFailed(ctx, event.ID, failures[i].Error(), retryAt); err != nil {
+						return err
+					}
+					continue
+				}
+
+				if err := r.store.MarkOutboxEventSent(ctx, event.ID, time.Now()); err != nil {
+					return err
+				}
+				published++
+				if len(queues[i]) > 1 {
+					remaining = append(remaining, queues[i][1:])
+				}
+			}
+			queues = remaining
+		}
+		return nil
+	})
+	return published, err
+}
+
+// write publishes a round of events, returning the error of each event that failed. An error
+// is only returned when the round could not be attempted at all.
+func (r *Relay) write(ctx context.Context, events []*models.OutboxEvent) ([]error, error) {
+	failures := make([]error, len(events))
+	messages := make([]kafka.Message, 0, len(events))
+	sent := make([]int, 0, len(events)) // Index in events of each message
+
+	for i, event := range events {
+		message, err := r.message(event)
+		if err != nil {
+			failures[i] = err
+			continue
+		}
+		messages = append(messages, message)
+		sent = append(sent, i)
+	}
+
+	err := r.writer.WriteMessages(ctx, messages...)
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	var writeErrors kafka.WriteErrors
+	switch {
+	case errors.As(err, &writeErrors):
+		for j, err := range writeErrors {
+			failures[sent[j]] = err
+		}
+	case err != nil:
+		for _, i := range sent {
+			failures[i] = err
+		}
+	}
+	return failures, nil
+}
+
+// message builds the Kafka message of an event
+func (r *Relay) message(event *models.OutboxEvent) (kafka.Message, error) {
+	topic, ok := r.topics[event.AggregateType]
+	if !ok || topic == "" {
+		return kafka.Message{}, fmt.Errorf("no topic for aggregate type: %s", event.AggregateType)
+	}
+
+	data, err := json.Marshal(Event{
+		ID:            event.ID,
+		Type:          event.EventType,
+		AggregateType: event.AggregateType,
+		AggregateID:   event.AggregateID,
+		Sequence:      event.Sequence,
+		OccurredAt:    event.CreatedAt,
+		Data:          event.Payload,
+	})
+	if err != nil {
+		return kafka.Message{}, fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	return kafka.Message{
+		Topic:   topic,
+		Key:     []byte(event.AggregateID),
+		Value:   data,
+		Headers: []kafka.Header{{Key: "event_type", Value: []byte(event.EventType)}},
+		Time:    event.CreatedAt,
+	}, nil
+}
+
+// aggregateQueues splits events, which are in sequence order, into one queue per aggregate,
+// ordered by each aggregate's first event
+func aggregateQueues(events []*models.OutboxEvent) [][]*models.OutboxEvent {
+	type aggregateKey struct{ aggregateType, aggregateID string }
+	index := make(map[aggregateKey]int)
+
+	var queues [][]*models.OutboxEvent
+	for _, event := range events {
+		k := aggregateKey{event.AggregateType, event.AggregateID}
+		i, ok := index[k]
+		if !ok {
+			i = len(queues)
+			index[k] = i
+			queues = append(queues, nil)
+		}
+		queues[i] = append(queues[i], event)
+	}
+	return queues
+}
+
+// relayBackoff doubles the wait after every failed attempt, up to a maximum
+func relayBackoff(attempts int) time.Duration {
+	backoff := minRelayBackoff
+	for i := 0; i < attempts && backoff < maxRelayBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > maxRelayBackoff {
+		backoff = maxRelayBackoff
+	}
+	return backoff
+}
+
+// Close closes the relay's Kafka writer
+func (r *Relay) Close() error {
+	r.logger.Info("Closing outbox relay")
+	return r.writer.Close()
+}
@@ -0,0 +1,270 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/araesf/ledgertime/internal/ledger"
+	"github.com/araesf/ledgertime/internal/memory"
+	"github.com/araesf/ledgertime/internal/models"
+	"github.com/araesf/ledgertime/pkg/logger"
+	"github.com/segmentio/kafka-go"
+)
+
+// fakeWriter records the events it is asked to publish, failing the ones listed in fail
+type fakeWriter struct {
+	t      *testing.T
+	store  ledger.Store
+	fail   map[string]error // Event ID -> error
+	down   error            // Fails every write when set
+	rounds [][]Event
+}
+
+func (w *fakeWriter) WriteMessages(ctx context.Context, messages ...kafka.Message) error {
+	// Nothing in a round may be marked sent before the round is written
+	pending, err := w.store.GetPendingOutboxEvents(ctx, time.Now().Add(time.Hour), -1)
+	if err != nil {
+		w.t.Fatalf("failed to get pending events: %v", err)
+	}
+	unsent := make(map[string]bool)
+	for _, event := range pending {
+		unsent[event.ID] = true
+	}
+
+	var round []Event
+	errs := make(kafka.WriteErrors, len(messages))
+	failed := false
+	for i, message := range messages {
+		var event Event
+		if err := json.Unmarshal(message.Value, &event); err != nil {
+			w.t.Fatalf("failed to decode message: %v", err)
+		}
+		if string(message.Key) != event.AggregateID {
+			w.t.Errorf("event %s keyed %q, want its aggregate ID %s", event.ID, message.Key, event.AggregateID)
+		}
+		if !unsent[event.ID] {
+			w.t.Errorf("event %s was marked sent before it was written", event.ID)
+		}
+		round = append(round, event)
+
+		if err := w.fail[event.ID]; err != nil {
+			errs[i] = err
+			failed = true
+		}
+	}
+	w.rounds = append(w.rounds, round)
+
+	if w.down != nil {
+		return w.down
+	}
+	if failed {
+		return errs
+	}
+	return nil
+}
+
+func (w *fakeWriter) Close() error { return nil }
+
+// newTestRelay creates a relay over a memory store that publishes through a fake writer
+func newTestRelay(t *testing.T) (*Relay, *fakeWriter, ledger.Store) {
+	t.Helper()
+	store := memory.NewStore()
+	writer := &fakeWriter{t: t, store: store, fail: make(map[string]error)}
+	relay := &Relay{
+		writer: writer,
+		store:  store,
+		topics: map[string]string{
+			models.AggregateTransaction: "transaction-events",
+			models.AggregateCard:        "card-events",
+		},
+		batchSize: 100,
+		logger:    logger.NewLoggerWithLevel("error"),
+	}
+	return relay, writer, store
+}
+
+// addEvents saves n events of one transaction, named <aggregateID>-1 to <aggregateID>-n
+func addEvents(t *testing.T, store ledger.Store, aggregateID string, n int) {
+	t.Helper()
+	for i := 1; i <= n; i++ {
+		event := &models.OutboxEvent{
+			ID:            fmt.Sprintf("%s-%d", aggregateID, i),
+			AggregateType: models.AggregateTransaction,
+			AggregateID:   aggregateID,
+			EventType:     "transaction.updated",
+			Payload:       json.RawMessage(`{}`),
+			NextAttemptAt: time.Now().Add(-time.Second),
+			CreatedAt:     time.Now(),
+		}
+		if err := store.CreateOutboxEvent(context.Background(), event); err != nil {
+			t.Fatalf("failed to create outbox event: %v", err)
+		}
+	}
+}
+
+// pendingEvents returns the events still unsent, including those waiting for a retry
+func pendingEvents(t *testing.T, store ledger.Store) []*models.OutboxEvent {
+	t.Helper()
+	events, err := store.GetPendingOutboxEvents(context.Background(), time.Now().Add(time.Hour), -1)
+	if err != nil {
+		t.Fatalf("failed to get pending events: %v", err)
+	}
+	return events
+}
+
+func TestRelayPublishesInOrderPerAggregate(t *testing.T) {
+	relay, writer, store := newTestRelay(t)
+	addEvents(t, store, "a", 3)
+	addEvents(t, store, "b", 2)
+
+	published, err := relay.Publish(context.Background())
+	if err != nil {
+		t.Fatalf("Publish() = %v", err)
+	}
+	if published != 5 {
+		t.Errorf("published %d events, want 5", published)
+	}
+
+	// One event per aggregate per round, each aggregate's events in sequence order
+	want := [][]string{{"a-1", "b-1"}, {"a-2", "b-2"}, {"a-3"}}
+	if len(writer.rounds) != len(want) {
+		t.Fatalf("got %d rounds, want %d", len(writer.rounds), len(want))
+	}
+	var last int64
+	for i, round := range writer.rounds {
+		if len(round) != len(want[i]) {
+			t.Fatalf("round %d has %d events, want %v", i, len(round), want[i])
+		}
+		for j, event := range round {
+			if event.ID != want[i][j] {
+				t.Errorf("round %d event %d = %s, want %s", i, j, event.ID, want[i][j])
+			}
+			if event.AggregateID == "a" {
+				if event.Sequence <= last {
+					t.Errorf("event %s has sequence %d after %d", event.ID, event.Sequence, last)
+				}
+				last = event.Sequence
+			}
+		}
+	}
+
+	if pending := pendingEvents(t, store); len(pending) != 0 {
+		t.Errorf("%d events still pending after publishing", len(pending))
+	}
+
+	// Nothing is published twice
+	if published, err := relay.Publish(context.Background()); err != nil || published != 0 {
+		t.Errorf("second Publish() = %d, %v, want 0", published, err)
+	}
+}
+
+func TestRelayRetriesFailedEvents(t *testing.T) {
+	relay, writer, store := newTestRelay(t)
+	addEvents(t, store, "a", 2)
+	addEvents(t, store, "b", 2)
+	writer.fail["a-1"] = errors.New("leader not available")
+
+	before := time.Now()
+	published, err := relay.Publish(context.Background())
+	if err != nil {
+		t.Fatalf("Publish() = %v", err)
+	}
+	if published != 2 {
+		t.Errorf("published %d events, want b's 2", published)
+	}
+
+	// a-1 waits for its backoff, holding back a-2
+	pending := pendingEvents(t, store)
+	if len(pending) != 2 || pending[0].ID != "a-1" || pending[1].ID != "a-2" {
+		t.Fatalf("pending = %v, want a-1 and a-2", eventIDs(pending))
+	}
+	failed := pending[0]
+	if failed.Attempts != 1 || failed.LastError != "leader not available" {
+		t.Errorf("a-1 has %d attempts and error %q, want 1 and the write error", failed.Attempts, failed.LastError)
+	}
+	if failed.NextAttemptAt.Before(before.Add(minRelayBackoff)) || failed.NextAttemptAt.After(time.Now().Add(minRelayBackoff)) {
+		t.Errorf("a-1 retries at %v, want %s after the attempt", failed.NextAttemptAt, minRelayBackoff)
+	}
+	if pending[1].Attempts != 0 {
+		t.Errorf("a-2 has %d attempts, want 0 since it was never tried", pending[1].Attempts)
+	}
+
+	rounds := len(writer.rounds)
+	if published, err := relay.Publish(context.Background()); err != nil || published != 0 {
+		t.Errorf("Publish() during the backoff = %d, %v, want 0", published, err)
+	}
+	if len(writer.rounds) != rounds {
+		t.Error("the relay wrote events that are waiting for their backoff")
+	}
+
+	// Once the backoff is over, a-1 and then a-2 go out
+	delete(writer.fail, "a-1")
+	if err := store.MarkOutboxEventFailed(context.Background(), "a-1", "leader not available", time.Now().Add(-time.Second)); err != nil {
+		t.Fatalf("failed to expire backoff: %v", err)
+	}
+	published, err = relay.Publish(context.Background())
+	if err != nil || published != 2 {
+		t.Fatalf("Publish() after the backoff = %d, %v, want 2", published, err)
+	}
+	retried := writer.rounds[rounds:]
+	if len(retried) != 2 || retried[0][0].ID != "a-1" || retried[1][0].ID != "a-2" {
+		t.Errorf("retried rounds = %v, want a-1 then a-2", retried)
+	}
+	if pending := pendingEvents(t, store); len(pending) != 0 {
+		t.Errorf("pending = %v, want none", eventIDs(pending))
+	}
+}
+
+func TestRelayBrokerDown(t *testing.T) {
+	relay, writer, store := newTestRelay(t)
+	addEvents(t, store, "a", 1)
+	addEvents(t, store, "b", 1)
+	writer.down = errors.New("connection refused")
+
+	published, err := relay.Publish(context.Background())
+	if err != nil || published != 0 {
+		t.Fatalf("Publish() = %d, %v, want 0 published", published, err)
+	}
+
+	pending := pendingEvents(t, store)
+	if len(pending) != 2 {
+		t.Fatalf("pending = %v, want both events", eventIDs(pending))
+	}
+	for _, event := range pending {
+		if event.Attempts != 1 || event.LastError != "connection refused" {
+			t.Errorf("%s has %d attempts and error %q, want 1 and the write error", event.ID, event.Attempts, event.LastError)
+		}
+	}
+}
+
+func TestRelayBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{0, time.Second},
+		{1, 2 * time.Second},
+		{2, 4 * time.Second},
+		{8, 256 * time.Second},
+		{9, maxRelayBackoff},
+		{100, maxRelayBackoff},
+	}
+
+	for _, tt := range tests {
+		if got := relayBackoff(tt.attempts); got != tt.want {
+			t.Errorf("relayBackoff(%d) = %s, want %s", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func eventIDs(events []*models.OutboxEvent) []string {
+	ids := make([]string, len(events))
+	for i, event := range events {
+		ids[i] = event.ID
+	}
+	return ids
+}
@@ -0,0 +1,192 @@
+package ledger
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/araesf/ledgertime/internal/models"
+)
+
+// maxSpendingBuckets caps how many intervals one spending query may span
+const maxSpendingBuckets = 400
+
+// defaultSpendingBuckets is how many intervals a spending query spans when it has no start
+var defaultSpendingBuckets = map[string]int{
+	models.SpendingIntervalDay:   30,
+	models.SpendingIntervalWeek:  12,
+	models.SpendingIntervalMonth: 12,
+}
+
+// GetSpending returns a user's net spend per interval, optionally per category, merchant or
+// card, with each bucket compared to the same group's previous interval
+func (s *Service) GetSpending(ctx context.Context, query *models.SpendingQuery) (*models.SpendingSeries, error) {
+	s.logger.Info("Getting spending", "user_id", query.UserID, "interval", query.Interval, "group_by", query.GroupBy)
+
+	if err := ValidateSpendingQuery(query); err != nil {
+		return nil, err
+	}
+	if _, err := s.store.GetUser(ctx, query.UserID); err != nil {
+		return nil, err
+	}
+
+	currency, err := s.walletCurrency(query.Currency)
+	if err != nil {
+		return nil, err
+	}
+	query.Currency = currency
+
+	// Start one interval early so the first bucket has something to compare with
+	since := previousBucket(query.From, query.Interval)
+	rows, err := s.store.GetSpending(ctx, query.UserID, query.Currency, query.Interval, query.GroupBy, since, query.To)
+	if err != nil {
+		s.logger.Error("Failed to get spending", "error", err, "user_id", query.UserID)
+		return nil, fmt.Errorf("failed to get spending: %w", err)
+	}
+
+	return spendingSeries(query, rows), nil
+}
+
+// spendingSeries arranges spending rows into buckets ordered by interval and group, filling
+// the intervals a group spent nothing in with zeros
+func spendingSeries(query *models.SpendingQuery, rows []models.SpendingRow) *models.SpendingSeries {
+	spending := make(map[string]map[time.Time]models.SpendingRow)
+	inRange := map[string]bool{}
+	for _, row := range rows {
+		if spending[row.Group] == nil {
+			spending[row.Group] = make(map[time.Time]models.SpendingRow)
+		}
+		spending[row.Group][row.PeriodStart] = row
+		// Groups that only spent in the interval before the range are left out
+		if !row.PeriodStart.Before(query.From) {
+			inRange[row.Group] = true
+		}
+	}
+
+	groups := []string{}
+	if query.GroupBy == "" {
+		groups = append(groups, "")
+	} else {
+		for group := range inRange {
+			groups = append(groups, group)
+		}
+		sort.Strings(groups)
+	}
+
+	series := &models.SpendingSeries{
+		UserID:   query.UserID,
+		Interval: query.Interval,
+		GroupBy:  query.GroupBy,
+		Currency: query.Currency,
+		From:     query.From,
+		To:       query.To,
+		Buckets:  []*models.SpendingBucket{},
+	}
+	for p := query.From; p.Before(query.To); p = nextBucket(p, query.Interval) {
+		previous := previousBucket(p, query.Interval)
+		for _, group := range groups {
+			current, prior := spending[group][p], spending[group][previous]
+			bucket := &models.SpendingBucket{
+				PeriodStart: p,
+				Group:       group,
+				Amount:      current.Amount,
+				Count:       current.Count,
+				Previous:    prior.Amount,
+				Change:      current.Amount - prior.Amount,
+			}
+			if prior.Amount > 0 {
+				percent := math.Round(float64(bucket.Change)*10000/float64(prior.Amount)) / 100
+				bucket.ChangePercent = &percent
+			}
+			series.Total += current.Amount
+			series.Buckets = append(series.Buckets, bucket)
+		}
+	}
+
+	return series
+}
+
+// ValidateSpendingQuery checks a spending query and fills in its defaults: monthly intervals
+// ending with the current one. The range is widened to whole intervals.
+func ValidateSpendingQuery(query *models.SpendingQuery) error {
+	if query.Interval == "" {
+		query.Interval = models.SpendingIntervalMonth
+	}
+	defaultBuckets, ok := defaultSpendingBuckets[query.Interval]
+	if !ok {
+		return fmt.Errorf("interval must be day, week or month, got: %q", query.Interval)
+	}
+
+	switch query.GroupBy {
+	case "", models.SpendingGroupCategory, models.SpendingGroupMerchant, models.SpendingGroupCard:
+	default:
+		return fmt.Errorf("group_by must be category, merchant or card, got: %q", query.GroupBy)
+	}
+
+	if query.To.IsZero() {
+		query.To = time.Now()
+	}
+	end := bucketStart(query.To, query.Interval)
+	if end.Before(query.To) {
+		end = nextBucket(end, query.Interval)
+	}
+	query.To = end
+
+	if query.From.IsZero() {
+		query.From = query.To
+		for i := 0; i < defaultBuckets; i++ {
+			query.From = previousBucket(query.From, query.Interval)
+		}
+	}
+	query.From = bucketStart(query.From, query.Interval)
+
+	if !query.From.Before(query.To) {
+		return fmt.Errorf("from must be before to")
+	}
+
+	buckets := 0
+	for p := query.From; p.Before(query.To); p = nextBucket(p, query.Interval) {
+		if buckets++; buckets > maxSpendingBuckets {
+			return fmt.Errorf("date range spans more than %d %s intervals", maxSpendingBuckets, query.Interval)
+		}
+	}
+
+	return nil
+}
+
+// bucketStart returns the UTC start of the interval containing t. Weeks start on Monday.
+func bucketStart(t time.Time, interval string) time.Time {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	switch interval {
+	case models.SpendingIntervalWeek:
+		return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+	case models.SpendingIntervalMonth:
+		return day.AddDate(0, 0, 1-day.Day())
+	}
+	return day
+}
+
+// nextBucket returns the start of the interval after the one starting at start
+func nextBucket(start time.Time, interval string) time.Time {
+	switch interval {
+	case models.SpendingIntervalWeek:
+		return start.AddDate(0, 0, 7)
+	case models.SpendingIntervalMonth:
+		return start.AddDate(0, 1, 0)
+	}
+	return start.AddDate(0, 0, 1)
+}
+
+// previousBucket returns the start of the interval before the one starting at start
+func previousBucket(start time.Time, interval string) time.Time {
+	switch interval {
+	case models.SpendingIntervalWeek:
+		return start.AddDate(0, 0, -7)
+	case models.SpendingIntervalMonth:
+		return start.AddDate(0, -1, 0)
+	}
+	return start.AddDate(0, 0, -1)
+}
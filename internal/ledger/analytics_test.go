@@ -0,0 +1,154 @@
+package ledger_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/araesf/ledgertime/internal/ledger"
+	"github.com/araesf/ledgertime/internal/models"
+)
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// bucketString formats a bucket for comparison, with "-" for a nil change percent
+func bucketString(b *models.SpendingBucket) string {
+	percent := "-"
+	if b.ChangePercent != nil {
+		percent = fmt.Sprintf("%.2f", *b.ChangePercent)
+	}
+	return fmt.Sprintf("%s %q %d (%d) prev %d change %d %s",
+		b.PeriodStart.Format("2006-01-02"), b.Group, b.Amount, b.Count, b.Previous, b.Change, percent)
+}
+
+func checkBuckets(t *testing.T, series *models.SpendingSeries, want []string) {
+	t.Helper()
+	if len(series.Buckets) != len(want) {
+		var got []string
+		for _, b := range series.Buckets {
+			got = append(got, bucketString(b))
+		}
+		t.Fatalf("got buckets %q, want %q", got, want)
+	}
+	for i, b := range series.Buckets {
+		if got := bucketString(b); got != want[i] {
+			t.Errorf("bucket %d = %s, want %s", i, got, want[i])
+		}
+	}
+}
+
+func TestSpendingPeriodOverPeriod(t *testing.T) {
+	service, store := newTestService(t)
+	card := newTestCard(t, service, store)
+	ctx := context.Background()
+
+	spend(t, service, card, 10000, "groceries", date(2024, 1, 10))
+	spend(t, service, card, 2000, "dining", date(2024, 1, 31).Add(23*time.Hour))
+	spend(t, service, card, 9000, "groceries", date(2024, 2, 1))
+	spend(t, service, card, 6000, "groceries", date(2024, 2, 29))
+	spend(t, service, card, 8000, "travel", date(2024, 3, 15))
+	spend(t, service, card, 5000, "groceries", date(2024, 4, 30).Add(23*time.Hour))
+	spend(t, service, card, 7000, "groceries", date(2024, 5, 1))
+
+	series, err := service.GetSpending(ctx, &models.SpendingQuery{
+		UserID:   card.UserID,
+		Interval: models.SpendingIntervalMonth,
+		From:     date(2024, 2, 1),
+		To:       date(2024, 5, 1),
+	})
+	if err != nil {
+		t.Fatalf("GetSpending() = %v", err)
+	}
+	if series.Currency != "USD" || series.Total != 28000 {
+		t.Errorf("got %d %s in total, want 28000 USD", series.Total, series.Currency)
+	}
+	checkBuckets(t, series, []string{
+		`2024-02-01 "" 15000 (2) prev 12000 change 3000 25.00`,
+		`2024-03-01 "" 8000 (1) prev 15000 change -7000 -46.67`,
+		`2024-04-01 "" 5000 (1) prev 8000 change -3000 -37.50`,
+	})
+
+	// Groups are compared with their own previous interval. Dining only spent before the range,
+	// so it is left out.
+	series, err = service.GetSpending(ctx, &models.SpendingQuery{
+		UserID:   card.UserID,
+		Interval: models.SpendingIntervalMonth,
+		GroupBy:  models.SpendingGroupCategory,
+		From:     date(2024, 2, 1),
+		To:       date(2024, 5, 1),
+	})
+	if err != nil {
+		t.Fatalf("GetSpending() = %v", err)
+	}
+	checkBuckets(t, series, []string{
+		`2024-02-01 "groceries" 15000 (2) prev 10000 change 5000 50.00`,
+		`2024-02-01 "travel" 0 (0) prev 0 change 0 -`,
+		`2024-03-01 "groceries" 0 (0) prev 15000 change -15000 -100.00`,
+		`2024-03-01 "travel" 8000 (1) prev 0 change 8000 -`,
+		`2024-04-01 "groceries" 5000 (1) prev 0 change 5000 -`,
+		`2024-04-01 "travel" 0 (0) prev 8000 change -8000 -100.00`,
+	})
+
+	// Weeks start on Monday: 2024-02-26 to 2024-03-04 holds the 29th
+	series, err = service.GetSpending(ctx, &models.SpendingQuery{
+		UserID:   card.UserID,
+		Interval: models.SpendingIntervalWeek,
+		GroupBy:  models.SpendingGroupCategory,
+		From:     date(2024, 2, 28),
+		To:       date(2024, 3, 5),
+	})
+	if err != nil {
+		t.Fatalf("GetSpending() = %v", err)
+	}
+	checkBuckets(t, series, []string{
+		`2024-02-26 "groceries" 6000 (1) prev 0 change 6000 -`,
+		`2024-03-04 "groceries" 0 (0) prev 6000 change -6000 -100.00`,
+	})
+}
+
+func TestValidateSpendingQuery(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    models.SpendingQuery
+		interval string
+		from     time.Time
+		to       time.Time
+		wantErr  bool
+	}{
+		{"widened to whole months", models.SpendingQuery{From: date(2024, 2, 10), To: date(2024, 4, 2)},
+			models.SpendingIntervalMonth, date(2024, 2, 1), date(2024, 5, 1), false},
+		{"twelve months by default", models.SpendingQuery{To: date(2024, 4, 1)},
+			models.SpendingIntervalMonth, date(2023, 4, 1), date(2024, 4, 1), false},
+		{"thirty days by default", models.SpendingQuery{Interval: models.SpendingIntervalDay, To: date(2024, 3, 1).Add(time.Hour)},
+			models.SpendingIntervalDay, date(2024, 2, 1), date(2024, 3, 2), false},
+		{"weeks from Monday", models.SpendingQuery{Interval: models.SpendingIntervalWeek, From: date(2024, 3, 3), To: date(2024, 3, 6)},
+			models.SpendingIntervalWeek, date(2024, 2, 26), date(2024, 3, 11), false},
+		{"unknown interval", models.SpendingQuery{Interval: "year"}, "", time.Time{}, time.Time{}, true},
+		{"unknown group", models.SpendingQuery{GroupBy: "country"}, "", time.Time{}, time.Time{}, true},
+		{"from after to", models.SpendingQuery{From: date(2024, 5, 1), To: date(2024, 2, 1)}, "", time.Time{}, time.Time{}, true},
+		{"too many buckets", models.SpendingQuery{Interval: models.SpendingIntervalDay, From: date(2023, 1, 1), To: date(2024, 3, 1)},
+			"", time.Time{}, time.Time{}, true},
+	}
+
+	for _, tt := range tests {
+		query := tt.query
+		err := ledger.ValidateSpendingQuery(&query)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: query was accepted", tt.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: ValidateSpendingQuery() = %v", tt.name, err)
+			continue
+		}
+		if query.Interval != tt.interval || !query.From.Equal(tt.from) || !query.To.Equal(tt.to) {
+			t.Errorf("%s: got %s from %s to %s, want %s from %s to %s", tt.name,
+				query.Interval, query.From, query.To, tt.interval, tt.from, tt.to)
+		}
+	}
+}
@@ -0,0 +1,307 @@
+package ledger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/araesf/ledgertime/internal/models"
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrInvalidTransactionState is returned when an operation is not allowed in the transaction's current status
+	ErrInvalidTransactionState = errors.New("invalid transaction state")
+
+	// ErrCaptureExceedsAuthorization is returned when a capture is larger than the authorized amount
+	ErrCaptureExceedsAuthorization = errors.New("capture exceeds authorized amount")
+
+	// ErrAuthorizationExpired is returned when an authorization's hold expired before it was captured or incremented
+	ErrAuthorizationExpired = errors.New("authorization expired")
+
+	// ErrIncrementDeclined is returned when the card checks, spending limits or processor chain
+	// decline the new total of an incremental authorization. The authorization keeps its previous amount.
+	ErrIncrementDeclined = errors.New("authorization increment declined")
+)
+
+// Authorize reserves funds for a card payment without moving them
+func (s *Service) Authorize(ctx context.Context, payload models.CardPayload) (*models.Transaction, error) {
+	s.logger.Info("Authorizing card payload", "card", payloadCardRef(payload), "amount", payload.Amount)
+
+	transaction, err := runInUnitOfWork(ctx, s.store, func(ctx context.Context) (*models.Transaction, error) {
+		return s.authorize(ctx, payload)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if transaction.Status == models.TransactionStatusAuthorized {
+		s.checkBudgets(ctx, transaction)
+	}
+
+	s.logger.Info("Authorization processed", "transaction_id", transaction.ID, "status", transaction.Status)
+	return transaction, nil
+}
+
+// authorize saves and checks a card payment and places a hold for it
+func (s *Service) authorize(ctx context.Context, payload models.CardPayload) (*models.Transaction, error) {
+	transaction, card, err := s.createCardTransaction(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	status, reason := models.TransactionStatusAuthorized, ""
+	var decline *DeclineError
+	var review *ReviewError
+	if err := checkCard(card, transaction.Timestamp); err != nil {
+		s.logger.Error("Authorization declined", "error", err, "transaction_id", transaction.ID)
+		status, reason = models.TransactionStatusDeclined, err.Error()
+	} else if err := s.checkSpendingLimits(ctx, transaction); errors.Is(err, ErrSpendingLimitExceeded) {
+		s.logger.Error("Authorization declined", "error", err, "transaction_id", transaction.ID)
+		status, reason = models.TransactionStatusDeclined, err.Error()
+	} else if err != nil {
+		return nil, err
+	} else if err := s.processor.Process(ctx, transaction); errors.As(err, &review) {
+		s.logger.Info("Authorization flagged for review", "reason", review.Reason, "transaction_id", transaction.ID)
+		if err := s.parkForReview(ctx, transaction, models.ReviewIntentAuthorization, review.Reason); err != nil {
+			return nil, err
+		}
+		return transaction, nil
+	} else if errors.As(err, &decline) {
+		s.logger.Error("Authorization declined", "error", err, "transaction_id", transaction.ID)
+		status, reason = models.TransactionStatusDeclined, err.Error()
+	} else if err != nil {
+		// The processor never decided; roll the authorization back so it can be retried
+		s.logger.Error("Authorization processing failed", "error", err, "transaction_id", transaction.ID)
+		return nil, fmt.Errorf("authorization processing failed: %w", err)
+	} else if err := s.placeHold(ctx, transaction, time.Now().Add(s.cfg.AuthorizationTTL)); errors.Is(err, models.ErrInsufficientFunds) {
+		s.logger.Error("Authorization declined", "error", err, "transaction_id", transaction.ID)
+		status, reason = models.TransactionStatusDeclined, err.Error()
+	} else if err != nil {
+		s.logger.Error("Failed to place hold", "error", err, "transaction_id", transaction.ID)
+		return nil, fmt.Errorf("failed to place hold: %w", err)
+	}
+
+	if err := s.transition(ctx, transaction, status, actorProcessor, reason); err != nil {
+		return nil, err
+	}
+	return transaction, nil
+}
+
+// Capture settles an authorization for the full amount, or a partial amount when amount is non-zero.
+// Any uncaptured remainder of the hold is released.
+func (s *Service) Capture(ctx context.Context, transactionID string, amount int64) (*models.Transaction, error) {
+	s.logger.Info("Capturing authorization", "transaction_id", transactionID, "amount", amount)
+
+	transaction, err := runInUnitOfWork(ctx, s.store, func(ctx context.Context) (*models.Transaction, error) {
+		return s.capture(ctx, transactionID, amount)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Authorization captured", "transaction_id", transactionID, "amount", transaction.Amount)
+	return transaction, nil
+}
+
+// capture releases the hold of an authorization and books the captured amount
+func (s *Service) capture(ctx context.Context, transactionID string, amount int64) (*models.Transaction, error) {
+	transaction, hold, err := s.activeAuthorization(ctx, transactionID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkHoldUnexpired(hold, time.Now()); err != nil {
+		return nil, err
+	}
+
+	if amount == 0 {
+		amount = transaction.AuthorizedAmount
+	}
+	if amount < 0 {
+		return nil, fmt.Errorf("capture amount must be positive, got: %d", amount)
+	}
+	if amount > transaction.AuthorizedAmount {
+		return nil, fmt.Errorf("%w: capture %d, authorized %d", ErrCaptureExceedsAuthorization, amount, transaction.AuthorizedAmount)
+	}
+
+	if err := s.store.ReleaseHold(ctx, hold.ID, models.HoldStatusCaptured); err != nil {
+		s.logger.Error("Failed to release hold", "error", err, "transaction_id", transactionID)
+		return nil, fmt.Errorf("failed to release hold: %w", err)
+	}
+
+	transaction.Amount = amount
+	if err := s.bookCardPayment(ctx, transaction); err != nil {
+		s.logger.Error("Failed to book capture", "error", err, "transaction_id", transactionID)
+		return nil, fmt.Errorf("failed to book capture: %w", err)
+	}
+
+	reason := fmt.Sprintf("captured %d of %d", amount, transaction.AuthorizedAmount)
+	if err := s.transition(ctx, transaction, models.TransactionStatusSettled, actorCapture, reason); err != nil {
+		return nil, err
+	}
+	return transaction, nil
+}
+
+// IncrementAuthorization raises the authorized amount and extends the hold's expiry
+func (s *Service) IncrementAuthorization(ctx context.Context, transactionID string, amount int64) (*models.Transaction, error) {
+	s.logger.Info("Incrementing authorization", "transaction_id", transactionID, "amount", amount)
+
+	if amount <= 0 {
+		return nil, fmt.Errorf("increment amount must be positive, got: %d", amount)
+	}
+
+	transaction, err := runInUnitOfWork(ctx, s.store, func(ctx context.Context) (*models.Transaction, error) {
+		return s.incrementAuthorization(ctx, transactionID, amount)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Authorization incremented", "transaction_id", transactionID, "authorized_amount", transaction.AuthorizedAmount)
+	return transaction, nil
+}
+
+// incrementAuthorization checks the card, spending limits and processor chain against the new
+// authorized total, then increases the hold of the authorization and its authorized amount
+func (s *Service) incrementAuthorization(ctx context.Context, transactionID string, amount int64) (*models.Transaction, error) {
+	transaction, hold, err := s.activeAuthorization(ctx, transactionID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if err := checkHoldUnexpired(hold, now); err != nil {
+		return nil, err
+	}
+
+	card, err := s.store.GetCard(ctx, transaction.CardID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Screen the transaction as if it had been authorized for the new total
+	transaction.AuthorizedAmount += amount
+	transaction.Amount = transaction.AuthorizedAmount
+	if err := s.checkIncrement(ctx, transaction, card, now); err != nil {
+		s.logger.Error("Authorization increment declined", "error", err, "transaction_id", transactionID)
+		return nil, err
+	}
+
+	if err := s.store.IncreaseHold(ctx, hold.ID, amount, now.Add(s.cfg.AuthorizationTTL)); err != nil {
+		s.logger.Error("Failed to increase hold", "error", err, "transaction_id", transactionID)
+		return nil, fmt.Errorf("failed to increase hold: %w", err)
+	}
+
+	reason := fmt.Sprintf("incremented by %d", amount)
+	if err := s.transition(ctx, transaction, models.TransactionStatusAuthorized, actorIncrement, reason); err != nil {
+		return nil, err
+	}
+	return transaction, nil
+}
+
+// checkIncrement runs the checks of a new authorization against an incremented one. An increment
+// cannot be parked for review, so a review request declines it like a decline does.
+func (s *Service) checkIncrement(ctx context.Context, tx *models.Transaction, card *models.Card, now time.Time) error {
+	if err := checkCard(card, now); err != nil {
+		return fmt.Errorf("%w: %w", ErrIncrementDeclined, err)
+	}
+
+	if err := s.checkSpendingLimits(ctx, tx); errors.Is(err, ErrSpendingLimitExceeded) {
+		return fmt.Errorf("%w: %w", ErrIncrementDeclined, err)
+	} else if err != nil {
+		return err
+	}
+
+	var decline *DeclineError
+	var review *ReviewError
+	if err := s.processor.Process(ctx, tx); errors.As(err, &decline) || errors.As(err, &review) {
+		return fmt.Errorf("%w: %w", ErrIncrementDeclined, err)
+	} else if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Void cancels an authorization and releases its hold
+func (s *Service) Void(ctx context.Context, transactionID string) (*models.Transaction, error) {
+	s.logger.Info("Voiding authorization", "transaction_id", transactionID)
+
+	transaction, err := runInUnitOfWork(ctx, s.store, func(ctx context.Context) (*models.Transaction, error) {
+		transaction, hold, err := s.activeAuthorization(ctx, transactionID)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := s.releaseAuthorization(ctx, transaction, hold, models.HoldStatusReleased, models.TransactionStatusVoided, actorVoid, ""); err != nil {
+			return nil, err
+		}
+		return transaction, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Authorization voided", "transaction_id", transactionID)
+	return transaction, nil
+}
+
+// placeHold reserves the authorized amount on the card holder's wallet until expiresAt
+func (s *Service) placeHold(ctx context.Context, tx *models.Transaction, expiresAt time.Time) error {
+	wallet, err := s.userAccount(ctx, tx.UserID, tx.Currency)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	return s.store.CreateHold(ctx, &models.Hold{
+		ID:            uuid.New().String(),
+		TransactionID: tx.ID,
+		AccountID:     wallet.ID,
+		Amount:        tx.AuthorizedAmount,
+		Status:        models.HoldStatusActive,
+		ExpiresAt:     expiresAt,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	})
+}
+
+// activeAuthorization loads an authorized transaction together with its active hold
+func (s *Service) activeAuthorization(ctx context.Context, transactionID string) (*models.Transaction, *models.Hold, error) {
+	transaction, err := s.store.GetTransaction(ctx, transactionID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if transaction.Status != models.TransactionStatusAuthorized {
+		return nil, nil, fmt.Errorf("%w: transaction %s is %s", ErrInvalidTransactionState, transactionID, transaction.Status)
+	}
+
+	hold, err := s.store.GetActiveHoldByTransaction(ctx, transactionID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return transaction, hold, nil
+}
+
+// checkHoldUnexpired rejects holds whose expiry has passed, even if the sweeper has not expired them yet
+func checkHoldUnexpired(hold *models.Hold, now time.Time) error {
+	if !now.Before(hold.ExpiresAt) {
+		return fmt.Errorf("%w: hold %s expired at %s", ErrAuthorizationExpired, hold.ID, hold.ExpiresAt.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// releaseAuthorization frees the held funds and moves the transaction to its final status
+func (s *Service) releaseAuthorization(ctx context.Context, tx *models.Transaction, hold *models.Hold, holdStatus, txStatus, actor, reason string) error {
+	if !CanTransition(tx.Status, txStatus) {
+		return fmt.Errorf("%w: %s -> %s for transaction %s", ErrIllegalTransition, tx.Status, txStatus, tx.ID)
+	}
+
+	if err := s.store.ReleaseHold(ctx, hold.ID, holdStatus); err != nil {
+		s.logger.Error("Failed to release hold", "error", err, "transaction_id", tx.ID)
+		return fmt.Errorf("failed to release hold: %w", err)
+	}
+
+	return s.transition(ctx, tx, txStatus, actor, reason)
+}
@@ -0,0 +1,192 @@
+package ledger_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/araesf/ledgertime/internal/ledger"
+	"github.com/araesf/ledgertime/internal/models"
+)
+
+func TestAuthorizeCapture(t *testing.T) {
+	service, store := newTestService(t)
+	card := newTestCard(t, service, store)
+	ctx := context.Background()
+
+	authorization, err := service.Authorize(ctx, payment(card, 4000, "Hotel"))
+	if err != nil {
+		t.Fatalf("authorization failed: %v", err)
+	}
+	if authorization.Status != models.TransactionStatusAuthorized {
+		t.Fatalf("authorization status = %s, want %s", authorization.Status, models.TransactionStatusAuthorized)
+	}
+	assertBalance(t, service, card.UserID, 0, 4000)
+
+	if _, err := service.Capture(ctx, authorization.ID, 4001); !errors.Is(err, ledger.ErrCaptureExceedsAuthorization) {
+		t.Errorf("capturing more than authorized: err=%v, want %v", err, ledger.ErrCaptureExceedsAuthorization)
+	}
+	assertBalance(t, service, card.UserID, 0, 4000)
+
+	captured, err := service.Capture(ctx, authorization.ID, 3000)
+	if err != nil {
+		t.Fatalf("capture failed: %v", err)
+	}
+	if captured.Amount != 3000 || captured.AuthorizedAmount != 4000 || captured.Status != models.TransactionStatusSettled {
+		t.Errorf("captured %d of %d in status %s, want 3000 of 4000 in status %s",
+			captured.Amount, captured.AuthorizedAmount, captured.Status, models.TransactionStatusSettled)
+	}
+	assertBalance(t, service, card.UserID, -3000, 0)
+
+	if _, err := service.Capture(ctx, authorization.ID, 0); !errors.Is(err, ledger.ErrInvalidTransactionState) {
+		t.Errorf("capturing twice: err=%v, want %v", err, ledger.ErrInvalidTransactionState)
+	}
+	if _, err := service.Void(ctx, authorization.ID); !errors.Is(err, ledger.ErrInvalidTransactionState) {
+		t.Errorf("voiding a capture: err=%v, want %v", err, ledger.ErrInvalidTransactionState)
+	}
+}
+
+func TestVoidReleasesHold(t *testing.T) {
+	service, store := newTestService(t)
+	card := newTestCard(t, service, store)
+	ctx := context.Background()
+
+	authorization, err := service.Authorize(ctx, payment(card, 4000, "Hotel"))
+	if err != nil {
+		t.Fatalf("authorization failed: %v", err)
+	}
+
+	voided, err := service.Void(ctx, authorization.ID)
+	if err != nil {
+		t.Fatalf("void failed: %v", err)
+	}
+	if voided.Status != models.TransactionStatusVoided {
+		t.Errorf("status = %s, want %s", voided.Status, models.TransactionStatusVoided)
+	}
+	assertBalance(t, service, card.UserID, 0, 0)
+
+	if _, err := service.Capture(ctx, authorization.ID, 0); !errors.Is(err, ledger.ErrInvalidTransactionState) {
+		t.Errorf("capturing a voided authorization: err=%v, want %v", err, ledger.ErrInvalidTransactionState)
+	}
+}
+
+func TestIncrementAuthorization(t *testing.T) {
+	service, store := newTestService(t)
+	card := newTestCard(t, service, store)
+	ctx := context.Background()
+
+	authorization, err := service.Authorize(ctx, payment(card, 4000, "Hotel"))
+	if err != nil {
+		t.Fatalf("authorization failed: %v", err)
+	}
+
+	incremented, err := service.IncrementAuthorization(ctx, authorization.ID, 1500)
+	if err != nil {
+		t.Fatalf("increment failed: %v", err)
+	}
+	if incremented.AuthorizedAmount != 5500 || incremented.Status != models.TransactionStatusAuthorized {
+		t.Errorf("authorized %d in status %s, want 5500 in status %s",
+			incremented.AuthorizedAmount, incremented.Status, models.TransactionStatusAuthorized)
+	}
+	assertBalance(t, service, card.UserID, 0, 5500)
+
+	// The new total is checked against the card's limits and status; a declined increment
+	// leaves the authorization as it was
+	if _, err := service.SetCardLimits(ctx, card.ID, &models.SpendingLimits{SingleTransaction: 6000}); err != nil {
+		t.Fatalf("failed to set card limits: %v", err)
+	}
+	if _, err := service.IncrementAuthorization(ctx, authorization.ID, 1000); !errors.Is(err, ledger.ErrIncrementDeclined) {
+		t.Errorf("increment over the card's limit: err=%v, want %v", err, ledger.ErrIncrementDeclined)
+	}
+	if _, err := service.FreezeCard(ctx, card.ID, "lost in a drawer"); err != nil {
+		t.Fatalf("failed to freeze card: %v", err)
+	}
+	if _, err := service.IncrementAuthorization(ctx, authorization.ID, 100); !errors.Is(err, ledger.ErrIncrementDeclined) {
+		t.Errorf("increment on a frozen card: err=%v, want %v", err, ledger.ErrIncrementDeclined)
+	}
+	assertBalance(t, service, card.UserID, 0, 5500)
+
+	captured, err := service.Capture(ctx, authorization.ID, 0)
+	if err != nil {
+		t.Fatalf("capture failed: %v", err)
+	}
+	if captured.Amount != 5500 {
+		t.Errorf("captured %d, want the incremented total 5500", captured.Amount)
+	}
+
+	if _, err := service.IncrementAuthorization(ctx, authorization.ID, 100); !errors.Is(err, ledger.ErrInvalidTransactionState) {
+		t.Errorf("incrementing a capture: err=%v, want %v", err, ledger.ErrInvalidTransactionState)
+	}
+}
+
+func TestExpiredAuthorization(t *testing.T) {
+	cfg := testConfig()
+	cfg.AuthorizationTTL = time.Millisecond
+	cfg.ReviewTimeout = time.Millisecond
+	service, store := newTestServiceWithConfig(t, cfg, reviewProcessor{})
+	card := newTestCard(t, service, store)
+	ctx := context.Background()
+
+	authorization, err := service.Authorize(ctx, payment(card, 4000, "Hotel"))
+	if err != nil {
+		t.Fatalf("authorization failed: %v", err)
+	}
+	time.Sleep(5 * time.Millisecond)
+
+	// The hold has expired even though the sweeper hasn't released it yet
+	if _, err := service.Capture(ctx, authorization.ID, 0); !errors.Is(err, ledger.ErrAuthorizationExpired) {
+		t.Errorf("capturing an expired hold: err=%v, want %v", err, ledger.ErrAuthorizationExpired)
+	}
+	if _, err := service.IncrementAuthorization(ctx, authorization.ID, 100); !errors.Is(err, ledger.ErrAuthorizationExpired) {
+		t.Errorf("incrementing an expired hold: err=%v, want %v", err, ledger.ErrAuthorizationExpired)
+	}
+	assertBalance(t, service, card.UserID, 0, 4000)
+
+	expired, err := service.ExpireAuthorizations(ctx, time.Now())
+	if err != nil || expired != 1 {
+		t.Fatalf("ExpireAuthorizations() = %d, %v, want 1", expired, err)
+	}
+	assertStatus(t, store, authorization.ID, models.TransactionStatusExpired)
+	assertBalance(t, service, card.UserID, 0, 0)
+
+	if expired, err := service.ExpireAuthorizations(ctx, time.Now()); err != nil || expired != 0 {
+		t.Errorf("second ExpireAuthorizations() = %d, %v, want 0", expired, err)
+	}
+}
+
+func TestExpireAuthorizationsSkipsFailingHolds(t *testing.T) {
+	cfg := testConfig()
+	cfg.AuthorizationTTL = time.Millisecond
+	cfg.ReviewTimeout = time.Millisecond
+	service, store := newTestServiceWithConfig(t, cfg, reviewProcessor{})
+	card := newTestCard(t, service, store)
+	ctx := context.Background()
+
+	bad, err := service.Authorize(ctx, payment(card, 4000, "Hotel"))
+	if err != nil {
+		t.Fatalf("authorization failed: %v", err)
+	}
+	time.Sleep(2 * time.Millisecond)
+	good, err := service.Authorize(ctx, payment(card, 1500, "Restaurant"))
+	if err != nil {
+		t.Fatalf("authorization failed: %v", err)
+	}
+	time.Sleep(5 * time.Millisecond)
+
+	// Move the first transaction on without releasing its hold, so expiring it is an illegal transition
+	if err := store.TransitionTransaction(ctx, bad, &models.StatusChange{
+		TransactionID: bad.ID,
+		FromStatus:    models.TransactionStatusAuthorized,
+		ToStatus:      models.TransactionStatusVoided,
+	}); err != nil {
+		t.Fatalf("failed to transition transaction: %v", err)
+	}
+
+	expired, err := service.ExpireAuthorizations(ctx, time.Now())
+	if expired != 1 || !errors.Is(err, ledger.ErrIllegalTransition) {
+		t.Errorf("ExpireAuthorizations() = %d, %v; want 1 and %v", expired, err, ledger.ErrIllegalTransition)
+	}
+	assertStatus(t, store, good.ID, models.TransactionStatusExpired)
+	assertBalance(t, service, card.UserID, 0, 4000)
+}
@@ -0,0 +1,102 @@
+package ledger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/araesf/ledgertime/internal/models"
+	"github.com/araesf/ledgertime/internal/money"
+)
+
+// userAccount returns the user's wallet in a currency, opening it with the default overdraft limit if needed
+func (s *Service) userAccount(ctx context.Context, userID, currency string) (*models.Account, error) {
+	account, err := s.store.EnsureUserAccount(ctx, userID, currency, s.cfg.DefaultOverdraftLimit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user account: %w", err)
+	}
+	return account, nil
+}
+
+// checkFunds ensures the wallet can absorb the transaction within its overdraft limit
+func (s *Service) checkFunds(ctx context.Context, tx *models.Transaction) error {
+	account, err := s.userAccount(ctx, tx.UserID, tx.Currency)
+	if err != nil {
+		return err
+	}
+
+	if !account.CanSpend(tx.Amount) {
+		return fmt.Errorf("%w: available %d, overdraft limit %d, requested %d",
+			models.ErrInsufficientFunds, account.AvailableBalance(), account.OverdraftLimit, tx.Amount)
+	}
+
+	return nil
+}
+
+// GetUserBalance retrieves the ledger and available balance of a user's wallet. An empty
+// currency selects the base currency. A wallet that was never opened reads as empty and is
+// left unopened.
+func (s *Service) GetUserBalance(ctx context.Context, userID, currency string) (*models.Balance, error) {
+	s.logger.Info("Getting user balance", "user_id", userID, "currency", currency)
+
+	currency, err := s.walletCurrency(currency)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := s.store.GetUser(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	account, err := s.store.GetUserAccount(ctx, userID, currency)
+	if errors.Is(err, models.ErrNotFound) {
+		return &models.Balance{UserID: userID, Currency: currency, OverdraftLimit: s.cfg.DefaultOverdraftLimit}, nil
+	}
+	if err != nil {
+		s.logger.Error("Failed to get user balance", "error", err, "user_id", userID)
+		return nil, fmt.Errorf("failed to get user account: %w", err)
+	}
+
+	return &models.Balance{
+		UserID:           userID,
+		AccountID:        account.ID,
+		Currency:         account.Currency,
+		LedgerBalance:    account.LedgerBalance,
+		PendingAmount:    account.HeldAmount,
+		AvailableBalance: account.AvailableBalance(),
+		OverdraftLimit:   account.OverdraftLimit,
+		UpdatedAt:        account.UpdatedAt,
+	}, nil
+}
+
+// SetOverdraftLimit changes how far a user's available balance may go below zero
+func (s *Service) SetOverdraftLimit(ctx context.Context, userID, currency string, limit int64) (*models.Balance, error) {
+	if limit < 0 {
+		return nil, fmt.Errorf("overdraft limit cannot be negative, got: %d", limit)
+	}
+
+	currency, err := s.walletCurrency(currency)
+	if err != nil {
+		return nil, err
+	}
+
+	account, err := s.userAccount(ctx, userID, currency)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.store.SetOverdraftLimit(ctx, account.ID, limit); err != nil {
+		s.logger.Error("Failed to set overdraft limit", "error", err, "user_id", userID)
+		return nil, err
+	}
+
+	return s.GetUserBalance(ctx, userID, currency)
+}
+
+// walletCurrency validates a requested wallet currency, defaulting to the base currency
+func (s *Service) walletCurrency(currency string) (string, error) {
+	if currency == "" {
+		return s.cfg.BaseCurrency, nil
+	}
+	return money.NormalizeCurrency(currency)
+}
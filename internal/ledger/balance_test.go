@@ -0,0 +1,96 @@
+package ledger_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/araesf/ledgertime/internal/models"
+)
+
+func TestPaymentDeclinedOverOverdraft(t *testing.T) {
+	service, store := newTestService(t)
+	card := newTestCard(t, service, store)
+
+	declined, err := service.ProcessCardPayload(context.Background(), payment(card, 100001, "Jeweller"))
+	if err != nil {
+		t.Fatalf("payment failed: %v", err)
+	}
+	if declined.Status != models.TransactionStatusDeclined {
+		t.Errorf("payment status = %s, want %s", declined.Status, models.TransactionStatusDeclined)
+	}
+	assertBalance(t, service, card.UserID, 0, 0)
+}
+
+func TestOverdraftLimitCountsHolds(t *testing.T) {
+	service, store := newTestService(t)
+	card := newTestCard(t, service, store)
+	ctx := context.Background()
+
+	if _, err := service.Authorize(ctx, payment(card, 60000, "Hotel")); err != nil {
+		t.Fatalf("authorization failed: %v", err)
+	}
+	if _, err := service.ProcessCardPayload(ctx, payment(card, 30000, "Airline")); err != nil {
+		t.Fatalf("payment failed: %v", err)
+	}
+	assertBalance(t, service, card.UserID, -30000, 60000)
+
+	steps := []struct {
+		name   string
+		amount int64
+		want   string
+	}{
+		{"over what the hold leaves", 10001, models.TransactionStatusDeclined},
+		{"exactly at the limit", 10000, models.TransactionStatusSettled},
+		{"past the limit", 1, models.TransactionStatusDeclined},
+	}
+	for _, step := range steps {
+		transaction, err := service.ProcessCardPayload(ctx, payment(card, step.amount, "Bakery"))
+		if err != nil {
+			t.Fatalf("%s: payment failed: %v", step.name, err)
+		}
+		if transaction.Status != step.want {
+			t.Errorf("%s: payment status = %s, want %s", step.name, transaction.Status, step.want)
+		}
+	}
+	assertBalance(t, service, card.UserID, -40000, 60000)
+
+	balance, err := service.SetOverdraftLimit(ctx, card.UserID, "", 150000)
+	if err != nil {
+		t.Fatalf("failed to set overdraft limit: %v", err)
+	}
+	if balance.OverdraftLimit != 150000 || balance.AvailableBalance != -100000 {
+		t.Errorf("balance = %+v, want a 150000 overdraft limit and -100000 available", balance)
+	}
+	if transaction, err := service.ProcessCardPayload(ctx, payment(card, 50000, "Bakery")); err != nil || transaction.Status != models.TransactionStatusSettled {
+		t.Errorf("payment within the raised limit: err=%v, want it settled", err)
+	}
+
+	if _, err := service.SetOverdraftLimit(ctx, card.UserID, "", -1); err == nil {
+		t.Error("negative overdraft limit was accepted")
+	}
+}
+
+func TestBalanceReadDoesNotOpenWallet(t *testing.T) {
+	service, store := newTestService(t)
+	card := newTestCard(t, service, store)
+	ctx := context.Background()
+
+	balance, err := service.GetUserBalance(ctx, card.UserID, "EUR")
+	if err != nil {
+		t.Fatalf("failed to get balance: %v", err)
+	}
+	if balance.Currency != "EUR" || balance.LedgerBalance != 0 || balance.AvailableBalance != 0 || balance.OverdraftLimit != 100000 {
+		t.Errorf("balance = %+v, want an empty EUR wallet with the default overdraft limit", balance)
+	}
+	if _, err := store.GetUserAccount(ctx, card.UserID, "EUR"); !errors.Is(err, models.ErrNotFound) {
+		t.Errorf("reading the balance opened a wallet: err=%v, want %v", err, models.ErrNotFound)
+	}
+
+	if _, err := service.GetUserBalance(ctx, "missing", ""); !errors.Is(err, models.ErrNotFound) {
+		t.Errorf("balance of a missing user: err=%v, want %v", err, models.ErrNotFound)
+	}
+	if _, err := store.EnsureUserAccount(ctx, "missing", "USD", 0); !errors.Is(err, models.ErrNotFound) {
+		t.Errorf("opening a wallet for a missing user: err=%v, want %v", err, models.ErrNotFound)
+	}
+}
@@ -0,0 +1,246 @@
+package ledger
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/araesf/ledgertime/internal/models"
+	"github.com/google/uuid"
+)
+
+// budgetThresholds are the percentages of a budget's available amount that raise alerts
+var budgetThresholds = []int{50, 80, 100}
+
+// budgetIntervals maps budget periods to the spending intervals they cover
+var budgetIntervals = map[string]string{
+	models.BudgetPeriodWeekly:  models.SpendingIntervalWeek,
+	models.BudgetPeriodMonthly: models.SpendingIntervalMonth,
+}
+
+// ListBudgets returns the progress of a user's budgets in the periods containing at
+func (s *Service) ListBudgets(ctx context.Context, userID string, at time.Time) ([]*models.BudgetProgress, error) {
+	if _, err := s.store.GetUser(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	budgets, err := s.store.GetBudgets(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	progress := make([]*models.BudgetProgress, 0, len(budgets))
+	for _, budget := range budgets {
+		p, err := s.budgetProgress(ctx, budget, at)
+		if err != nil {
+			return nil, err
+		}
+		progress = append(progress, p)
+	}
+	return progress, nil
+}
+
+// GetBudget returns a budget's progress in the period containing at
+func (s *Service) GetBudget(ctx context.Context, id string, at time.Time) (*models.BudgetProgress, error) {
+	budget, err := s.store.GetBudget(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.budgetProgress(ctx, budget, at)
+}
+
+// CreateBudget adds a budget for one of the user's categories. An empty currency selects the
+// base currency.
+func (s *Service) CreateBudget(ctx context.Context, userID string, budget *models.Budget) (*models.Budget, error) {
+	s.logger.Info("Creating budget", "user_id", userID, "category", budget.Category, "period", budget.Period)
+
+	if err := ValidateBudget(budget); err != nil {
+		return nil, err
+	}
+	if _, err := s.store.GetUser(ctx, userID); err != nil {
+		return nil, err
+	}
+	if err := s.checkCategory(ctx, userID, budget.Category); err != nil {
+		return nil, err
+	}
+
+	currency, err := s.walletCurrency(budget.Currency)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	budget.ID = uuid.New().String()
+	budget.UserID = userID
+	budget.Currency = currency
+	budget.CreatedAt = now
+	budget.UpdatedAt = now
+	if err := s.store.CreateBudget(ctx, budget); err != nil {
+		return nil, err
+	}
+	return budget, nil
+}
+
+// UpdateBudget replaces a budget's amount, currency and rollover. Rollover is recomputed from
+// the budget's first period with the new amount.
+func (s *Service) UpdateBudget(ctx context.Context, id string, update *models.Budget) (*models.Budget, error) {
+	s.logger.Info("Updating budget", "budget_id", id)
+
+	existing, err := s.store.GetBudget(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// The category and period cannot change, so validate the update against the existing ones
+	update.Category, update.Period = existing.Category, existing.Period
+	if err := ValidateBudget(update); err != nil {
+		return nil, err
+	}
+
+	currency, err := s.walletCurrency(update.Currency)
+	if err != nil {
+		return nil, err
+	}
+
+	existing.Amount = update.Amount
+	existing.Currency = currency
+	existing.Rollover = update.Rollover
+
+	if err := s.store.UpdateBudget(ctx, existing); err != nil {
+		return nil, err
+	}
+	return existing, nil
+}
+
+// DeleteBudget removes a budget and its alerts
+func (s *Service) DeleteBudget(ctx context.Context, id string) error {
+	s.logger.Info("Deleting budget", "budget_id", id)
+	return s.store.DeleteBudget(ctx, id)
+}
+
+// ListBudgetAlerts retrieves a user's budget alerts, newest first
+func (s *Service) ListBudgetAlerts(ctx context.Context, userID string, limit, offset int) ([]*models.BudgetAlert, error) {
+	if _, err := s.store.GetUser(ctx, userID); err != nil {
+		return nil, err
+	}
+	return s.store.GetBudgetAlerts(ctx, userID, limit, offset)
+}
+
+// ValidateBudget checks a budget's period and amount and normalizes its category
+func ValidateBudget(budget *models.Budget) error {
+	category, err := normalizeCategory(budget.Category)
+	if err != nil {
+		return err
+	}
+	budget.Category = category
+
+	switch budget.Period {
+	case models.BudgetPeriodWeekly, models.BudgetPeriodMonthly:
+	default:
+		return fmt.Errorf("budget period must be weekly or monthly, got: %q", budget.Period)
+	}
+
+	if budget.Amount <= 0 {
+		return fmt.Errorf("budget amount must be positive, got: %d", budget.Amount)
+	}
+
+	return nil
+}
+
+// budgetProgress computes a budget's spend in the period containing at. With rollover, what
+// was left unspent in each earlier period since the budget was created is added to the
+// period's amount; overspending does not reduce later periods.
+func (s *Service) budgetProgress(ctx context.Context, budget *models.Budget, at time.Time) (*models.BudgetProgress, error) {
+	interval := budgetIntervals[budget.Period]
+	start := bucketStart(at, interval)
+	end := nextBucket(start, interval)
+
+	since := start
+	if first := bucketStart(budget.CreatedAt, interval); budget.Rollover && first.Before(start) {
+		since = first
+	}
+
+	spending, err := s.store.GetBudgetSpending(ctx, budget, since, end)
+	if err != nil {
+		return nil, err
+	}
+
+	var rolledOver int64
+	for p := since; p.Before(start); p = nextBucket(p, interval) {
+		rolledOver += budget.Amount - spending[p]
+		if rolledOver < 0 {
+			rolledOver = 0
+		}
+	}
+
+	available := budget.Amount + rolledOver
+	spent := spending[start]
+	return &models.BudgetProgress{
+		Budget:      budget,
+		PeriodStart: start,
+		PeriodEnd:   end,
+		RolledOver:  rolledOver,
+		Available:   available,
+		Spent:       spent,
+		Remaining:   available - spent,
+		PercentUsed: int(spent * 100 / available),
+	}, nil
+}
+
+// checkBudgets raises an alert for every threshold an accepted payment pushed its category's
+// budgets past. Failures are logged rather than returned so they never affect the payment.
+func (s *Service) checkBudgets(ctx context.Context, tx *models.Transaction) {
+	if tx.Type != models.TransactionTypePayment {
+		return
+	}
+
+	budgets, err := s.store.GetBudgets(ctx, tx.UserID)
+	if err != nil {
+		s.logger.Error("Failed to check budgets", "error", err, "transaction_id", tx.ID)
+		return
+	}
+
+	category := strings.ToLower(tx.Category)
+	for _, budget := range budgets {
+		if budget.Category != category || budget.Currency != tx.Currency {
+			continue
+		}
+
+		progress, err := s.budgetProgress(ctx, budget, tx.Timestamp)
+		if err != nil {
+			s.logger.Error("Failed to check budget", "error", err, "budget_id", budget.ID, "transaction_id", tx.ID)
+			continue
+		}
+
+		before := progress.Spent - tx.Amount
+		for _, threshold := range budgetThresholds {
+			mark := int64(threshold) * progress.Available
+			if before*100 >= mark || progress.Spent*100 < mark {
+				continue
+			}
+
+			alert := &models.BudgetAlert{
+				ID:            uuid.New().String(),
+				BudgetID:      budget.ID,
+				UserID:        budget.UserID,
+				Category:      budget.Category,
+				Threshold:     threshold,
+				PeriodStart:   progress.PeriodStart,
+				Spent:         progress.Spent,
+				Available:     progress.Available,
+				TransactionID: tx.ID,
+				CreatedAt:     time.Now(),
+			}
+			created, err := s.store.CreateBudgetAlert(ctx, alert)
+			if err != nil {
+				s.logger.Error("Failed to create budget alert", "error", err, "budget_id", budget.ID, "transaction_id", tx.ID)
+				continue
+			}
+			if created {
+				s.logger.Info("Budget threshold crossed", "budget_id", budget.ID, "user_id", budget.UserID,
+					"category", budget.Category, "threshold", threshold, "spent", progress.Spent, "available", progress.Available)
+			}
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package ledger_test
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/araesf/ledgertime/internal/ledger"
+	"github.com/araesf/ledgertime/internal/models"
+)
+
+// spend makes a payment in a category at a time and returns it
+func spend(t *testing.T, service *ledger.Service, card *models.Card, amount int64, category string, at time.Time) *models.Transaction {
+	t.Helper()
+	p := payment(card, amount, "Shop")
+	p.Category = category
+	p.Timestamp = at.UTC().Format(time.RFC3339)
+
+	transaction, err := service.ProcessCardPayload(context.Background(), p)
+	if err != nil {
+		t.Fatalf("payment failed: %v", err)
+	}
+	return transaction
+}
+
+// alertThresholds returns the thresholds of a user's budget alerts per category, oldest first
+func alertThresholds(t *testing.T, service *ledger.Service, userID string) map[string][]int {
+	t.Helper()
+	alerts, err := service.ListBudgetAlerts(context.Background(), userID, 100, 0)
+	if err != nil {
+		t.Fatalf("failed to list budget alerts: %v", err)
+	}
+	thresholds := make(map[string][]int)
+	for i := len(alerts) - 1; i >= 0; i-- {
+		thresholds[alerts[i].Category] = append(thresholds[alerts[i].Category], alerts[i].Threshold)
+	}
+	return thresholds
+}
+
+func TestBudgetThresholdAlerts(t *testing.T) {
+	service, store := newTestService(t)
+	card := newTestCard(t, service, store)
+	ctx := context.Background()
+	now := time.Now()
+
+	for _, budget := range []*models.Budget{
+		{Category: "groceries", Period: models.BudgetPeriodMonthly, Amount: 10000},
+		{Category: "gas", Period: models.BudgetPeriodMonthly, Amount: 1000},
+	} {
+		if _, err := service.CreateBudget(ctx, card.UserID, budget); err != nil {
+			t.Fatalf("failed to create budget: %v", err)
+		}
+	}
+
+	steps := []struct {
+		name     string
+		amount   int64
+		category string
+		want     []int // Groceries thresholds alerted so far
+	}{
+		{"below every threshold", 4000, "groceries", nil},
+		{"at 50%", 1000, "groceries", []int{50}},
+		{"past 80%", 3500, "groceries", []int{50, 80}},
+		{"other category", 9000, "dining", []int{50, 80}},
+		{"past 100%", 2000, "groceries", []int{50, 80, 100}},
+		{"already over", 1000, "groceries", []int{50, 80, 100}},
+	}
+	for _, step := range steps {
+		spend(t, service, card, step.amount, step.category, now)
+		if got := alertThresholds(t, service, card.UserID)["groceries"]; !slices.Equal(got, step.want) {
+			t.Errorf("%s: groceries alerts = %v, want %v", step.name, got, step.want)
+		}
+	}
+
+	// One payment can cross several thresholds, each alerting once
+	crossing := spend(t, service, card, 1500, "gas", now)
+	if got := alertThresholds(t, service, card.UserID)["gas"]; !slices.Equal(got, []int{50, 80, 100}) {
+		t.Errorf("gas alerts = %v, want 50, 80 and 100", got)
+	}
+	alerts, err := service.ListBudgetAlerts(ctx, card.UserID, 3, 0)
+	if err != nil {
+		t.Fatalf("failed to list budget alerts: %v", err)
+	}
+	for _, alert := range alerts {
+		if alert.TransactionID != crossing.ID || alert.Spent != 1500 || alert.Available != 1000 {
+			t.Errorf("alert %+v, want transaction %s with 1500 spent of 1000", alert, crossing.ID)
+		}
+	}
+
+	// Dropping back below a threshold and crossing it again in the same period doesn't alert twice
+	if _, err := service.Refund(ctx, crossing.ID, 0, "returned"); err != nil {
+		t.Fatalf("refund failed: %v", err)
+	}
+	spend(t, service, card, 1200, "gas", now)
+	if got := alertThresholds(t, service, card.UserID)["gas"]; !slices.Equal(got, []int{50, 80, 100}) {
+		t.Errorf("gas alerts after crossing again = %v, want each threshold once", got)
+	}
+}
+
+func TestBudgetAlertsWaitForReview(t *testing.T) {
+	service, store := newTestService(t)
+	card := newTestCard(t, service, store)
+	ctx := context.Background()
+
+	if _, err := service.CreateBudget(ctx, card.UserID, &models.Budget{Category: "general", Period: models.BudgetPeriodWeekly, Amount: 5000}); err != nil {
+		t.Fatalf("failed to create budget: %v", err)
+	}
+
+	_, review := pendingReview(t, service, card, 3000)
+	if got := alertThresholds(t, service, card.UserID); len(got) != 0 {
+		t.Errorf("alerts for a payment in review = %v, want none", got)
+	}
+
+	if _, err := service.ApproveReview(ctx, review.ID, "alice", ""); err != nil {
+		t.Fatalf("approve failed: %v", err)
+	}
+	if got := alertThresholds(t, service, card.UserID)["general"]; !slices.Equal(got, []int{50}) {
+		t.Errorf("alerts once approved = %v, want 50", got)
+	}
+}
+
+func TestBudgetProgressRollover(t *testing.T) {
+	service, store := newTestService(t)
+	card := newTestCard(t, service, store)
+	ctx := context.Background()
+
+	now := time.Now().UTC()
+	thisMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	nextMonth := thisMonth.AddDate(0, 1, 0)
+	later := thisMonth.AddDate(0, 2, 0)
+
+	plain, err := service.CreateBudget(ctx, card.UserID, &models.Budget{Category: "dining", Period: models.BudgetPeriodMonthly, Amount: 10000})
+	if err != nil {
+		t.Fatalf("failed to create budget: %v", err)
+	}
+	rollover, err := service.CreateBudget(ctx, card.UserID, &models.Budget{Category: "groceries", Period: models.BudgetPeriodMonthly, Amount: 10000, Rollover: true})
+	if err != nil {
+		t.Fatalf("failed to create budget: %v", err)
+	}
+
+	for _, category := range []string{"dining", "groceries"} {
+		spend(t, service, card, 4000, category, thisMonth.Add(time.Hour))
+		spend(t, service, card, 12000, category, nextMonth.Add(time.Hour))
+		spend(t, service, card, 3000, category, later.Add(time.Hour))
+	}
+
+	tests := []struct {
+		name       string
+		budget     *models.Budget
+		at         time.Time
+		rolledOver int64
+		spent      int64
+	}{
+		{"first period", rollover, thisMonth, 0, 4000},
+		// 6000 was left over, and overspending 2000 takes it down to 4000 rather than carrying a debt
+		{"overspent period", rollover, nextMonth, 6000, 12000},
+		{"after overspending", rollover, later, 4000, 3000},
+		{"without rollover", plain, later, 0, 3000},
+	}
+	for _, tt := range tests {
+		progress, err := service.GetBudget(ctx, tt.budget.ID, tt.at)
+		if err != nil {
+			t.Fatalf("%s: failed to get budget: %v", tt.name, err)
+		}
+		available := tt.budget.Amount + tt.rolledOver
+		if !progress.PeriodStart.Equal(tt.at) || !progress.PeriodEnd.Equal(tt.at.AddDate(0, 1, 0)) {
+			t.Errorf("%s: period %s to %s, want the month from %s", tt.name, progress.PeriodStart, progress.PeriodEnd, tt.at)
+		}
+		if progress.RolledOver != tt.rolledOver || progress.Available != available || progress.Spent != tt.spent ||
+			progress.Remaining != available-tt.spent || progress.PercentUsed != int(tt.spent*100/available) {
+			t.Errorf("%s: progress %+v, want %d rolled over and %d spent", tt.name, progress, tt.rolledOver, tt.spent)
+		}
+	}
+}
@@ -0,0 +1,398 @@
+package ledger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/araesf/ledgertime/internal/models"
+	"github.com/araesf/ledgertime/internal/vault"
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrIllegalCardTransition is returned when the card lifecycle does not allow a status change
+	ErrIllegalCardTransition = errors.New("illegal card status transition")
+
+	// ErrCardNotUsable is returned when a payment is attempted on a card that is not active or has expired
+	ErrCardNotUsable = errors.New("card cannot be used")
+)
+
+// Actors recorded in the card status history
+const (
+	actorCardManagement  = "card-management"
+	actorCardReplacement = "card-replacement"
+	actorCardSweeper     = "card-expiry-sweeper"
+)
+
+// cardTransitions lists the card statuses reachable from each status.
+//
+//	active <-> frozen --> lost/stolen/expired --> closed
+//
+// Active cards may also become lost, stolen or expired directly, and every status other
+// than closed may be closed directly.
+var cardTransitions = map[string][]string{
+	models.CardStatusActive: {
+		models.CardStatusFrozen,
+		models.CardStatusLost,
+		models.CardStatusStolen,
+		models.CardStatusClosed,
+		models.CardStatusExpired,
+	},
+	models.CardStatusFrozen: {
+		models.CardStatusActive,
+		models.CardStatusLost,
+		models.CardStatusStolen,
+		models.CardStatusClosed,
+		models.CardStatusExpired,
+	},
+	models.CardStatusLost:    {models.CardStatusClosed},
+	models.CardStatusStolen:  {models.CardStatusClosed},
+	models.CardStatusExpired: {models.CardStatusClosed},
+}
+
+// CanTransitionCard reports whether a card may move from one status to another
+func CanTransitionCard(from, to string) bool {
+	for _, allowed := range cardTransitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// RegisterCard creates an active card for a user. An empty card type is derived from the card number,
+// and an empty currency bills the card in the base currency.
+func (s *Service) RegisterCard(ctx context.Context, userID, cardNumber, cardType, currency string) (*models.Card, error) {
+	currency, err := s.walletCurrency(currency)
+	if err != nil {
+		return nil, fmt.Errorf("invalid card currency: %w", err)
+	}
+
+	card, err := s.newCard(userID, cardNumber, cardType, currency)
+	if err != nil {
+		return nil, err
+	}
+	err = s.store.RunUnitOfWork(ctx, func(ctx context.Context) error {
+		if err := s.store.CreateCard(ctx, card); err != nil {
+			return err
+		}
+		return s.recordEvent(ctx, models.AggregateCard, card.ID, models.EventCardIssued, &models.CardEventPayload{Card: card})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return card, nil
+}
+
+// newCard builds an active card valid for the configured card validity, storing its number
+// encrypted in the vault under a new token. The number is validated and its network and
+// issuer derived from the BIN table; an invalid number returns a *cardnet.ValidationError.
+func (s *Service) newCard(userID, cardNumber, cardType, currency string) (*models.Card, error) {
+	validated, err := s.bins.Validate(cardNumber, cardType)
+	if err != nil {
+		return nil, err
+	}
+	cardNumber = validated.Number
+
+	token, err := vault.NewToken()
+	if err != nil {
+		return nil, err
+	}
+
+	sealed, err := s.vault.Seal(cardNumber, token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt card number: %w", err)
+	}
+
+	now := time.Now()
+	last4 := vault.Last4(cardNumber)
+	return &models.Card{
+		ID:            uuid.New().String(),
+		UserID:        userID,
+		Token:         token,
+		CardNumber:    vault.MaskLast4(last4),
+		Last4:         last4,
+		CardType:      validated.Network,
+		Issuer:        validated.Issuer,
+		IssuerCountry: validated.IssuerCountry,
+		Funding:       validated.Funding,
+		Currency:      currency,
+		Status:        models.CardStatusActive,
+		IsActive:      true,
+		ExpiresAt:     now.Add(s.cfg.CardValidity),
+		CreatedAt:     now,
+		UpdatedAt:     now,
+		PANHash:       s.vault.LookupHash(cardNumber),
+		PANKeyID:      sealed.KeyID,
+		PANDataKey:    sealed.DataKey,
+		PANCiphertext: sealed.Ciphertext,
+	}, nil
+}
+
+// GetCardByNumber finds a card by its number through the vault's lookup hash
+func (s *Service) GetCardByNumber(ctx context.Context, cardNumber string) (*models.Card, error) {
+	normalized, err := vault.Normalize(cardNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	card, err := s.store.GetCardByPANHash(ctx, s.vault.LookupHash(normalized))
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			return nil, fmt.Errorf("%w: %s", err, vault.Mask(normalized))
+		}
+		return nil, err
+	}
+	return card, nil
+}
+
+// GetCardByToken finds a card by its token
+func (s *Service) GetCardByToken(ctx context.Context, token string) (*models.Card, error) {
+	return s.store.GetCardByToken(ctx, token)
+}
+
+// CardToken returns the token of a card number, for callers that must pass the card on
+// without handling its number
+func (s *Service) CardToken(ctx context.Context, cardNumber string) (string, error) {
+	card, err := s.GetCardByNumber(ctx, cardNumber)
+	if err != nil {
+		return "", err
+	}
+	return card.Token, nil
+}
+
+// payloadCard resolves the card of a payload by token, or by number for untokenized payloads
+func (s *Service) payloadCard(ctx context.Context, payload models.CardPayload) (*models.Card, error) {
+	if payload.CardToken != "" {
+		return s.GetCardByToken(ctx, payload.CardToken)
+	}
+	return s.GetCardByNumber(ctx, payload.CardNumber)
+}
+
+// payloadCardRef identifies the card of a payload in logs without revealing its number
+func payloadCardRef(payload models.CardPayload) string {
+	if payload.CardToken != "" {
+		return payload.CardToken
+	}
+	return vault.Mask(payload.CardNumber)
+}
+
+// FreezeCard temporarily blocks payments on a card
+func (s *Service) FreezeCard(ctx context.Context, cardID, reason string) (*models.Card, error) {
+	return s.changeCardStatus(ctx, cardID, models.CardStatusFrozen, reason)
+}
+
+// UnfreezeCard makes a frozen card usable again
+func (s *Service) UnfreezeCard(ctx context.Context, cardID, reason string) (*models.Card, error) {
+	card, err := s.store.GetCard(ctx, cardID)
+	if err != nil {
+		return nil, err
+	}
+
+	if card.Status != models.CardStatusFrozen {
+		return nil, fmt.Errorf("%w: card %s is %s, not frozen", ErrIllegalCardTransition, cardID, card.Status)
+	}
+
+	if err := s.transitionCard(ctx, card, models.CardStatusActive, actorCardManagement, reason); err != nil {
+		return nil, err
+	}
+	return card, nil
+}
+
+// ReportCard marks a card lost or stolen, blocking it permanently
+func (s *Service) ReportCard(ctx context.Context, cardID, status, reason string) (*models.Card, error) {
+	if status != models.CardStatusLost && status != models.CardStatusStolen {
+		return nil, fmt.Errorf("%w: cards can only be reported lost or stolen, got: %s", ErrIllegalCardTransition, status)
+	}
+	return s.changeCardStatus(ctx, cardID, status, reason)
+}
+
+// CloseCard permanently closes a card
+func (s *Service) CloseCard(ctx context.Context, cardID, reason string) (*models.Card, error) {
+	return s.changeCardStatus(ctx, cardID, models.CardStatusClosed, reason)
+}
+
+// ReplaceCard issues a new card number for a card, carrying over its spending limits. The old card is
+// closed and linked from the new one, so limit windows and fraud history continue across both. Only
+// cards that can no longer pay are replaced, so an active card must be frozen or reported first.
+func (s *Service) ReplaceCard(ctx context.Context, cardID, newCardNumber, reason string) (*models.Card, error) {
+	s.logger.Info("Replacing card", "card_id", cardID)
+
+	if newCardNumber == "" {
+		return nil, fmt.Errorf("replacement card number cannot be empty")
+	}
+
+	old, err := s.store.GetCard(ctx, cardID)
+	if err != nil {
+		return nil, err
+	}
+
+	if old.Status == models.CardStatusActive || !CanTransitionCard(old.Status, models.CardStatusClosed) {
+		return nil, fmt.Errorf("%w: card %s is %s and cannot be replaced", ErrIllegalCardTransition, cardID, old.Status)
+	}
+
+	replacement, err := s.newCard(old.UserID, newCardNumber, old.CardType, old.Currency)
+	if err != nil {
+		return nil, err
+	}
+	replacement.ReplacesCardID = old.ID
+
+	if reason == "" {
+		reason = "replaced"
+	}
+	change := s.cardStatusChange(old, models.CardStatusClosed, actorCardReplacement, fmt.Sprintf("%s by card %s", reason, replacement.ID))
+
+	err = s.store.RunUnitOfWork(ctx, func(ctx context.Context) error {
+		if err := s.store.ReplaceCard(ctx, old, replacement, change); err != nil {
+			return err
+		}
+
+		closed := *old
+		closed.Status, closed.IsActive, closed.UpdatedAt = change.ToStatus, false, change.CreatedAt
+		if err := s.recordEvent(ctx, models.AggregateCard, old.ID, models.EventCardClosed, &models.CardEventPayload{Card: &closed, Change: change}); err != nil {
+			return err
+		}
+		return s.recordEvent(ctx, models.AggregateCard, replacement.ID, models.EventCardIssued, &models.CardEventPayload{Card: replacement})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Card replaced", "card_id", cardID, "replacement_card_id", replacement.ID)
+	return replacement, nil
+}
+
+// GetCardHistory retrieves the status changes of a card, oldest first
+func (s *Service) GetCardHistory(ctx context.Context, cardID string) ([]*models.CardStatusChange, error) {
+	if _, err := s.store.GetCard(ctx, cardID); err != nil {
+		return nil, err
+	}
+
+	return s.store.GetCardStatusHistory(ctx, cardID)
+}
+
+// GetCardTransactions retrieves the transactions of a card, including those made with the cards it replaced
+func (s *Service) GetCardTransactions(ctx context.Context, cardID string, limit, offset int) ([]*models.Transaction, error) {
+	if _, err := s.store.GetCard(ctx, cardID); err != nil {
+		return nil, err
+	}
+
+	return s.store.GetCardTransactions(ctx, cardID, limit, offset)
+}
+
+// ExpireCards marks active and frozen cards past their expiry date as expired
+func (s *Service) ExpireCards(ctx context.Context, now time.Time) (int, error) {
+	expired := 0
+	for {
+		cards, err := s.store.GetExpiredCards(ctx, now, sweepBatchSize)
+		if err != nil {
+			return expired, err
+		}
+
+		for _, card := range cards {
+			err := s.transitionCard(ctx, card, models.CardStatusExpired, actorCardSweeper, "card expired")
+			if errors.Is(err, models.ErrCardStatusConflict) {
+				// Changed concurrently
+				continue
+			}
+			if err != nil {
+				return expired, err
+			}
+			expired++
+		}
+
+		if len(cards) < sweepBatchSize {
+			return expired, nil
+		}
+	}
+}
+
+// RunCardExpirySweeper periodically expires cards past their expiry date until ctx is cancelled
+func (s *Service) RunCardExpirySweeper(ctx context.Context) {
+	s.logger.Info("Starting card expiry sweeper", "interval", s.cfg.SweepInterval)
+
+	ticker := time.NewTicker(s.cfg.SweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			s.logger.Info("Card expiry sweeper stopped")
+			return
+		case now := <-ticker.C:
+			expired, err := s.ExpireCards(ctx, now)
+			if err != nil {
+				s.logger.Error("Failed to expire cards", "error", err)
+			}
+			if expired > 0 {
+				s.logger.Info("Expired cards", "count", expired)
+			}
+		}
+	}
+}
+
+// checkCard ensures a card can pay at the given time
+func checkCard(card *models.Card, at time.Time) error {
+	if card.Status != models.CardStatusActive {
+		return fmt.Errorf("%w: card is %s", ErrCardNotUsable, card.Status)
+	}
+	if !at.Before(card.ExpiresAt) {
+		return fmt.Errorf("%w: card expired on %s", ErrCardNotUsable, card.ExpiresAt.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// changeCardStatus loads a card and moves it to a new status on behalf of card management
+func (s *Service) changeCardStatus(ctx context.Context, cardID, to, reason string) (*models.Card, error) {
+	s.logger.Info("Changing card status", "card_id", cardID, "status", to)
+
+	card, err := s.store.GetCard(ctx, cardID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.transitionCard(ctx, card, to, actorCardManagement, reason); err != nil {
+		return nil, err
+	}
+	return card, nil
+}
+
+// transitionCard moves the card to a new status and persists the change with its cause,
+// announcing it in the outbox
+func (s *Service) transitionCard(ctx context.Context, card *models.Card, to, actor, reason string) error {
+	if !CanTransitionCard(card.Status, to) {
+		return fmt.Errorf("%w: %s -> %s for card %s", ErrIllegalCardTransition, card.Status, to, card.ID)
+	}
+
+	change := s.cardStatusChange(card, to, actor, reason)
+	err := s.store.RunUnitOfWork(ctx, func(ctx context.Context) error {
+		if err := s.store.TransitionCard(ctx, card, change); err != nil {
+			return err
+		}
+		changed := *card
+		changed.Status, changed.IsActive, changed.UpdatedAt = to, to == models.CardStatusActive, change.CreatedAt
+		return s.recordEvent(ctx, models.AggregateCard, card.ID, cardEvents[to], &models.CardEventPayload{Card: &changed, Change: change})
+	})
+	if err != nil {
+		return err
+	}
+
+	card.Status, card.IsActive, card.UpdatedAt = to, to == models.CardStatusActive, change.CreatedAt
+	return nil
+}
+
+// cardStatusChange builds the history entry for a card status change
+func (s *Service) cardStatusChange(card *models.Card, to, actor, reason string) *models.CardStatusChange {
+	return &models.CardStatusChange{
+		ID:         uuid.New().String(),
+		CardID:     card.ID,
+		FromStatus: card.Status,
+		ToStatus:   to,
+		Actor:      actor,
+		Reason:     reason,
+		CreatedAt:  time.Now(),
+	}
+}
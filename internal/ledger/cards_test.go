@@ -0,0 +1,383 @@
+package ledger_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/araesf/ledgertime/internal/ledger"
+	"github.com/araesf/ledgertime/internal/memory"
+	"github.com/araesf/ledgertime/internal/models"
+)
+
+// replacementCardNumber is a second test card number, for replacements
+const replacementCardNumber = "4111111111111111"
+
+// cardWithStatus creates a user with a card and moves the card to status through card management
+func cardWithStatus(t *testing.T, service *ledger.Service, store *memory.Store, status string) *models.Card {
+	t.Helper()
+	ctx := context.Background()
+
+	card := newTestCard(t, service, store)
+	var err error
+	switch status {
+	case models.CardStatusActive:
+	case models.CardStatusFrozen:
+		_, err = service.FreezeCard(ctx, card.ID, "")
+	case models.CardStatusLost, models.CardStatusStolen:
+		_, err = service.ReportCard(ctx, card.ID, status, "")
+	case models.CardStatusClosed:
+		_, err = service.CloseCard(ctx, card.ID, "")
+	case models.CardStatusExpired:
+		_, err = service.ExpireCards(ctx, card.ExpiresAt)
+	default:
+		t.Fatalf("unknown card status %s", status)
+	}
+	if err != nil {
+		t.Fatalf("failed to move card to %s: %v", status, err)
+	}
+
+	if card, err = store.GetCard(ctx, card.ID); err != nil {
+		t.Fatalf("failed to get card: %v", err)
+	}
+	if card.Status != status {
+		t.Fatalf("card status = %s, want %s", card.Status, status)
+	}
+	return card
+}
+
+// cardHistoryOf returns a card's status changes formatted as "from -> to by actor"
+func cardHistoryOf(t *testing.T, service *ledger.Service, cardID string) []string {
+	t.Helper()
+	history, err := service.GetCardHistory(context.Background(), cardID)
+	if err != nil {
+		t.Fatalf("failed to get card history: %v", err)
+	}
+	var changes []string
+	for _, change := range history {
+		changes = append(changes, fmt.Sprintf("%s -> %s by %s", change.FromStatus, change.ToStatus, change.Actor))
+	}
+	return changes
+}
+
+// cardOperations change a card's status through the service
+var cardOperations = map[string]func(ctx context.Context, service *ledger.Service, cardID string) (*models.Card, error){
+	"freeze": func(ctx context.Context, service *ledger.Service, cardID string) (*models.Card, error) {
+		return service.FreezeCard(ctx, cardID, "")
+	},
+	"unfreeze": func(ctx context.Context, service *ledger.Service, cardID string) (*models.Card, error) {
+		return service.UnfreezeCard(ctx, cardID, "")
+	},
+	"report lost": func(ctx context.Context, service *ledger.Service, cardID string) (*models.Card, error) {
+		return service.ReportCard(ctx, cardID, models.CardStatusLost, "")
+	},
+	"report stolen": func(ctx context.Context, service *ledger.Service, cardID string) (*models.Card, error) {
+		return service.ReportCard(ctx, cardID, models.CardStatusStolen, "")
+	},
+	"report closed": func(ctx context.Context, service *ledger.Service, cardID string) (*models.Card, error) {
+		return service.ReportCard(ctx, cardID, models.CardStatusClosed, "")
+	},
+	"close": func(ctx context.Context, service *ledger.Service, cardID string) (*models.Card, error) {
+		return service.CloseCard(ctx, cardID, "")
+	},
+	"replace": func(ctx context.Context, service *ledger.Service, cardID string) (*models.Card, error) {
+		return service.ReplaceCard(ctx, cardID, replacementCardNumber, "")
+	},
+}
+
+func TestCanTransitionCard(t *testing.T) {
+	tests := []struct {
+		from, to string
+		allowed  bool
+	}{
+		{models.CardStatusActive, models.CardStatusFrozen, true},
+		{models.CardStatusActive, models.CardStatusLost, true},
+		{models.CardStatusActive, models.CardStatusStolen, true},
+		{models.CardStatusActive, models.CardStatusClosed, true},
+		{models.CardStatusActive, models.CardStatusExpired, true},
+		{models.CardStatusFrozen, models.CardStatusActive, true},
+		{models.CardStatusFrozen, models.CardStatusLost, true},
+		{models.CardStatusFrozen, models.CardStatusStolen, true},
+		{models.CardStatusFrozen, models.CardStatusClosed, true},
+		{models.CardStatusFrozen, models.CardStatusExpired, true},
+		{models.CardStatusLost, models.CardStatusClosed, true},
+		{models.CardStatusStolen, models.CardStatusClosed, true},
+		{models.CardStatusExpired, models.CardStatusClosed, true},
+
+		{models.CardStatusLost, models.CardStatusActive, false},
+		{models.CardStatusStolen, models.CardStatusFrozen, false},
+		{models.CardStatusExpired, models.CardStatusActive, false},
+		{models.CardStatusClosed, models.CardStatusActive, false},
+		{models.CardStatusClosed, models.CardStatusFrozen, false},
+		{models.CardStatusClosed, models.CardStatusClosed, false},
+		{models.CardStatusActive, models.CardStatusActive, false},
+		{"unknown", models.CardStatusClosed, false},
+	}
+
+	for _, tt := range tests {
+		if got := ledger.CanTransitionCard(tt.from, tt.to); got != tt.allowed {
+			t.Errorf("CanTransitionCard(%s, %s) = %v, want %v", tt.from, tt.to, got, tt.allowed)
+		}
+	}
+}
+
+func TestCardTransitions(t *testing.T) {
+	tests := []struct {
+		from, operation, want string
+	}{
+		{models.CardStatusActive, "freeze", models.CardStatusFrozen},
+		{models.CardStatusFrozen, "unfreeze", models.CardStatusActive},
+		{models.CardStatusActive, "report lost", models.CardStatusLost},
+		{models.CardStatusActive, "report stolen", models.CardStatusStolen},
+		{models.CardStatusFrozen, "report lost", models.CardStatusLost},
+		{models.CardStatusFrozen, "report stolen", models.CardStatusStolen},
+		{models.CardStatusActive, "close", models.CardStatusClosed},
+		{models.CardStatusFrozen, "close", models.CardStatusClosed},
+		{models.CardStatusLost, "close", models.CardStatusClosed},
+		{models.CardStatusStolen, "close", models.CardStatusClosed},
+		{models.CardStatusExpired, "close", models.CardStatusClosed},
+	}
+
+	for _, tt := range tests {
+		service, store := newTestService(t)
+		card := cardWithStatus(t, service, store, tt.from)
+
+		changed, err := cardOperations[tt.operation](context.Background(), service, card.ID)
+		if err != nil {
+			t.Errorf("%s %s card failed: %v", tt.operation, tt.from, err)
+			continue
+		}
+		if changed.Status != tt.want || changed.IsActive != (tt.want == models.CardStatusActive) {
+			t.Errorf("%s %s card = %s (active %v), want %s", tt.operation, tt.from, changed.Status, changed.IsActive, tt.want)
+		}
+
+		history := cardHistoryOf(t, service, card.ID)
+		if last := history[len(history)-1]; last != fmt.Sprintf("%s -> %s by card-management", tt.from, tt.want) {
+			t.Errorf("%s %s card recorded %q", tt.operation, tt.from, last)
+		}
+	}
+}
+
+func TestIllegalCardTransitions(t *testing.T) {
+	tests := []struct {
+		from, operation string
+	}{
+		{models.CardStatusActive, "unfreeze"},
+		{models.CardStatusLost, "unfreeze"},
+		{models.CardStatusExpired, "unfreeze"},
+		{models.CardStatusClosed, "unfreeze"},
+		{models.CardStatusFrozen, "freeze"},
+		{models.CardStatusStolen, "freeze"},
+		{models.CardStatusClosed, "freeze"},
+		{models.CardStatusLost, "report stolen"},
+		{models.CardStatusExpired, "report lost"},
+		{models.CardStatusClosed, "report lost"},
+		{models.CardStatusActive, "report closed"},
+		{models.CardStatusClosed, "close"},
+		{models.CardStatusActive, "replace"},
+		{models.CardStatusClosed, "replace"},
+	}
+
+	for _, tt := range tests {
+		service, store := newTestService(t)
+		card := cardWithStatus(t, service, store, tt.from)
+		before := cardHistoryOf(t, service, card.ID)
+
+		if _, err := cardOperations[tt.operation](context.Background(), service, card.ID); !errors.Is(err, ledger.ErrIllegalCardTransition) {
+			t.Errorf("%s %s card: err=%v, want %v", tt.operation, tt.from, err, ledger.ErrIllegalCardTransition)
+		}
+
+		after, err := store.GetCard(context.Background(), card.ID)
+		if err != nil {
+			t.Fatalf("failed to get card: %v", err)
+		}
+		if after.Status != tt.from {
+			t.Errorf("%s %s card left it %s", tt.operation, tt.from, after.Status)
+		}
+		if history := cardHistoryOf(t, service, card.ID); len(history) != len(before) {
+			t.Errorf("%s %s card recorded %q", tt.operation, tt.from, history[len(before):])
+		}
+	}
+}
+
+func TestReplaceCard(t *testing.T) {
+	ctx := context.Background()
+
+	for _, from := range []string{models.CardStatusFrozen, models.CardStatusLost, models.CardStatusStolen, models.CardStatusExpired} {
+		service, store := newTestService(t)
+		card := cardWithStatus(t, service, store, from)
+
+		replacement, err := service.ReplaceCard(ctx, card.ID, replacementCardNumber, "")
+		if err != nil {
+			t.Errorf("replacing %s card failed: %v", from, err)
+			continue
+		}
+		if replacement.Status != models.CardStatusActive || replacement.ReplacesCardID != card.ID || replacement.UserID != card.UserID {
+			t.Errorf("replacement of %s card = %s, replaces %q, user %s; want an active card of the same user replacing %s",
+				from, replacement.Status, replacement.ReplacesCardID, replacement.UserID, card.ID)
+		}
+		if history := cardHistoryOf(t, service, card.ID); history[len(history)-1] != from+" -> closed by card-replacement" {
+			t.Errorf("replacing %s card recorded %q", from, history[len(history)-1])
+		}
+	}
+
+	service, store := newTestService(t)
+	card := cardWithStatus(t, service, store, models.CardStatusFrozen)
+	if _, err := service.ReplaceCard(ctx, card.ID, "", ""); err == nil {
+		t.Error("replacing a card without a new number succeeded")
+	}
+}
+
+func TestReplacementCardCarriesLimitsAndTransactions(t *testing.T) {
+	service, store := newTestService(t)
+	card := newTestCard(t, service, store)
+	ctx := context.Background()
+	now := time.Now()
+
+	limits := &models.SpendingLimits{Daily: 50000, BlockedCategories: []string{"gambling"}, CategoryCaps: []models.CategoryCap{{Category: "dining", Amount: 10000}}}
+	if _, err := service.SetCardLimits(ctx, card.ID, limits); err != nil {
+		t.Fatalf("failed to set card limits: %v", err)
+	}
+	first := spend(t, service, card, 1000, "", now.Add(-3*time.Minute))
+
+	// Replace the card twice, paying with each generation
+	if _, err := service.ReportCard(ctx, card.ID, models.CardStatusStolen, ""); err != nil {
+		t.Fatalf("failed to report card: %v", err)
+	}
+	second, err := service.ReplaceCard(ctx, card.ID, replacementCardNumber, "stolen")
+	if err != nil {
+		t.Fatalf("failed to replace card: %v", err)
+	}
+	middle := spend(t, service, second, 2000, "", now.Add(-2*time.Minute))
+
+	if _, err := service.FreezeCard(ctx, second.ID, ""); err != nil {
+		t.Fatalf("failed to freeze card: %v", err)
+	}
+	third, err := service.ReplaceCard(ctx, second.ID, "4000056655665556", "damaged")
+	if err != nil {
+		t.Fatalf("failed to replace card: %v", err)
+	}
+	last := spend(t, service, third, 3000, "", now.Add(-time.Minute))
+
+	got, err := service.GetCardLimits(ctx, third.ID)
+	if err != nil {
+		t.Fatalf("failed to get replacement card limits: %v", err)
+	}
+	if got.ScopeID != third.ID || got.Daily != limits.Daily || fmt.Sprint(got.BlockedCategories) != fmt.Sprint(limits.BlockedCategories) ||
+		fmt.Sprint(got.CategoryCaps) != fmt.Sprint(limits.CategoryCaps) {
+		t.Errorf("replacement card limits = %+v, want the limits of the card it replaced", got)
+	}
+
+	tests := []struct {
+		name   string
+		cardID string
+		want   []string
+	}{
+		{"latest card", third.ID, []string{last.ID, middle.ID, first.ID}},
+		{"middle card", second.ID, []string{middle.ID, first.ID}},
+		{"first card", card.ID, []string{first.ID}},
+	}
+	for _, tt := range tests {
+		transactions, err := service.GetCardTransactions(ctx, tt.cardID, 10, 0)
+		if err != nil {
+			t.Fatalf("failed to get card transactions: %v", err)
+		}
+		var ids []string
+		for _, transaction := range transactions {
+			ids = append(ids, transaction.ID)
+		}
+		if fmt.Sprint(ids) != fmt.Sprint(tt.want) {
+			t.Errorf("%s transactions = %v, want %v", tt.name, ids, tt.want)
+		}
+	}
+}
+
+func TestExpireCards(t *testing.T) {
+	service, store := newTestService(t)
+	ctx := context.Background()
+
+	active := newTestCard(t, service, store)
+	frozen, err := service.RegisterCard(ctx, active.UserID, replacementCardNumber, "", "USD")
+	if err != nil {
+		t.Fatalf("failed to register card: %v", err)
+	}
+	if frozen, err = service.FreezeCard(ctx, frozen.ID, ""); err != nil {
+		t.Fatalf("failed to freeze card: %v", err)
+	}
+	lost, err := service.RegisterCard(ctx, active.UserID, "4000056655665556", "", "USD")
+	if err != nil {
+		t.Fatalf("failed to register card: %v", err)
+	}
+	if lost, err = service.ReportCard(ctx, lost.ID, models.CardStatusLost, ""); err != nil {
+		t.Fatalf("failed to report card: %v", err)
+	}
+
+	if expired, err := service.ExpireCards(ctx, active.ExpiresAt.Add(-time.Hour)); err != nil || expired != 0 {
+		t.Fatalf("sweep before expiry = %d, %v; want 0", expired, err)
+	}
+
+	now := frozen.ExpiresAt.Add(time.Second)
+	if expired, err := service.ExpireCards(ctx, now); err != nil || expired != 2 {
+		t.Fatalf("sweep after expiry = %d, %v; want 2", expired, err)
+	}
+	if expired, err := service.ExpireCards(ctx, now); err != nil || expired != 0 {
+		t.Errorf("repeated sweep = %d, %v; want 0", expired, err)
+	}
+
+	tests := []struct {
+		card *models.Card
+		from string
+		want string
+	}{
+		{active, models.CardStatusActive, models.CardStatusExpired},
+		{frozen, models.CardStatusFrozen, models.CardStatusExpired},
+		{lost, models.CardStatusLost, models.CardStatusLost},
+	}
+	for _, tt := range tests {
+		card, err := store.GetCard(ctx, tt.card.ID)
+		if err != nil {
+			t.Fatalf("failed to get card: %v", err)
+		}
+		if card.Status != tt.want {
+			t.Errorf("%s card after sweep = %s, want %s", tt.from, card.Status, tt.want)
+		}
+		if history := cardHistoryOf(t, service, card.ID); tt.want == models.CardStatusExpired &&
+			history[len(history)-1] != tt.from+" -> expired by card-expiry-sweeper" {
+			t.Errorf("sweeping %s card recorded %q", tt.from, history[len(history)-1])
+		}
+	}
+}
+
+func TestPaymentsOnUnusableCardsAreDeclined(t *testing.T) {
+	for _, status := range []string{models.CardStatusFrozen, models.CardStatusLost, models.CardStatusStolen, models.CardStatusClosed, models.CardStatusExpired} {
+		service, store := newTestService(t)
+		card := cardWithStatus(t, service, store, status)
+
+		transaction := spend(t, service, card, 1000, "", time.Now())
+		if transaction.Status != models.TransactionStatusDeclined || !strings.Contains(transaction.DeclineReason, "card is "+status) {
+			t.Errorf("payment on %s card = %s (%s), want declined as %s", status, transaction.Status, transaction.DeclineReason, status)
+		}
+		assertBalance(t, service, card.UserID, 0, 0)
+	}
+
+	// A card past its expiry date declines payments before the sweeper expires it
+	service, store := newTestService(t)
+	card := newTestCard(t, service, store)
+	transaction := spend(t, service, card, 1000, "", card.ExpiresAt.Add(time.Second))
+	if transaction.Status != models.TransactionStatusDeclined || !strings.Contains(transaction.DeclineReason, "card expired on") {
+		t.Errorf("payment past expiry = %s (%s), want declined as expired", transaction.Status, transaction.DeclineReason)
+	}
+
+	// Unfreezing makes the card usable again
+	service, store = newTestService(t)
+	card = cardWithStatus(t, service, store, models.CardStatusFrozen)
+	if _, err := service.UnfreezeCard(context.Background(), card.ID, ""); err != nil {
+		t.Fatalf("failed to unfreeze card: %v", err)
+	}
+	if transaction := spend(t, service, card, 1000, "", time.Now()); transaction.Status != models.TransactionStatusSettled {
+		t.Errorf("payment on unfrozen card = %s (%s), want settled", transaction.Status, transaction.DeclineReason)
+	}
+}
@@ -0,0 +1,335 @@
+package ledger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/araesf/ledgertime/internal/merchant"
+	"github.com/araesf/ledgertime/internal/models"
+	"github.com/google/uuid"
+)
+
+// ErrUnknownCategory is returned when assigning a category that is neither built-in nor defined by the user
+var ErrUnknownCategory = errors.New("unknown category")
+
+// categorizeBatchSize is how many transactions ApplyCategoryRules loads at a time
+const categorizeBatchSize = 500
+
+// ListCategories returns the built-in categories followed by the user's own
+func (s *Service) ListCategories(ctx context.Context, userID string) ([]*models.Category, error) {
+	if _, err := s.store.GetUser(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	var categories []*models.Category
+	for _, name := range merchant.Categories() {
+		categories = append(categories, &models.Category{Name: name, BuiltIn: true})
+	}
+
+	own, err := s.store.GetCategories(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return append(categories, own...), nil
+}
+
+// CreateCategory defines a new category for a user
+func (s *Service) CreateCategory(ctx context.Context, userID string, category *models.Category) (*models.Category, error) {
+	s.logger.Info("Creating category", "user_id", userID, "name", category.Name)
+
+	if err := ValidateCategory(category); err != nil {
+		return nil, err
+	}
+	if merchant.IsCategory(category.Name) {
+		return nil, fmt.Errorf("%w: %s is built in", models.ErrCategoryExists, category.Name)
+	}
+
+	if _, err := s.store.GetUser(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	category.ID = uuid.New().String()
+	category.UserID = userID
+	category.CreatedAt = time.Now()
+	if err := s.store.CreateCategory(ctx, category); err != nil {
+		return nil, err
+	}
+	return category, nil
+}
+
+// DeleteCategory removes a user's category. Rules assigning it must be deleted first.
+func (s *Service) DeleteCategory(ctx context.Context, id string) error {
+	s.logger.Info("Deleting category", "category_id", id)
+	return s.store.DeleteCategory(ctx, id)
+}
+
+// ValidateCategory checks a category's name and normalizes it to lowercase
+func ValidateCategory(category *models.Category) error {
+	name, err := normalizeCategory(category.Name)
+	if err != nil {
+		return err
+	}
+	category.Name = name
+	return nil
+}
+
+// normalizeCategory lowercases a category name and collapses its whitespace
+func normalizeCategory(name string) (string, error) {
+	name = strings.ToLower(strings.Join(strings.Fields(name), " "))
+	if name == "" {
+		return "", fmt.Errorf("category cannot be empty")
+	}
+	if len(name) > 100 {
+		return "", fmt.Errorf("category cannot be longer than 100 characters")
+	}
+	return name, nil
+}
+
+// checkCategory ensures a category is built in or defined by the user
+func (s *Service) checkCategory(ctx context.Context, userID, name string) error {
+	if merchant.IsCategory(name) {
+		return nil
+	}
+
+	own, err := s.store.GetCategories(ctx, userID)
+	if err != nil {
+		return err
+	}
+	for _, category := range own {
+		if category.Name == name {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s", ErrUnknownCategory, name)
+}
+
+// ListCategoryRules returns a user's rules in evaluation order
+func (s *Service) ListCategoryRules(ctx context.Context, userID string) ([]*models.CategoryRule, error) {
+	if _, err := s.store.GetUser(ctx, userID); err != nil {
+		return nil, err
+	}
+	return s.store.GetCategoryRules(ctx, userID)
+}
+
+// CreateCategoryRule adds a rule that categorizes the user's future transactions. Use
+// ApplyCategoryRules to recategorize past ones.
+func (s *Service) CreateCategoryRule(ctx context.Context, userID string, rule *models.CategoryRule) (*models.CategoryRule, error) {
+	s.logger.Info("Creating category rule", "user_id", userID, "category", rule.Category)
+
+	if err := ValidateCategoryRule(rule); err != nil {
+		return nil, err
+	}
+	if _, err := s.store.GetUser(ctx, userID); err != nil {
+		return nil, err
+	}
+	if err := s.checkCategory(ctx, userID, rule.Category); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	rule.ID = uuid.New().String()
+	rule.UserID = userID
+	rule.CreatedAt = now
+	rule.UpdatedAt = now
+	if err := s.store.CreateCategoryRule(ctx, rule); err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+// UpdateCategoryRule replaces a rule's condition, category and priority
+func (s *Service) UpdateCategoryRule(ctx context.Context, id string, update *models.CategoryRule) (*models.CategoryRule, error) {
+	s.logger.Info("Updating category rule", "rule_id", id)
+
+	existing, err := s.store.GetCategoryRule(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ValidateCategoryRule(update); err != nil {
+		return nil, err
+	}
+	if err := s.checkCategory(ctx, existing.UserID, update.Category); err != nil {
+		return nil, err
+	}
+
+	existing.Field = update.Field
+	existing.Operator = update.Operator
+	existing.Value = update.Value
+	existing.Category = update.Category
+	existing.Priority = update.Priority
+
+	if err := s.store.UpdateCategoryRule(ctx, existing); err != nil {
+		return nil, err
+	}
+	return existing, nil
+}
+
+// DeleteCategoryRule removes a rule. Transactions it categorized keep their category.
+func (s *Service) DeleteCategoryRule(ctx context.Context, id string) error {
+	s.logger.Info("Deleting category rule", "rule_id", id)
+	return s.store.DeleteCategoryRule(ctx, id)
+}
+
+// ValidateCategoryRule checks a rule's condition and normalizes its category
+func ValidateCategoryRule(rule *models.CategoryRule) error {
+	switch rule.Field {
+	case models.RuleFieldMerchant, models.RuleFieldMCC, models.RuleFieldMerchantCity, models.RuleFieldMerchantCountry:
+	default:
+		return fmt.Errorf("rule field must be merchant, mcc, merchant_city or merchant_country, got: %q", rule.Field)
+	}
+
+	switch rule.Operator {
+	case models.RuleOperatorContains, models.RuleOperatorEquals, models.RuleOperatorStartsWith:
+	default:
+		return fmt.Errorf("rule operator must be contains, equals or starts_with, got: %q", rule.Operator)
+	}
+
+	rule.Value = strings.TrimSpace(rule.Value)
+	if rule.Value == "" {
+		return fmt.Errorf("rule value cannot be empty")
+	}
+
+	category, err := normalizeCategory(rule.Category)
+	if err != nil {
+		return err
+	}
+	rule.Category = category
+
+	return nil
+}
+
+// matchCategoryRule returns the first rule matching the transaction, or nil
+func matchCategoryRule(rules []*models.CategoryRule, tx *models.Transaction) *models.CategoryRule {
+	for _, rule := range rules {
+		if ruleMatches(rule, tx) {
+			return rule
+		}
+	}
+	return nil
+}
+
+// ruleMatches compares a rule's value with the transaction field it names, ignoring case
+func ruleMatches(rule *models.CategoryRule, tx *models.Transaction) bool {
+	var candidates []string
+	switch rule.Field {
+	case models.RuleFieldMerchant:
+		candidates = []string{tx.MerchantName, tx.MerchantDescriptor}
+	case models.RuleFieldMCC:
+		candidates = []string{tx.MCC}
+	case models.RuleFieldMerchantCity:
+		candidates = []string{tx.MerchantCity}
+	case models.RuleFieldMerchantCountry:
+		candidates = []string{tx.MerchantCountry}
+	}
+
+	value := strings.ToUpper(rule.Value)
+	for _, candidate := range candidates {
+		candidate = strings.ToUpper(candidate)
+		switch rule.Operator {
+		case models.RuleOperatorContains:
+			if strings.Contains(candidate, value) {
+				return true
+			}
+		case models.RuleOperatorEquals:
+			if candidate == value {
+				return true
+			}
+		case models.RuleOperatorStartsWith:
+			if strings.HasPrefix(candidate, value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// categorize applies the user's rules to a new transaction. A matching rule overrides the
+// category sent with the payment or derived from the MCC.
+func (s *Service) categorize(ctx context.Context, tx *models.Transaction) error {
+	rules, err := s.store.GetCategoryRules(ctx, tx.UserID)
+	if err != nil {
+		return err
+	}
+
+	if rule := matchCategoryRule(rules, tx); rule != nil {
+		tx.Category, tx.CategorySource = rule.Category, models.CategorySourceRule
+	}
+	return nil
+}
+
+// RecategorizeTransaction sets a payment's category together with its refunds and reversals.
+// Categories set this way are never changed by rules.
+func (s *Service) RecategorizeTransaction(ctx context.Context, id, category string) (*models.Transaction, error) {
+	s.logger.Info("Recategorizing transaction", "transaction_id", id, "category", category)
+
+	category, err := normalizeCategory(category)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.store.GetTransaction(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if tx.Type != models.TransactionTypePayment {
+		return nil, fmt.Errorf("%w: %s follows the category of transaction %s",
+			ErrInvalidTransactionState, tx.Type, tx.ParentTransactionID)
+	}
+
+	if err := s.checkCategory(ctx, tx.UserID, category); err != nil {
+		return nil, err
+	}
+
+	if err := s.store.SetTransactionCategory(ctx, tx.ID, category, models.CategorySourceUser); err != nil {
+		return nil, err
+	}
+
+	tx.Category, tx.CategorySource = category, models.CategorySourceUser
+	return tx, nil
+}
+
+// ApplyCategoryRules runs the user's current rules over their past payments, skipping those the
+// user categorized by hand, and returns how many were recategorized. Payments no rule matches
+// keep their category.
+func (s *Service) ApplyCategoryRules(ctx context.Context, userID string) (int, error) {
+	s.logger.Info("Applying category rules", "user_id", userID)
+
+	rules, err := s.ListCategoryRules(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+	if len(rules) == 0 {
+		return 0, nil
+	}
+
+	recategorized, after := 0, ""
+	for {
+		batch, err := s.store.GetRuleCategorizableTransactions(ctx, userID, after, categorizeBatchSize)
+		if err != nil {
+			return recategorized, err
+		}
+
+		for _, tx := range batch {
+			rule := matchCategoryRule(rules, tx)
+			if rule == nil || (tx.Category == rule.Category && tx.CategorySource == models.CategorySourceRule) {
+				continue
+			}
+			if err := s.store.SetTransactionCategory(ctx, tx.ID, rule.Category, models.CategorySourceRule); err != nil {
+				return recategorized, err
+			}
+			recategorized++
+		}
+
+		if len(batch) < categorizeBatchSize {
+			break
+		}
+		after = batch[len(batch)-1].ID
+	}
+
+	s.logger.Info("Category rules applied", "user_id", userID, "recategorized", recategorized)
+	return recategorized, nil
+}
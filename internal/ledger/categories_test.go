@@ -0,0 +1,218 @@
+package ledger_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/araesf/ledgertime/internal/ledger"
+	"github.com/araesf/ledgertime/internal/models"
+)
+
+// categorized makes a payment and returns its category and where the category came from
+func categorized(t *testing.T, service *ledger.Service, payload models.CardPayload) (string, string) {
+	t.Helper()
+	transaction, err := service.ProcessCardPayload(context.Background(), payload)
+	if err != nil {
+		t.Fatalf("payment failed: %v", err)
+	}
+	return transaction.Category, transaction.CategorySource
+}
+
+func TestCategoryRulesCategorizeNewPayments(t *testing.T) {
+	service, store := newTestService(t)
+	card := newTestCard(t, service, store)
+	ctx := context.Background()
+
+	if _, err := service.CreateCategory(ctx, card.UserID, &models.Category{Name: "  Coffee   Runs "}); err != nil {
+		t.Fatalf("failed to create category: %v", err)
+	}
+	rules := []*models.CategoryRule{
+		{Field: models.RuleFieldMerchant, Operator: models.RuleOperatorContains, Value: "amazon", Category: "Shopping", Priority: 10},
+		{Field: models.RuleFieldMerchant, Operator: models.RuleOperatorStartsWith, Value: "amazon fresh", Category: "groceries", Priority: 5},
+		{Field: models.RuleFieldMCC, Operator: models.RuleOperatorEquals, Value: "5812", Category: "coffee runs", Priority: 20},
+		{Field: models.RuleFieldMerchantCountry, Operator: models.RuleOperatorEquals, Value: "fr", Category: "travel", Priority: 30},
+	}
+	for _, rule := range rules {
+		if _, err := service.CreateCategoryRule(ctx, card.UserID, rule); err != nil {
+			t.Fatalf("failed to create rule %s %s %q: %v", rule.Field, rule.Operator, rule.Value, err)
+		}
+	}
+
+	withMCC := func(p models.CardPayload, mcc, country string) models.CardPayload {
+		p.MCC, p.MerchantCountry = mcc, country
+		return p
+	}
+	withCategory := func(p models.CardPayload, category string) models.CardPayload {
+		p.Category = category
+		return p
+	}
+
+	tests := []struct {
+		name     string
+		payload  models.CardPayload
+		category string
+		source   string
+	}{
+		{"contains, ignoring case", payment(card, 1000, "AMAZON MKTPLACE"), "shopping", models.CategorySourceRule},
+		{"lower priority wins", payment(card, 1000, "Amazon Fresh"), "groceries", models.CategorySourceRule},
+		{"rule overrides the payload", withCategory(payment(card, 1000, "Amazon"), "entertainment"), "shopping", models.CategorySourceRule},
+		{"mcc equals", withMCC(payment(card, 1000, "Corner Cafe"), "5812", ""), "coffee runs", models.CategorySourceRule},
+		{"country equals", withMCC(payment(card, 1000, "Boulangerie"), "5411", "FR"), "travel", models.CategorySourceRule},
+		{"no match keeps the mcc category", withMCC(payment(card, 1000, "Supermarket"), "5411", "US"), "groceries", models.CategorySourceMCC},
+		{"no match keeps the payload category", withCategory(payment(card, 1000, "Cinema"), "entertainment"), "entertainment", models.CategorySourcePayload},
+	}
+
+	for _, tt := range tests {
+		category, source := categorized(t, service, tt.payload)
+		if category != tt.category || source != tt.source {
+			t.Errorf("%s: categorized as %s from %s, want %s from %s", tt.name, category, source, tt.category, tt.source)
+		}
+	}
+}
+
+func TestCategoryRuleValidation(t *testing.T) {
+	service, store := newTestService(t)
+	card := newTestCard(t, service, store)
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		rule models.CategoryRule
+	}{
+		{"unknown field", models.CategoryRule{Field: "amount", Operator: models.RuleOperatorEquals, Value: "10", Category: "shopping"}},
+		{"unknown operator", models.CategoryRule{Field: models.RuleFieldMerchant, Operator: "matches", Value: "x", Category: "shopping"}},
+		{"empty value", models.CategoryRule{Field: models.RuleFieldMerchant, Operator: models.RuleOperatorEquals, Value: "  ", Category: "shopping"}},
+		{"empty category", models.CategoryRule{Field: models.RuleFieldMerchant, Operator: models.RuleOperatorEquals, Value: "x"}},
+	}
+	for _, tt := range tests {
+		rule := tt.rule
+		if _, err := service.CreateCategoryRule(ctx, card.UserID, &rule); err == nil {
+			t.Errorf("%s: rule was created", tt.name)
+		}
+	}
+
+	rule := &models.CategoryRule{Field: models.RuleFieldMerchant, Operator: models.RuleOperatorEquals, Value: "x", Category: "pets"}
+	if _, err := service.CreateCategoryRule(ctx, card.UserID, rule); !errors.Is(err, ledger.ErrUnknownCategory) {
+		t.Errorf("rule for an undefined category: err=%v, want %v", err, ledger.ErrUnknownCategory)
+	}
+
+	if _, err := service.CreateCategory(ctx, card.UserID, &models.Category{Name: "Groceries"}); !errors.Is(err, models.ErrCategoryExists) {
+		t.Errorf("redefining a built-in category: err=%v, want %v", err, models.ErrCategoryExists)
+	}
+	pets, err := service.CreateCategory(ctx, card.UserID, &models.Category{Name: "Pets"})
+	if err != nil {
+		t.Fatalf("failed to create category: %v", err)
+	}
+	if _, err := service.CreateCategoryRule(ctx, card.UserID, rule); err != nil {
+		t.Fatalf("failed to create rule: %v", err)
+	}
+	if err := service.DeleteCategory(ctx, pets.ID); !errors.Is(err, models.ErrCategoryInUse) {
+		t.Errorf("deleting a category a rule assigns: err=%v, want %v", err, models.ErrCategoryInUse)
+	}
+}
+
+func TestApplyCategoryRules(t *testing.T) {
+	service, store := newTestService(t)
+	card := newTestCard(t, service, store)
+	ctx := context.Background()
+
+	first, err := service.ProcessCardPayload(ctx, payment(card, 3000, "Amazon"))
+	if err != nil {
+		t.Fatalf("payment failed: %v", err)
+	}
+	refund, err := service.Refund(ctx, first.ID, 1000, "returned")
+	if err != nil {
+		t.Fatalf("refund failed: %v", err)
+	}
+	handPicked, err := service.ProcessCardPayload(ctx, payment(card, 2000, "Amazon"))
+	if err != nil {
+		t.Fatalf("payment failed: %v", err)
+	}
+	if _, err := service.RecategorizeTransaction(ctx, handPicked.ID, "entertainment"); err != nil {
+		t.Fatalf("recategorize failed: %v", err)
+	}
+	unmatched, err := service.ProcessCardPayload(ctx, payment(card, 500, "Bakery"))
+	if err != nil {
+		t.Fatalf("payment failed: %v", err)
+	}
+
+	rule := &models.CategoryRule{Field: models.RuleFieldMerchant, Operator: models.RuleOperatorContains, Value: "amazon", Category: "shopping"}
+	if _, err := service.CreateCategoryRule(ctx, card.UserID, rule); err != nil {
+		t.Fatalf("failed to create rule: %v", err)
+	}
+
+	recategorized, err := service.ApplyCategoryRules(ctx, card.UserID)
+	if err != nil {
+		t.Fatalf("ApplyCategoryRules() = %v", err)
+	}
+	if recategorized != 1 {
+		t.Errorf("recategorized %d payments, want 1", recategorized)
+	}
+
+	tests := []struct {
+		name     string
+		id       string
+		category string
+		source   string
+	}{
+		{"matching payment", first.ID, "shopping", models.CategorySourceRule},
+		{"its refund", refund.ID, "shopping", models.CategorySourceRule},
+		{"categorized by hand", handPicked.ID, "entertainment", models.CategorySourceUser},
+		{"no matching rule", unmatched.ID, unmatched.Category, unmatched.CategorySource},
+	}
+	for _, tt := range tests {
+		transaction, err := store.GetTransaction(ctx, tt.id)
+		if err != nil {
+			t.Fatalf("failed to get transaction: %v", err)
+		}
+		if transaction.Category != tt.category || transaction.CategorySource != tt.source {
+			t.Errorf("%s: category %s from %s, want %s from %s", tt.name,
+				transaction.Category, transaction.CategorySource, tt.category, tt.source)
+		}
+	}
+
+	// Applying the rules again changes nothing
+	if recategorized, err := service.ApplyCategoryRules(ctx, card.UserID); err != nil || recategorized != 0 {
+		t.Errorf("second ApplyCategoryRules() = %d, %v, want 0", recategorized, err)
+	}
+}
+
+func TestRecategorizeTransaction(t *testing.T) {
+	service, store := newTestService(t)
+	card := newTestCard(t, service, store)
+	ctx := context.Background()
+
+	paid, err := service.ProcessCardPayload(ctx, payment(card, 3000, "Bookshop"))
+	if err != nil {
+		t.Fatalf("payment failed: %v", err)
+	}
+	refund, err := service.Refund(ctx, paid.ID, 1000, "damaged")
+	if err != nil {
+		t.Fatalf("refund failed: %v", err)
+	}
+
+	if _, err := service.RecategorizeTransaction(ctx, paid.ID, "pets"); !errors.Is(err, ledger.ErrUnknownCategory) {
+		t.Errorf("recategorizing into an undefined category: err=%v, want %v", err, ledger.ErrUnknownCategory)
+	}
+	if _, err := service.RecategorizeTransaction(ctx, refund.ID, "education"); !errors.Is(err, ledger.ErrInvalidTransactionState) {
+		t.Errorf("recategorizing a refund: err=%v, want %v", err, ledger.ErrInvalidTransactionState)
+	}
+
+	recategorized, err := service.RecategorizeTransaction(ctx, paid.ID, " Education ")
+	if err != nil {
+		t.Fatalf("recategorize failed: %v", err)
+	}
+	if recategorized.Category != "education" || recategorized.CategorySource != models.CategorySourceUser {
+		t.Errorf("recategorized as %s from %s, want education from %s",
+			recategorized.Category, recategorized.CategorySource, models.CategorySourceUser)
+	}
+
+	stored, err := store.GetTransaction(ctx, refund.ID)
+	if err != nil {
+		t.Fatalf("failed to get refund: %v", err)
+	}
+	if stored.Category != "education" {
+		t.Errorf("refund category = %s, want it to follow its payment to education", stored.Category)
+	}
+}
@@ -0,0 +1,106 @@
+package ledger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/araesf/ledgertime/internal/models"
+	"github.com/google/uuid"
+)
+
+// ErrUnbalancedEntry is returned when the legs of a ledger entry do not balance
+var ErrUnbalancedEntry = errors.New("unbalanced ledger entry")
+
+// newPosting builds a single leg of a ledger entry
+func newPosting(transactionID, accountID, direction string, amount int64) *models.Posting {
+	return &models.Posting{
+		ID:            uuid.New().String(),
+		TransactionID: transactionID,
+		AccountID:     accountID,
+		Direction:     direction,
+		Amount:        amount,
+		CreatedAt:     time.Now(),
+	}
+}
+
+// ValidateEntry ensures a set of postings forms a balanced double-entry
+func ValidateEntry(postings []*models.Posting) error {
+	if len(postings) < 2 {
+		return fmt.Errorf("%w: need at least two postings, got %d", ErrUnbalancedEntry, len(postings))
+	}
+
+	var debits, credits int64
+	transactionID := postings[0].TransactionID
+	for _, p := range postings {
+		if p.TransactionID != transactionID {
+			return fmt.Errorf("%w: postings span multiple transactions", ErrUnbalancedEntry)
+		}
+		if p.Amount <= 0 {
+			return fmt.Errorf("%w: posting amount must be positive, got: %d", ErrUnbalancedEntry, p.Amount)
+		}
+
+		switch p.Direction {
+		case models.PostingDirectionDebit:
+			debits += p.Amount
+		case models.PostingDirectionCredit:
+			credits += p.Amount
+		default:
+			return fmt.Errorf("%w: unknown posting direction %q", ErrUnbalancedEntry, p.Direction)
+		}
+	}
+
+	if debits != credits {
+		return fmt.Errorf("%w: debits %d != credits %d", ErrUnbalancedEntry, debits, credits)
+	}
+
+	return nil
+}
+
+// postEntry validates and persists a balanced set of postings
+func (s *Service) postEntry(ctx context.Context, postings []*models.Posting) error {
+	if err := ValidateEntry(postings); err != nil {
+		s.logger.Error("Rejected unbalanced entry", "error", err)
+		return err
+	}
+
+	if err := s.store.CreatePostings(ctx, postings); err != nil {
+		return fmt.Errorf("failed to post entry: %w", err)
+	}
+
+	return nil
+}
+
+// bookCardPayment moves the payment amount from the card holder's wallet to merchant clearing
+func (s *Service) bookCardPayment(ctx context.Context, tx *models.Transaction) error {
+	return s.bookWalletEntry(ctx, tx, models.PostingDirectionDebit)
+}
+
+// bookCardRefund moves a refunded or reversed amount from merchant clearing back to the card holder's wallet
+func (s *Service) bookCardRefund(ctx context.Context, tx *models.Transaction) error {
+	return s.bookWalletEntry(ctx, tx, models.PostingDirectionCredit)
+}
+
+// bookWalletEntry posts the transaction amount between the card holder's wallet and merchant clearing
+func (s *Service) bookWalletEntry(ctx context.Context, tx *models.Transaction, walletDirection string) error {
+	wallet, err := s.userAccount(ctx, tx.UserID, tx.Currency)
+	if err != nil {
+		return err
+	}
+
+	clearing, err := s.store.EnsureSystemAccount(ctx, models.AccountTypeMerchantClearing, tx.Currency)
+	if err != nil {
+		return fmt.Errorf("failed to get merchant clearing account: %w", err)
+	}
+
+	clearingDirection := models.PostingDirectionCredit
+	if walletDirection == models.PostingDirectionCredit {
+		clearingDirection = models.PostingDirectionDebit
+	}
+
+	return s.postEntry(ctx, []*models.Posting{
+		newPosting(tx.ID, wallet.ID, walletDirection, tx.Amount),
+		newPosting(tx.ID, clearing.ID, clearingDirection, tx.Amount),
+	})
+}
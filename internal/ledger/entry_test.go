@@ -0,0 +1,45 @@
+package ledger
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/araesf/ledgertime/internal/models"
+)
+
+func TestValidateEntry(t *testing.T) {
+	debit := func(transactionID string, amount int64) *models.Posting {
+		return newPosting(transactionID, "wallet", models.PostingDirectionDebit, amount)
+	}
+	credit := func(transactionID string, amount int64) *models.Posting {
+		return newPosting(transactionID, "clearing", models.PostingDirectionCredit, amount)
+	}
+
+	tests := []struct {
+		name     string
+		postings []*models.Posting
+		valid    bool
+	}{
+		{"balanced", []*models.Posting{debit("t1", 500), credit("t1", 500)}, true},
+		{"split legs", []*models.Posting{debit("t1", 500), credit("t1", 200), credit("t1", 300)}, true},
+		{"no postings", nil, false},
+		{"single posting", []*models.Posting{debit("t1", 500)}, false},
+		{"unbalanced", []*models.Posting{debit("t1", 500), credit("t1", 499)}, false},
+		{"two transactions", []*models.Posting{debit("t1", 500), credit("t2", 500)}, false},
+		{"zero amount", []*models.Posting{debit("t1", 0), credit("t1", 0)}, false},
+		{"negative amount", []*models.Posting{debit("t1", -500), credit("t1", -500)}, false},
+		{"unknown direction", []*models.Posting{debit("t1", 500), newPosting("t1", "clearing", "sideways", 500)}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateEntry(tt.postings)
+			if tt.valid && err != nil {
+				t.Errorf("ValidateEntry() = %v, want nil", err)
+			}
+			if !tt.valid && !errors.Is(err, ErrUnbalancedEntry) {
+				t.Errorf("ValidateEntry() = %v, want %v", err, ErrUnbalancedEntry)
+			}
+		})
+	}
+}
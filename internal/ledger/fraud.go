@@ -0,0 +1,55 @@
+package ledger
+
+import (
+	"context"
+	"strings"
+
+	"github.com/araesf/ledgertime/internal/fraud"
+	"github.com/araesf/ledgertime/internal/models"
+	"github.com/araesf/ledgertime/pkg/logger"
+)
+
+// FraudProcessor screens transactions with the fraud engine and stores the result on the transaction
+type FraudProcessor struct {
+	engine *fraud.Engine
+	store  TransactionStore
+	logger *logger.Logger
+}
+
+// NewFraudProcessor creates the "fraud" processor for use in the processor chain
+func NewFraudProcessor(engine *fraud.Engine, store TransactionStore, log *logger.Logger) *FraudProcessor {
+	return &FraudProcessor{
+		engine: engine,
+		store:  store,
+		logger: log,
+	}
+}
+
+// Name identifies the processor
+func (p *FraudProcessor) Name() string {
+	return "fraud"
+}
+
+// Process scores the transaction and declines or parks it for review when the engine decides so
+func (p *FraudProcessor) Process(ctx context.Context, tx *models.Transaction) error {
+	result, err := p.engine.Evaluate(ctx, tx)
+	if err != nil {
+		p.logger.Error("Fraud screening failed", "error", err, "transaction_id", tx.ID)
+		return err
+	}
+
+	if err := p.store.SetTransactionFraud(ctx, tx.ID, result.Score, result.Decision, result.Reasons); err != nil {
+		return err
+	}
+	tx.FraudScore, tx.FraudDecision, tx.FraudReasons = result.Score, result.Decision, result.Reasons
+
+	p.logger.Info("Transaction screened", "transaction_id", tx.ID, "score", result.Score, "decision", result.Decision)
+
+	switch result.Decision {
+	case fraud.DecisionDecline:
+		return &DeclineError{Processor: p.Name(), Reason: strings.Join(result.Reasons, "; ")}
+	case fraud.DecisionReview:
+		return &ReviewError{Processor: p.Name(), Reason: strings.Join(result.Reasons, "; ")}
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package ledger_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/araesf/ledgertime/internal/fraud"
+	"github.com/araesf/ledgertime/internal/ledger"
+	"github.com/araesf/ledgertime/internal/memory"
+	"github.com/araesf/ledgertime/internal/models"
+	"github.com/araesf/ledgertime/pkg/logger"
+)
+
+func TestFraudProcessor(t *testing.T) {
+	store := memory.NewStore()
+	engine := fraud.NewEngineWithRules([]fraud.Rule{
+		&fraud.NewMerchantRule{Amount: 50000},            // 30
+		fraud.NewCategoryBlockRule([]string{"gambling"}), // 100
+	}, store, 30, 100)
+	service := newTestServiceOverStore(t, testConfig(), store,
+		ledger.NewFraudProcessor(engine, store, logger.NewLoggerWithLevel("error")))
+	card := newTestCard(t, service, store)
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		amount   int64
+		merchant string
+		category string
+		status   string
+		score    int
+		decision string
+		reason   string
+	}{
+		{"approved", 1000, "Bakery", "groceries", models.TransactionStatusSettled, 0, fraud.DecisionApprove, ""},
+		{"reviewed", 60000, "Jeweller", "shopping", models.TransactionStatusInReview, 30, fraud.DecisionReview, "first purchase at Jeweller"},
+		{"declined", 1000, "Casino", "gambling", models.TransactionStatusDeclined, 100, fraud.DecisionDecline, "category gambling is blocked"},
+	}
+	for _, tt := range tests {
+		p := payment(card, tt.amount, tt.merchant)
+		p.Category = tt.category
+
+		transaction, err := service.ProcessCardPayload(ctx, p)
+		if err != nil {
+			t.Fatalf("%s: payment failed: %v", tt.name, err)
+		}
+		if transaction.Status != tt.status {
+			t.Errorf("%s: payment status = %s, want %s", tt.name, transaction.Status, tt.status)
+		}
+
+		// The screening result is stored whatever the decision
+		stored, err := store.GetTransaction(ctx, transaction.ID)
+		if err != nil {
+			t.Fatalf("%s: failed to get transaction: %v", tt.name, err)
+		}
+		if stored.FraudScore != tt.score || stored.FraudDecision != tt.decision {
+			t.Errorf("%s: stored score %d and %s, want %d and %s", tt.name, stored.FraudScore, stored.FraudDecision, tt.score, tt.decision)
+		}
+		if reasons := strings.Join(stored.FraudReasons, "; "); !strings.Contains(reasons, tt.reason) || (tt.reason == "") != (reasons == "") {
+			t.Errorf("%s: stored reasons %q, want %q", tt.name, reasons, tt.reason)
+		}
+		if tt.status == models.TransactionStatusDeclined && !strings.Contains(stored.DeclineReason, tt.reason) {
+			t.Errorf("%s: decline reason %q, want it to mention %q", tt.name, stored.DeclineReason, tt.reason)
+		}
+	}
+	assertBalance(t, service, card.UserID, -1000, 60000)
+}
@@ -0,0 +1,168 @@
+package ledger
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/araesf/ledgertime/internal/models"
+	"github.com/araesf/ledgertime/internal/vault"
+)
+
+var (
+	// ErrIdempotencyKeyReused is returned when a key is replayed with a different request
+	ErrIdempotencyKeyReused = errors.New("idempotency key reused with a different request")
+
+	// ErrIdempotencyInProgress is returned for a key claimed but never completed, which only a
+	// claim committed outside a unit of work can leave behind
+	ErrIdempotencyInProgress = errors.New("request with this idempotency key is still in progress")
+)
+
+// Idempotent operations. Keys are unique per operation, so keys that clients choose and keys
+// derived from Kafka messages are kept apart and can never replay or block each other.
+const (
+	operationPayment      = "payment"
+	operationKafkaPayment = "kafka_payment"
+)
+
+// ProcessCardPayloadIdempotent processes a card payment at most once per client-supplied key,
+// as sent to the REST and GraphQL APIs. Replays of a completed key return the originally
+// stored transaction with replayed=true. An empty key disables idempotency.
+func (s *Service) ProcessCardPayloadIdempotent(ctx context.Context, key string, payload models.CardPayload) (*models.Transaction, bool, error) {
+	return s.processCardPayloadIdempotent(ctx, operationPayment, key, payload)
+}
+
+// ProcessKafkaPayloadIdempotent processes a card payment consumed from Kafka at most once per
+// key derived from the message, like ProcessCardPayloadIdempotent
+func (s *Service) ProcessKafkaPayloadIdempotent(ctx context.Context, key string, payload models.CardPayload) (*models.Transaction, bool, error) {
+	return s.processCardPayloadIdempotent(ctx, operationKafkaPayment, key, payload)
+}
+
+func (s *Service) processCardPayloadIdempotent(ctx context.Context, operation, key string, payload models.CardPayload) (*models.Transaction, bool, error) {
+	if key == "" {
+		transaction, err := s.ProcessCardPayload(ctx, payload)
+		return transaction, false, err
+	}
+
+	s.logger.Info("Processing card payload", "card", payloadCardRef(payload), "amount", payload.Amount, "idempotency_key", key)
+
+	transaction, replayed, err := s.runIdempotent(ctx, operation, key, s.paymentFingerprint(payload), func(ctx context.Context) (*models.Transaction, error) {
+		return s.processPayment(ctx, payload)
+	})
+	if err != nil || replayed {
+		return transaction, replayed, err
+	}
+	if transaction.Status == models.TransactionStatusSettled {
+		s.checkBudgets(ctx, transaction)
+	}
+
+	s.logger.Info("Transaction processed", "transaction_id", transaction.ID, "status", transaction.Status)
+	return transaction, false, nil
+}
+
+// runIdempotent claims the key, runs fn and stores its result for later replays in one unit of
+// work. A concurrent request with the same key waits on the claim until the unit ends, then
+// replays its result; if fn fails, the claim is rolled back with everything else.
+func (s *Service) runIdempotent(ctx context.Context, operation, key, requestHash string, fn func(ctx context.Context) (*models.Transaction, error)) (*models.Transaction, bool, error) {
+	var replayed bool
+	transaction, err := runInUnitOfWork(ctx, s.store, func(ctx context.Context) (*models.Transaction, error) {
+		replayed = false
+
+		now := time.Now()
+		claim := &models.IdempotencyRecord{
+			Key:         key,
+			Operation:   operation,
+			RequestHash: requestHash,
+			Status:      models.IdempotencyStatusInProgress,
+			LockedAt:    now,
+		}
+
+		existing, claimed, err := s.store.ClaimIdempotencyKey(ctx, claim)
+		if err != nil {
+			return nil, err
+		}
+
+		if !claimed {
+			if existing.RequestHash != requestHash {
+				return nil, fmt.Errorf("%w: %s", ErrIdempotencyKeyReused, key)
+			}
+			if existing.Status != models.IdempotencyStatusCompleted {
+				return nil, fmt.Errorf("%w: %s", ErrIdempotencyInProgress, key)
+			}
+
+			transaction := &models.Transaction{}
+			if err := json.Unmarshal(existing.Response, transaction); err != nil {
+				return nil, fmt.Errorf("failed to decode stored response: %w", err)
+			}
+			replayed = true
+			return transaction, nil
+		}
+
+		transaction, err := fn(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		response, err := json.Marshal(transaction)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode response: %w", err)
+		}
+
+		if err := s.store.CompleteIdempotencyKey(ctx, operation, key, transaction.ID, response); err != nil {
+			return nil, err
+		}
+		return transaction, nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	if replayed {
+		s.logger.Info("Replaying idempotent request", "operation", operation, "key", key, "transaction_id", transaction.ID)
+	}
+	return transaction, replayed, nil
+}
+
+// paymentFingerprint hashes the fields that identify a charge. The timestamp is left out
+// because some callers stamp it at request time, which would make every retry look new.
+// Card numbers are replaced by their lookup hash so they are never hashed in the clear.
+func (s *Service) paymentFingerprint(payload models.CardPayload) string {
+	card := payload.CardToken
+	if card == "" {
+		normalized, err := vault.Normalize(payload.CardNumber)
+		if err != nil {
+			normalized = payload.CardNumber
+		}
+		card = s.vault.LookupHash(normalized)
+	}
+
+	fingerprint := struct {
+		Card             string `json:"card"`
+		Amount           int64  `json:"amount"`
+		Currency         string `json:"currency"`
+		MerchantName     string `json:"merchant_name"`
+		MerchantCity     string `json:"merchant_city"`
+		MerchantCountry  string `json:"merchant_country"`
+		MCC              string `json:"mcc"`
+		Category         string `json:"category"`
+		NetworkReference string `json:"network_reference"`
+	}{
+		Card:             card,
+		Amount:           payload.Amount,
+		Currency:         payload.Currency,
+		MerchantName:     payload.MerchantName,
+		MerchantCity:     payload.MerchantCity,
+		MerchantCountry:  payload.MerchantCountry,
+		MCC:              payload.MCC,
+		Category:         payload.Category,
+		NetworkReference: payload.NetworkReference,
+	}
+
+	data, _ := json.Marshal(fingerprint)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
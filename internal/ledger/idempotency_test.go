@@ -0,0 +1,59 @@
+package ledger_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/araesf/ledgertime/internal/ledger"
+)
+
+func TestIdempotentPaymentReplays(t *testing.T) {
+	service, store := newTestService(t)
+	card := newTestCard(t, service, store)
+	ctx := context.Background()
+
+	first, replayed, err := service.ProcessCardPayloadIdempotent(ctx, "key-1", payment(card, 1200, "Bakery"))
+	if err != nil || replayed {
+		t.Fatalf("first payment: replayed=%v, err=%v", replayed, err)
+	}
+
+	second, replayed, err := service.ProcessCardPayloadIdempotent(ctx, "key-1", payment(card, 1200, "Bakery"))
+	if err != nil || !replayed {
+		t.Fatalf("retried payment: replayed=%v, err=%v", replayed, err)
+	}
+	if second.ID != first.ID {
+		t.Errorf("replay returned transaction %s, want %s", second.ID, first.ID)
+	}
+	assertBalance(t, service, card.UserID, -1200, 0)
+
+	_, _, err = service.ProcessCardPayloadIdempotent(ctx, "key-1", payment(card, 9900, "Bakery"))
+	if !errors.Is(err, ledger.ErrIdempotencyKeyReused) {
+		t.Errorf("reusing the key for another payment: err=%v, want %v", err, ledger.ErrIdempotencyKeyReused)
+	}
+}
+
+func TestKafkaKeysDoNotCollideWithClientKeys(t *testing.T) {
+	service, store := newTestService(t)
+	card := newTestCard(t, service, store)
+	ctx := context.Background()
+
+	consumed, replayed, err := service.ProcessKafkaPayloadIdempotent(ctx, "network:XYZ", payment(card, 1200, "Bakery"))
+	if err != nil || replayed {
+		t.Fatalf("Kafka payment: replayed=%v, err=%v", replayed, err)
+	}
+
+	client, replayed, err := service.ProcessCardPayloadIdempotent(ctx, "network:XYZ", payment(card, 4500, "Bookshop"))
+	if err != nil || replayed {
+		t.Fatalf("client payment with the same key: replayed=%v, err=%v", replayed, err)
+	}
+	if client.ID == consumed.ID {
+		t.Error("client key replayed the Kafka payment")
+	}
+
+	redelivered, replayed, err := service.ProcessKafkaPayloadIdempotent(ctx, "network:XYZ", payment(card, 1200, "Bakery"))
+	if err != nil || !replayed || redelivered.ID != consumed.ID {
+		t.Errorf("redelivered Kafka payment: replayed=%v, err=%v; want a replay of %s", replayed, err, consumed.ID)
+	}
+	assertBalance(t, service, card.UserID, -5700, 0)
+}
@@ -0,0 +1,227 @@
+package ledger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/araesf/ledgertime/internal/models"
+	"github.com/araesf/ledgertime/internal/money"
+)
+
+// ErrSpendingLimitExceeded is returned when a transaction would exceed a card or user spending limit
+var ErrSpendingLimitExceeded = errors.New("spending limit exceeded")
+
+// Rolling spending windows
+const (
+	dailyWindow   = 24 * time.Hour
+	weeklyWindow  = 7 * 24 * time.Hour
+	monthlyWindow = 30 * 24 * time.Hour
+)
+
+// GetCardLimits retrieves the spending limits of a card
+func (s *Service) GetCardLimits(ctx context.Context, cardID string) (*models.SpendingLimits, error) {
+	return s.store.GetSpendingLimits(ctx, models.LimitScopeCard, cardID)
+}
+
+// SetCardLimits replaces the spending limits of a card. Card limits are always in the card's billing currency.
+func (s *Service) SetCardLimits(ctx context.Context, cardID string, limits *models.SpendingLimits) (*models.SpendingLimits, error) {
+	s.logger.Info("Setting card limits", "card_id", cardID)
+
+	card, err := s.store.GetCard(ctx, cardID)
+	if err != nil {
+		return nil, err
+	}
+
+	limits.Scope, limits.ScopeID, limits.Currency = models.LimitScopeCard, card.ID, card.Currency
+	return s.setLimits(ctx, limits)
+}
+
+// GetUserLimits retrieves the spending limits of a user
+func (s *Service) GetUserLimits(ctx context.Context, userID string) (*models.SpendingLimits, error) {
+	return s.store.GetSpendingLimits(ctx, models.LimitScopeUser, userID)
+}
+
+// SetUserLimits replaces the spending limits of a user across all their cards. An empty
+// currency selects the base currency.
+func (s *Service) SetUserLimits(ctx context.Context, userID string, limits *models.SpendingLimits) (*models.SpendingLimits, error) {
+	s.logger.Info("Setting user limits", "user_id", userID)
+
+	if _, err := s.store.GetUser(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	currency, err := s.walletCurrency(limits.Currency)
+	if err != nil {
+		return nil, err
+	}
+
+	limits.Scope, limits.ScopeID, limits.Currency = models.LimitScopeUser, userID, currency
+	return s.setLimits(ctx, limits)
+}
+
+// setLimits validates limits before storing them
+func (s *Service) setLimits(ctx context.Context, limits *models.SpendingLimits) (*models.SpendingLimits, error) {
+	if err := ValidateSpendingLimits(limits); err != nil {
+		return nil, err
+	}
+
+	limits.UpdatedAt = time.Now()
+	if err := s.store.SetSpendingLimits(ctx, limits); err != nil {
+		return nil, err
+	}
+
+	return limits, nil
+}
+
+// ValidateSpendingLimits checks that limits are non-negative and normalizes their categories
+func ValidateSpendingLimits(limits *models.SpendingLimits) error {
+	for _, l := range []struct {
+		name   string
+		amount int64
+	}{
+		{"single transaction", limits.SingleTransaction},
+		{"daily", limits.Daily},
+		{"weekly", limits.Weekly},
+		{"monthly", limits.Monthly},
+	} {
+		if l.amount < 0 {
+			return fmt.Errorf("%s limit cannot be negative, got: %d", l.name, l.amount)
+		}
+	}
+
+	blocked := []string{}
+	for _, category := range limits.BlockedCategories {
+		if category = strings.ToLower(strings.TrimSpace(category)); category != "" {
+			blocked = append(blocked, category)
+		}
+	}
+	limits.BlockedCategories = blocked
+
+	caps := []models.CategoryCap{}
+	for _, c := range limits.CategoryCaps {
+		c.Category = strings.ToLower(strings.TrimSpace(c.Category))
+		if c.Category == "" {
+			return fmt.Errorf("category cap needs a category")
+		}
+		if c.Amount < 0 {
+			return fmt.Errorf("category cap for %s cannot be negative, got: %d", c.Category, c.Amount)
+		}
+		caps = append(caps, c)
+	}
+	limits.CategoryCaps = caps
+
+	return nil
+}
+
+// checkSpendingLimits ensures the transaction fits within its card's and user's limits. It runs
+// in the payment's unit of work and locks each set of limits before summing the spend against
+// them, so concurrent payments cannot both fit into the same headroom. Card limits are always
+// locked before user limits.
+func (s *Service) checkSpendingLimits(ctx context.Context, tx *models.Transaction) error {
+	for _, scope := range []struct{ name, id string }{
+		{models.LimitScopeCard, tx.CardID},
+		{models.LimitScopeUser, tx.UserID},
+	} {
+		limits, err := s.store.LockSpendingLimits(ctx, scope.name, scope.id)
+		if errors.Is(err, models.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := s.checkLimits(ctx, tx, limits); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkLimits applies one set of limits to a transaction. A user's cards can bill in other
+// currencies than the user's limits, so the transaction and the spend it is summed with are
+// converted into the limits' currency at the transaction's time.
+func (s *Service) checkLimits(ctx context.Context, tx *models.Transaction, limits *models.SpendingLimits) error {
+	category := strings.ToLower(tx.Category)
+	for _, blocked := range limits.BlockedCategories {
+		if blocked == category {
+			return fmt.Errorf("%w: %s blocks category %s", ErrSpendingLimitExceeded, limits.Scope, category)
+		}
+	}
+
+	amount, err := s.convertSpending(map[string]int64{tx.Currency: tx.Amount}, limits.Currency, tx.Timestamp)
+	if err != nil {
+		return err
+	}
+
+	if limits.SingleTransaction > 0 && amount > limits.SingleTransaction {
+		return fmt.Errorf("%w: %s single transaction limit %d, requested %d",
+			ErrSpendingLimitExceeded, limits.Scope, limits.SingleTransaction, amount)
+	}
+
+	windows := []struct {
+		name   string
+		limit  int64
+		window time.Duration
+	}{
+		{"daily", limits.Daily, dailyWindow},
+		{"weekly", limits.Weekly, weeklyWindow},
+		{"monthly", limits.Monthly, monthlyWindow},
+	}
+	for _, w := range windows {
+		if w.limit == 0 {
+			continue
+		}
+		if err := s.checkWindow(ctx, tx, limits, amount, "", w.name, w.limit, w.window); err != nil {
+			return err
+		}
+	}
+
+	for _, c := range limits.CategoryCaps {
+		if c.Category != category || c.Amount == 0 {
+			continue
+		}
+		if err := s.checkWindow(ctx, tx, limits, amount, c.Category, c.Category, c.Amount, monthlyWindow); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkWindow ensures spend within a rolling window, including the transaction's amount in the
+// limits' currency, stays within limit
+func (s *Service) checkWindow(ctx context.Context, tx *models.Transaction, limits *models.SpendingLimits, amount int64, category, name string, limit int64, window time.Duration) error {
+	spending, err := s.store.GetSpendingSince(ctx, limits.Scope, limits.ScopeID, category, tx.Timestamp.Add(-window), tx.ID)
+	if err != nil {
+		return err
+	}
+
+	spent, err := s.convertSpending(spending, limits.Currency, tx.Timestamp)
+	if err != nil {
+		return err
+	}
+
+	if spent+amount > limit {
+		return fmt.Errorf("%w: %s %s limit %d, spent %d, requested %d",
+			ErrSpendingLimitExceeded, limits.Scope, name, limit, spent, amount)
+	}
+
+	return nil
+}
+
+// convertSpending totals amounts keyed by currency in another currency, at the rates effective at the given time
+func (s *Service) convertSpending(amounts map[string]int64, currency string, at time.Time) (int64, error) {
+	var total int64
+	for from, amount := range amounts {
+		converted, _, err := s.rates.Convert(money.Money{Amount: amount, Currency: from}, currency, at)
+		if err != nil {
+			return 0, fmt.Errorf("failed to convert spending to %s: %w", currency, err)
+		}
+		total += converted.Amount
+	}
+	return total, nil
+}
@@ -0,0 +1,216 @@
+package ledger_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/araesf/ledgertime/internal/ledger"
+	"github.com/araesf/ledgertime/internal/models"
+)
+
+func TestUserLimitsCountCardsInOtherCurrencies(t *testing.T) {
+	service, store := newTestService(t)
+	usdCard := newTestCard(t, service, store)
+	ctx := context.Background()
+
+	eurCard, err := service.RegisterCard(ctx, usdCard.UserID, "4111111111111111", "", "EUR")
+	if err != nil {
+		t.Fatalf("failed to register card: %v", err)
+	}
+	eurPayment := func(amount int64) models.CardPayload {
+		p := payment(eurCard, amount, "Boulangerie")
+		p.Currency = "EUR"
+		return p
+	}
+
+	if _, err := service.SetUserLimits(ctx, usdCard.UserID, &models.SpendingLimits{SingleTransaction: 15000, Daily: 20000}); err != nil {
+		t.Fatalf("failed to set user limits: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		payload models.CardPayload
+		want    string
+	}{
+		// EUR 141.00 is USD 150.87, over the single transaction limit
+		{"single transaction converted", eurPayment(14100), models.TransactionStatusDeclined},
+		{"within daily limit", payment(usdCard, 10000, "Grocer"), models.TransactionStatusSettled},
+		// EUR 90.00 is USD 96.30, bringing the day to USD 196.30
+		{"daily limit converted", eurPayment(9000), models.TransactionStatusSettled},
+		// EUR 4.00 is USD 4.28, over the daily limit once the earlier EUR spend is converted too
+		{"daily limit exceeded across currencies", eurPayment(400), models.TransactionStatusDeclined},
+	}
+
+	for _, tt := range tests {
+		transaction, err := service.ProcessCardPayload(ctx, tt.payload)
+		if err != nil {
+			t.Fatalf("%s: payment failed: %v", tt.name, err)
+		}
+		if transaction.Status != tt.want {
+			t.Errorf("%s: payment status = %s (%s), want %s", tt.name, transaction.Status, transaction.DeclineReason, tt.want)
+		}
+	}
+}
+
+func TestCardLimitWindows(t *testing.T) {
+	// Midnight UTC on Monday 1 June 2026 starts a day, a week and a month
+	boundary := time.Date(2026, time.June, 1, 0, 0, 0, 0, time.UTC)
+	first := boundary.Add(-time.Hour)
+
+	tests := []struct {
+		name   string
+		limits models.SpendingLimits
+		window time.Duration
+	}{
+		{"daily", models.SpendingLimits{Daily: 10000}, 24 * time.Hour},
+		{"weekly", models.SpendingLimits{Weekly: 10000}, 7 * 24 * time.Hour},
+		{"monthly", models.SpendingLimits{Monthly: 10000}, 30 * 24 * time.Hour},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, store := newTestService(t)
+			card := newTestCard(t, service, store)
+			if _, err := service.SetCardLimits(context.Background(), card.ID, &tt.limits); err != nil {
+				t.Fatalf("failed to set card limits: %v", err)
+			}
+
+			// Windows roll with the payment's time instead of resetting at midnight UTC
+			payments := []struct {
+				name   string
+				amount int64
+				at     time.Time
+				want   string
+			}{
+				{"before the boundary", 6000, first, models.TransactionStatusSettled},
+				{"after the boundary", 5000, boundary, models.TransactionStatusDeclined},
+				{"after the boundary in another zone", 5000, boundary.In(time.FixedZone("UTC+2", 2*60*60)), models.TransactionStatusDeclined},
+				{"at the end of the window", 5000, first.Add(tt.window), models.TransactionStatusDeclined},
+				{"past the end of the window", 5000, first.Add(tt.window + time.Second), models.TransactionStatusSettled},
+			}
+			for _, p := range payments {
+				transaction := spend(t, service, card, p.amount, "", p.at)
+				if transaction.Status != p.want {
+					t.Errorf("%s: payment status = %s (%s), want %s", p.name, transaction.Status, transaction.DeclineReason, p.want)
+				}
+			}
+		})
+	}
+}
+
+func TestCardCategoryLimits(t *testing.T) {
+	service, store := newTestService(t)
+	card := newTestCard(t, service, store)
+	ctx := context.Background()
+	now := time.Now()
+
+	limits, err := service.SetCardLimits(ctx, card.ID, &models.SpendingLimits{
+		BlockedCategories: []string{" Gambling ", ""},
+		CategoryCaps:      []models.CategoryCap{{Category: "Dining", Amount: 5000}},
+	})
+	if err != nil {
+		t.Fatalf("failed to set card limits: %v", err)
+	}
+	if len(limits.BlockedCategories) != 1 || limits.BlockedCategories[0] != "gambling" || limits.CategoryCaps[0].Category != "dining" {
+		t.Errorf("limits = %v blocked, %v caps; want normalized categories", limits.BlockedCategories, limits.CategoryCaps)
+	}
+
+	tests := []struct {
+		name     string
+		amount   int64
+		category string
+		at       time.Time
+		want     string
+	}{
+		{"blocked category", 100, "gambling", now, models.TransactionStatusDeclined},
+		{"blocked category in other case", 100, "GAMBLING", now, models.TransactionStatusDeclined},
+		{"capped category before the cap window", 4000, "dining", now.Add(-31 * 24 * time.Hour), models.TransactionStatusSettled},
+		{"capped category within cap", 3000, "dining", now, models.TransactionStatusSettled},
+		{"capped category over cap", 2500, "dining", now, models.TransactionStatusDeclined},
+		{"other category", 2500, "groceries", now, models.TransactionStatusSettled},
+		{"capped category up to cap", 2000, "Dining", now, models.TransactionStatusSettled},
+	}
+	for _, tt := range tests {
+		transaction := spend(t, service, card, tt.amount, tt.category, tt.at)
+		if transaction.Status != tt.want {
+			t.Errorf("%s: payment status = %s (%s), want %s", tt.name, transaction.Status, transaction.DeclineReason, tt.want)
+		}
+	}
+}
+
+func TestLimitsExcludeOwnTransaction(t *testing.T) {
+	service, store := newTestService(t)
+	card := newTestCard(t, service, store)
+	ctx := context.Background()
+
+	if _, err := service.SetCardLimits(ctx, card.ID, &models.SpendingLimits{Daily: 10000}); err != nil {
+		t.Fatalf("failed to set card limits: %v", err)
+	}
+
+	authorization, err := service.Authorize(ctx, payment(card, 6000, "Hotel"))
+	if err != nil {
+		t.Fatalf("authorization failed: %v", err)
+	}
+
+	// The authorization counts towards the window once, at its new total
+	incremented, err := service.IncrementAuthorization(ctx, authorization.ID, 4000)
+	if err != nil {
+		t.Fatalf("increment up to the daily limit failed: %v", err)
+	}
+	if incremented.AuthorizedAmount != 10000 {
+		t.Errorf("authorized amount = %d, want 10000", incremented.AuthorizedAmount)
+	}
+
+	if _, err := service.IncrementAuthorization(ctx, authorization.ID, 1); !errors.Is(err, ledger.ErrSpendingLimitExceeded) {
+		t.Errorf("increment over the daily limit: err=%v, want %v", err, ledger.ErrSpendingLimitExceeded)
+	}
+}
+
+func TestReplacementCardInheritsSpend(t *testing.T) {
+	service, store := newTestService(t)
+	old := newTestCard(t, service, store)
+	ctx := context.Background()
+	now := time.Now()
+
+	if _, err := service.SetCardLimits(ctx, old.ID, &models.SpendingLimits{Daily: 10000}); err != nil {
+		t.Fatalf("failed to set card limits: %v", err)
+	}
+	spend(t, service, old, 6000, "", now)
+
+	if _, err := service.ReportCard(ctx, old.ID, models.CardStatusLost, "left on the train"); err != nil {
+		t.Fatalf("failed to report card: %v", err)
+	}
+	replacement, err := service.ReplaceCard(ctx, old.ID, "4111111111111111", "")
+	if err != nil {
+		t.Fatalf("failed to replace card: %v", err)
+	}
+
+	spent, err := store.GetSpendingSince(ctx, models.LimitScopeCard, replacement.ID, "", now.Add(-time.Hour), "")
+	if err != nil {
+		t.Fatalf("failed to get spending: %v", err)
+	}
+	if spent["USD"] != 6000 {
+		t.Errorf("replacement card spending = %v, want the old card's 6000 USD", spent)
+	}
+
+	if transaction := spend(t, service, replacement, 5000, "", now); transaction.Status != models.TransactionStatusDeclined {
+		t.Errorf("payment over the inherited daily limit = %s, want %s", transaction.Status, models.TransactionStatusDeclined)
+	} else if !strings.Contains(transaction.DeclineReason, "daily limit 10000, spent 6000") {
+		t.Errorf("decline reason = %q, want the old card's spend counted", transaction.DeclineReason)
+	}
+	if transaction := spend(t, service, replacement, 4000, "", now); transaction.Status != models.TransactionStatusSettled {
+		t.Errorf("payment within the inherited daily limit = %s (%s), want %s", transaction.Status, transaction.DeclineReason, models.TransactionStatusSettled)
+	}
+
+	// The old card's window does not include its replacement's spend
+	spent, err = store.GetSpendingSince(ctx, models.LimitScopeCard, old.ID, "", now.Add(-time.Hour), "")
+	if err != nil {
+		t.Fatalf("failed to get spending: %v", err)
+	}
+	if spent["USD"] != 6000 {
+		t.Errorf("old card spending = %v, want 6000 USD", spent)
+	}
+}
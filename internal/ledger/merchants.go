@@ -0,0 +1,100 @@
+package ledger
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/araesf/ledgertime/internal/merchant"
+	"github.com/araesf/ledgertime/internal/models"
+	"github.com/google/uuid"
+)
+
+// resolveMerchant matches a raw descriptor to its canonical merchant, creating the merchant the
+// first time the descriptor's normalized name is seen. Returns nil for an empty descriptor.
+func (s *Service) resolveMerchant(ctx context.Context, descriptor, mcc, city, country string) (*models.Merchant, error) {
+	descriptor = strings.TrimSpace(descriptor)
+	if descriptor == "" {
+		return nil, nil
+	}
+
+	key := merchant.Normalize(descriptor, city, country)
+	if key == "" {
+		// Nothing recognizable is left, so the descriptor is its own merchant
+		key = strings.ToUpper(descriptor)
+	}
+
+	now := time.Now()
+	found, err := s.store.FindOrCreateMerchant(ctx, &models.Merchant{
+		ID:             uuid.New().String(),
+		Name:           merchant.DisplayName(key),
+		NormalizedName: key,
+		MCC:            mcc,
+		City:           city,
+		Country:        country,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return found, nil
+}
+
+// GetMerchant retrieves a merchant
+func (s *Service) GetMerchant(ctx context.Context, id string) (*models.Merchant, error) {
+	return s.store.GetMerchant(ctx, id)
+}
+
+// ListMerchants retrieves merchants by name
+func (s *Service) ListMerchants(ctx context.Context, limit, offset int) ([]*models.Merchant, error) {
+	return s.store.GetMerchants(ctx, limit, offset)
+}
+
+// UpdateMerchant replaces a merchant's name, MCC, location and logo. Transactions already
+// booked keep the details they were recorded with.
+func (s *Service) UpdateMerchant(ctx context.Context, id string, update *models.Merchant) (*models.Merchant, error) {
+	s.logger.Info("Updating merchant", "merchant_id", id)
+
+	existing, err := s.store.GetMerchant(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ValidateMerchant(update); err != nil {
+		return nil, err
+	}
+
+	existing.Name = update.Name
+	existing.MCC = update.MCC
+	existing.City = update.City
+	existing.Country = update.Country
+	existing.LogoURL = update.LogoURL
+
+	if err := s.store.UpdateMerchant(ctx, existing); err != nil {
+		return nil, err
+	}
+	return existing, nil
+}
+
+// ValidateMerchant checks a merchant's details and normalizes its country code
+func ValidateMerchant(m *models.Merchant) error {
+	m.Name = strings.TrimSpace(m.Name)
+	if m.Name == "" {
+		return fmt.Errorf("merchant name cannot be empty")
+	}
+
+	if m.MCC != "" {
+		if _, err := merchant.LookupMCC(m.MCC); err != nil {
+			return err
+		}
+	}
+
+	m.Country = strings.ToUpper(strings.TrimSpace(m.Country))
+	if m.Country != "" && len(m.Country) != 2 {
+		return fmt.Errorf("merchant country must be an ISO 3166-1 alpha-2 code, got: %q", m.Country)
+	}
+
+	return nil
+}
@@ -0,0 +1,53 @@
+package ledger
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/araesf/ledgertime/internal/models"
+	"github.com/google/uuid"
+)
+
+// cardEvents maps card statuses to the event announcing a change to them
+var cardEvents = map[string]string{
+	models.CardStatusActive:  models.EventCardUnfrozen,
+	models.CardStatusFrozen:  models.EventCardFrozen,
+	models.CardStatusLost:    models.EventCardReported,
+	models.CardStatusStolen:  models.EventCardReported,
+	models.CardStatusClosed:  models.EventCardClosed,
+	models.CardStatusExpired: models.EventCardExpired,
+}
+
+// recordEvent saves a domain event in the outbox for the relay to publish. It must run in the
+// unit of work of the change the event announces.
+func (s *Service) recordEvent(ctx context.Context, aggregateType, aggregateID, eventType string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s event: %w", eventType, err)
+	}
+
+	now := time.Now()
+	event := &models.OutboxEvent{
+		ID:            uuid.New().String(),
+		AggregateType: aggregateType,
+		AggregateID:   aggregateID,
+		EventType:     eventType,
+		Payload:       data,
+		NextAttemptAt: now,
+		CreatedAt:     now,
+	}
+	if err := s.store.CreateOutboxEvent(ctx, event); err != nil {
+		s.logger.Error("Failed to record event", "error", err, "event_type", eventType, "aggregate_id", aggregateID)
+		return fmt.Errorf("failed to record %s event: %w", eventType, err)
+	}
+
+	return nil
+}
+
+// recordTransactionCreated announces a new transaction
+func (s *Service) recordTransactionCreated(ctx context.Context, tx *models.Transaction) error {
+	return s.recordEvent(ctx, models.AggregateTransaction, tx.ID, models.EventTransactionCreated,
+		&models.TransactionEventPayload{Transaction: tx})
+}
@@ -0,0 +1,234 @@
+package ledger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/araesf/ledgertime/internal/config"
+	"github.com/araesf/ledgertime/internal/models"
+	"github.com/araesf/ledgertime/internal/money"
+)
+
+// Processor decides whether a transaction may proceed. Returning a *DeclineError declines it and
+// a *ReviewError parks it for manual review. Any other error means the processor could not decide:
+// the transaction is rolled back and the error returned, so the caller can retry.
+type Processor interface {
+	Name() string
+	Process(ctx context.Context, tx *models.Transaction) error
+}
+
+// DeclineError explains why a processor declined a transaction
+type DeclineError struct {
+	Processor string
+	Reason    string
+}
+
+func (e *DeclineError) Error() string {
+	return fmt.Sprintf("declined by %s: %s", e.Processor, e.Reason)
+}
+
+// ReviewError asks for a transaction to be reviewed by an analyst instead of declined
+type ReviewError struct {
+	Processor string
+	Reason    string
+}
+
+func (e *ReviewError) Error() string {
+	return fmt.Sprintf("review requested by %s: %s", e.Processor, e.Reason)
+}
+
+// Chain runs processors in order and stops at the first decline. A review request does not stop
+// the chain, so a later decline still wins over it.
+type Chain []Processor
+
+// Name lists the processors in the chain
+func (c Chain) Name() string {
+	names := make([]string, len(c))
+	for i, p := range c {
+		names[i] = p.Name()
+	}
+	return "chain(" + strings.Join(names, ",") + ")"
+}
+
+// Process runs every processor in the chain
+func (c Chain) Process(ctx context.Context, tx *models.Transaction) error {
+	var review error
+	for _, p := range c {
+		err := p.Process(ctx, tx)
+		var r *ReviewError
+		if errors.As(err, &r) {
+			if review == nil {
+				review = err
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return review
+}
+
+// LimitProcessor declines transactions above a fixed amount. It is deterministic and is the default processor.
+type LimitProcessor struct {
+	MaxAmount int64            // In minor units of Currency; zero disables the limit
+	Currency  string           // ISO 4217 currency of MaxAmount
+	Rates     *money.RateTable // Converts transactions in other currencies
+}
+
+// Name identifies the processor
+func (p *LimitProcessor) Name() string {
+	return "limit"
+}
+
+// Process declines transactions over the limit, converting them into the limit's currency at
+// the rate effective at the transaction's time
+func (p *LimitProcessor) Process(ctx context.Context, tx *models.Transaction) error {
+	if p.MaxAmount <= 0 {
+		return nil
+	}
+
+	amount := tx.Amount
+	if tx.Currency != p.Currency {
+		if p.Rates == nil {
+			return fmt.Errorf("failed to convert amount to %s: %w", p.Currency, money.ErrNoRate)
+		}
+		converted, _, err := p.Rates.Convert(money.Money{Amount: tx.Amount, Currency: tx.Currency}, p.Currency, tx.Timestamp)
+		if err != nil {
+			return fmt.Errorf("failed to convert amount to %s: %w", p.Currency, err)
+		}
+		amount = converted.Amount
+	}
+
+	if amount > p.MaxAmount {
+		return &DeclineError{Processor: p.Name(), Reason: fmt.Sprintf("amount %d %s exceeds limit %d %s", amount, p.Currency, p.MaxAmount, p.Currency)}
+	}
+	return nil
+}
+
+// SimulatorProcessor adds latency and random failures for load testing.
+// A fixed seed makes the failure sequence reproducible.
+type SimulatorProcessor struct {
+	Latency     time.Duration
+	FailureRate float64 // Between 0 and 1
+
+	mu  sync.Mutex
+	rng *rand.Rand
+}
+
+// NewSimulatorProcessor creates a simulator with its own seeded random source
+func NewSimulatorProcessor(latency time.Duration, failureRate float64, seed int64) *SimulatorProcessor {
+	return &SimulatorProcessor{
+		Latency:     latency,
+		FailureRate: failureRate,
+		rng:         rand.New(rand.NewSource(seed)),
+	}
+}
+
+// Name identifies the processor
+func (p *SimulatorProcessor) Name() string {
+	return "simulator"
+}
+
+// Process waits for the configured latency and fails at the configured rate. The wait ends
+// early with the context's error if the context is done first.
+func (p *SimulatorProcessor) Process(ctx context.Context, tx *models.Transaction) error {
+	if p.Latency > 0 {
+		timer := time.NewTimer(p.Latency)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	p.mu.Lock()
+	roll := p.rng.Float64()
+	p.mu.Unlock()
+
+	if roll < p.FailureRate {
+		return &DeclineError{Processor: p.Name(), Reason: "processing failed due to simulated network error"}
+	}
+	return nil
+}
+
+// IssuerStub stands in for the downstream issuer. Like most issuer sandboxes it answers
+// deterministically from the last two digits of the amount:
+//
+//	xx51  insufficient funds
+//	xx05  do not honor
+//	xx54  expired card
+//
+// Every other amount is approved.
+type IssuerStub struct{}
+
+// issuerResponses maps amount suffixes to issuer decline reasons
+var issuerResponses = map[int64]string{
+	51: "insufficient funds",
+	5:  "do not honor",
+	54: "expired card",
+}
+
+// Name identifies the processor
+func (p *IssuerStub) Name() string {
+	return "issuer"
+}
+
+// Process returns the sandbox response for the amount
+func (p *IssuerStub) Process(ctx context.Context, tx *models.Transaction) error {
+	if reason, ok := issuerResponses[tx.Amount%100]; ok {
+		return &DeclineError{Processor: p.Name(), Reason: reason}
+	}
+	return nil
+}
+
+// NewProcessor builds the processor chain named in the configuration. The limit processor
+// converts amounts with rates. Processors that need other dependencies, such as the fraud
+// processor, are passed in as plugins and referenced by name.
+func NewProcessor(cfg config.ProcessorConfig, rates *money.RateTable, plugins ...Processor) (Processor, error) {
+	currency, err := money.NormalizeCurrency(cfg.Currency)
+	if err != nil {
+		return nil, fmt.Errorf("invalid limit processor currency: %w", err)
+	}
+	limit := &LimitProcessor{MaxAmount: cfg.MaxAmount, Currency: currency, Rates: rates}
+
+	if len(cfg.Chain) == 0 {
+		return limit, nil
+	}
+
+	registered := make(map[string]Processor, len(plugins))
+	for _, p := range plugins {
+		registered[p.Name()] = p
+	}
+
+	var chain Chain
+	for _, name := range cfg.Chain {
+		name = strings.TrimSpace(name)
+		if p, ok := registered[name]; ok {
+			chain = append(chain, p)
+			continue
+		}
+
+		switch name {
+		case "limit":
+			chain = append(chain, limit)
+		case "simulator":
+			if cfg.SimulatorFailureRate < 0 || cfg.SimulatorFailureRate > 1 {
+				return nil, fmt.Errorf("simulator failure rate must be between 0 and 1, got: %v", cfg.SimulatorFailureRate)
+			}
+			chain = append(chain, NewSimulatorProcessor(cfg.SimulatorLatency, cfg.SimulatorFailureRate, cfg.SimulatorSeed))
+		case "issuer":
+			chain = append(chain, &IssuerStub{})
+		default:
+			return nil, fmt.Errorf("unknown processor: %q", name)
+		}
+	}
+
+	return chain, nil
+}
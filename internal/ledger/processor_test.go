@@ -0,0 +1,146 @@
+package ledger_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/araesf/ledgertime/internal/ledger"
+	"github.com/araesf/ledgertime/internal/models"
+	"github.com/araesf/ledgertime/internal/money"
+)
+
+// failingProcessor fails without deciding, like a processor whose store is unavailable
+type failingProcessor struct{ err error }
+
+func (failingProcessor) Name() string {
+	return "failing"
+}
+
+func (p failingProcessor) Process(ctx context.Context, tx *models.Transaction) error {
+	return p.err
+}
+
+func TestProcessorFailureRollsBackPayment(t *testing.T) {
+	service, store := newTestServiceWithProcessor(t, failingProcessor{err: errors.New("connection reset")})
+	card := newTestCard(t, service, store)
+	ctx := context.Background()
+
+	if _, err := service.ProcessCardPayload(ctx, payment(card, 1500, "Bakery")); err == nil {
+		t.Fatal("payment succeeded although the processor failed")
+	}
+	if _, err := service.Authorize(ctx, payment(card, 1500, "Bakery")); err == nil {
+		t.Fatal("authorization succeeded although the processor failed")
+	}
+
+	transactions, err := service.GetUserTransactions(ctx, card.UserID, 10, 0)
+	if err != nil {
+		t.Fatalf("failed to get transactions: %v", err)
+	}
+	if len(transactions) != 0 {
+		t.Errorf("got %d transactions, want the failed ones rolled back", len(transactions))
+	}
+	assertBalance(t, service, card.UserID, 0, 0)
+}
+
+func TestProcessorDeclineIsRecorded(t *testing.T) {
+	service, store := newTestServiceWithProcessor(t, &ledger.LimitProcessor{MaxAmount: 1000, Currency: "USD"})
+	card := newTestCard(t, service, store)
+
+	declined, err := service.ProcessCardPayload(context.Background(), payment(card, 1500, "Bakery"))
+	if err != nil {
+		t.Fatalf("payment failed: %v", err)
+	}
+	if declined.Status != models.TransactionStatusDeclined || !strings.Contains(declined.DeclineReason, "declined by limit") {
+		t.Errorf("payment is %s with reason %q, want declined by limit", declined.Status, declined.DeclineReason)
+	}
+	assertBalance(t, service, card.UserID, 0, 0)
+}
+
+// stubProcessor returns err for every transaction and records that it ran
+type stubProcessor struct {
+	name string
+	err  error
+	ran  *[]string
+}
+
+func (p stubProcessor) Name() string {
+	return p.name
+}
+
+func (p stubProcessor) Process(ctx context.Context, tx *models.Transaction) error {
+	*p.ran = append(*p.ran, p.name)
+	return p.err
+}
+
+func TestChain(t *testing.T) {
+	review := &ledger.ReviewError{Processor: "review", Reason: "flagged"}
+	decline := &ledger.DeclineError{Processor: "decline", Reason: "blocked"}
+	failure := errors.New("connection reset")
+
+	tests := []struct {
+		name string
+		errs []error
+		want error
+		ran  int
+	}{
+		{"all approve", []error{nil, nil}, nil, 2},
+		{"review keeps going", []error{review, nil}, review, 2},
+		{"a later decline wins over a review", []error{review, decline, nil}, decline, 2},
+		{"decline stops the chain", []error{decline, review}, decline, 1},
+		{"failure stops the chain", []error{nil, failure, decline}, failure, 2},
+	}
+	for _, tt := range tests {
+		var ran []string
+		chain := make(ledger.Chain, len(tt.errs))
+		for i, err := range tt.errs {
+			chain[i] = stubProcessor{name: fmt.Sprintf("p%d", i), err: err, ran: &ran}
+		}
+
+		if err := chain.Process(context.Background(), &models.Transaction{}); err != tt.want {
+			t.Errorf("%s: Process() = %v, want %v", tt.name, err, tt.want)
+		}
+		if len(ran) != tt.ran {
+			t.Errorf("%s: ran %v, want the first %d", tt.name, ran, tt.ran)
+		}
+	}
+}
+
+func TestLimitProcessorConvertsCurrencies(t *testing.T) {
+	rates, err := money.LoadRates("../../data/fx_rates.json")
+	if err != nil {
+		t.Fatalf("failed to load rates: %v", err)
+	}
+	limit := &ledger.LimitProcessor{MaxAmount: 1000, Currency: "USD", Rates: rates}
+	at := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		amount   int64
+		currency string
+		declined bool
+	}{
+		{1000, "USD", false},
+		{1001, "USD", true},
+		{1000, "JPY", false}, // 6.80 USD
+		{2000, "JPY", true},  // 13.60 USD
+		{900, "EUR", false},  // 9.77 USD
+		{1000, "EUR", true},  // 10.85 USD
+	}
+	for _, tt := range tests {
+		err := limit.Process(context.Background(), &models.Transaction{Amount: tt.amount, Currency: tt.currency, Timestamp: at})
+		var decline *ledger.DeclineError
+		if got := errors.As(err, &decline); got != tt.declined {
+			t.Errorf("%d %s: Process() = %v, want declined=%v", tt.amount, tt.currency, err, tt.declined)
+		}
+	}
+
+	// Without a rate the processor can't decide, so the payment is rolled back rather than declined
+	err = limit.Process(context.Background(), &models.Transaction{Amount: 1, Currency: "SEK", Timestamp: at})
+	var decline *ledger.DeclineError
+	if !errors.Is(err, money.ErrNoRate) || errors.As(err, &decline) {
+		t.Errorf("payment without a rate: Process() = %v, want %v", err, money.ErrNoRate)
+	}
+}
@@ -0,0 +1,211 @@
+package ledger
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/araesf/ledgertime/internal/models"
+	"github.com/google/uuid"
+)
+
+// Refund returns part or all of a completed payment to the card holder.
+// A zero amount refunds whatever has not been refunded yet.
+func (s *Service) Refund(ctx context.Context, transactionID string, amount int64, reason string) (*models.Transaction, error) {
+	s.logger.Info("Refunding transaction", "transaction_id", transactionID, "amount", amount)
+
+	refund, err := runInUnitOfWork(ctx, s.store, func(ctx context.Context) (*models.Transaction, error) {
+		return s.refund(ctx, transactionID, amount, reason)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Transaction refunded", "transaction_id", transactionID, "refund_id", refund.ID, "amount", refund.Amount)
+	return refund, nil
+}
+
+// refund books a refund against a payment. The refunded total is read in the same unit of work,
+// so concurrent refunds can't exceed the payment.
+func (s *Service) refund(ctx context.Context, transactionID string, amount int64, reason string) (*models.Transaction, error) {
+	original, err := s.store.GetTransaction(ctx, transactionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if original.Type != models.TransactionTypePayment ||
+		!CanTransition(original.Status, models.TransactionStatusRefunded) {
+		return nil, fmt.Errorf("%w: cannot refund %s transaction in status %s", ErrInvalidTransactionState, original.Type, original.Status)
+	}
+
+	refunded, err := s.refundedAmount(ctx, original.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if amount == 0 {
+		amount = original.Amount - refunded
+	}
+	if amount <= 0 {
+		return nil, fmt.Errorf("refund amount must be positive, got: %d", amount)
+	}
+
+	refund := newLinkedTransaction(original, models.TransactionTypeRefund, amount, reason)
+	if err := s.store.CreateLinkedTransaction(ctx, refund); err != nil {
+		s.logger.Error("Failed to create refund", "error", err, "transaction_id", transactionID)
+		return nil, fmt.Errorf("failed to create refund: %w", err)
+	}
+	if err := s.recordTransactionCreated(ctx, refund); err != nil {
+		return nil, err
+	}
+
+	if err := s.bookCardRefund(ctx, refund); err != nil {
+		s.logger.Error("Failed to book refund", "error", err, "transaction_id", refund.ID)
+		return nil, fmt.Errorf("failed to book refund: %w", err)
+	}
+
+	if err := s.transition(ctx, refund, models.TransactionStatusSettled, actorRefund, reason); err != nil {
+		return nil, err
+	}
+
+	status := models.TransactionStatusPartiallyRefunded
+	if refunded+amount == original.Amount {
+		status = models.TransactionStatusRefunded
+	}
+	if err := s.transition(ctx, original, status, actorRefund, fmt.Sprintf("refund %s", refund.ID)); err != nil {
+		return nil, err
+	}
+	return refund, nil
+}
+
+// Reverse cancels a transaction in full. Authorizations have their hold released;
+// completed payments are booked back to the card holder.
+func (s *Service) Reverse(ctx context.Context, transactionID string, reason string) (*models.Transaction, error) {
+	s.logger.Info("Reversing transaction", "transaction_id", transactionID)
+
+	reversal, err := runInUnitOfWork(ctx, s.store, func(ctx context.Context) (*models.Transaction, error) {
+		return s.reverse(ctx, transactionID, reason)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Transaction reversed", "transaction_id", transactionID, "reversal_id", reversal.ID)
+	return reversal, nil
+}
+
+// reverse books a reversal and releases or books back the original payment
+func (s *Service) reverse(ctx context.Context, transactionID string, reason string) (*models.Transaction, error) {
+	original, err := s.store.GetTransaction(ctx, transactionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if original.Type != models.TransactionTypePayment ||
+		!CanTransition(original.Status, models.TransactionStatusReversed) {
+		return nil, fmt.Errorf("%w: cannot reverse %s transaction in status %s", ErrInvalidTransactionState, original.Type, original.Status)
+	}
+
+	reversal := newLinkedTransaction(original, models.TransactionTypeReversal, original.Amount, reason)
+	if err := s.store.CreateLinkedTransaction(ctx, reversal); err != nil {
+		s.logger.Error("Failed to create reversal", "error", err, "transaction_id", transactionID)
+		return nil, fmt.Errorf("failed to create reversal: %w", err)
+	}
+	if err := s.recordTransactionCreated(ctx, reversal); err != nil {
+		return nil, err
+	}
+
+	if original.Status == models.TransactionStatusAuthorized {
+		hold, err := s.store.GetActiveHoldByTransaction(ctx, original.ID)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.releaseAuthorization(ctx, original, hold, models.HoldStatusReleased, models.TransactionStatusReversed, actorReversal, reason); err != nil {
+			return nil, err
+		}
+	} else {
+		if err := s.bookCardRefund(ctx, reversal); err != nil {
+			s.logger.Error("Failed to book reversal", "error", err, "transaction_id", reversal.ID)
+			return nil, fmt.Errorf("failed to book reversal: %w", err)
+		}
+		if err := s.transition(ctx, original, models.TransactionStatusReversed, actorReversal, reason); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.transition(ctx, reversal, models.TransactionStatusSettled, actorReversal, reason); err != nil {
+		return nil, err
+	}
+	return reversal, nil
+}
+
+// GetRefunds retrieves the refunds and reversals linked to a transaction
+func (s *Service) GetRefunds(ctx context.Context, transactionID string) ([]*models.Transaction, error) {
+	if _, err := s.store.GetTransaction(ctx, transactionID); err != nil {
+		return nil, err
+	}
+
+	return s.store.GetLinkedTransactions(ctx, transactionID)
+}
+
+// refundedAmount sums the successful refunds of a transaction
+func (s *Service) refundedAmount(ctx context.Context, transactionID string) (int64, error) {
+	linked, err := s.store.GetLinkedTransactions(ctx, transactionID)
+	if err != nil {
+		return 0, err
+	}
+
+	var total int64
+	for _, tx := range linked {
+		if tx.Type == models.TransactionTypeRefund && tx.Status == models.TransactionStatusSettled {
+			total += tx.Amount
+		}
+	}
+	return total, nil
+}
+
+// newLinkedTransaction builds a pending counter-transaction referencing the original
+func newLinkedTransaction(original *models.Transaction, txType string, amount int64, reason string) *models.Transaction {
+	description := fmt.Sprintf("%s of %s", txType, original.ID)
+	if reason != "" {
+		description = fmt.Sprintf("%s: %s", description, reason)
+	}
+
+	// Refund the merchant-currency amount in proportion to the billing amount
+	originalAmount := amount
+	if original.OriginalCurrency != original.Currency && original.Amount > 0 {
+		originalAmount = new(big.Int).Div(
+			new(big.Int).Mul(big.NewInt(original.OriginalAmount), big.NewInt(amount)),
+			big.NewInt(original.Amount),
+		).Int64()
+	}
+
+	now := time.Now()
+	return &models.Transaction{
+		ID:                  uuid.New().String(),
+		UserID:              original.UserID,
+		CardID:              original.CardID,
+		ParentTransactionID: original.ID,
+		Type:                txType,
+		Amount:              amount,
+		AuthorizedAmount:    amount,
+		Currency:            original.Currency,
+		OriginalAmount:      originalAmount,
+		OriginalCurrency:    original.OriginalCurrency,
+		FXRate:              original.FXRate,
+		MerchantID:          original.MerchantID,
+		MerchantName:        original.MerchantName,
+		MerchantDescriptor:  original.MerchantDescriptor,
+		MCC:                 original.MCC,
+		MerchantCity:        original.MerchantCity,
+		MerchantCountry:     original.MerchantCountry,
+		Category:            original.Category,
+		CategorySource:      original.CategorySource,
+		Description:         description,
+		Status:              models.TransactionStatusPending,
+		Timestamp:           now,
+		CreatedAt:           now,
+		UpdatedAt:           now,
+	}
+}
@@ -0,0 +1,123 @@
+package ledger_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/araesf/ledgertime/internal/ledger"
+	"github.com/araesf/ledgertime/internal/models"
+)
+
+func TestRefunds(t *testing.T) {
+	service, store := newTestService(t)
+	card := newTestCard(t, service, store)
+	ctx := context.Background()
+
+	paid, err := service.ProcessCardPayload(ctx, payment(card, 3000, "Bookshop"))
+	if err != nil {
+		t.Fatalf("payment failed: %v", err)
+	}
+	assertBalance(t, service, card.UserID, -3000, 0)
+
+	refund, err := service.Refund(ctx, paid.ID, 1000, "damaged")
+	if err != nil {
+		t.Fatalf("refund failed: %v", err)
+	}
+	if refund.Type != models.TransactionTypeRefund || refund.Status != models.TransactionStatusSettled ||
+		refund.Amount != 1000 || refund.ParentTransactionID != paid.ID {
+		t.Errorf("refund is a %s of %d in status %s for %s, want a settled %s of 1000 for %s",
+			refund.Type, refund.Amount, refund.Status, refund.ParentTransactionID, models.TransactionTypeRefund, paid.ID)
+	}
+	assertStatus(t, store, paid.ID, models.TransactionStatusPartiallyRefunded)
+	assertBalance(t, service, card.UserID, -2000, 0)
+
+	if _, err := service.Refund(ctx, paid.ID, 2001, "too much"); !errors.Is(err, models.ErrRefundLimitExceeded) {
+		t.Errorf("refund beyond the payment: err=%v, want %v", err, models.ErrRefundLimitExceeded)
+	}
+	assertBalance(t, service, card.UserID, -2000, 0)
+
+	// A zero amount refunds the remainder
+	rest, err := service.Refund(ctx, paid.ID, 0, "returned")
+	if err != nil {
+		t.Fatalf("refunding the remainder failed: %v", err)
+	}
+	if rest.Amount != 2000 {
+		t.Errorf("refunded %d, want the remaining 2000", rest.Amount)
+	}
+	assertStatus(t, store, paid.ID, models.TransactionStatusRefunded)
+	assertBalance(t, service, card.UserID, 0, 0)
+
+	if _, err := service.Refund(ctx, paid.ID, 0, "again"); !errors.Is(err, ledger.ErrInvalidTransactionState) {
+		t.Errorf("refunding a refunded payment: err=%v, want %v", err, ledger.ErrInvalidTransactionState)
+	}
+	if _, err := service.Refund(ctx, refund.ID, 0, "refund of a refund"); !errors.Is(err, ledger.ErrInvalidTransactionState) {
+		t.Errorf("refunding a refund: err=%v, want %v", err, ledger.ErrInvalidTransactionState)
+	}
+
+	refunds, err := service.GetRefunds(ctx, paid.ID)
+	if err != nil {
+		t.Fatalf("failed to get refunds: %v", err)
+	}
+	if len(refunds) != 2 {
+		t.Errorf("got %d refunds, want 2", len(refunds))
+	}
+}
+
+func TestRefundRequiresCompletedPayment(t *testing.T) {
+	service, store := newTestService(t)
+	card := newTestCard(t, service, store)
+	ctx := context.Background()
+
+	authorization, err := service.Authorize(ctx, payment(card, 4000, "Hotel"))
+	if err != nil {
+		t.Fatalf("authorization failed: %v", err)
+	}
+	declined, err := service.ProcessCardPayload(ctx, payment(card, 100001, "Jeweller"))
+	if err != nil {
+		t.Fatalf("payment failed: %v", err)
+	}
+
+	for _, id := range []string{authorization.ID, declined.ID} {
+		if _, err := service.Refund(ctx, id, 0, "refund"); !errors.Is(err, ledger.ErrInvalidTransactionState) {
+			t.Errorf("refunding transaction %s: err=%v, want %v", id, err, ledger.ErrInvalidTransactionState)
+		}
+	}
+	assertBalance(t, service, card.UserID, 0, 4000)
+}
+
+func TestReversals(t *testing.T) {
+	service, store := newTestService(t)
+	card := newTestCard(t, service, store)
+	ctx := context.Background()
+
+	paid, err := service.ProcessCardPayload(ctx, payment(card, 3000, "Bookshop"))
+	if err != nil {
+		t.Fatalf("payment failed: %v", err)
+	}
+	authorization, err := service.Authorize(ctx, payment(card, 4000, "Hotel"))
+	if err != nil {
+		t.Fatalf("authorization failed: %v", err)
+	}
+	assertBalance(t, service, card.UserID, -3000, 4000)
+
+	// A completed payment is booked back; an authorization has its hold released
+	for _, id := range []string{paid.ID, authorization.ID} {
+		reversal, err := service.Reverse(ctx, id, "merchant error")
+		if err != nil {
+			t.Fatalf("reversal failed: %v", err)
+		}
+		if reversal.Type != models.TransactionTypeReversal || reversal.ParentTransactionID != id {
+			t.Errorf("reversal is a %s for %s, want a %s for %s", reversal.Type, reversal.ParentTransactionID, models.TransactionTypeReversal, id)
+		}
+		assertStatus(t, store, id, models.TransactionStatusReversed)
+	}
+	assertBalance(t, service, card.UserID, 0, 0)
+
+	if _, err := service.Reverse(ctx, paid.ID, "again"); !errors.Is(err, ledger.ErrInvalidTransactionState) {
+		t.Errorf("reversing twice: err=%v, want %v", err, ledger.ErrInvalidTransactionState)
+	}
+	if _, err := service.Refund(ctx, paid.ID, 0, "after reversal"); !errors.Is(err, ledger.ErrInvalidTransactionState) {
+		t.Errorf("refunding a reversed payment: err=%v, want %v", err, ledger.ErrInvalidTransactionState)
+	}
+}
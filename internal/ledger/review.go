@@ -0,0 +1,262 @@
+package ledger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/araesf/ledgertime/internal/models"
+	"github.com/google/uuid"
+)
+
+// parkForReview holds the transaction's funds and queues it for an analyst. Intent records
+// whether approval should settle the payment or leave it authorized. The hold lasts the
+// authorization TTL beyond the review deadline, so the review sweeper always closes the review
+// before the authorization sweeper could expire its hold.
+func (s *Service) parkForReview(ctx context.Context, tx *models.Transaction, intent, reason string) error {
+	now := time.Now()
+	reviewExpiresAt := now.Add(s.cfg.ReviewTimeout)
+
+	if err := s.placeHold(ctx, tx, reviewExpiresAt.Add(s.cfg.AuthorizationTTL)); errors.Is(err, models.ErrInsufficientFunds) {
+		s.logger.Error("Transaction declined", "error", err, "transaction_id", tx.ID)
+		return s.transition(ctx, tx, models.TransactionStatusDeclined, actorProcessor, err.Error())
+	} else if err != nil {
+		s.logger.Error("Failed to place hold", "error", err, "transaction_id", tx.ID)
+		return fmt.Errorf("failed to place hold: %w", err)
+	}
+
+	item := &models.ReviewItem{
+		ID:            uuid.New().String(),
+		TransactionID: tx.ID,
+		Intent:        intent,
+		Status:        models.ReviewStatusPending,
+		Reason:        reason,
+		ExpiresAt:     reviewExpiresAt,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	if err := s.store.CreateReviewItem(ctx, item); err != nil {
+		return err
+	}
+
+	return s.transition(ctx, tx, models.TransactionStatusInReview, actorProcessor, reason)
+}
+
+// ListReviews returns review items with the given status, or all open items when status is empty
+func (s *Service) ListReviews(ctx context.Context, status string, limit, offset int) ([]*models.ReviewItem, error) {
+	items, err := s.store.GetReviewItems(ctx, status, limit, offset)
+	if err != nil {
+		s.logger.Error("Failed to list reviews", "error", err, "status", status)
+		return nil, fmt.Errorf("failed to list reviews: %w", err)
+	}
+
+	return items, nil
+}
+
+// GetReview returns a single review item
+func (s *Service) GetReview(ctx context.Context, reviewID string) (*models.ReviewItem, error) {
+	return s.store.GetReviewItem(ctx, reviewID)
+}
+
+// ClaimReview assigns a review to an analyst so others don't work it at the same time
+func (s *Service) ClaimReview(ctx context.Context, reviewID, analyst string) (*models.ReviewItem, error) {
+	s.logger.Info("Claiming review", "review_id", reviewID, "analyst", analyst)
+
+	if analyst == "" {
+		return nil, fmt.Errorf("analyst cannot be empty")
+	}
+
+	return s.store.ClaimReviewItem(ctx, reviewID, analyst, time.Now())
+}
+
+// ApproveReview lets a reviewed transaction through: payments settle, authorizations keep their hold
+func (s *Service) ApproveReview(ctx context.Context, reviewID, analyst, notes string) (*models.ReviewItem, error) {
+	s.logger.Info("Approving review", "review_id", reviewID, "analyst", analyst)
+
+	if analyst == "" {
+		return nil, fmt.Errorf("analyst cannot be empty")
+	}
+
+	var item *models.ReviewItem
+	transaction, err := runInUnitOfWork(ctx, s.store, func(ctx context.Context) (*models.Transaction, error) {
+		var err error
+		item, err = s.store.DecideReviewItem(ctx, reviewID, models.ReviewStatusApproved, analyst, notes, time.Now())
+		if err != nil {
+			return nil, err
+		}
+		return s.approveReview(ctx, item, analyst, notes)
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.checkBudgets(ctx, transaction)
+
+	s.logger.Info("Review approved", "review_id", reviewID, "transaction_id", transaction.ID)
+	return item, nil
+}
+
+// approveReview moves an approved transaction on: payments settle, authorizations keep their hold
+func (s *Service) approveReview(ctx context.Context, item *models.ReviewItem, analyst, notes string) (*models.Transaction, error) {
+	transaction, hold, err := s.reviewedTransaction(ctx, item)
+	if err != nil {
+		return nil, err
+	}
+
+	reason := reviewReason("approved", analyst, notes)
+	if item.Intent == models.ReviewIntentAuthorization {
+		if err := s.transition(ctx, transaction, models.TransactionStatusAuthorized, actorReview, reason); err != nil {
+			return nil, err
+		}
+		return transaction, nil
+	}
+
+	if err := s.store.ReleaseHold(ctx, hold.ID, models.HoldStatusCaptured); err != nil {
+		s.logger.Error("Failed to release hold", "error", err, "transaction_id", transaction.ID)
+		return nil, fmt.Errorf("failed to release hold: %w", err)
+	}
+
+	if err := s.bookCardPayment(ctx, transaction); err != nil {
+		s.logger.Error("Failed to book reviewed payment", "error", err, "transaction_id", transaction.ID)
+		return nil, fmt.Errorf("failed to book reviewed payment: %w", err)
+	}
+
+	if err := s.transition(ctx, transaction, models.TransactionStatusSettled, actorReview, reason); err != nil {
+		return nil, err
+	}
+	return transaction, nil
+}
+
+// DeclineReview declines a reviewed transaction and releases its hold
+func (s *Service) DeclineReview(ctx context.Context, reviewID, analyst, notes string) (*models.ReviewItem, error) {
+	s.logger.Info("Declining review", "review_id", reviewID, "analyst", analyst)
+
+	if analyst == "" {
+		return nil, fmt.Errorf("analyst cannot be empty")
+	}
+
+	var item *models.ReviewItem
+	transaction, err := runInUnitOfWork(ctx, s.store, func(ctx context.Context) (*models.Transaction, error) {
+		var err error
+		item, err = s.store.DecideReviewItem(ctx, reviewID, models.ReviewStatusDeclined, analyst, notes, time.Now())
+		if err != nil {
+			return nil, err
+		}
+
+		transaction, hold, err := s.reviewedTransaction(ctx, item)
+		if err != nil {
+			return nil, err
+		}
+
+		reason := reviewReason("declined", analyst, notes)
+		if err := s.releaseAuthorization(ctx, transaction, hold, models.HoldStatusReleased, models.TransactionStatusDeclined, actorReview, reason); err != nil {
+			return nil, err
+		}
+		return transaction, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Review declined", "review_id", reviewID, "transaction_id", transaction.ID)
+	return item, nil
+}
+
+// ExpireReviews auto-declines reviews nobody decided before their deadline
+func (s *Service) ExpireReviews(ctx context.Context, now time.Time) (int, error) {
+	expired := 0
+	for {
+		items, err := s.store.GetExpiredReviewItems(ctx, now, sweepBatchSize)
+		if err != nil {
+			return expired, err
+		}
+
+		for _, item := range items {
+			released := false
+			err := s.store.RunUnitOfWork(ctx, func(ctx context.Context) error {
+				released = false
+				item, err := s.store.DecideReviewItem(ctx, item.ID, models.ReviewStatusExpired, actorTimeout, "", now)
+				if err != nil {
+					return err
+				}
+
+				transaction, hold, err := s.reviewedTransaction(ctx, item)
+				if errors.Is(err, ErrInvalidTransactionState) {
+					// The transaction left review outside the queue; the review still closes
+					return nil
+				}
+				if err != nil {
+					return err
+				}
+
+				released = true
+				return s.releaseAuthorization(ctx, transaction, hold, models.HoldStatusReleased, models.TransactionStatusDeclined, actorTimeout, "review timed out")
+			})
+			if errors.Is(err, models.ErrReviewClosed) {
+				// Decided concurrently
+				continue
+			}
+			if err != nil {
+				return expired, err
+			}
+			if released {
+				expired++
+			}
+		}
+
+		if len(items) < sweepBatchSize {
+			return expired, nil
+		}
+	}
+}
+
+// RunReviewSweeper periodically auto-declines timed out reviews until ctx is cancelled
+func (s *Service) RunReviewSweeper(ctx context.Context) {
+	s.logger.Info("Starting review sweeper", "interval", s.cfg.SweepInterval, "timeout", s.cfg.ReviewTimeout)
+
+	ticker := time.NewTicker(s.cfg.SweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			s.logger.Info("Review sweeper stopped")
+			return
+		case now := <-ticker.C:
+			expired, err := s.ExpireReviews(ctx, now)
+			if err != nil {
+				s.logger.Error("Failed to expire reviews", "error", err)
+			}
+			if expired > 0 {
+				s.logger.Info("Auto-declined timed out reviews", "count", expired)
+			}
+		}
+	}
+}
+
+// reviewedTransaction loads the in-review transaction of a review item together with its hold
+func (s *Service) reviewedTransaction(ctx context.Context, item *models.ReviewItem) (*models.Transaction, *models.Hold, error) {
+	transaction, err := s.store.GetTransaction(ctx, item.TransactionID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if transaction.Status != models.TransactionStatusInReview {
+		return nil, nil, fmt.Errorf("%w: transaction %s is %s", ErrInvalidTransactionState, transaction.ID, transaction.Status)
+	}
+
+	hold, err := s.store.GetActiveHoldByTransaction(ctx, transaction.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return transaction, hold, nil
+}
+
+// reviewReason describes an analyst's decision for the status history
+func reviewReason(decision, analyst, notes string) string {
+	if notes == "" {
+		return fmt.Sprintf("%s by %s", decision, analyst)
+	}
+	return fmt.Sprintf("%s by %s: %s", decision, analyst, notes)
+}
@@ -0,0 +1,188 @@
+package ledger_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/araesf/ledgertime/internal/ledger"
+	"github.com/araesf/ledgertime/internal/memory"
+	"github.com/araesf/ledgertime/internal/models"
+	"github.com/araesf/ledgertime/pkg/logger"
+)
+
+// pendingReview parks a payment for review and returns its review item
+func pendingReview(t *testing.T, service *ledger.Service, card *models.Card, amount int64) (*models.Transaction, *models.ReviewItem) {
+	t.Helper()
+	ctx := context.Background()
+
+	transaction, err := service.ProcessCardPayload(ctx, payment(card, amount, "Review Me"))
+	if err != nil {
+		t.Fatalf("payment failed: %v", err)
+	}
+	if transaction.Status != models.TransactionStatusInReview {
+		t.Fatalf("payment status = %s, want %s", transaction.Status, models.TransactionStatusInReview)
+	}
+
+	reviews, err := service.ListReviews(ctx, models.ReviewStatusPending, 10, 0)
+	if err != nil {
+		t.Fatalf("failed to list reviews: %v", err)
+	}
+	for _, review := range reviews {
+		if review.TransactionID == transaction.ID {
+			return transaction, review
+		}
+	}
+	t.Fatalf("no pending review for transaction %s", transaction.ID)
+	return nil, nil
+}
+
+func TestReviewApprove(t *testing.T) {
+	service, store := newTestService(t)
+	card := newTestCard(t, service, store)
+	ctx := context.Background()
+
+	transaction, review := pendingReview(t, service, card, 3000)
+	assertBalance(t, service, card.UserID, 0, 3000)
+
+	if _, err := service.ClaimReview(ctx, review.ID, "alice"); err != nil {
+		t.Fatalf("claim failed: %v", err)
+	}
+	if _, err := service.ApproveReview(ctx, review.ID, "bob", ""); !errors.Is(err, models.ErrReviewClaimed) {
+		t.Errorf("approving a review claimed by another analyst: err=%v, want %v", err, models.ErrReviewClaimed)
+	}
+
+	approved, err := service.ApproveReview(ctx, review.ID, "alice", "known customer")
+	if err != nil {
+		t.Fatalf("approve failed: %v", err)
+	}
+	if approved.Status != models.ReviewStatusApproved {
+		t.Errorf("review status = %s, want %s", approved.Status, models.ReviewStatusApproved)
+	}
+	assertStatus(t, store, transaction.ID, models.TransactionStatusSettled)
+	assertBalance(t, service, card.UserID, -3000, 0)
+
+	if _, err := service.DeclineReview(ctx, review.ID, "alice", ""); !errors.Is(err, models.ErrReviewClosed) {
+		t.Errorf("declining an approved review: err=%v, want %v", err, models.ErrReviewClosed)
+	}
+}
+
+func TestReviewDecline(t *testing.T) {
+	service, store := newTestService(t)
+	card := newTestCard(t, service, store)
+	ctx := context.Background()
+
+	transaction, review := pendingReview(t, service, card, 3000)
+
+	declined, err := service.DeclineReview(ctx, review.ID, "alice", "stolen card")
+	if err != nil {
+		t.Fatalf("decline failed: %v", err)
+	}
+	if declined.Status != models.ReviewStatusDeclined {
+		t.Errorf("review status = %s, want %s", declined.Status, models.ReviewStatusDeclined)
+	}
+	assertStatus(t, store, transaction.ID, models.TransactionStatusDeclined)
+	assertBalance(t, service, card.UserID, 0, 0)
+
+	history, err := service.GetTransactionHistory(ctx, transaction.ID)
+	if err != nil {
+		t.Fatalf("failed to get history: %v", err)
+	}
+	last := history[len(history)-1]
+	if last.FromStatus != models.TransactionStatusInReview || last.ToStatus != models.TransactionStatusDeclined {
+		t.Errorf("last change = %s -> %s, want %s -> %s", last.FromStatus, last.ToStatus,
+			models.TransactionStatusInReview, models.TransactionStatusDeclined)
+	}
+}
+
+func TestReviewedAuthorizationStaysAuthorized(t *testing.T) {
+	service, store := newTestService(t)
+	card := newTestCard(t, service, store)
+	ctx := context.Background()
+
+	authorization, err := service.Authorize(ctx, payment(card, 4000, "Review Me"))
+	if err != nil {
+		t.Fatalf("authorization failed: %v", err)
+	}
+	if authorization.Status != models.TransactionStatusInReview {
+		t.Fatalf("authorization status = %s, want %s", authorization.Status, models.TransactionStatusInReview)
+	}
+	reviews, err := service.ListReviews(ctx, models.ReviewStatusPending, 10, 0)
+	if err != nil || len(reviews) != 1 {
+		t.Fatalf("ListReviews() = %d reviews, %v; want 1", len(reviews), err)
+	}
+	if reviews[0].Intent != models.ReviewIntentAuthorization {
+		t.Errorf("review intent = %s, want %s", reviews[0].Intent, models.ReviewIntentAuthorization)
+	}
+
+	// Approval leaves the hold in place for a later capture
+	if _, err := service.ApproveReview(ctx, reviews[0].ID, "alice", ""); err != nil {
+		t.Fatalf("approve failed: %v", err)
+	}
+	assertStatus(t, store, authorization.ID, models.TransactionStatusAuthorized)
+	assertBalance(t, service, card.UserID, 0, 4000)
+
+	if _, err := service.Capture(ctx, authorization.ID, 0); err != nil {
+		t.Fatalf("capture failed: %v", err)
+	}
+	assertBalance(t, service, card.UserID, -4000, 0)
+}
+
+func TestExpireReviews(t *testing.T) {
+	service, store := newTestService(t)
+	card := newTestCard(t, service, store)
+	ctx := context.Background()
+
+	expiring, _ := pendingReview(t, service, card, 3000)
+	_, decided := pendingReview(t, service, card, 700)
+	if _, err := service.DeclineReview(ctx, decided.ID, "alice", ""); err != nil {
+		t.Fatalf("decline failed: %v", err)
+	}
+
+	if expired, err := service.ExpireReviews(ctx, time.Now()); err != nil || expired != 0 {
+		t.Errorf("ExpireReviews() before the timeout = %d, %v, want 0", expired, err)
+	}
+	assertBalance(t, service, card.UserID, 0, 3000)
+
+	// Only the undecided review times out; it is declined and its hold released
+	expired, err := service.ExpireReviews(ctx, time.Now().Add(2*time.Hour))
+	if err != nil || expired != 1 {
+		t.Fatalf("ExpireReviews() after the timeout = %d, %v, want 1", expired, err)
+	}
+	assertStatus(t, store, expiring.ID, models.TransactionStatusDeclined)
+	assertBalance(t, service, card.UserID, 0, 0)
+
+	reviews, err := service.ListReviews(ctx, models.ReviewStatusExpired, 10, 0)
+	if err != nil || len(reviews) != 1 || reviews[0].TransactionID != expiring.ID {
+		t.Errorf("expired reviews = %d, %v; want the review of %s", len(reviews), err, expiring.ID)
+	}
+}
+
+func TestReviewHoldOutlivesReview(t *testing.T) {
+	service, store := newTestService(t)
+	card := newTestCard(t, service, store)
+	ctx := context.Background()
+
+	transaction, _ := pendingReview(t, service, card, 3000)
+
+	// Past both the review timeout and the authorization TTL the hold is still the review's to release
+	at := time.Now().Add(90 * time.Minute)
+	if expired, err := service.ExpireAuthorizations(ctx, at); err != nil || expired != 0 {
+		t.Errorf("ExpireAuthorizations() = %d, %v, want 0", expired, err)
+	}
+	if expired, err := service.ExpireReviews(ctx, at); err != nil || expired != 1 {
+		t.Fatalf("ExpireReviews() = %d, %v, want 1", expired, err)
+	}
+	assertStatus(t, store, transaction.ID, models.TransactionStatusDeclined)
+	assertBalance(t, service, card.UserID, 0, 0)
+}
+
+func TestReviewTimeoutWithinAuthorizationTTL(t *testing.T) {
+	cfg := testConfig()
+	cfg.ReviewTimeout = cfg.AuthorizationTTL + time.Minute
+
+	if _, err := ledger.NewService(cfg, memory.NewStore(), reviewProcessor{}, nil, logger.NewLoggerWithLevel("error")); err == nil {
+		t.Error("a review timeout beyond the authorization TTL was accepted")
+	}
+}
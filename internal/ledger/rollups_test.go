@@ -0,0 +1,143 @@
+package ledger_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/araesf/ledgertime/internal/ledger"
+	"github.com/araesf/ledgertime/internal/models"
+)
+
+// dailySpending returns a user's net spend per category over the last three days, keyed by
+// "<days ago> <category>"
+func dailySpending(t *testing.T, service *ledger.Service, userID string) map[string]int64 {
+	t.Helper()
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+
+	series, err := service.GetSpending(context.Background(), &models.SpendingQuery{
+		UserID:   userID,
+		Interval: models.SpendingIntervalDay,
+		GroupBy:  models.SpendingGroupCategory,
+		From:     today.AddDate(0, 0, -2),
+		To:       today.AddDate(0, 0, 1),
+	})
+	if err != nil {
+		t.Fatalf("GetSpending() = %v", err)
+	}
+
+	spending := make(map[string]int64)
+	for _, b := range series.Buckets {
+		if b.Amount != 0 || b.Count != 0 {
+			daysAgo := int(today.Sub(b.PeriodStart).Hours() / 24)
+			spending[fmt.Sprintf("%d %s", daysAgo, b.Group)] = b.Amount
+		}
+	}
+	return spending
+}
+
+func checkDailySpending(t *testing.T, step string, got, want map[string]int64) {
+	t.Helper()
+	for key, amount := range want {
+		if got[key] != amount {
+			t.Errorf("%s: %s = %d, want %d", step, key, got[key], amount)
+		}
+	}
+	for key, amount := range got {
+		if _, ok := want[key]; !ok {
+			t.Errorf("%s: unexpected %s = %d", step, key, amount)
+		}
+	}
+}
+
+func TestDailySpendingFollowsStatusChanges(t *testing.T) {
+	service, store := newTestService(t)
+	card := newTestCard(t, service, store)
+	ctx := context.Background()
+	now := time.Now().UTC()
+	daysAgo := func(p models.CardPayload, days int, category string) models.CardPayload {
+		p.Category = category
+		p.Timestamp = now.AddDate(0, 0, -days).Format(time.RFC3339)
+		return p
+	}
+
+	settled, err := service.ProcessCardPayload(ctx, daysAgo(payment(card, 5000, "Grocer"), 2, "groceries"))
+	if err != nil {
+		t.Fatalf("payment failed: %v", err)
+	}
+	if declined, err := service.ProcessCardPayload(ctx, daysAgo(payment(card, 200000, "Jeweller"), 2, "shopping")); err != nil || declined.Status != models.TransactionStatusDeclined {
+		t.Fatalf("payment over the overdraft limit: err=%v, want it declined", err)
+	}
+	held, err := service.Authorize(ctx, daysAgo(payment(card, 4000, "Restaurant"), 1, "dining"))
+	if err != nil {
+		t.Fatalf("authorization failed: %v", err)
+	}
+	voided, err := service.Authorize(ctx, daysAgo(payment(card, 2500, "Hotel"), 1, "travel"))
+	if err != nil {
+		t.Fatalf("authorization failed: %v", err)
+	}
+	_, approved := pendingReview(t, service, card, 3000)
+	_, declinedReview := pendingReview(t, service, card, 700)
+
+	// Authorizations count from the moment they are accepted; declines and payments in review don't
+	checkDailySpending(t, "accepted", dailySpending(t, service, card.UserID), map[string]int64{
+		"2 groceries": 5000,
+		"1 dining":    4000,
+		"1 travel":    2500,
+	})
+
+	if _, err := service.IncrementAuthorization(ctx, held.ID, 1000); err != nil {
+		t.Fatalf("increment failed: %v", err)
+	}
+	if _, err := service.Void(ctx, voided.ID); err != nil {
+		t.Fatalf("void failed: %v", err)
+	}
+	checkDailySpending(t, "incremented and voided", dailySpending(t, service, card.UserID), map[string]int64{
+		"2 groceries": 5000,
+		"1 dining":    5000,
+	})
+
+	// Capturing less than was held counts the captured amount on the authorization's day
+	if _, err := service.Capture(ctx, held.ID, 3500); err != nil {
+		t.Fatalf("capture failed: %v", err)
+	}
+	if _, err := service.ApproveReview(ctx, approved.ID, "alice", ""); err != nil {
+		t.Fatalf("approve failed: %v", err)
+	}
+	if _, err := service.DeclineReview(ctx, declinedReview.ID, "alice", ""); err != nil {
+		t.Fatalf("decline failed: %v", err)
+	}
+	checkDailySpending(t, "captured and reviewed", dailySpending(t, service, card.UserID), map[string]int64{
+		"2 groceries": 5000,
+		"1 dining":    3500,
+		"0 general":   3000,
+	})
+
+	// Refunds come off the day they are issued, leaving the payment's day alone
+	if _, err := service.Refund(ctx, settled.ID, 1500, "damaged"); err != nil {
+		t.Fatalf("refund failed: %v", err)
+	}
+	if _, err := service.Refund(ctx, held.ID, 0, "cancelled"); err != nil {
+		t.Fatalf("refund failed: %v", err)
+	}
+	checkDailySpending(t, "refunded", dailySpending(t, service, card.UserID), map[string]int64{
+		"2 groceries": 5000,
+		"1 dining":    3500,
+		"0 general":   3000,
+		"0 groceries": -1500,
+		"0 dining":    -3500,
+	})
+
+	// Recategorizing a payment moves it and its refunds to the new category
+	if _, err := service.RecategorizeTransaction(ctx, settled.ID, "shopping"); err != nil {
+		t.Fatalf("recategorize failed: %v", err)
+	}
+	checkDailySpending(t, "recategorized", dailySpending(t, service, card.UserID), map[string]int64{
+		"2 shopping": 5000,
+		"1 dining":   3500,
+		"0 general":  3000,
+		"0 shopping": -1500,
+		"0 dining":   -3500,
+	})
+}
@@ -1,85 +1,292 @@
 package ledger
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
-	"github.com/google/uuid"
-	"github.com/araesf/ledgertime/internal/db"
+	"github.com/araesf/ledgertime/internal/cardnet"
+	"github.com/araesf/ledgertime/internal/config"
+	"github.com/araesf/ledgertime/internal/merchant"
 	"github.com/araesf/ledgertime/internal/models"
+	"github.com/araesf/ledgertime/internal/money"
+	"github.com/araesf/ledgertime/internal/vault"
 	"github.com/araesf/ledgertime/pkg/logger"
+	"github.com/google/uuid"
 )
 
 // Service handles ledger operations
 type Service struct {
-	db     *db.DB
-	logger *logger.Logger
+	cfg       config.LedgerConfig
+	store     Store
+	processor Processor
+	rates     *money.RateTable
+	bins      *cardnet.BINTable
+	vault     *vault.Vault
+	logger    *logger.Logger
 }
 
-// NewService creates a new ledger service
-func NewService(database *db.DB, log *logger.Logger) *Service {
+// NewService creates a new ledger service, loading FX rates and BIN ranges from the configured files
+func NewService(cfg config.LedgerConfig, store Store, processor Processor, cardVault *vault.Vault, log *logger.Logger) (*Service, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid ledger configuration: %w", err)
+	}
+
+	baseCurrency, err := money.NormalizeCurrency(cfg.BaseCurrency)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base currency: %w", err)
+	}
+	cfg.BaseCurrency = baseCurrency
+
+	rates, err := LoadRates(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	bins, err := cardnet.NewBINTable(nil)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.BINRangesFile != "" {
+		if bins, err = cardnet.LoadBINTable(cfg.BINRangesFile); err != nil {
+			return nil, fmt.Errorf("failed to load BIN ranges: %w", err)
+		}
+	}
+
 	return &Service{
-		db:     database,
-		logger: log,
+		cfg:       cfg,
+		store:     store,
+		processor: processor,
+		rates:     rates,
+		bins:      bins,
+		vault:     cardVault,
+		logger:    log,
+	}, nil
+}
+
+// LoadRates loads the configured FX rate table, or an empty table when no file is configured
+func LoadRates(cfg config.LedgerConfig) (*money.RateTable, error) {
+	if cfg.FXRatesFile == "" {
+		return money.NewRateTable(nil)
+	}
+	rates, err := money.LoadRates(cfg.FXRatesFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load FX rates: %w", err)
 	}
+	return rates, nil
 }
 
 // ProcessCardPayload converts a card payment into a transaction
-func (s *Service) ProcessCardPayload(payload models.CardPayload) (*models.Transaction, error) {
-	s.logger.Info("Processing card payload", "card_number", payload.CardNumber, "amount", payload.Amount)
+func (s *Service) ProcessCardPayload(ctx context.Context, payload models.CardPayload) (*models.Transaction, error) {
+	s.logger.Info("Processing card payload", "card", payloadCardRef(payload), "amount", payload.Amount)
+
+	transaction, err := runInUnitOfWork(ctx, s.store, func(ctx context.Context) (*models.Transaction, error) {
+		return s.processPayment(ctx, payload)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if transaction.Status == models.TransactionStatusSettled {
+		s.checkBudgets(ctx, transaction)
+	}
+
+	s.logger.Info("Transaction processed", "transaction_id", transaction.ID, "status", transaction.Status)
+	return transaction, nil
+}
+
+// processPayment saves, checks and books a card payment. Its writes only make sense together,
+// so it runs in a unit of work.
+func (s *Service) processPayment(ctx context.Context, payload models.CardPayload) (*models.Transaction, error) {
+	transaction, card, err := s.createCardTransaction(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	// Check the card, funds and spending limits, run the processor chain and book the transaction as postings
+	status, reason := models.TransactionStatusSettled, ""
+	var decline *DeclineError
+	var review *ReviewError
+	if err := checkCard(card, transaction.Timestamp); err != nil {
+		s.logger.Error("Transaction declined", "error", err, "transaction_id", transaction.ID)
+		status, reason = models.TransactionStatusDeclined, err.Error()
+	} else if err := s.checkFunds(ctx, transaction); errors.Is(err, models.ErrInsufficientFunds) {
+		s.logger.Error("Transaction declined", "error", err, "transaction_id", transaction.ID)
+		status, reason = models.TransactionStatusDeclined, err.Error()
+	} else if err != nil {
+		return nil, err
+	} else if err := s.checkSpendingLimits(ctx, transaction); errors.Is(err, ErrSpendingLimitExceeded) {
+		s.logger.Error("Transaction declined", "error", err, "transaction_id", transaction.ID)
+		status, reason = models.TransactionStatusDeclined, err.Error()
+	} else if err != nil {
+		return nil, err
+	} else if err := s.processor.Process(ctx, transaction); errors.As(err, &review) {
+		s.logger.Info("Transaction flagged for review", "reason", review.Reason, "transaction_id", transaction.ID)
+		if err := s.parkForReview(ctx, transaction, models.ReviewIntentPayment, review.Reason); err != nil {
+			return nil, err
+		}
+		return transaction, nil
+	} else if errors.As(err, &decline) {
+		s.logger.Error("Transaction declined", "error", err, "transaction_id", transaction.ID)
+		status, reason = models.TransactionStatusDeclined, err.Error()
+	} else if err != nil {
+		// The processor never decided, for example because the caller gave up or the store
+		// failed; roll the transaction back so it can be retried
+		s.logger.Error("Transaction processing failed", "error", err, "transaction_id", transaction.ID)
+		return nil, fmt.Errorf("transaction processing failed: %w", err)
+	} else if err := s.bookCardPayment(ctx, transaction); errors.Is(err, models.ErrInsufficientFunds) {
+		s.logger.Error("Transaction declined", "error", err, "transaction_id", transaction.ID)
+		status, reason = models.TransactionStatusDeclined, err.Error()
+	} else if err != nil {
+		s.logger.Error("Failed to book transaction", "error", err, "transaction_id", transaction.ID)
+		return nil, fmt.Errorf("failed to book transaction: %w", err)
+	}
+
+	if err := s.transition(ctx, transaction, status, actorProcessor, reason); err != nil {
+		return nil, err
+	}
+	return transaction, nil
+}
+
+// runInUnitOfWork runs fn in a unit of work and returns its result once the unit has committed
+func runInUnitOfWork[T any](ctx context.Context, uow UnitOfWork, fn func(ctx context.Context) (T, error)) (T, error) {
+	var result T
+	err := uow.RunUnitOfWork(ctx, func(ctx context.Context) error {
+		var err error
+		result, err = fn(ctx)
+		return err
+	})
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return result, nil
+}
 
+// createCardTransaction resolves the card, validates the payload and saves a pending transaction
+func (s *Service) createCardTransaction(ctx context.Context, payload models.CardPayload) (*models.Transaction, *models.Card, error) {
 	// Find the card and user
-	card, err := s.db.GetCardByNumber(payload.CardNumber)
+	card, err := s.payloadCard(ctx, payload)
 	if err != nil {
-		s.logger.Error("Card not found", "error", err, "card_number", payload.CardNumber)
-		return nil, fmt.Errorf("card not found: %w", err)
+		s.logger.Error("Card not found", "error", err, "card", payloadCardRef(payload))
+		return nil, nil, fmt.Errorf("card not found: %w", err)
 	}
 
 	// Parse timestamp
 	timestamp, err := time.Parse(time.RFC3339, payload.Timestamp)
 	if err != nil {
 		s.logger.Error("Invalid timestamp", "error", err, "timestamp", payload.Timestamp)
-		return nil, fmt.Errorf("invalid timestamp: %w", err)
+		return nil, nil, fmt.Errorf("invalid timestamp: %w", err)
+	}
+
+	// Convert the merchant's amount into the card's billing currency
+	original := money.Money{Amount: payload.Amount, Currency: card.Currency}
+	if payload.Currency != "" {
+		if original, err = money.New(payload.Amount, payload.Currency); err != nil {
+			s.logger.Error("Invalid currency", "error", err, "currency", payload.Currency)
+			return nil, nil, fmt.Errorf("invalid currency: %w", err)
+		}
+	}
+
+	billing, rate, err := s.rates.Convert(original, card.Currency, timestamp)
+	if err != nil {
+		s.logger.Error("Currency conversion failed", "error", err, "from", original.Currency, "to", card.Currency)
+		return nil, nil, fmt.Errorf("currency conversion failed: %w", err)
+	}
+
+	fxRate := ""
+	if original.Currency != billing.Currency {
+		fxRate = rate.Rate
+		s.logger.Info("Converted transaction amount", "original", original.String(), "billing", billing.String(), "rate", fxRate)
+	}
+
+	// Match the descriptor to a canonical merchant, filling in details the payload lacks from it
+	mcc := strings.TrimSpace(payload.MCC)
+	if mcc != "" {
+		if _, err := merchant.LookupMCC(mcc); err != nil {
+			s.logger.Error("Invalid merchant category code", "error", err, "mcc", mcc)
+			return nil, nil, fmt.Errorf("transaction validation failed: %w", err)
+		}
+	}
+	city := strings.TrimSpace(payload.MerchantCity)
+	country := strings.ToUpper(strings.TrimSpace(payload.MerchantCountry))
+
+	m, err := s.resolveMerchant(ctx, payload.MerchantName, mcc, city, country)
+	if err != nil {
+		s.logger.Error("Failed to resolve merchant", "error", err)
+		return nil, nil, fmt.Errorf("failed to resolve merchant: %w", err)
+	}
+
+	merchantID, merchantName := "", payload.MerchantName
+	if m != nil {
+		merchantID, merchantName = m.ID, m.Name
+		if mcc == "" {
+			mcc = m.MCC
+		}
+		if city == "" {
+			city = m.City
+		}
+		if country == "" {
+			country = m.Country
+		}
+	}
+
+	category, categorySource := payload.Category, models.CategorySourcePayload
+	if category == "" {
+		category, categorySource = merchant.CategoryForMCC(mcc), models.CategorySourceMCC
 	}
 
 	// Create transaction
 	now := time.Now()
 	transaction := &models.Transaction{
-		ID:           uuid.New().String(),
-		UserID:       card.UserID,
-		CardID:       card.ID,
-		Amount:       payload.Amount,
-		MerchantName: payload.MerchantName,
-		Category:     payload.Category,
-		Description:  fmt.Sprintf("Card payment at %s", payload.MerchantName),
-		Status:       models.TransactionStatusPending,
-		Timestamp:    timestamp,
-		CreatedAt:    now,
-		UpdatedAt:    now,
+		ID:                 uuid.New().String(),
+		UserID:             card.UserID,
+		CardID:             card.ID,
+		Type:               models.TransactionTypePayment,
+		Amount:             billing.Amount,
+		AuthorizedAmount:   billing.Amount,
+		Currency:           billing.Currency,
+		OriginalAmount:     original.Amount,
+		OriginalCurrency:   original.Currency,
+		FXRate:             fxRate,
+		MerchantID:         merchantID,
+		MerchantName:       merchantName,
+		MerchantDescriptor: payload.MerchantName,
+		MCC:                mcc,
+		MerchantCity:       city,
+		MerchantCountry:    country,
+		Category:           category,
+		CategorySource:     categorySource,
+		Description:        fmt.Sprintf("Card payment at %s", merchantName),
+		Status:             models.TransactionStatusPending,
+		Timestamp:          timestamp,
+		CreatedAt:          now,
+		UpdatedAt:          now,
+	}
+
+	// Let the user's category rules override the payload's category
+	if err := s.categorize(ctx, transaction); err != nil {
+		s.logger.Error("Failed to categorize transaction", "error", err, "transaction_id", transaction.ID)
+		return nil, nil, fmt.Errorf("failed to categorize transaction: %w", err)
 	}
 
 	// Validate transaction
 	if err := s.ValidateTransaction(transaction); err != nil {
 		s.logger.Error("Transaction validation failed", "error", err, "transaction_id", transaction.ID)
-		return nil, fmt.Errorf("transaction validation failed: %w", err)
+		return nil, nil, fmt.Errorf("transaction validation failed: %w", err)
 	}
 
 	// Save to database
-	if err := s.db.CreateTransaction(transaction); err != nil {
+	if err := s.store.CreateTransaction(ctx, transaction); err != nil {
 		s.logger.Error("Failed to save transaction", "error", err, "transaction_id", transaction.ID)
-		return nil, fmt.Errorf("failed to save transaction: %w", err)
+		return nil, nil, fmt.Errorf("failed to save transaction: %w", err)
 	}
-
-	// Process the transaction (simulate processing)
-	if err := s.processTransaction(transaction); err != nil {
-		s.logger.Error("Transaction processing failed", "error", err, "transaction_id", transaction.ID)
-		transaction.Status = models.TransactionStatusFailed
-	} else {
-		transaction.Status = models.TransactionStatusCompleted
+	if err := s.recordTransactionCreated(ctx, transaction); err != nil {
+		return nil, nil, err
 	}
 
-	s.logger.Info("Transaction processed", "transaction_id", transaction.ID, "status", transaction.Status)
-	return transaction, nil
+	return transaction, card, nil
 }
 
 // ValidateTransaction ensures the transaction has valid data
@@ -96,10 +303,18 @@ func (s *Service) ValidateTransaction(tx *models.Transaction) error {
 		return fmt.Errorf("card ID cannot be empty")
 	}
 
+	if _, err := money.LookupCurrency(tx.Currency); err != nil {
+		return err
+	}
+
 	if tx.MerchantName == "" {
 		return fmt.Errorf("merchant name cannot be empty")
 	}
 
+	if tx.MerchantCountry != "" && len(tx.MerchantCountry) != 2 {
+		return fmt.Errorf("merchant country must be an ISO 3166-1 alpha-2 code, got: %q", tx.MerchantCountry)
+	}
+
 	if tx.Category == "" {
 		return fmt.Errorf("category cannot be empty")
 	}
@@ -108,10 +323,10 @@ func (s *Service) ValidateTransaction(tx *models.Transaction) error {
 }
 
 // GetUserTransactions retrieves transactions for a user
-func (s *Service) GetUserTransactions(userID string, limit, offset int) ([]*models.Transaction, error) {
+func (s *Service) GetUserTransactions(ctx context.Context, userID string, limit, offset int) ([]*models.Transaction, error) {
 	s.logger.Info("Getting user transactions", "user_id", userID, "limit", limit, "offset", offset)
 
-	transactions, err := s.db.GetTransactionsByUser(userID, limit, offset)
+	transactions, err := s.store.GetTransactionsByUser(ctx, userID, limit, offset)
 	if err != nil {
 		s.logger.Error("Failed to get user transactions", "error", err, "user_id", userID)
 		return nil, fmt.Errorf("failed to get user transactions: %w", err)
@@ -119,34 +334,3 @@ func (s *Service) GetUserTransactions(userID string, limit, offset int) ([]*mode
 
 	return transactions, nil
 }
-
-// GetUserSummary retrieves transaction summary for a user
-func (s *Service) GetUserSummary(userID string) (*models.TransactionSummary, error) {
-	s.logger.Info("Getting user summary", "user_id", userID)
-
-	summary, err := s.db.GetTransactionSummary(userID)
-	if err != nil {
-		s.logger.Error("Failed to get user summary", "error", err, "user_id", userID)
-		return nil, fmt.Errorf("failed to get user summary: %w", err)
-	}
-
-	return summary, nil
-}
-
-// processTransaction simulates transaction processing logic
-func (s *Service) processTransaction(tx *models.Transaction) error {
-	// Simulate processing time
-	time.Sleep(100 * time.Millisecond)
-
-	// Simulate fraud detection
-	if tx.Amount > 100000 { // $1000 in cents
-		return fmt.Errorf("transaction amount exceeds limit")
-	}
-
-	// Simulate random failures (5% failure rate)
-	if time.Now().UnixNano()%20 == 0 {
-		return fmt.Errorf("processing failed due to network error")
-	}
-
-	return nil
-}
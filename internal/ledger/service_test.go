@@ -0,0 +1,134 @@
+package ledger_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/araesf/ledgertime/internal/config"
+	"github.com/araesf/ledgertime/internal/ledger"
+	"github.com/araesf/ledgertime/internal/memory"
+	"github.com/araesf/ledgertime/internal/models"
+	"github.com/araesf/ledgertime/internal/vault"
+	"github.com/araesf/ledgertime/pkg/logger"
+	"github.com/google/uuid"
+)
+
+const testCardNumber = "4012888888881881"
+
+// reviewProcessor flags every transaction from a merchant named "REVIEW ME" for review
+type reviewProcessor struct{}
+
+func (reviewProcessor) Name() string {
+	return "review"
+}
+
+func (p reviewProcessor) Process(ctx context.Context, tx *models.Transaction) error {
+	if strings.EqualFold(tx.MerchantName, "review me") {
+		return &ledger.ReviewError{Processor: p.Name(), Reason: "flagged by test"}
+	}
+	return nil
+}
+
+func newTestService(t *testing.T) (*ledger.Service, *memory.Store) {
+	t.Helper()
+	return newTestServiceWithProcessor(t, reviewProcessor{})
+}
+
+// newTestServiceWithProcessor creates a service over an empty memory store that runs processor
+func newTestServiceWithProcessor(t *testing.T, processor ledger.Processor) (*ledger.Service, *memory.Store) {
+	t.Helper()
+	return newTestServiceWithConfig(t, testConfig(), processor)
+}
+
+// testConfig returns the ledger configuration test services run with
+func testConfig() config.LedgerConfig {
+	return config.LedgerConfig{
+		BaseCurrency:          "USD",
+		FXRatesFile:           "../../data/fx_rates.json",
+		BINRangesFile:         "../../data/bin_ranges.json",
+		DefaultOverdraftLimit: 100000,
+		AuthorizationTTL:      time.Hour,
+		ReviewTimeout:         time.Hour,
+		CardValidity:          365 * 24 * time.Hour,
+	}
+}
+
+// newTestServiceWithConfig creates a service with cfg over an empty memory store that runs processor
+func newTestServiceWithConfig(t *testing.T, cfg config.LedgerConfig, processor ledger.Processor) (*ledger.Service, *memory.Store) {
+	t.Helper()
+	store := memory.NewStore()
+	return newTestServiceOverStore(t, cfg, store, processor), store
+}
+
+// newTestServiceOverStore creates a service with cfg over store, for processors that need the store themselves
+func newTestServiceOverStore(t *testing.T, cfg config.LedgerConfig, store *memory.Store, processor ledger.Processor) *ledger.Service {
+	t.Helper()
+
+	cardVault, err := vault.New("test", make([]byte, 32))
+	if err != nil {
+		t.Fatalf("failed to create vault: %v", err)
+	}
+
+	service, err := ledger.NewService(cfg, store, processor, cardVault, logger.NewLoggerWithLevel("error"))
+	if err != nil {
+		t.Fatalf("failed to create service: %v", err)
+	}
+	return service
+}
+
+// newTestCard creates a user with a card
+func newTestCard(t *testing.T, service *ledger.Service, store *memory.Store) *models.Card {
+	t.Helper()
+	ctx := context.Background()
+
+	user := &models.User{ID: uuid.New().String(), Name: "Test", Email: "test@example.com", CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	if err := store.CreateUser(ctx, user); err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+
+	card, err := service.RegisterCard(ctx, user.ID, testCardNumber, "", "USD")
+	if err != nil {
+		t.Fatalf("failed to register card: %v", err)
+	}
+	return card
+}
+
+func payment(card *models.Card, amount int64, merchant string) models.CardPayload {
+	return models.CardPayload{
+		CardToken:    card.Token,
+		Amount:       amount,
+		Currency:     "USD",
+		MerchantName: merchant,
+		Timestamp:    time.Now().UTC().Format(time.RFC3339),
+	}
+}
+
+func assertBalance(t *testing.T, service *ledger.Service, userID string, ledgerBalance, pending int64) {
+	t.Helper()
+
+	balance, err := service.GetUserBalance(context.Background(), userID, "USD")
+	if err != nil {
+		t.Fatalf("failed to get balance: %v", err)
+	}
+	if balance.LedgerBalance != ledgerBalance || balance.PendingAmount != pending {
+		t.Errorf("balance = %d ledger, %d pending; want %d ledger, %d pending",
+			balance.LedgerBalance, balance.PendingAmount, ledgerBalance, pending)
+	}
+	if balance.AvailableBalance != ledgerBalance-pending {
+		t.Errorf("available balance = %d, want %d", balance.AvailableBalance, ledgerBalance-pending)
+	}
+}
+
+func assertStatus(t *testing.T, store *memory.Store, transactionID, want string) {
+	t.Helper()
+
+	transaction, err := store.GetTransaction(context.Background(), transactionID)
+	if err != nil {
+		t.Fatalf("failed to get transaction: %v", err)
+	}
+	if transaction.Status != want {
+		t.Errorf("transaction status = %s, want %s", transaction.Status, want)
+	}
+}
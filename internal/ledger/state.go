@@ -0,0 +1,130 @@
+package ledger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/araesf/ledgertime/internal/models"
+	"github.com/google/uuid"
+)
+
+// ErrIllegalTransition is returned when the state machine does not allow a status change
+var ErrIllegalTransition = errors.New("illegal status transition")
+
+// Actors recorded in the status history
+const (
+	actorProcessor = "processor"
+	actorCapture   = "capture"
+	actorIncrement = "incremental-authorization"
+	actorVoid      = "void"
+	actorRefund    = "refund"
+	actorReversal  = "reversal"
+	actorSweeper   = "authorization-sweeper"
+	actorReview    = "manual-review"
+	actorTimeout   = "review-timeout"
+)
+
+// transitions lists the statuses reachable from each status.
+//
+//	pending -> authorized -> settled -> partially_refunded -> refunded
+//	   |           |           |                 \-> reversed
+//	   v           v           v
+//	declined   voided/expired/reversed
+//
+// Transactions flagged for manual review wait in in_review, holding their funds, until an
+// analyst approves them (authorized or settled) or declines them.
+//
+// Self-transitions record amount changes (incremental authorizations, further partial refunds).
+var transitions = map[string][]string{
+	models.TransactionStatusPending: {
+		models.TransactionStatusInReview,
+		models.TransactionStatusAuthorized,
+		models.TransactionStatusSettled,
+		models.TransactionStatusDeclined,
+	},
+	models.TransactionStatusInReview: {
+		models.TransactionStatusAuthorized,
+		models.TransactionStatusSettled,
+		models.TransactionStatusDeclined,
+		models.TransactionStatusExpired,
+	},
+	models.TransactionStatusAuthorized: {
+		models.TransactionStatusAuthorized,
+		models.TransactionStatusSettled,
+		models.TransactionStatusVoided,
+		models.TransactionStatusExpired,
+		models.TransactionStatusReversed,
+	},
+	models.TransactionStatusSettled: {
+		models.TransactionStatusPartiallyRefunded,
+		models.TransactionStatusRefunded,
+		models.TransactionStatusReversed,
+	},
+	models.TransactionStatusPartiallyRefunded: {
+		models.TransactionStatusPartiallyRefunded,
+		models.TransactionStatusRefunded,
+	},
+}
+
+// CanTransition reports whether a transaction may move from one status to another
+func CanTransition(from, to string) bool {
+	for _, allowed := range transitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// transition moves the transaction to a new status and persists the change with its cause,
+// announcing it in the outbox
+func (s *Service) transition(ctx context.Context, tx *models.Transaction, to, actor, reason string) error {
+	from := tx.Status
+	if !CanTransition(from, to) {
+		return fmt.Errorf("%w: %s -> %s for transaction %s", ErrIllegalTransition, from, to, tx.ID)
+	}
+
+	now := time.Now()
+	change := &models.StatusChange{
+		ID:            uuid.New().String(),
+		TransactionID: tx.ID,
+		FromStatus:    from,
+		ToStatus:      to,
+		Actor:         actor,
+		Reason:        reason,
+		CreatedAt:     now,
+	}
+
+	declineReason := tx.DeclineReason
+	if to == models.TransactionStatusDeclined {
+		tx.DeclineReason = reason
+	}
+
+	tx.Status = to
+	tx.UpdatedAt = now
+	err := s.store.RunUnitOfWork(ctx, func(ctx context.Context) error {
+		if err := s.store.TransitionTransaction(ctx, tx, change); err != nil {
+			return err
+		}
+		return s.recordEvent(ctx, models.AggregateTransaction, tx.ID, models.EventTransactionStatusChanged,
+			&models.TransactionEventPayload{Transaction: tx, Change: change})
+	})
+	if err != nil {
+		tx.Status, tx.DeclineReason = from, declineReason
+		s.logger.Error("Failed to persist status transition", "error", err, "transaction_id", tx.ID, "from", from, "to", to)
+		return fmt.Errorf("failed to persist status transition: %w", err)
+	}
+
+	return nil
+}
+
+// GetTransactionHistory retrieves the status transitions of a transaction, oldest first
+func (s *Service) GetTransactionHistory(ctx context.Context, transactionID string) ([]*models.StatusChange, error) {
+	if _, err := s.store.GetTransaction(ctx, transactionID); err != nil {
+		return nil, err
+	}
+
+	return s.store.GetStatusHistory(ctx, transactionID)
+}
@@ -0,0 +1,122 @@
+package ledger_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/araesf/ledgertime/internal/ledger"
+	"github.com/araesf/ledgertime/internal/models"
+)
+
+// historyOf returns a transaction's status changes formatted as "from -> to by actor"
+func historyOf(t *testing.T, service *ledger.Service, transactionID string) []string {
+	t.Helper()
+	history, err := service.GetTransactionHistory(context.Background(), transactionID)
+	if err != nil {
+		t.Fatalf("failed to get history: %v", err)
+	}
+	var changes []string
+	for _, change := range history {
+		if change.TransactionID != transactionID {
+			t.Errorf("history of %s includes a change of %s", transactionID, change.TransactionID)
+		}
+		changes = append(changes, fmt.Sprintf("%s -> %s by %s", change.FromStatus, change.ToStatus, change.Actor))
+	}
+	return changes
+}
+
+func TestTransactionHistory(t *testing.T) {
+	service, store := newTestService(t)
+	card := newTestCard(t, service, store)
+	ctx := context.Background()
+
+	paid, err := service.ProcessCardPayload(ctx, payment(card, 2500, "Coffee Shop"))
+	if err != nil {
+		t.Fatalf("payment failed: %v", err)
+	}
+	declined, err := service.ProcessCardPayload(ctx, payment(card, 100001, "Jeweller"))
+	if err != nil {
+		t.Fatalf("payment failed: %v", err)
+	}
+
+	authorization, err := service.Authorize(ctx, payment(card, 4000, "Hotel"))
+	if err != nil {
+		t.Fatalf("authorization failed: %v", err)
+	}
+	if _, err := service.IncrementAuthorization(ctx, authorization.ID, 500); err != nil {
+		t.Fatalf("increment failed: %v", err)
+	}
+	if _, err := service.Capture(ctx, authorization.ID, 3000); err != nil {
+		t.Fatalf("capture failed: %v", err)
+	}
+	refund, err := service.Refund(ctx, authorization.ID, 1000, "damaged")
+	if err != nil {
+		t.Fatalf("refund failed: %v", err)
+	}
+	if _, err := service.Refund(ctx, authorization.ID, 0, "returned"); err != nil {
+		t.Fatalf("refund failed: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		id   string
+		want []string
+	}{
+		{"payment", paid.ID, []string{"pending -> settled by processor"}},
+		{"declined payment", declined.ID, []string{"pending -> declined by processor"}},
+		{"authorization", authorization.ID, []string{
+			"pending -> authorized by processor",
+			"authorized -> authorized by incremental-authorization",
+			"authorized -> settled by capture",
+			"settled -> partially_refunded by refund",
+			"partially_refunded -> refunded by refund",
+		}},
+		{"refund", refund.ID, []string{"pending -> settled by refund"}},
+	}
+	for _, tt := range tests {
+		got := historyOf(t, service, tt.id)
+		if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("%s history = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	if _, err := service.GetTransactionHistory(ctx, "missing"); !errors.Is(err, models.ErrNotFound) {
+		t.Errorf("history of a missing transaction: err=%v, want %v", err, models.ErrNotFound)
+	}
+}
+
+func TestCanTransition(t *testing.T) {
+	tests := []struct {
+		from, to string
+		allowed  bool
+	}{
+		{models.TransactionStatusPending, models.TransactionStatusAuthorized, true},
+		{models.TransactionStatusPending, models.TransactionStatusSettled, true},
+		{models.TransactionStatusPending, models.TransactionStatusDeclined, true},
+		{models.TransactionStatusPending, models.TransactionStatusInReview, true},
+		{models.TransactionStatusInReview, models.TransactionStatusSettled, true},
+		{models.TransactionStatusInReview, models.TransactionStatusDeclined, true},
+		{models.TransactionStatusAuthorized, models.TransactionStatusSettled, true},
+		{models.TransactionStatusAuthorized, models.TransactionStatusVoided, true},
+		{models.TransactionStatusAuthorized, models.TransactionStatusExpired, true},
+		{models.TransactionStatusSettled, models.TransactionStatusPartiallyRefunded, true},
+		{models.TransactionStatusPartiallyRefunded, models.TransactionStatusPartiallyRefunded, true},
+		{models.TransactionStatusPartiallyRefunded, models.TransactionStatusRefunded, true},
+
+		{models.TransactionStatusPending, models.TransactionStatusRefunded, false},
+		{models.TransactionStatusDeclined, models.TransactionStatusSettled, false},
+		{models.TransactionStatusSettled, models.TransactionStatusAuthorized, false},
+		{models.TransactionStatusRefunded, models.TransactionStatusPartiallyRefunded, false},
+		{models.TransactionStatusVoided, models.TransactionStatusSettled, false},
+		{models.TransactionStatusExpired, models.TransactionStatusAuthorized, false},
+		{"unknown", models.TransactionStatusSettled, false},
+	}
+
+	for _, tt := range tests {
+		if got := ledger.CanTransition(tt.from, tt.to); got != tt.allowed {
+			t.Errorf("CanTransition(%s, %s) = %v, want %v", tt.from, tt.to, got, tt.allowed)
+		}
+	}
+}
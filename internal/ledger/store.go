@@ -0,0 +1,225 @@
+package ledger
+
+import (
+	"context"
+	"time"
+
+	"github.com/araesf/ledgertime/internal/fraud"
+	"github.com/araesf/ledgertime/internal/models"
+)
+
+// Store persists everything the ledger service reads and writes. Implementations return the
+// models package's store errors (models.ErrNotFound, models.ErrStatusConflict, ...) wrapped,
+// so callers can rely on errors.Is whichever store they run against.
+type Store interface {
+	UnitOfWork
+	UserStore
+	CardStore
+	TransactionStore
+	AccountStore
+	IdempotencyStore
+	ReviewStore
+	MerchantStore
+	CategoryStore
+	BudgetStore
+	OutboxStore
+}
+
+// UnitOfWork groups store calls into one atomic change
+type UnitOfWork interface {
+	// RunUnitOfWork runs fn so that the writes of the store calls made with the context it is
+	// given take effect together, or not at all if fn returns an error. fn may run again after
+	// a conflict with a concurrent unit of work, so it must be safe to repeat.
+	RunUnitOfWork(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// UserStore persists users
+type UserStore interface {
+	CreateUser(ctx context.Context, user *models.User) error
+	GetUser(ctx context.Context, id string) (*models.User, error)
+}
+
+// CardStore persists cards, their status history and spending limits
+type CardStore interface {
+	CreateCard(ctx context.Context, card *models.Card) error
+	GetCard(ctx context.Context, id string) (*models.Card, error)
+	// GetCardByPANHash returns the card whose number has a lookup hash, regardless of its status
+	GetCardByPANHash(ctx context.Context, panHash string) (*models.Card, error)
+	// GetCardByToken returns the card with a token regardless of its status
+	GetCardByToken(ctx context.Context, token string) (*models.Card, error)
+	// TransitionCard changes a card's status unless it changed since the card was read
+	// (models.ErrCardStatusConflict), recording the change in the card's status history
+	TransitionCard(ctx context.Context, card *models.Card, change *models.CardStatusChange) error
+	// ReplaceCard moves the old card to its final status, issues the replacement and copies the
+	// old card's spending limits to it, all or nothing
+	ReplaceCard(ctx context.Context, old, replacement *models.Card, change *models.CardStatusChange) error
+	// GetCardStatusHistory returns a card's status changes, oldest first
+	GetCardStatusHistory(ctx context.Context, cardID string) ([]*models.CardStatusChange, error)
+	// GetExpiredCards returns active or frozen cards whose expiry has passed, soonest expired first
+	GetExpiredCards(ctx context.Context, now time.Time, limit int) ([]*models.Card, error)
+
+	GetSpendingLimits(ctx context.Context, scope, scopeID string) (*models.SpendingLimits, error)
+	// LockSpendingLimits loads the limits of a card or user and holds them until the unit of work
+	// ctx runs in ends (models.ErrNotInUnitOfWork outside one), so concurrent payments checked
+	// against the same limits sum their windowed spend one at a time
+	LockSpendingLimits(ctx context.Context, scope, scopeID string) (*models.SpendingLimits, error)
+	// SetSpendingLimits creates or replaces the limits of a card or user
+	SetSpendingLimits(ctx context.Context, limits *models.SpendingLimits) error
+}
+
+// TransactionStore persists transactions and their status history and answers the spending
+// queries over them. Net spend counts accepted payments when made and deducts settled refunds
+// when issued.
+type TransactionStore interface {
+	fraud.History
+
+	CreateTransaction(ctx context.Context, tx *models.Transaction) error
+	GetTransaction(ctx context.Context, id string) (*models.Transaction, error)
+	// GetTransactionsByUser returns a user's transactions, newest first
+	GetTransactionsByUser(ctx context.Context, userID string, limit, offset int) ([]*models.Transaction, error)
+	// GetCardTransactions returns the transactions of a card and the cards it replaced, newest first
+	GetCardTransactions(ctx context.Context, cardID string, limit, offset int) ([]*models.Transaction, error)
+	// CreateLinkedTransaction saves a refund or reversal unless the transactions linked to the
+	// original would exceed its amount (models.ErrRefundLimitExceeded)
+	CreateLinkedTransaction(ctx context.Context, tx *models.Transaction) error
+	// GetLinkedTransactions returns the refunds and reversals of a transaction, oldest first
+	GetLinkedTransactions(ctx context.Context, parentID string) ([]*models.Transaction, error)
+	// TransitionTransaction moves a transaction from one status to another unless it changed
+	// since the transaction was read (models.ErrStatusConflict), persisting its amounts and decline
+	// reason and recording the change in its status history
+	TransitionTransaction(ctx context.Context, tx *models.Transaction, change *models.StatusChange) error
+	// GetStatusHistory returns a transaction's status changes, oldest first
+	GetStatusHistory(ctx context.Context, transactionID string) ([]*models.StatusChange, error)
+	SetTransactionFraud(ctx context.Context, id string, score int, decision string, reasons []string) error
+	// SetTransactionCategory recategorizes a transaction together with its refunds and reversals
+	SetTransactionCategory(ctx context.Context, id, category, source string) error
+	// GetRuleCategorizableTransactions pages through a user's payments whose category was not
+	// set by the user, in ID order starting after afterID
+	GetRuleCategorizableTransactions(ctx context.Context, userID, afterID string, limit int) ([]*models.Transaction, error)
+	// GetSpendingSince sums the accepted and in-review payments of a card (with the cards it
+	// replaced) or user per currency since a time, excluding one transaction. An empty
+	// category sums all categories.
+	GetSpendingSince(ctx context.Context, scope, scopeID, category string, since time.Time, excludeID string) (map[string]int64, error)
+	// GetSpending sums a user's net spend per interval and group between the UTC dates from
+	// and to, ordered by interval and group
+	GetSpending(ctx context.Context, userID, currency, interval, groupBy string, from, to time.Time) ([]models.SpendingRow, error)
+	// GetSpendingTotals sums a user's net spend per group between the UTC dates from and to,
+	// largest first. A zero from or to leaves that end open and a zero limit returns every group.
+	GetSpendingTotals(ctx context.Context, userID, currency, groupBy string, from, to time.Time, limit int) ([]models.SpendingTotal, error)
+}
+
+// AccountStore persists ledger accounts, their postings and the holds against them
+type AccountStore interface {
+	// EnsureUserAccount returns a user's wallet in a currency, opening it if needed
+	EnsureUserAccount(ctx context.Context, userID, currency string, overdraftLimit int64) (*models.Account, error)
+	// GetUserAccount returns a user's wallet in a currency, or models.ErrNotFound if it was never opened
+	GetUserAccount(ctx context.Context, userID, currency string) (*models.Account, error)
+	// EnsureSystemAccount returns the shared account of a type in a currency, opening it if needed
+	EnsureSystemAccount(ctx context.Context, accountType, currency string) (*models.Account, error)
+	SetOverdraftLimit(ctx context.Context, accountID string, limit int64) error
+	// CreatePostings records balanced postings and moves the balances of their accounts, failing
+	// with models.ErrInsufficientFunds if a wallet would pass its overdraft limit
+	CreatePostings(ctx context.Context, postings []*models.Posting) error
+
+	// CreateHold places a hold, failing with models.ErrInsufficientFunds if its wallet cannot cover it
+	CreateHold(ctx context.Context, hold *models.Hold) error
+	GetActiveHoldByTransaction(ctx context.Context, transactionID string) (*models.Hold, error)
+	// IncreaseHold raises an active hold by delta and pushes out its expiry
+	IncreaseHold(ctx context.Context, holdID string, delta int64, expiresAt time.Time) error
+	// ReleaseHold returns the held funds to the account and closes the hold with the given status
+	ReleaseHold(ctx context.Context, holdID, status string) error
+	// GetExpiredHolds returns active holds whose expiry has passed, soonest expired first
+	GetExpiredHolds(ctx context.Context, now time.Time, limit int) ([]*models.Hold, error)
+}
+
+// IdempotencyStore persists idempotency keys and the responses stored under them
+type IdempotencyStore interface {
+	// ClaimIdempotencyKey reserves a key for processing until the unit of work ctx runs in ends.
+	// It returns claimed=true when the caller now owns the key; otherwise it waits for any unit
+	// holding the key to end and returns the existing record.
+	ClaimIdempotencyKey(ctx context.Context, rec *models.IdempotencyRecord) (*models.IdempotencyRecord, bool, error)
+	// CompleteIdempotencyKey stores the response for a claimed key
+	CompleteIdempotencyKey(ctx context.Context, operation, key, transactionID string, response []byte) error
+}
+
+// ReviewStore persists the manual review queue
+type ReviewStore interface {
+	CreateReviewItem(ctx context.Context, item *models.ReviewItem) error
+	GetReviewItem(ctx context.Context, id string) (*models.ReviewItem, error)
+	// GetReviewItems lists review items with a status, oldest first. An empty status lists open items.
+	GetReviewItems(ctx context.Context, status string, limit, offset int) ([]*models.ReviewItem, error)
+	// ClaimReviewItem assigns an open review to an analyst
+	ClaimReviewItem(ctx context.Context, id, analyst string, now time.Time) (*models.ReviewItem, error)
+	// DecideReviewItem closes an open review with a final status
+	DecideReviewItem(ctx context.Context, id, status, decidedBy, notes string, now time.Time) (*models.ReviewItem, error)
+	// GetExpiredReviewItems returns open review items whose deadline has passed
+	GetExpiredReviewItems(ctx context.Context, now time.Time, limit int) ([]*models.ReviewItem, error)
+}
+
+// MerchantStore persists canonical merchants
+type MerchantStore interface {
+	// FindOrCreateMerchant returns the merchant with the given merchant's normalized name,
+	// creating it first if none exists
+	FindOrCreateMerchant(ctx context.Context, merchant *models.Merchant) (*models.Merchant, error)
+	GetMerchant(ctx context.Context, id string) (*models.Merchant, error)
+	// GetMerchants lists merchants by name
+	GetMerchants(ctx context.Context, limit, offset int) ([]*models.Merchant, error)
+	// UpdateMerchant saves a merchant's display details
+	UpdateMerchant(ctx context.Context, merchant *models.Merchant) error
+}
+
+// CategoryStore persists user-defined categories and categorization rules
+type CategoryStore interface {
+	// CreateCategory adds a category unless the user has one by that name (models.ErrCategoryExists)
+	CreateCategory(ctx context.Context, category *models.Category) error
+	GetCategory(ctx context.Context, id string) (*models.Category, error)
+	// GetCategories lists a user's own categories by name
+	GetCategories(ctx context.Context, userID string) ([]*models.Category, error)
+	// DeleteCategory removes a category unless a rule assigns it (models.ErrCategoryInUse)
+	DeleteCategory(ctx context.Context, id string) error
+
+	CreateCategoryRule(ctx context.Context, rule *models.CategoryRule) error
+	GetCategoryRule(ctx context.Context, id string) (*models.CategoryRule, error)
+	// GetCategoryRules lists a user's rules in evaluation order
+	GetCategoryRules(ctx context.Context, userID string) ([]*models.CategoryRule, error)
+	UpdateCategoryRule(ctx context.Context, rule *models.CategoryRule) error
+	DeleteCategoryRule(ctx context.Context, id string) error
+}
+
+// BudgetStore persists budgets and their alerts
+type BudgetStore interface {
+	// CreateBudget adds a budget unless the user budgets the category for the same period
+	// (models.ErrBudgetExists)
+	CreateBudget(ctx context.Context, budget *models.Budget) error
+	GetBudget(ctx context.Context, id string) (*models.Budget, error)
+	// GetBudgets lists a user's budgets by category and period
+	GetBudgets(ctx context.Context, userID string) ([]*models.Budget, error)
+	// UpdateBudget saves a budget's amount, currency and rollover
+	UpdateBudget(ctx context.Context, budget *models.Budget) error
+	DeleteBudget(ctx context.Context, id string) error
+	// GetBudgetSpending sums a budget's net spend per period from the UTC date since up to
+	// until, keyed by the UTC start of each period
+	GetBudgetSpending(ctx context.Context, budget *models.Budget, since, until time.Time) (map[time.Time]int64, error)
+
+	// CreateBudgetAlert records an alert unless its budget already alerted the threshold this
+	// period. Reports whether the alert was created.
+	CreateBudgetAlert(ctx context.Context, alert *models.BudgetAlert) (bool, error)
+	// GetBudgetAlerts lists a user's budget alerts, newest first
+	GetBudgetAlerts(ctx context.Context, userID string, limit, offset int) ([]*models.BudgetAlert, error)
+}
+
+// OutboxStore persists domain events until the outbox relay has published them
+type OutboxStore interface {
+	// CreateOutboxEvent saves an event, assigning its sequence. Events are saved in the unit of
+	// work of the change they announce, after that change, so they never outlive or precede it.
+	CreateOutboxEvent(ctx context.Context, event *models.OutboxEvent) error
+	// LockOutbox takes the outbox until the unit of work ctx runs in ends, reporting false
+	// without waiting when another relay holds it (models.ErrNotInUnitOfWork outside one)
+	LockOutbox(ctx context.Context) (bool, error)
+	// GetPendingOutboxEvents returns unsent events due by now in sequence order, leaving out
+	// events queued behind an earlier event of their aggregate that is waiting to be retried
+	GetPendingOutboxEvents(ctx context.Context, now time.Time, limit int) ([]*models.OutboxEvent, error)
+	MarkOutboxEventSent(ctx context.Context, id string, sentAt time.Time) error
+	// MarkOutboxEventFailed records a failed publish attempt and when to try again
+	MarkOutboxEventFailed(ctx context.Context, id, lastError string, nextAttemptAt time.Time) error
+}
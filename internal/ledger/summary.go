@@ -0,0 +1,183 @@
+package ledger
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/araesf/ledgertime/internal/models"
+)
+
+// Summary limits
+const (
+	defaultTopMerchants = 5
+	maxTopMerchants     = 50
+)
+
+// SummaryQuery selects the transactions a summary covers
+type SummaryQuery struct {
+	UserID       string
+	Currency     string    // Only transactions billed in this currency count; empty for the base currency
+	From         time.Time // Zero for no lower bound
+	To           time.Time // Exclusive; zero for no upper bound
+	TopMerchants int       // How many merchants to rank; zero for the default
+}
+
+// Summary aggregates a user's net spend, accepted payments less settled refunds, over a date range
+type Summary struct {
+	UserID            string           `json:"user_id"`
+	Currency          string           `json:"currency"`
+	From              *time.Time       `json:"from"` // Nil when unbounded
+	To                *time.Time       `json:"to"`   // Exclusive; nil when unbounded
+	TotalAmount       int64            `json:"total_amount"`
+	TotalTransactions int              `json:"total_transactions"` // Accepted payments
+	AvgAmount         int64            `json:"avg_amount"`         // Net spend per accepted payment
+	TopCategory       string           `json:"top_category"`
+	TopMerchant       string           `json:"top_merchant"`
+	CategoryBreakdown []*CategorySpend `json:"category_breakdown"` // Largest first
+	TopMerchants      []*MerchantSpend `json:"top_merchants"`      // Largest first
+}
+
+// CategorySpend is a category's part of a summary
+type CategorySpend struct {
+	Category string  `json:"category"`
+	Amount   int64   `json:"amount"`
+	Count    int     `json:"count"`
+	Share    float64 `json:"share"` // Percent of the summary's total amount
+}
+
+// MerchantSpend is a merchant's part of a summary
+type MerchantSpend struct {
+	MerchantName string  `json:"merchant_name"`
+	Amount       int64   `json:"amount"`
+	Count        int     `json:"count"`
+	Share        float64 `json:"share"` // Percent of the summary's total amount
+}
+
+// ParseDateRange reads optional inclusive YYYY-MM-DD dates as the start of from and the
+// exclusive end of to. Empty dates give zero times.
+func ParseDateRange(from, to string) (time.Time, time.Time, error) {
+	var start, end time.Time
+	if from != "" {
+		date, err := time.Parse("2006-01-02", from)
+		if err != nil {
+			return start, end, fmt.Errorf("from must be formatted as YYYY-MM-DD, got: %q", from)
+		}
+		start = date
+	}
+	if to != "" {
+		date, err := time.Parse("2006-01-02", to)
+		if err != nil {
+			return start, end, fmt.Errorf("to must be formatted as YYYY-MM-DD, got: %q", to)
+		}
+		end = date.AddDate(0, 0, 1)
+	}
+	return start, end, nil
+}
+
+// ValidateSummaryQuery checks a summary's date range and merchant count
+func ValidateSummaryQuery(query *SummaryQuery) error {
+	if !query.From.IsZero() && !query.To.IsZero() && !query.From.Before(query.To) {
+		return fmt.Errorf("from must be before to")
+	}
+
+	if query.TopMerchants == 0 {
+		query.TopMerchants = defaultTopMerchants
+	}
+	if query.TopMerchants < 0 || query.TopMerchants > maxTopMerchants {
+		return fmt.Errorf("top merchants must be between 1 and %d, got: %d", maxTopMerchants, query.TopMerchants)
+	}
+
+	return nil
+}
+
+// GetUserSummary summarizes a user's spend with a per-category breakdown and their top merchants
+func (s *Service) GetUserSummary(ctx context.Context, query *SummaryQuery) (*Summary, error) {
+	s.logger.Info("Getting user summary", "user_id", query.UserID)
+
+	if err := ValidateSummaryQuery(query); err != nil {
+		return nil, err
+	}
+	if _, err := s.store.GetUser(ctx, query.UserID); err != nil {
+		return nil, err
+	}
+
+	currency, err := s.walletCurrency(query.Currency)
+	if err != nil {
+		return nil, err
+	}
+
+	categories, err := s.store.GetSpendingTotals(ctx, query.UserID, currency, models.SpendingGroupCategory, query.From, query.To, 0)
+	if err != nil {
+		s.logger.Error("Failed to get user summary", "error", err, "user_id", query.UserID)
+		return nil, fmt.Errorf("failed to get user summary: %w", err)
+	}
+
+	merchants, err := s.store.GetSpendingTotals(ctx, query.UserID, currency, models.SpendingGroupMerchant, query.From, query.To, query.TopMerchants)
+	if err != nil {
+		s.logger.Error("Failed to get user summary", "error", err, "user_id", query.UserID)
+		return nil, fmt.Errorf("failed to get user summary: %w", err)
+	}
+
+	return newSummary(query, currency, categories, merchants), nil
+}
+
+// newSummary builds a summary from its category and merchant totals
+func newSummary(query *SummaryQuery, currency string, categories, merchants []models.SpendingTotal) *Summary {
+	summary := &Summary{
+		UserID:            query.UserID,
+		Currency:          currency,
+		CategoryBreakdown: []*CategorySpend{},
+		TopMerchants:      []*MerchantSpend{},
+	}
+	if !query.From.IsZero() {
+		summary.From = &query.From
+	}
+	if !query.To.IsZero() {
+		summary.To = &query.To
+	}
+
+	// Every transaction has a category, so the categories add up to the total
+	for _, c := range categories {
+		summary.TotalAmount += c.Amount
+		summary.TotalTransactions += c.Count
+	}
+	if summary.TotalTransactions > 0 {
+		summary.AvgAmount = summary.TotalAmount / int64(summary.TotalTransactions)
+	}
+
+	for _, c := range categories {
+		summary.CategoryBreakdown = append(summary.CategoryBreakdown, &CategorySpend{
+			Category: c.Group,
+			Amount:   c.Amount,
+			Count:    c.Count,
+			Share:    share(c.Amount, summary.TotalAmount),
+		})
+	}
+	for _, m := range merchants {
+		summary.TopMerchants = append(summary.TopMerchants, &MerchantSpend{
+			MerchantName: m.Group,
+			Amount:       m.Amount,
+			Count:        m.Count,
+			Share:        share(m.Amount, summary.TotalAmount),
+		})
+	}
+
+	if len(categories) > 0 {
+		summary.TopCategory = categories[0].Group
+	}
+	if len(merchants) > 0 {
+		summary.TopMerchant = merchants[0].Group
+	}
+
+	return summary
+}
+
+// share returns amount as a percentage of total, rounded to two decimals
+func share(amount, total int64) float64 {
+	if total <= 0 {
+		return 0
+	}
+	return math.Round(float64(amount)*10000/float64(total)) / 100
+}
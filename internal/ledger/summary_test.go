@@ -0,0 +1,169 @@
+package ledger_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/araesf/ledgertime/internal/ledger"
+	"github.com/araesf/ledgertime/internal/models"
+)
+
+func TestUserSummary(t *testing.T) {
+	service, store := newTestService(t)
+	card := newTestCard(t, service, store)
+	ctx := context.Background()
+
+	purchases := []struct {
+		amount   int64
+		merchant string
+		category string
+		at       time.Time
+	}{
+		{20000, "Airline", "travel", date(2024, 2, 28)},
+		{500, "Coffee Shop", "dining", date(2024, 3, 1)},
+		{500, "Coffee Shop", "dining", date(2024, 3, 2)},
+		{500, "Coffee Shop", "dining", date(2024, 3, 3)},
+		{4000, "Bookshop", "shopping", date(2024, 3, 10)},
+		{10000, "Hotel", "travel", date(2024, 3, 20)},
+		{1500, "Grocer", "groceries", date(2024, 3, 31).Add(23 * time.Hour)},
+		{9900, "Hotel", "travel", date(2024, 4, 1)},
+	}
+	var bookshop *models.Transaction
+	for _, p := range purchases {
+		payload := payment(card, p.amount, p.merchant)
+		payload.Category = p.category
+		payload.Timestamp = p.at.Format(time.RFC3339)
+		transaction, err := service.ProcessCardPayload(ctx, payload)
+		if err != nil {
+			t.Fatalf("payment failed: %v", err)
+		}
+		if p.merchant == "Bookshop" {
+			bookshop = transaction
+		}
+	}
+
+	summary, err := service.GetUserSummary(ctx, &ledger.SummaryQuery{
+		UserID:       card.UserID,
+		From:         date(2024, 3, 1),
+		To:           date(2024, 4, 1),
+		TopMerchants: 3,
+	})
+	if err != nil {
+		t.Fatalf("GetUserSummary() = %v", err)
+	}
+
+	if summary.TotalAmount != 17000 || summary.TotalTransactions != 6 || summary.AvgAmount != 2833 {
+		t.Errorf("got %d over %d payments, averaging %d; want 17000 over 6, averaging 2833",
+			summary.TotalAmount, summary.TotalTransactions, summary.AvgAmount)
+	}
+	if summary.TopCategory != "travel" || summary.TopMerchant != "Hotel" {
+		t.Errorf("top category %s and merchant %s, want travel and Hotel", summary.TopCategory, summary.TopMerchant)
+	}
+
+	// Largest first, ties by name, with shares of the total
+	checkSpend(t, "categories", categorySpend(summary), []string{
+		"travel 10000 (1) 58.82%",
+		"shopping 4000 (1) 23.53%",
+		"dining 1500 (3) 8.82%",
+		"groceries 1500 (1) 8.82%",
+	})
+	checkSpend(t, "top merchants", merchantSpend(summary), []string{
+		"Hotel 10000 (1) 58.82%",
+		"Bookshop 4000 (1) 23.53%",
+		"Coffee Shop 1500 (3) 8.82%",
+	})
+
+	// Refunds come off their merchant's and category's spend but not the payment count
+	if _, err := service.Refund(ctx, bookshop.ID, 0, "returned"); err != nil {
+		t.Fatalf("refund failed: %v", err)
+	}
+	summary, err = service.GetUserSummary(ctx, &ledger.SummaryQuery{UserID: card.UserID})
+	if err != nil {
+		t.Fatalf("GetUserSummary() = %v", err)
+	}
+	if summary.From != nil || summary.To != nil {
+		t.Errorf("summary covers %v to %v, want no bounds", summary.From, summary.To)
+	}
+	if summary.TotalAmount != 42900 || summary.TotalTransactions != 8 {
+		t.Errorf("got %d over %d payments, want 42900 over 8", summary.TotalAmount, summary.TotalTransactions)
+	}
+	checkSpend(t, "top merchants", merchantSpend(summary), []string{
+		"Airline 20000 (1) 46.62%",
+		"Hotel 19900 (2) 46.39%",
+		"Coffee Shop 1500 (3) 3.50%",
+		"Grocer 1500 (1) 3.50%",
+		"Bookshop 0 (1) 0.00%",
+	})
+}
+
+func categorySpend(summary *ledger.Summary) []string {
+	var spend []string
+	for _, c := range summary.CategoryBreakdown {
+		spend = append(spend, fmt.Sprintf("%s %d (%d) %.2f%%", c.Category, c.Amount, c.Count, c.Share))
+	}
+	return spend
+}
+
+func merchantSpend(summary *ledger.Summary) []string {
+	var spend []string
+	for _, m := range summary.TopMerchants {
+		spend = append(spend, fmt.Sprintf("%s %d (%d) %.2f%%", m.MerchantName, m.Amount, m.Count, m.Share))
+	}
+	return spend
+}
+
+func checkSpend(t *testing.T, name string, got, want []string) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Errorf("%s = %q, want %q", name, got, want)
+		return
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			t.Errorf("%s[%d] = %s, want %s", name, i, got[i], want[i])
+		}
+	}
+}
+
+func TestValidateSummaryQuery(t *testing.T) {
+	from, to, err := ledger.ParseDateRange("2024-03-01", "2024-03-31")
+	if err != nil {
+		t.Fatalf("ParseDateRange() = %v", err)
+	}
+	if !from.Equal(date(2024, 3, 1)) || !to.Equal(date(2024, 4, 1)) {
+		t.Errorf("ParseDateRange() = %s, %s, want the start of March 1st and the end of March 31st", from, to)
+	}
+	for _, dates := range [][2]string{{"2024-3-1", ""}, {"", "31/03/2024"}} {
+		if _, _, err := ledger.ParseDateRange(dates[0], dates[1]); err == nil {
+			t.Errorf("ParseDateRange(%q, %q) accepted a malformed date", dates[0], dates[1])
+		}
+	}
+
+	tests := []struct {
+		name    string
+		query   ledger.SummaryQuery
+		top     int
+		wantErr bool
+	}{
+		{"defaults", ledger.SummaryQuery{}, 5, false},
+		{"open start", ledger.SummaryQuery{To: date(2024, 4, 1), TopMerchants: 50}, 50, false},
+		{"empty range", ledger.SummaryQuery{From: date(2024, 4, 1), To: date(2024, 4, 1)}, 0, true},
+		{"too many merchants", ledger.SummaryQuery{TopMerchants: 51}, 0, true},
+		{"negative merchants", ledger.SummaryQuery{TopMerchants: -1}, 0, true},
+	}
+	for _, tt := range tests {
+		query := tt.query
+		err := ledger.ValidateSummaryQuery(&query)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: query was accepted", tt.name)
+			}
+			continue
+		}
+		if err != nil || query.TopMerchants != tt.top {
+			t.Errorf("%s: ValidateSummaryQuery() = %v with %d top merchants, want %d", tt.name, err, query.TopMerchants, tt.top)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package ledger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/araesf/ledgertime/internal/models"
+)
+
+// sweepBatchSize bounds how many expired holds are loaded per query
+const sweepBatchSize = 100
+
+// ExpireAuthorizations releases holds that have passed their expiry and marks their transactions
+// expired. A hold that can't be released is logged and skipped so it doesn't hold up the rest of
+// the sweep; the returned error joins the failures of every skipped hold.
+func (s *Service) ExpireAuthorizations(ctx context.Context, now time.Time) (int, error) {
+	expired := 0
+	skipped := make(map[string]bool)
+	var errs []error
+	for {
+		holds, err := s.store.GetExpiredHolds(ctx, now, sweepBatchSize)
+		if err != nil {
+			return expired, errors.Join(append(errs, err)...)
+		}
+
+		progress := false
+		for _, hold := range holds {
+			if skipped[hold.ID] {
+				continue
+			}
+			if err := ctx.Err(); err != nil {
+				return expired, errors.Join(append(errs, err)...)
+			}
+
+			err := s.store.RunUnitOfWork(ctx, func(ctx context.Context) error {
+				transaction, err := s.store.GetTransaction(ctx, hold.TransactionID)
+				if err != nil {
+					return err
+				}
+				return s.releaseAuthorization(ctx, transaction, hold, models.HoldStatusExpired, models.TransactionStatusExpired, actorSweeper, "authorization expired")
+			})
+			if errors.Is(err, models.ErrHoldNotActive) {
+				// Captured or voided concurrently
+				progress = true
+				continue
+			}
+			if err != nil {
+				s.logger.Error("Failed to expire authorization", "error", err, "hold_id", hold.ID, "transaction_id", hold.TransactionID)
+				skipped[hold.ID] = true
+				errs = append(errs, fmt.Errorf("hold %s of transaction %s: %w", hold.ID, hold.TransactionID, err))
+				continue
+			}
+			expired++
+			progress = true
+		}
+
+		// Skipped holds stay expired and come back first, so a batch without progress is the last
+		if len(holds) < sweepBatchSize || !progress {
+			return expired, errors.Join(errs...)
+		}
+	}
+}
+
+// RunAuthorizationSweeper periodically expires stale authorizations until ctx is cancelled
+func (s *Service) RunAuthorizationSweeper(ctx context.Context) {
+	s.logger.Info("Starting authorization sweeper", "interval", s.cfg.SweepInterval)
+
+	ticker := time.NewTicker(s.cfg.SweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			s.logger.Info("Authorization sweeper stopped")
+			return
+		case now := <-ticker.C:
+			expired, err := s.ExpireAuthorizations(ctx, now)
+			if err != nil {
+				s.logger.Error("Failed to expire authorizations", "error", err)
+			}
+			if expired > 0 {
+				s.logger.Info("Expired stale authorizations", "count", expired)
+			}
+		}
+	}
+}
+
+// StartSweepers runs the authorization, review and card expiry sweepers in the background until
+// ctx is cancelled. Every process that opens a store should start them: each sweep skips rows
+// another sweeper got to first, so several processes can sweep the same database.
+func (s *Service) StartSweepers(ctx context.Context) {
+	// Release stale authorizations
+	go s.RunAuthorizationSweeper(ctx)
+
+	// Auto-decline reviews nobody decided in time
+	go s.RunReviewSweeper(ctx)
+
+	// Mark cards past their expiry date as expired
+	go s.RunCardExpirySweeper(ctx)
+}
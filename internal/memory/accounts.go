@@ -0,0 +1,226 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/araesf/ledgertime/internal/models"
+	"github.com/google/uuid"
+)
+
+// Account operations
+
+// findAccount returns the account of a user, or the system account when userID is empty, of a
+// type in a currency
+func (s *Store) findAccount(userID, accountType, currency string) *models.Account {
+	for _, account := range s.accounts {
+		if account.UserID == userID && account.AccountType == accountType && account.Currency == currency {
+			return account
+		}
+	}
+	return nil
+}
+
+// openAccount returns an existing account or opens it
+func (s *Store) openAccount(userID, accountType, name, currency string, overdraftLimit int64) *models.Account {
+	account := s.findAccount(userID, accountType, currency)
+	if account == nil {
+		now := time.Now()
+		account = &models.Account{
+			ID:             uuid.New().String(),
+			UserID:         userID,
+			AccountType:    accountType,
+			Name:           name,
+			Currency:       currency,
+			OverdraftLimit: overdraftLimit,
+			CreatedAt:      now,
+			UpdatedAt:      now,
+		}
+		s.accounts[account.ID] = account
+	}
+	a := *account
+	return &a
+}
+
+func (s *Store) EnsureUserAccount(ctx context.Context, userID, currency string, overdraftLimit int64) (*models.Account, error) {
+	defer s.lock(ctx)()
+
+	if _, ok := s.users[userID]; !ok {
+		return nil, fmt.Errorf("user not found: %s: %w", userID, models.ErrNotFound)
+	}
+
+	name := fmt.Sprintf("User wallet (%s)", currency)
+	return s.openAccount(userID, models.AccountTypeUserWallet, name, currency, overdraftLimit), nil
+}
+
+func (s *Store) GetUserAccount(ctx context.Context, userID, currency string) (*models.Account, error) {
+	defer s.rlock(ctx)()
+
+	account := s.findAccount(userID, models.AccountTypeUserWallet, currency)
+	if account == nil {
+		return nil, fmt.Errorf("account not found: user %s, %s: %w", userID, currency, models.ErrNotFound)
+	}
+	a := *account
+	return &a, nil
+}
+
+func (s *Store) EnsureSystemAccount(ctx context.Context, accountType, currency string) (*models.Account, error) {
+	defer s.lock(ctx)()
+
+	name := fmt.Sprintf("System %s (%s)", accountType, currency)
+	return s.openAccount("", accountType, name, currency, 0), nil
+}
+
+func (s *Store) SetOverdraftLimit(ctx context.Context, accountID string, limit int64) error {
+	defer s.lock(ctx)()
+
+	account, ok := s.accounts[accountID]
+	if !ok {
+		return fmt.Errorf("account not found: %s: %w", accountID, models.ErrNotFound)
+	}
+	account.OverdraftLimit = limit
+	account.UpdatedAt = time.Now()
+	return nil
+}
+
+// Posting operations
+func (s *Store) CreatePostings(ctx context.Context, postings []*models.Posting) error {
+	defer s.lock(ctx)()
+
+	// Work out every balance first so a failing posting leaves all accounts untouched
+	balances := make(map[string]int64)
+	for _, p := range postings {
+		account, ok := s.accounts[p.AccountID]
+		if !ok {
+			return fmt.Errorf("failed to update account balance: account not found: %s: %w", p.AccountID, models.ErrNotFound)
+		}
+		balance, seen := balances[p.AccountID]
+		if !seen {
+			balance = account.LedgerBalance
+		}
+
+		delta := p.Amount
+		if p.Direction == models.PostingDirectionDebit {
+			delta = -delta
+		}
+
+		// Wallet debits may not push available balance past the overdraft limit
+		if delta < 0 && account.AccountType == models.AccountTypeUserWallet &&
+			balance-account.HeldAmount+account.OverdraftLimit+delta < 0 {
+			return fmt.Errorf("%w: account %s", models.ErrInsufficientFunds, p.AccountID)
+		}
+		balances[p.AccountID] = balance + delta
+	}
+
+	now := time.Now()
+	for accountID, balance := range balances {
+		s.accounts[accountID].LedgerBalance = balance
+		s.accounts[accountID].UpdatedAt = now
+	}
+	for _, p := range postings {
+		posting := *p
+		s.postings = append(s.postings, &posting)
+	}
+
+	return nil
+}
+
+// reserveFunds adjusts the held amount on an account, refusing increases that exceed the overdraft limit
+func (s *Store) reserveFunds(accountID string, delta int64) error {
+	account, ok := s.accounts[accountID]
+	if !ok || (delta > 0 && account.AccountType == models.AccountTypeUserWallet &&
+		account.LedgerBalance-account.HeldAmount+account.OverdraftLimit-delta < 0) {
+		return fmt.Errorf("%w: account %s", models.ErrInsufficientFunds, accountID)
+	}
+	account.HeldAmount += delta
+	account.UpdatedAt = time.Now()
+	return nil
+}
+
+// Hold operations
+func (s *Store) CreateHold(ctx context.Context, hold *models.Hold) error {
+	defer s.lock(ctx)()
+
+	if _, ok := s.holds[hold.ID]; ok {
+		return fmt.Errorf("failed to create hold: duplicate id %s", hold.ID)
+	}
+	if err := s.reserveFunds(hold.AccountID, hold.Amount); err != nil {
+		return err
+	}
+
+	h := *hold
+	s.holds[hold.ID] = &h
+	return nil
+}
+
+func (s *Store) GetActiveHoldByTransaction(ctx context.Context, transactionID string) (*models.Hold, error) {
+	defer s.rlock(ctx)()
+
+	for _, hold := range s.holds {
+		if hold.TransactionID == transactionID && hold.Status == models.HoldStatusActive {
+			h := *hold
+			return &h, nil
+		}
+	}
+	return nil, fmt.Errorf("active hold %w for transaction: %s", models.ErrNotFound, transactionID)
+}
+
+// activeHold returns a hold unless it is no longer active
+func (s *Store) activeHold(holdID string) (*models.Hold, error) {
+	hold, ok := s.holds[holdID]
+	if !ok || hold.Status != models.HoldStatusActive {
+		return nil, fmt.Errorf("%w: %s", models.ErrHoldNotActive, holdID)
+	}
+	return hold, nil
+}
+
+func (s *Store) IncreaseHold(ctx context.Context, holdID string, delta int64, expiresAt time.Time) error {
+	defer s.lock(ctx)()
+
+	hold, err := s.activeHold(holdID)
+	if err != nil {
+		return err
+	}
+	if err := s.reserveFunds(hold.AccountID, delta); err != nil {
+		return err
+	}
+
+	hold.Amount += delta
+	hold.ExpiresAt = expiresAt
+	hold.UpdatedAt = time.Now()
+	return nil
+}
+
+func (s *Store) ReleaseHold(ctx context.Context, holdID, status string) error {
+	defer s.lock(ctx)()
+
+	hold, err := s.activeHold(holdID)
+	if err != nil {
+		return err
+	}
+	if err := s.reserveFunds(hold.AccountID, -hold.Amount); err != nil {
+		return err
+	}
+
+	hold.Status = status
+	hold.UpdatedAt = time.Now()
+	return nil
+}
+
+func (s *Store) GetExpiredHolds(ctx context.Context, now time.Time, limit int) ([]*models.Hold, error) {
+	defer s.rlock(ctx)()
+
+	var holds []*models.Hold
+	for _, hold := range s.holds {
+		if hold.Status == models.HoldStatusActive && !hold.ExpiresAt.After(now) {
+			h := *hold
+			holds = append(holds, &h)
+		}
+	}
+	sort.Slice(holds, func(i, j int) bool { return holds[i].ExpiresAt.Before(holds[j].ExpiresAt) })
+
+	start, end := page(len(holds), limit, 0)
+	return holds[start:end], nil
+}
@@ -0,0 +1,152 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/araesf/ledgertime/internal/models"
+)
+
+// acceptedStatuses are the payment statuses that count as spent
+var acceptedStatuses = map[string]bool{
+	models.TransactionStatusAuthorized:        true,
+	models.TransactionStatusSettled:           true,
+	models.TransactionStatusPartiallyRefunded: true,
+	models.TransactionStatusRefunded:          true,
+}
+
+// netSpend returns what a transaction adds to net spend and to the payment count. Net spend
+// counts accepted payments when made and deducts settled refunds when issued; ok is false for
+// transactions that do neither.
+func netSpend(tx *models.Transaction) (amount int64, count int, ok bool) {
+	switch {
+	case tx.Type == models.TransactionTypePayment && acceptedStatuses[tx.Status]:
+		return tx.Amount, 1, true
+	case tx.Type == models.TransactionTypeRefund && tx.Status == models.TransactionStatusSettled:
+		return -tx.Amount, 0, true
+	}
+	return 0, 0, false
+}
+
+// spendingGroup returns the group a transaction's spend is counted in
+func spendingGroup(tx *models.Transaction, groupBy string) (string, error) {
+	switch groupBy {
+	case "":
+		return "", nil
+	case models.SpendingGroupCategory:
+		return strings.ToLower(tx.Category), nil
+	case models.SpendingGroupMerchant:
+		return tx.MerchantName, nil
+	case models.SpendingGroupCard:
+		return tx.CardID, nil
+	}
+	return "", fmt.Errorf("unknown spending group: %s", groupBy)
+}
+
+// utcDay returns the UTC date of a time as midnight
+func utcDay(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// truncate returns the start of the interval containing a UTC day. Weeks start on Monday.
+func truncate(day time.Time, interval string) time.Time {
+	switch interval {
+	case models.SpendingIntervalWeek:
+		return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+	case models.SpendingIntervalMonth:
+		return day.AddDate(0, 0, 1-day.Day())
+	}
+	return day
+}
+
+type spendingKey struct {
+	periodStart time.Time
+	group       string
+}
+
+// sumSpending adds up a user's net spend in a currency per interval and group between the UTC
+// dates from and to. A zero from or to leaves that end open; an empty interval sums the whole
+// range. Groups whose spend and count both net to zero are left out.
+func (s *Store) sumSpending(ctx context.Context, userID, currency, interval, groupBy string, from, to time.Time) (map[spendingKey]*models.SpendingRow, error) {
+	if _, err := spendingGroup(&models.Transaction{}, groupBy); err != nil {
+		return nil, err
+	}
+
+	defer s.rlock(ctx)()
+
+	sums := make(map[spendingKey]*models.SpendingRow)
+	for _, tx := range s.transactions {
+		amount, count, ok := netSpend(tx)
+		day := utcDay(tx.Timestamp)
+		if !ok || tx.UserID != userID || tx.Currency != currency ||
+			(!from.IsZero() && day.Before(utcDay(from))) || (!to.IsZero() && !day.Before(utcDay(to))) {
+			continue
+		}
+
+		group, _ := spendingGroup(tx, groupBy)
+		key := spendingKey{group: group}
+		if interval != "" {
+			key.periodStart = truncate(day, interval)
+		}
+		row, ok := sums[key]
+		if !ok {
+			row = &models.SpendingRow{PeriodStart: key.periodStart, Group: group}
+			sums[key] = row
+		}
+		row.Amount += amount
+		row.Count += count
+	}
+
+	for key, row := range sums {
+		if row.Amount == 0 && row.Count == 0 {
+			delete(sums, key)
+		}
+	}
+	return sums, nil
+}
+
+func (s *Store) GetSpending(ctx context.Context, userID, currency, interval, groupBy string, from, to time.Time) ([]models.SpendingRow, error) {
+	sums, err := s.sumSpending(ctx, userID, currency, interval, groupBy, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get spending: %w", err)
+	}
+
+	var spending []models.SpendingRow
+	for _, row := range sums {
+		spending = append(spending, *row)
+	}
+	sort.Slice(spending, func(i, j int) bool {
+		if !spending[i].PeriodStart.Equal(spending[j].PeriodStart) {
+			return spending[i].PeriodStart.Before(spending[j].PeriodStart)
+		}
+		return spending[i].Group < spending[j].Group
+	})
+	return spending, nil
+}
+
+func (s *Store) GetSpendingTotals(ctx context.Context, userID, currency, groupBy string, from, to time.Time, limit int) ([]models.SpendingTotal, error) {
+	sums, err := s.sumSpending(ctx, userID, currency, "", groupBy, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get spending totals: %w", err)
+	}
+
+	var totals []models.SpendingTotal
+	for _, row := range sums {
+		totals = append(totals, models.SpendingTotal{Group: row.Group, Amount: row.Amount, Count: row.Count})
+	}
+	sort.Slice(totals, func(i, j int) bool {
+		if totals[i].Amount != totals[j].Amount {
+			return totals[i].Amount > totals[j].Amount
+		}
+		return totals[i].Group < totals[j].Group
+	})
+
+	if limit > 0 && len(totals) > limit {
+		totals = totals[:limit]
+	}
+	return totals, nil
+}
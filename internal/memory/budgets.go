@@ -0,0 +1,156 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/araesf/ledgertime/internal/models"
+)
+
+// Budget operations
+func (s *Store) CreateBudget(ctx context.Context, budget *models.Budget) error {
+	defer s.lock(ctx)()
+
+	for _, existing := range s.budgets {
+		if existing.UserID == budget.UserID && existing.Category == budget.Category && existing.Period == budget.Period {
+			return fmt.Errorf("%w: %s %s", models.ErrBudgetExists, budget.Period, budget.Category)
+		}
+	}
+
+	b := *budget
+	s.budgets[budget.ID] = &b
+	return nil
+}
+
+func (s *Store) GetBudget(ctx context.Context, id string) (*models.Budget, error) {
+	defer s.rlock(ctx)()
+
+	budget, ok := s.budgets[id]
+	if !ok {
+		return nil, fmt.Errorf("budget %w: %s", models.ErrNotFound, id)
+	}
+	b := *budget
+	return &b, nil
+}
+
+func (s *Store) GetBudgets(ctx context.Context, userID string) ([]*models.Budget, error) {
+	defer s.rlock(ctx)()
+
+	var budgets []*models.Budget
+	for _, budget := range s.budgets {
+		if budget.UserID == userID {
+			b := *budget
+			budgets = append(budgets, &b)
+		}
+	}
+	sort.Slice(budgets, func(i, j int) bool {
+		if budgets[i].Category != budgets[j].Category {
+			return budgets[i].Category < budgets[j].Category
+		}
+		return budgets[i].Period < budgets[j].Period
+	})
+	return budgets, nil
+}
+
+func (s *Store) UpdateBudget(ctx context.Context, budget *models.Budget) error {
+	defer s.lock(ctx)()
+
+	stored, ok := s.budgets[budget.ID]
+	if !ok {
+		return fmt.Errorf("budget %w: %s", models.ErrNotFound, budget.ID)
+	}
+
+	stored.Amount = budget.Amount
+	stored.Currency = budget.Currency
+	stored.Rollover = budget.Rollover
+	stored.UpdatedAt = time.Now()
+	budget.UpdatedAt = stored.UpdatedAt
+	return nil
+}
+
+func (s *Store) DeleteBudget(ctx context.Context, id string) error {
+	defer s.lock(ctx)()
+
+	if _, ok := s.budgets[id]; !ok {
+		return fmt.Errorf("budget %w: %s", models.ErrNotFound, id)
+	}
+	delete(s.budgets, id)
+
+	// Alerts go with their budget
+	alerts := s.budgetAlerts[:0]
+	for _, alert := range s.budgetAlerts {
+		if alert.BudgetID != id {
+			alerts = append(alerts, alert)
+		}
+	}
+	s.budgetAlerts = alerts
+	return nil
+}
+
+// budgetIntervals maps budget periods to the intervals their spend is summed over
+var budgetIntervals = map[string]string{
+	models.BudgetPeriodWeekly:  models.SpendingIntervalWeek,
+	models.BudgetPeriodMonthly: models.SpendingIntervalMonth,
+}
+
+func (s *Store) GetBudgetSpending(ctx context.Context, budget *models.Budget, since, until time.Time) (map[time.Time]int64, error) {
+	interval, ok := budgetIntervals[budget.Period]
+	if !ok {
+		return nil, fmt.Errorf("unknown budget period: %s", budget.Period)
+	}
+
+	defer s.rlock(ctx)()
+
+	from, to := utcDay(since), utcDay(until)
+	spending := make(map[time.Time]int64)
+	for _, tx := range s.transactions {
+		amount, _, ok := netSpend(tx)
+		day := utcDay(tx.Timestamp)
+		if !ok || tx.UserID != budget.UserID || strings.ToLower(tx.Category) != budget.Category ||
+			tx.Currency != budget.Currency || day.Before(from) || !day.Before(to) {
+			continue
+		}
+		spending[truncate(day, interval)] += amount
+	}
+
+	return spending, nil
+}
+
+// Budget alert operations
+func (s *Store) CreateBudgetAlert(ctx context.Context, alert *models.BudgetAlert) (bool, error) {
+	defer s.lock(ctx)()
+
+	for _, existing := range s.budgetAlerts {
+		if existing.BudgetID == alert.BudgetID && existing.PeriodStart.Equal(alert.PeriodStart) && existing.Threshold == alert.Threshold {
+			return false, nil
+		}
+	}
+
+	a := *alert
+	s.budgetAlerts = append(s.budgetAlerts, &a)
+	return true, nil
+}
+
+func (s *Store) GetBudgetAlerts(ctx context.Context, userID string, limit, offset int) ([]*models.BudgetAlert, error) {
+	defer s.rlock(ctx)()
+
+	var alerts []*models.BudgetAlert
+	for _, alert := range s.budgetAlerts {
+		if alert.UserID == userID {
+			a := *alert
+			alerts = append(alerts, &a)
+		}
+	}
+	sort.SliceStable(alerts, func(i, j int) bool {
+		if !alerts[i].CreatedAt.Equal(alerts[j].CreatedAt) {
+			return alerts[i].CreatedAt.After(alerts[j].CreatedAt)
+		}
+		return alerts[i].Threshold > alerts[j].Threshold
+	})
+
+	start, end := page(len(alerts), limit, offset)
+	return alerts[start:end], nil
+}
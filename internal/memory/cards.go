@@ -0,0 +1,235 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/araesf/ledgertime/internal/models"
+	"github.com/araesf/ledgertime/internal/vault"
+)
+
+// User operations
+func (s *Store) CreateUser(ctx context.Context, user *models.User) error {
+	defer s.lock(ctx)()
+
+	if _, ok := s.users[user.ID]; ok {
+		return fmt.Errorf("failed to create user: duplicate id %s", user.ID)
+	}
+	for _, u := range s.users {
+		if u.Email == user.Email {
+			return fmt.Errorf("failed to create user: duplicate email %s", user.Email)
+		}
+	}
+
+	u := *user
+	s.users[user.ID] = &u
+	return nil
+}
+
+func (s *Store) GetUser(ctx context.Context, id string) (*models.User, error) {
+	defer s.rlock(ctx)()
+
+	user, ok := s.users[id]
+	if !ok {
+		return nil, fmt.Errorf("user not found: %s: %w", id, models.ErrNotFound)
+	}
+	u := *user
+	return &u, nil
+}
+
+// Card operations
+func copyCard(card *models.Card) *models.Card {
+	c := *card
+	c.CardNumber = vault.MaskLast4(c.Last4)
+	c.PANDataKey = append([]byte(nil), card.PANDataKey...)
+	c.PANCiphertext = append([]byte(nil), card.PANCiphertext...)
+	return &c
+}
+
+// insertCard adds a card, enforcing the unique ID, token and number
+func (s *Store) insertCard(card *models.Card) error {
+	if _, ok := s.cards[card.ID]; ok {
+		return fmt.Errorf("duplicate id %s", card.ID)
+	}
+	for _, c := range s.cards {
+		if c.Token == card.Token || c.PANHash == card.PANHash {
+			return fmt.Errorf("duplicate card %s", card.ID)
+		}
+	}
+
+	c := copyCard(card)
+	c.CardNumber = ""
+	s.cards[card.ID] = c
+	return nil
+}
+
+func (s *Store) CreateCard(ctx context.Context, card *models.Card) error {
+	defer s.lock(ctx)()
+
+	if err := s.insertCard(card); err != nil {
+		return fmt.Errorf("failed to create card: %w", err)
+	}
+	return nil
+}
+
+func (s *Store) GetCardByPANHash(ctx context.Context, panHash string) (*models.Card, error) {
+	defer s.rlock(ctx)()
+
+	for _, card := range s.cards {
+		if card.PANHash == panHash {
+			return copyCard(card), nil
+		}
+	}
+	return nil, fmt.Errorf("card %w", models.ErrNotFound)
+}
+
+func (s *Store) GetCardByToken(ctx context.Context, token string) (*models.Card, error) {
+	defer s.rlock(ctx)()
+
+	for _, card := range s.cards {
+		if card.Token == token {
+			return copyCard(card), nil
+		}
+	}
+	return nil, fmt.Errorf("card %w: %s", models.ErrNotFound, token)
+}
+
+func (s *Store) GetCard(ctx context.Context, id string) (*models.Card, error) {
+	defer s.rlock(ctx)()
+
+	card, ok := s.cards[id]
+	if !ok {
+		return nil, fmt.Errorf("card %w: %s", models.ErrNotFound, id)
+	}
+	return copyCard(card), nil
+}
+
+// transitionCard moves a card from one status to another and records the change
+func (s *Store) transitionCard(card *models.Card, change *models.CardStatusChange) error {
+	stored, ok := s.cards[card.ID]
+	if !ok || stored.Status != change.FromStatus {
+		return fmt.Errorf("%w: card %s is no longer %s", models.ErrCardStatusConflict, card.ID, change.FromStatus)
+	}
+
+	stored.Status = change.ToStatus
+	stored.IsActive = change.ToStatus == models.CardStatusActive
+	stored.UpdatedAt = change.CreatedAt
+
+	c := *change
+	s.cardHistory = append(s.cardHistory, &c)
+	return nil
+}
+
+func (s *Store) TransitionCard(ctx context.Context, card *models.Card, change *models.CardStatusChange) error {
+	defer s.lock(ctx)()
+
+	return s.transitionCard(card, change)
+}
+
+func (s *Store) ReplaceCard(ctx context.Context, old, replacement *models.Card, change *models.CardStatusChange) error {
+	defer s.lock(ctx)()
+
+	// Check the replacement first so a failure leaves the old card untouched
+	stored, ok := s.cards[old.ID]
+	if !ok || stored.Status != change.FromStatus {
+		return fmt.Errorf("%w: card %s is no longer %s", models.ErrCardStatusConflict, old.ID, change.FromStatus)
+	}
+	if err := s.insertCard(replacement); err != nil {
+		return fmt.Errorf("failed to create replacement card: %w", err)
+	}
+	if err := s.transitionCard(old, change); err != nil {
+		return err
+	}
+
+	if limits, ok := s.limits[limitKey{models.LimitScopeCard, old.ID}]; ok {
+		copied := copyLimits(limits)
+		copied.ScopeID = replacement.ID
+		copied.UpdatedAt = replacement.CreatedAt
+		s.limits[limitKey{models.LimitScopeCard, replacement.ID}] = copied
+	}
+
+	return nil
+}
+
+func (s *Store) GetCardStatusHistory(ctx context.Context, cardID string) ([]*models.CardStatusChange, error) {
+	defer s.rlock(ctx)()
+
+	var history []*models.CardStatusChange
+	for _, change := range s.cardHistory {
+		if change.CardID == cardID {
+			c := *change
+			history = append(history, &c)
+		}
+	}
+	sort.SliceStable(history, func(i, j int) bool {
+		if !history[i].CreatedAt.Equal(history[j].CreatedAt) {
+			return history[i].CreatedAt.Before(history[j].CreatedAt)
+		}
+		return history[i].ID < history[j].ID
+	})
+	return history, nil
+}
+
+func (s *Store) GetExpiredCards(ctx context.Context, now time.Time, limit int) ([]*models.Card, error) {
+	defer s.rlock(ctx)()
+
+	var cards []*models.Card
+	for _, card := range s.cards {
+		if (card.Status == models.CardStatusActive || card.Status == models.CardStatusFrozen) && !card.ExpiresAt.After(now) {
+			cards = append(cards, copyCard(card))
+		}
+	}
+	sort.Slice(cards, func(i, j int) bool { return cards[i].ExpiresAt.Before(cards[j].ExpiresAt) })
+
+	start, end := page(len(cards), limit, 0)
+	return cards[start:end], nil
+}
+
+// cardLineage returns the IDs of a card and every card it replaced, directly or indirectly
+func (s *Store) cardLineage(cardID string) map[string]bool {
+	lineage := make(map[string]bool)
+	for id := cardID; id != "" && !lineage[id]; {
+		card, ok := s.cards[id]
+		if !ok {
+			break
+		}
+		lineage[id] = true
+		id = card.ReplacesCardID
+	}
+	return lineage
+}
+
+// Spending limit operations
+func copyLimits(limits *models.SpendingLimits) *models.SpendingLimits {
+	l := *limits
+	l.BlockedCategories = append([]string(nil), limits.BlockedCategories...)
+	l.CategoryCaps = append([]models.CategoryCap(nil), limits.CategoryCaps...)
+	return &l
+}
+
+func (s *Store) GetSpendingLimits(ctx context.Context, scope, scopeID string) (*models.SpendingLimits, error) {
+	defer s.rlock(ctx)()
+
+	limits, ok := s.limits[limitKey{scope, scopeID}]
+	if !ok {
+		return nil, fmt.Errorf("%s spending limits %w: %s", scope, models.ErrNotFound, scopeID)
+	}
+	return copyLimits(limits), nil
+}
+
+// LockSpendingLimits only checks for a unit of work, which already holds the store's only lock
+func (s *Store) LockSpendingLimits(ctx context.Context, scope, scopeID string) (*models.SpendingLimits, error) {
+	if !s.inUnitOfWork(ctx) {
+		return nil, fmt.Errorf("failed to lock spending limits: %w", models.ErrNotInUnitOfWork)
+	}
+	return s.GetSpendingLimits(ctx, scope, scopeID)
+}
+
+func (s *Store) SetSpendingLimits(ctx context.Context, limits *models.SpendingLimits) error {
+	defer s.lock(ctx)()
+
+	s.limits[limitKey{limits.Scope, limits.ScopeID}] = copyLimits(limits)
+	return nil
+}
@@ -0,0 +1,142 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/araesf/ledgertime/internal/models"
+)
+
+// Category operations
+func (s *Store) CreateCategory(ctx context.Context, category *models.Category) error {
+	defer s.lock(ctx)()
+
+	for _, existing := range s.categories {
+		if existing.UserID == category.UserID && existing.Name == category.Name {
+			return fmt.Errorf("%w: %s", models.ErrCategoryExists, category.Name)
+		}
+	}
+
+	c := *category
+	s.categories[category.ID] = &c
+	return nil
+}
+
+func (s *Store) GetCategory(ctx context.Context, id string) (*models.Category, error) {
+	defer s.rlock(ctx)()
+
+	category, ok := s.categories[id]
+	if !ok {
+		return nil, fmt.Errorf("category %w: %s", models.ErrNotFound, id)
+	}
+	c := *category
+	return &c, nil
+}
+
+func (s *Store) GetCategories(ctx context.Context, userID string) ([]*models.Category, error) {
+	defer s.rlock(ctx)()
+
+	var categories []*models.Category
+	for _, category := range s.categories {
+		if category.UserID == userID {
+			c := *category
+			categories = append(categories, &c)
+		}
+	}
+	sort.Slice(categories, func(i, j int) bool { return categories[i].Name < categories[j].Name })
+	return categories, nil
+}
+
+func (s *Store) DeleteCategory(ctx context.Context, id string) error {
+	defer s.lock(ctx)()
+
+	category, ok := s.categories[id]
+	if !ok {
+		return fmt.Errorf("category %w: %s", models.ErrNotFound, id)
+	}
+	for _, rule := range s.rules {
+		if rule.UserID == category.UserID && rule.Category == category.Name {
+			return fmt.Errorf("%w: %s", models.ErrCategoryInUse, category.Name)
+		}
+	}
+
+	delete(s.categories, id)
+	return nil
+}
+
+// Category rule operations
+func (s *Store) CreateCategoryRule(ctx context.Context, rule *models.CategoryRule) error {
+	defer s.lock(ctx)()
+
+	if _, ok := s.rules[rule.ID]; ok {
+		return fmt.Errorf("failed to create category rule: duplicate id %s", rule.ID)
+	}
+
+	r := *rule
+	s.rules[rule.ID] = &r
+	return nil
+}
+
+func (s *Store) GetCategoryRule(ctx context.Context, id string) (*models.CategoryRule, error) {
+	defer s.rlock(ctx)()
+
+	rule, ok := s.rules[id]
+	if !ok {
+		return nil, fmt.Errorf("category rule %w: %s", models.ErrNotFound, id)
+	}
+	r := *rule
+	return &r, nil
+}
+
+func (s *Store) GetCategoryRules(ctx context.Context, userID string) ([]*models.CategoryRule, error) {
+	defer s.rlock(ctx)()
+
+	var rules []*models.CategoryRule
+	for _, rule := range s.rules {
+		if rule.UserID == userID {
+			r := *rule
+			rules = append(rules, &r)
+		}
+	}
+	sort.Slice(rules, func(i, j int) bool {
+		a, b := rules[i], rules[j]
+		if a.Priority != b.Priority {
+			return a.Priority < b.Priority
+		}
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.Before(b.CreatedAt)
+		}
+		return a.ID < b.ID
+	})
+	return rules, nil
+}
+
+func (s *Store) UpdateCategoryRule(ctx context.Context, rule *models.CategoryRule) error {
+	defer s.lock(ctx)()
+
+	stored, ok := s.rules[rule.ID]
+	if !ok {
+		return fmt.Errorf("category rule %w: %s", models.ErrNotFound, rule.ID)
+	}
+
+	stored.Field = rule.Field
+	stored.Operator = rule.Operator
+	stored.Value = rule.Value
+	stored.Category = rule.Category
+	stored.Priority = rule.Priority
+	stored.UpdatedAt = time.Now()
+	rule.UpdatedAt = stored.UpdatedAt
+	return nil
+}
+
+func (s *Store) DeleteCategoryRule(ctx context.Context, id string) error {
+	defer s.lock(ctx)()
+
+	if _, ok := s.rules[id]; !ok {
+		return fmt.Errorf("category rule %w: %s", models.ErrNotFound, id)
+	}
+	delete(s.rules, id)
+	return nil
+}
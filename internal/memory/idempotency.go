@@ -0,0 +1,48 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/araesf/ledgertime/internal/models"
+)
+
+// Idempotency key operations
+func copyIdempotencyRecord(rec *models.IdempotencyRecord) *models.IdempotencyRecord {
+	r := *rec
+	r.Response = append([]byte(nil), rec.Response...)
+	r.CompletedAt = copyTime(rec.CompletedAt)
+	return &r
+}
+
+// ClaimIdempotencyKey needs a unit of work, which holds the store's only lock until it ends
+func (s *Store) ClaimIdempotencyKey(ctx context.Context, rec *models.IdempotencyRecord) (*models.IdempotencyRecord, bool, error) {
+	if !s.inUnitOfWork(ctx) {
+		return nil, false, fmt.Errorf("failed to claim idempotency key: %w", models.ErrNotInUnitOfWork)
+	}
+
+	k := idempotencyKey{rec.Operation, rec.Key}
+	if existing, ok := s.idempotency[k]; ok {
+		return copyIdempotencyRecord(existing), false, nil
+	}
+
+	claimed := copyIdempotencyRecord(rec)
+	claimed.Status = models.IdempotencyStatusInProgress
+	claimed.CreatedAt = rec.LockedAt
+	s.idempotency[k] = claimed
+	return rec, true, nil
+}
+
+func (s *Store) CompleteIdempotencyKey(ctx context.Context, operation, key, transactionID string, response []byte) error {
+	defer s.lock(ctx)()
+
+	if rec, ok := s.idempotency[idempotencyKey{operation, key}]; ok {
+		now := time.Now()
+		rec.Status = models.IdempotencyStatusCompleted
+		rec.TransactionID = transactionID
+		rec.Response = append([]byte(nil), response...)
+		rec.CompletedAt = &now
+	}
+	return nil
+}
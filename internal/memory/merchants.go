@@ -0,0 +1,77 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/araesf/ledgertime/internal/models"
+)
+
+// Merchant operations
+func (s *Store) FindOrCreateMerchant(ctx context.Context, merchant *models.Merchant) (*models.Merchant, error) {
+	defer s.lock(ctx)()
+
+	for _, existing := range s.merchants {
+		if existing.NormalizedName == merchant.NormalizedName {
+			m := *existing
+			return &m, nil
+		}
+	}
+	if _, ok := s.merchants[merchant.ID]; ok {
+		return nil, fmt.Errorf("failed to create merchant: duplicate id %s", merchant.ID)
+	}
+
+	m := *merchant
+	s.merchants[merchant.ID] = &m
+	return merchant, nil
+}
+
+func (s *Store) GetMerchant(ctx context.Context, id string) (*models.Merchant, error) {
+	defer s.rlock(ctx)()
+
+	merchant, ok := s.merchants[id]
+	if !ok {
+		return nil, fmt.Errorf("merchant %w: %s", models.ErrNotFound, id)
+	}
+	m := *merchant
+	return &m, nil
+}
+
+func (s *Store) GetMerchants(ctx context.Context, limit, offset int) ([]*models.Merchant, error) {
+	defer s.rlock(ctx)()
+
+	merchants := make([]*models.Merchant, 0, len(s.merchants))
+	for _, merchant := range s.merchants {
+		m := *merchant
+		merchants = append(merchants, &m)
+	}
+	sort.Slice(merchants, func(i, j int) bool {
+		if merchants[i].Name != merchants[j].Name {
+			return merchants[i].Name < merchants[j].Name
+		}
+		return merchants[i].ID < merchants[j].ID
+	})
+
+	start, end := page(len(merchants), limit, offset)
+	return merchants[start:end], nil
+}
+
+func (s *Store) UpdateMerchant(ctx context.Context, merchant *models.Merchant) error {
+	defer s.lock(ctx)()
+
+	stored, ok := s.merchants[merchant.ID]
+	if !ok {
+		return fmt.Errorf("merchant %w: %s", models.ErrNotFound, merchant.ID)
+	}
+
+	stored.Name = merchant.Name
+	stored.MCC = merchant.MCC
+	stored.City = merchant.City
+	stored.Country = merchant.Country
+	stored.LogoURL = merchant.LogoURL
+	stored.UpdatedAt = time.Now()
+	merchant.UpdatedAt = stored.UpdatedAt
+	return nil
+}
@@ -0,0 +1,91 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/araesf/ledgertime/internal/models"
+)
+
+// Outbox operations
+func copyOutboxEvent(event *models.OutboxEvent) *models.OutboxEvent {
+	e := *event
+	e.Payload = append([]byte(nil), event.Payload...)
+	e.SentAt = copyTime(event.SentAt)
+	return &e
+}
+
+func (s *Store) CreateOutboxEvent(ctx context.Context, event *models.OutboxEvent) error {
+	defer s.lock(ctx)()
+
+	s.outboxSequence++
+	event.Sequence = s.outboxSequence
+	s.outbox[event.ID] = copyOutboxEvent(event)
+	return nil
+}
+
+// LockOutbox always succeeds in a unit of work, which already holds the store's only lock
+func (s *Store) LockOutbox(ctx context.Context) (bool, error) {
+	if !s.inUnitOfWork(ctx) {
+		return false, fmt.Errorf("failed to lock outbox: %w", models.ErrNotInUnitOfWork)
+	}
+	return true, nil
+}
+
+func (s *Store) GetPendingOutboxEvents(ctx context.Context, now time.Time, limit int) ([]*models.OutboxEvent, error) {
+	defer s.rlock(ctx)()
+
+	var unsent []*models.OutboxEvent
+	for _, event := range s.outbox {
+		if event.SentAt == nil {
+			unsent = append(unsent, event)
+		}
+	}
+	sort.Slice(unsent, func(i, j int) bool {
+		return unsent[i].Sequence < unsent[j].Sequence
+	})
+
+	type aggregateKey struct{ aggregateType, aggregateID string }
+	waiting := make(map[aggregateKey]bool)
+	var events []*models.OutboxEvent
+	for _, event := range unsent {
+		k := aggregateKey{event.AggregateType, event.AggregateID}
+		if event.NextAttemptAt.After(now) {
+			waiting[k] = true
+			continue
+		}
+		if waiting[k] {
+			continue
+		}
+		events = append(events, copyOutboxEvent(event))
+	}
+
+	start, end := page(len(events), limit, 0)
+	return events[start:end], nil
+}
+
+func (s *Store) MarkOutboxEventSent(ctx context.Context, id string, sentAt time.Time) error {
+	defer s.lock(ctx)()
+
+	event, ok := s.outbox[id]
+	if !ok || event.SentAt != nil {
+		return fmt.Errorf("unsent outbox event %w: %s", models.ErrNotFound, id)
+	}
+	event.SentAt = &sentAt
+	return nil
+}
+
+func (s *Store) MarkOutboxEventFailed(ctx context.Context, id, lastError string, nextAttemptAt time.Time) error {
+	defer s.lock(ctx)()
+
+	event, ok := s.outbox[id]
+	if !ok || event.SentAt != nil {
+		return fmt.Errorf("unsent outbox event %w: %s", models.ErrNotFound, id)
+	}
+	event.Attempts++
+	event.LastError = lastError
+	event.NextAttemptAt = nextAttemptAt
+	return nil
+}
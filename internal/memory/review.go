@@ -0,0 +1,129 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/araesf/ledgertime/internal/models"
+)
+
+// Review queue operations
+func copyReviewItem(item *models.ReviewItem) *models.ReviewItem {
+	i := *item
+	i.ClaimedAt = copyTime(item.ClaimedAt)
+	i.DecidedAt = copyTime(item.DecidedAt)
+	return &i
+}
+
+func (s *Store) CreateReviewItem(ctx context.Context, item *models.ReviewItem) error {
+	defer s.lock(ctx)()
+
+	for _, existing := range s.reviews {
+		if existing.ID == item.ID || existing.TransactionID == item.TransactionID {
+			return fmt.Errorf("failed to create review item: duplicate review for transaction %s", item.TransactionID)
+		}
+	}
+
+	s.reviews[item.ID] = copyReviewItem(item)
+	s.reviewOrder = append(s.reviewOrder, item.ID)
+	return nil
+}
+
+func (s *Store) GetReviewItem(ctx context.Context, id string) (*models.ReviewItem, error) {
+	defer s.rlock(ctx)()
+
+	item, ok := s.reviews[id]
+	if !ok {
+		return nil, fmt.Errorf("review %w: %s", models.ErrNotFound, id)
+	}
+	return copyReviewItem(item), nil
+}
+
+// selectReviewItems copies the review items matching a filter in insertion order
+func (s *Store) selectReviewItems(match func(item *models.ReviewItem) bool) []*models.ReviewItem {
+	var items []*models.ReviewItem
+	for _, id := range s.reviewOrder {
+		if item := s.reviews[id]; match(item) {
+			items = append(items, copyReviewItem(item))
+		}
+	}
+	return items
+}
+
+// isOpen reports whether a review still waits for a decision
+func isOpen(item *models.ReviewItem) bool {
+	return item.Status == models.ReviewStatusPending || item.Status == models.ReviewStatusClaimed
+}
+
+func (s *Store) GetReviewItems(ctx context.Context, status string, limit, offset int) ([]*models.ReviewItem, error) {
+	defer s.rlock(ctx)()
+
+	items := s.selectReviewItems(func(item *models.ReviewItem) bool {
+		if status == "" {
+			return isOpen(item)
+		}
+		return item.Status == status
+	})
+	sort.SliceStable(items, func(i, j int) bool { return items[i].CreatedAt.Before(items[j].CreatedAt) })
+
+	start, end := page(len(items), limit, offset)
+	return items[start:end], nil
+}
+
+// openReviewItem returns a review item, failing if it is no longer open
+func (s *Store) openReviewItem(id string) (*models.ReviewItem, error) {
+	item, ok := s.reviews[id]
+	if !ok {
+		return nil, fmt.Errorf("review %w: %s", models.ErrNotFound, id)
+	}
+	if !isOpen(item) {
+		return nil, fmt.Errorf("%w: review %s is %s", models.ErrReviewClosed, id, item.Status)
+	}
+	return item, nil
+}
+
+func (s *Store) ClaimReviewItem(ctx context.Context, id, analyst string, now time.Time) (*models.ReviewItem, error) {
+	defer s.lock(ctx)()
+
+	item, err := s.openReviewItem(id)
+	if err != nil {
+		return nil, err
+	}
+	if item.Status == models.ReviewStatusClaimed && item.ClaimedBy != analyst {
+		return nil, fmt.Errorf("%w: %s", models.ErrReviewClaimed, item.ClaimedBy)
+	}
+
+	item.Status, item.ClaimedBy, item.ClaimedAt = models.ReviewStatusClaimed, analyst, &now
+	item.UpdatedAt = time.Now()
+	return copyReviewItem(item), nil
+}
+
+func (s *Store) DecideReviewItem(ctx context.Context, id, status, decidedBy, notes string, now time.Time) (*models.ReviewItem, error) {
+	defer s.lock(ctx)()
+
+	item, err := s.openReviewItem(id)
+	if err != nil {
+		return nil, err
+	}
+	if status != models.ReviewStatusExpired && item.Status == models.ReviewStatusClaimed && item.ClaimedBy != decidedBy {
+		return nil, fmt.Errorf("%w: %s", models.ErrReviewClaimed, item.ClaimedBy)
+	}
+
+	item.Status, item.DecidedBy, item.DecidedAt, item.Notes = status, decidedBy, &now, notes
+	item.UpdatedAt = time.Now()
+	return copyReviewItem(item), nil
+}
+
+func (s *Store) GetExpiredReviewItems(ctx context.Context, now time.Time, limit int) ([]*models.ReviewItem, error) {
+	defer s.rlock(ctx)()
+
+	items := s.selectReviewItems(func(item *models.ReviewItem) bool {
+		return isOpen(item) && !item.ExpiresAt.After(now)
+	})
+	sort.SliceStable(items, func(i, j int) bool { return items[i].ExpiresAt.Before(items[j].ExpiresAt) })
+
+	start, end := page(len(items), limit, 0)
+	return items[start:end], nil
+}
@@ -0,0 +1,183 @@
+// Package memory implements the ledger's storage in process memory. It behaves like the
+// Postgres store in internal/db, returning the same sentinel errors in the same order with
+// the same pagination, for tests and the --storage=memory demo mode. Nothing is persisted.
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/araesf/ledgertime/internal/ledger"
+	"github.com/araesf/ledgertime/internal/models"
+)
+
+var _ ledger.Store = (*Store)(nil)
+
+// Store keeps every table in maps guarded by one lock, so each method is atomic like a database
+// transaction. Records are copied on the way in and out; callers never share them with the store.
+type Store struct {
+	mu sync.RWMutex
+	tables
+}
+
+// tables holds the store's data
+type tables struct {
+	users map[string]*models.User
+
+	cards       map[string]*models.Card
+	cardHistory []*models.CardStatusChange
+	limits      map[limitKey]*models.SpendingLimits
+
+	transactions      map[string]*models.Transaction
+	transactionOrder  []string // Transaction IDs in insertion order, so ties sort stably
+	transactionStatus []*models.StatusChange
+
+	accounts map[string]*models.Account
+	postings []*models.Posting
+	holds    map[string]*models.Hold
+
+	idempotency map[idempotencyKey]*models.IdempotencyRecord
+
+	reviews     map[string]*models.ReviewItem
+	reviewOrder []string
+
+	merchants map[string]*models.Merchant
+
+	categories map[string]*models.Category
+	rules      map[string]*models.CategoryRule
+
+	budgets      map[string]*models.Budget
+	budgetAlerts []*models.BudgetAlert
+
+	outbox         map[string]*models.OutboxEvent
+	outboxSequence int64
+}
+
+type limitKey struct{ scope, scopeID string }
+
+type idempotencyKey struct{ operation, key string }
+
+// NewStore creates an empty store
+func NewStore() *Store {
+	return &Store{tables: tables{
+		users:        make(map[string]*models.User),
+		cards:        make(map[string]*models.Card),
+		limits:       make(map[limitKey]*models.SpendingLimits),
+		transactions: make(map[string]*models.Transaction),
+		accounts:     make(map[string]*models.Account),
+		holds:        make(map[string]*models.Hold),
+		idempotency:  make(map[idempotencyKey]*models.IdempotencyRecord),
+		reviews:      make(map[string]*models.ReviewItem),
+		merchants:    make(map[string]*models.Merchant),
+		categories:   make(map[string]*models.Category),
+		rules:        make(map[string]*models.CategoryRule),
+		budgets:      make(map[string]*models.Budget),
+		outbox:       make(map[string]*models.OutboxEvent),
+	}}
+}
+
+// clone copies the tables and their records, so a unit of work can be undone by restoring the copy.
+// Rows in slices are only ever appended or removed, never changed, so they are shared.
+func (t *tables) clone() tables {
+	return tables{
+		users:             cloneMap(t.users),
+		cards:             cloneMap(t.cards),
+		cardHistory:       append([]*models.CardStatusChange(nil), t.cardHistory...),
+		limits:            cloneMap(t.limits),
+		transactions:      cloneMap(t.transactions),
+		transactionOrder:  append([]string(nil), t.transactionOrder...),
+		transactionStatus: append([]*models.StatusChange(nil), t.transactionStatus...),
+		accounts:          cloneMap(t.accounts),
+		postings:          append([]*models.Posting(nil), t.postings...),
+		holds:             cloneMap(t.holds),
+		idempotency:       cloneMap(t.idempotency),
+		reviews:           cloneMap(t.reviews),
+		reviewOrder:       append([]string(nil), t.reviewOrder...),
+		merchants:         cloneMap(t.merchants),
+		categories:        cloneMap(t.categories),
+		rules:             cloneMap(t.rules),
+		budgets:           cloneMap(t.budgets),
+		budgetAlerts:      append([]*models.BudgetAlert(nil), t.budgetAlerts...),
+		outbox:            cloneMap(t.outbox),
+		outboxSequence:    t.outboxSequence,
+	}
+}
+
+// cloneMap copies a table and each record in it
+func cloneMap[K comparable, V any](m map[K]*V) map[K]*V {
+	c := make(map[K]*V, len(m))
+	for k, v := range m {
+		record := *v
+		c[k] = &record
+	}
+	return c
+}
+
+type unitOfWorkKey struct{}
+
+// RunUnitOfWork runs fn holding the store's lock, so units of work are serializable and never
+// need to retry. If fn returns an error every table is restored to how it was before fn ran.
+// A unit of work started inside another one joins it.
+func (s *Store) RunUnitOfWork(ctx context.Context, fn func(ctx context.Context) error) error {
+	if s.inUnitOfWork(ctx) {
+		return fn(ctx)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	snapshot := s.tables.clone()
+	if err := fn(context.WithValue(ctx, unitOfWorkKey{}, s)); err != nil {
+		s.tables = snapshot
+		return err
+	}
+	return nil
+}
+
+// inUnitOfWork reports whether ctx runs in a unit of work of this store, which already holds the lock
+func (s *Store) inUnitOfWork(ctx context.Context) bool {
+	return ctx.Value(unitOfWorkKey{}) == s
+}
+
+// lock takes the write lock unless ctx runs in a unit of work, returning the matching unlock
+func (s *Store) lock(ctx context.Context) func() {
+	if s.inUnitOfWork(ctx) {
+		return func() {}
+	}
+	s.mu.Lock()
+	return s.mu.Unlock
+}
+
+// rlock takes the read lock unless ctx runs in a unit of work, returning the matching unlock
+func (s *Store) rlock(ctx context.Context) func() {
+	if s.inUnitOfWork(ctx) {
+		return func() {}
+	}
+	s.mu.RLock()
+	return s.mu.RUnlock
+}
+
+// page applies LIMIT and OFFSET to n sorted rows, returning the bounds of the page
+func page(n, limit, offset int) (int, int) {
+	if offset < 0 {
+		offset = 0
+	}
+	if offset > n {
+		offset = n
+	}
+	end := n
+	if limit >= 0 && offset+limit < n {
+		end = offset + limit
+	}
+	return offset, end
+}
+
+// copyTime copies an optional timestamp
+func copyTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	c := *t
+	return &c
+}
@@ -0,0 +1,258 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/araesf/ledgertime/internal/models"
+)
+
+// Transaction operations
+func copyTransaction(tx *models.Transaction) *models.Transaction {
+	t := *tx
+	t.FraudReasons = append([]string(nil), tx.FraudReasons...)
+	return &t
+}
+
+// insertTransaction adds a transaction, enforcing its unique ID
+func (s *Store) insertTransaction(tx *models.Transaction) error {
+	if _, ok := s.transactions[tx.ID]; ok {
+		return fmt.Errorf("duplicate id %s", tx.ID)
+	}
+	s.transactions[tx.ID] = copyTransaction(tx)
+	s.transactionOrder = append(s.transactionOrder, tx.ID)
+	return nil
+}
+
+// selectTransactions copies the transactions matching a filter in insertion order
+func (s *Store) selectTransactions(match func(tx *models.Transaction) bool) []*models.Transaction {
+	var transactions []*models.Transaction
+	for _, id := range s.transactionOrder {
+		if tx := s.transactions[id]; match(tx) {
+			transactions = append(transactions, copyTransaction(tx))
+		}
+	}
+	return transactions
+}
+
+// newestFirst sorts transactions by timestamp, newest first
+func newestFirst(transactions []*models.Transaction) {
+	sort.SliceStable(transactions, func(i, j int) bool {
+		return transactions[i].Timestamp.After(transactions[j].Timestamp)
+	})
+}
+
+func (s *Store) CreateTransaction(ctx context.Context, tx *models.Transaction) error {
+	defer s.lock(ctx)()
+
+	if err := s.insertTransaction(tx); err != nil {
+		return fmt.Errorf("failed to create transaction: %w", err)
+	}
+	return nil
+}
+
+func (s *Store) GetTransaction(ctx context.Context, id string) (*models.Transaction, error) {
+	defer s.rlock(ctx)()
+
+	tx, ok := s.transactions[id]
+	if !ok {
+		return nil, fmt.Errorf("transaction %w: %s", models.ErrNotFound, id)
+	}
+	return copyTransaction(tx), nil
+}
+
+func (s *Store) GetTransactionsByUser(ctx context.Context, userID string, limit, offset int) ([]*models.Transaction, error) {
+	defer s.rlock(ctx)()
+
+	transactions := s.selectTransactions(func(tx *models.Transaction) bool { return tx.UserID == userID })
+	newestFirst(transactions)
+
+	start, end := page(len(transactions), limit, offset)
+	return transactions[start:end], nil
+}
+
+func (s *Store) GetCardTransactions(ctx context.Context, cardID string, limit, offset int) ([]*models.Transaction, error) {
+	defer s.rlock(ctx)()
+
+	lineage := s.cardLineage(cardID)
+	transactions := s.selectTransactions(func(tx *models.Transaction) bool { return lineage[tx.CardID] })
+	newestFirst(transactions)
+
+	start, end := page(len(transactions), limit, offset)
+	return transactions[start:end], nil
+}
+
+func (s *Store) CreateLinkedTransaction(ctx context.Context, tx *models.Transaction) error {
+	defer s.lock(ctx)()
+
+	original, ok := s.transactions[tx.ParentTransactionID]
+	if !ok {
+		return fmt.Errorf("transaction %w: %s", models.ErrNotFound, tx.ParentTransactionID)
+	}
+
+	var linked int64
+	for _, t := range s.transactions {
+		if t.ParentTransactionID == tx.ParentTransactionID && t.Status != models.TransactionStatusDeclined {
+			linked += t.Amount
+		}
+	}
+	if linked+tx.Amount > original.Amount {
+		return fmt.Errorf("%w: original %d, already refunded %d, requested %d",
+			models.ErrRefundLimitExceeded, original.Amount, linked, tx.Amount)
+	}
+
+	if err := s.insertTransaction(tx); err != nil {
+		return fmt.Errorf("failed to create linked transaction: %w", err)
+	}
+	return nil
+}
+
+func (s *Store) GetLinkedTransactions(ctx context.Context, parentID string) ([]*models.Transaction, error) {
+	defer s.rlock(ctx)()
+
+	transactions := s.selectTransactions(func(tx *models.Transaction) bool { return tx.ParentTransactionID == parentID })
+	sort.SliceStable(transactions, func(i, j int) bool {
+		return transactions[i].CreatedAt.Before(transactions[j].CreatedAt)
+	})
+	return transactions, nil
+}
+
+func (s *Store) TransitionTransaction(ctx context.Context, tx *models.Transaction, change *models.StatusChange) error {
+	defer s.lock(ctx)()
+
+	stored, ok := s.transactions[tx.ID]
+	if !ok || stored.Status != change.FromStatus {
+		return fmt.Errorf("%w: transaction %s is no longer %s", models.ErrStatusConflict, tx.ID, change.FromStatus)
+	}
+
+	stored.Amount = tx.Amount
+	stored.AuthorizedAmount = tx.AuthorizedAmount
+	stored.Status = change.ToStatus
+	stored.DeclineReason = tx.DeclineReason
+	stored.UpdatedAt = tx.UpdatedAt
+
+	c := *change
+	s.transactionStatus = append(s.transactionStatus, &c)
+	return nil
+}
+
+func (s *Store) GetStatusHistory(ctx context.Context, transactionID string) ([]*models.StatusChange, error) {
+	defer s.rlock(ctx)()
+
+	var history []*models.StatusChange
+	for _, change := range s.transactionStatus {
+		if change.TransactionID == transactionID {
+			c := *change
+			history = append(history, &c)
+		}
+	}
+	sort.SliceStable(history, func(i, j int) bool {
+		if !history[i].CreatedAt.Equal(history[j].CreatedAt) {
+			return history[i].CreatedAt.Before(history[j].CreatedAt)
+		}
+		return history[i].ID < history[j].ID
+	})
+	return history, nil
+}
+
+func (s *Store) SetTransactionFraud(ctx context.Context, id string, score int, decision string, reasons []string) error {
+	defer s.lock(ctx)()
+
+	tx, ok := s.transactions[id]
+	if !ok {
+		return fmt.Errorf("transaction %w: %s", models.ErrNotFound, id)
+	}
+	tx.FraudScore = score
+	tx.FraudDecision = decision
+	tx.FraudReasons = append([]string(nil), reasons...)
+	tx.UpdatedAt = time.Now()
+	return nil
+}
+
+func (s *Store) SetTransactionCategory(ctx context.Context, id, category, source string) error {
+	defer s.lock(ctx)()
+
+	now := time.Now()
+	updated := 0
+	for _, tx := range s.transactions {
+		if tx.ID == id || tx.ParentTransactionID == id {
+			tx.Category = category
+			tx.CategorySource = source
+			tx.UpdatedAt = now
+			updated++
+		}
+	}
+	if updated == 0 {
+		return fmt.Errorf("transaction %w: %s", models.ErrNotFound, id)
+	}
+	return nil
+}
+
+func (s *Store) GetRuleCategorizableTransactions(ctx context.Context, userID, afterID string, limit int) ([]*models.Transaction, error) {
+	defer s.rlock(ctx)()
+
+	transactions := s.selectTransactions(func(tx *models.Transaction) bool {
+		return tx.UserID == userID && tx.Type == models.TransactionTypePayment &&
+			tx.CategorySource != models.CategorySourceUser && tx.ID > afterID
+	})
+	sort.Slice(transactions, func(i, j int) bool { return transactions[i].ID < transactions[j].ID })
+
+	start, end := page(len(transactions), limit, 0)
+	return transactions[start:end], nil
+}
+
+func (s *Store) GetCardTransactionsSince(ctx context.Context, cardID string, since time.Time) ([]*models.Transaction, error) {
+	defer s.rlock(ctx)()
+
+	lineage := s.cardLineage(cardID)
+	transactions := s.selectTransactions(func(tx *models.Transaction) bool {
+		return lineage[tx.CardID] && tx.Type == models.TransactionTypePayment && !tx.Timestamp.Before(since)
+	})
+	newestFirst(transactions)
+	return transactions, nil
+}
+
+func (s *Store) HasCardUsedMerchant(ctx context.Context, cardID, merchantName string, before time.Time) (bool, error) {
+	defer s.rlock(ctx)()
+
+	lineage := s.cardLineage(cardID)
+	for _, tx := range s.transactions {
+		if lineage[tx.CardID] && tx.MerchantName == merchantName && tx.Type == models.TransactionTypePayment &&
+			tx.Status != models.TransactionStatusDeclined && tx.Timestamp.Before(before) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// limitedStatuses are the payment statuses that count against spending limits
+var limitedStatuses = map[string]bool{
+	models.TransactionStatusInReview:          true,
+	models.TransactionStatusAuthorized:        true,
+	models.TransactionStatusSettled:           true,
+	models.TransactionStatusPartiallyRefunded: true,
+}
+
+func (s *Store) GetSpendingSince(ctx context.Context, scope, scopeID, category string, since time.Time, excludeID string) (map[string]int64, error) {
+	defer s.rlock(ctx)()
+
+	// Replacement cards inherit their predecessors' spend
+	owns := func(tx *models.Transaction) bool { return tx.UserID == scopeID }
+	if scope != models.LimitScopeUser {
+		lineage := s.cardLineage(scopeID)
+		owns = func(tx *models.Transaction) bool { return lineage[tx.CardID] }
+	}
+
+	spent := make(map[string]int64)
+	for _, tx := range s.transactions {
+		if owns(tx) && !tx.Timestamp.Before(since) && tx.ID != excludeID &&
+			tx.Type == models.TransactionTypePayment && limitedStatuses[tx.Status] &&
+			(category == "" || strings.EqualFold(tx.Category, category)) {
+			spent[tx.Currency] += tx.Amount
+		}
+	}
+	return spent, nil
+}
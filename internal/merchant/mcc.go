@@ -0,0 +1,489 @@
+package merchant
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Spending categories derived from merchant category codes
+const (
+	CategoryAgriculture   = "agriculture"
+	CategoryAutomotive    = "automotive"
+	CategoryBusiness      = "business"
+	CategoryCharity       = "charity"
+	CategoryDining        = "dining"
+	CategoryEducation     = "education"
+	CategoryEntertainment = "entertainment"
+	CategoryFinancial     = "financial"
+	CategoryGambling      = "gambling"
+	CategoryGas           = "gas"
+	CategoryGeneral       = "general"
+	CategoryGovernment    = "government"
+	CategoryGroceries     = "groceries"
+	CategoryHealthcare    = "healthcare"
+	CategoryHome          = "home"
+	CategoryServices      = "services"
+	CategoryShopping      = "shopping"
+	CategoryTransport     = "transport"
+	CategoryTravel        = "travel"
+	CategoryUtilities     = "utilities"
+)
+
+// MCC describes an ISO 18245 merchant category code
+type MCC struct {
+	Code        string `json:"code"`
+	Description string `json:"description"`
+	Category    string `json:"category"`
+}
+
+type mccEntry struct {
+	description string
+	category    string
+}
+
+// mccRange covers the blocks of codes ISO 18245 assigns to individual airlines, car rental
+// agencies and hotel chains
+type mccRange struct {
+	low, high   int
+	description string
+	category    string
+}
+
+var mccRanges = []mccRange{
+	{3000, 3350, "Airlines", CategoryTravel},
+	{3351, 3500, "Car Rental Agencies", CategoryTravel},
+	{3501, 3999, "Lodging - Hotels, Motels, Resorts", CategoryTravel},
+}
+
+// mccs lists the ISO 18245 merchant category codes outside the chain ranges
+var mccs = map[string]mccEntry{
+	// Agricultural services
+	"0742": {"Veterinary Services", CategoryServices},
+	"0763": {"Agricultural Cooperatives", CategoryAgriculture},
+	"0780": {"Horticultural and Landscaping Services", CategoryAgriculture},
+
+	// Contracted services
+	"1520": {"General Contractors - Residential and Commercial", CategoryHome},
+	"1711": {"Heating, Plumbing and Air Conditioning Contractors", CategoryHome},
+	"1731": {"Electrical Contractors", CategoryHome},
+	"1740": {"Masonry, Stonework, Tile Setting, Plastering and Insulation Contractors", CategoryHome},
+	"1750": {"Carpentry Contractors", CategoryHome},
+	"1761": {"Roofing, Siding and Sheet Metal Work Contractors", CategoryHome},
+	"1771": {"Concrete Work Contractors", CategoryHome},
+	"1799": {"Special Trade Contractors", CategoryHome},
+	"2741": {"Miscellaneous Publishing and Printing", CategoryBusiness},
+	"2791": {"Typesetting, Platemaking and Related Services", CategoryBusiness},
+	"2842": {"Specialty Cleaning, Polishing and Sanitation Preparations", CategoryBusiness},
+
+	// Transportation
+	"4011": {"Railroads", CategoryTransport},
+	"4111": {"Local and Suburban Commuter Passenger Transportation, Including Ferries", CategoryTransport},
+	"4112": {"Passenger Railways", CategoryTransport},
+	"4119": {"Ambulance Services", CategoryHealthcare},
+	"4121": {"Taxicabs and Limousines", CategoryTransport},
+	"4131": {"Bus Lines", CategoryTransport},
+	"4214": {"Motor Freight Carriers, Trucking and Moving and Storage Companies", CategoryServices},
+	"4215": {"Courier Services - Air and Ground, and Freight Forwarders", CategoryServices},
+	"4225": {"Public Warehousing and Storage", CategoryServices},
+	"4411": {"Steamship and Cruise Lines", CategoryTravel},
+	"4457": {"Boat Rentals and Leasing", CategoryTravel},
+	"4468": {"Marinas, Marine Service and Supplies", CategoryAutomotive},
+	"4511": {"Airlines and Air Carriers", CategoryTravel},
+	"4582": {"Airports, Flying Fields and Airport Terminals", CategoryTravel},
+	"4722": {"Travel Agencies and Tour Operators", CategoryTravel},
+	"4723": {"Package Tour Operators", CategoryTravel},
+	"4784": {"Tolls and Bridge Fees", CategoryTransport},
+	"4789": {"Transportation Services", CategoryTransport},
+
+	// Utilities and telecommunications
+	"4812": {"Telecommunication Equipment and Telephone Sales", CategoryShopping},
+	"4813": {"Key-entry Telecom Merchant Providing Single Local and Long-Distance Phone Calls", CategoryUtilities},
+	"4814": {"Telecommunication Services", CategoryUtilities},
+	"4815": {"Monthly Summary Telephone Charges", CategoryUtilities},
+	"4816": {"Computer Network and Information Services", CategoryUtilities},
+	"4821": {"Telegraph Services", CategoryUtilities},
+	"4829": {"Wire Transfers and Money Orders", CategoryFinancial},
+	"4899": {"Cable, Satellite and Other Pay Television and Radio Services", CategoryUtilities},
+	"4900": {"Utilities - Electric, Gas, Water and Sanitary", CategoryUtilities},
+
+	// Wholesale distributors and manufacturers
+	"5013": {"Motor Vehicle Supplies and New Parts", CategoryAutomotive},
+	"5021": {"Office and Commercial Furniture", CategoryBusiness},
+	"5039": {"Construction Materials", CategoryHome},
+	"5044": {"Office, Photographic, Photocopy and Microfilm Equipment", CategoryBusiness},
+	"5045": {"Computers, Computer Peripheral Equipment and Software", CategoryShopping},
+	"5046": {"Commercial Equipment", CategoryBusiness},
+	"5047": {"Dental, Laboratory, Medical and Ophthalmic Hospital Equipment and Supplies", CategoryHealthcare},
+	"5051": {"Metal Service Centers and Offices", CategoryBusiness},
+	"5065": {"Electrical Parts and Equipment", CategoryBusiness},
+	"5072": {"Hardware Equipment and Supplies", CategoryHome},
+	"5074": {"Plumbing and Heating Equipment and Supplies", CategoryHome},
+	"5085": {"Industrial Supplies", CategoryBusiness},
+	"5094": {"Precious Stones and Metals, Watches and Jewelry", CategoryShopping},
+	"5099": {"Durable Goods", CategoryBusiness},
+	"5111": {"Stationery, Office Supplies and Printing and Writing Paper", CategoryBusiness},
+	"5122": {"Drugs, Drug Proprietaries and Druggists' Sundries", CategoryHealthcare},
+	"5131": {"Piece Goods, Notions and Other Dry Goods", CategoryBusiness},
+	"5137": {"Men's, Women's and Children's Uniforms and Commercial Clothing", CategoryShopping},
+	"5139": {"Commercial Footwear", CategoryShopping},
+	"5169": {"Chemicals and Allied Products", CategoryBusiness},
+	"5172": {"Petroleum and Petroleum Products", CategoryBusiness},
+	"5192": {"Books, Periodicals and Newspapers", CategoryShopping},
+	"5193": {"Florists' Supplies, Nursery Stock and Flowers", CategoryHome},
+	"5198": {"Paints, Varnishes and Supplies", CategoryHome},
+	"5199": {"Nondurable Goods", CategoryBusiness},
+
+	// Home and building supplies
+	"5200": {"Home Supply Warehouse Stores", CategoryHome},
+	"5211": {"Lumber and Building Materials Stores", CategoryHome},
+	"5231": {"Glass, Paint and Wallpaper Stores", CategoryHome},
+	"5251": {"Hardware Stores", CategoryHome},
+	"5261": {"Lawn and Garden Supply Stores, Including Nurseries", CategoryHome},
+	"5271": {"Mobile Home Dealers", CategoryHome},
+
+	// General merchandise and food stores
+	"5262": {"Marketplaces", CategoryShopping},
+	"5300": {"Wholesale Clubs", CategoryShopping},
+	"5309": {"Duty Free Stores", CategoryShopping},
+	"5310": {"Discount Stores", CategoryShopping},
+	"5311": {"Department Stores", CategoryShopping},
+	"5331": {"Variety Stores", CategoryShopping},
+	"5399": {"Miscellaneous General Merchandise", CategoryShopping},
+	"5411": {"Grocery Stores and Supermarkets", CategoryGroceries},
+	"5422": {"Freezer and Locker Meat Provisioners", CategoryGroceries},
+	"5441": {"Candy, Nut and Confectionery Stores", CategoryGroceries},
+	"5451": {"Dairy Products Stores", CategoryGroceries},
+	"5462": {"Bakeries", CategoryGroceries},
+	"5499": {"Miscellaneous Food Stores - Convenience Stores and Specialty Markets", CategoryGroceries},
+
+	// Automotive
+	"5511": {"Car and Truck Dealers (New and Used) - Sales, Service, Repairs, Parts and Leasing", CategoryAutomotive},
+	"5521": {"Car and Truck Dealers (Used Only) - Sales, Service, Repairs, Parts and Leasing", CategoryAutomotive},
+	"5531": {"Auto and Home Supply Stores", CategoryAutomotive},
+	"5532": {"Automotive Tire Stores", CategoryAutomotive},
+	"5533": {"Automotive Parts and Accessories Stores", CategoryAutomotive},
+	"5541": {"Service Stations (With or Without Ancillary Services)", CategoryGas},
+	"5542": {"Automated Fuel Dispensers", CategoryGas},
+	"5551": {"Boat Dealers", CategoryAutomotive},
+	"5552": {"Electric Vehicle Charging", CategoryGas},
+	"5561": {"Camper, Recreational and Utility Trailer Dealers", CategoryAutomotive},
+	"5571": {"Motorcycle Shops and Dealers", CategoryAutomotive},
+	"5592": {"Motor Homes Dealers", CategoryAutomotive},
+	"5598": {"Snowmobile Dealers", CategoryAutomotive},
+	"5599": {"Miscellaneous Automotive, Aircraft and Farm Equipment Dealers", CategoryAutomotive},
+
+	// Clothing
+	"5611": {"Men's and Boys' Clothing and Accessories Stores", CategoryShopping},
+	"5621": {"Women's Ready-To-Wear Stores", CategoryShopping},
+	"5631": {"Women's Accessory and Specialty Shops", CategoryShopping},
+	"5641": {"Children's and Infants' Wear Stores", CategoryShopping},
+	"5651": {"Family Clothing Stores", CategoryShopping},
+	"5655": {"Sports and Riding Apparel Stores", CategoryShopping},
+	"5661": {"Shoe Stores", CategoryShopping},
+	"5681": {"Furriers and Fur Shops", CategoryShopping},
+	"5691": {"Men's and Women's Clothing Stores", CategoryShopping},
+	"5697": {"Tailors, Seamstresses, Mending and Alterations", CategoryServices},
+	"5698": {"Wig and Toupee Stores", CategoryShopping},
+	"5699": {"Miscellaneous Apparel and Accessory Shops", CategoryShopping},
+
+	// Furnishings and electronics
+	"5712": {"Furniture, Home Furnishings and Equipment Stores, Except Appliances", CategoryHome},
+	"5713": {"Floor Covering Stores", CategoryHome},
+	"5714": {"Drapery, Window Covering and Upholstery Stores", CategoryHome},
+	"5715": {"Alcoholic Beverage Wholesalers", CategoryBusiness},
+	"5718": {"Fireplaces, Fireplace Screens and Accessories Stores", CategoryHome},
+	"5719": {"Miscellaneous Home Furnishing Specialty Stores", CategoryHome},
+	"5722": {"Household Appliance Stores", CategoryHome},
+	"5732": {"Electronics Stores", CategoryShopping},
+	"5733": {"Music Stores - Musical Instruments, Pianos and Sheet Music", CategoryShopping},
+	"5734": {"Computer Software Stores", CategoryShopping},
+	"5735": {"Record Stores", CategoryShopping},
+
+	// Eating and drinking places
+	"5811": {"Caterers", CategoryDining},
+	"5812": {"Eating Places and Restaurants", CategoryDining},
+	"5813": {"Drinking Places - Bars, Taverns, Nightclubs, Cocktail Lounges and Discotheques", CategoryDining},
+	"5814": {"Fast Food Restaurants", CategoryDining},
+	"5815": {"Digital Goods - Books, Movies, Music", CategoryEntertainment},
+	"5816": {"Digital Goods - Games", CategoryEntertainment},
+	"5817": {"Digital Goods - Applications (Excludes Games)", CategoryShopping},
+	"5818": {"Digital Goods - Large Digital Goods Merchant", CategoryShopping},
+
+	// Miscellaneous retail
+	"5912": {"Drug Stores and Pharmacies", CategoryHealthcare},
+	"5921": {"Package Stores - Beer, Wine and Liquor", CategoryShopping},
+	"5931": {"Used Merchandise and Secondhand Stores", CategoryShopping},
+	"5932": {"Antique Shops - Sales, Repairs and Restoration Services", CategoryShopping},
+	"5933": {"Pawn Shops", CategoryShopping},
+	"5935": {"Wrecking and Salvage Yards", CategoryAutomotive},
+	"5937": {"Antique Reproduction Stores", CategoryShopping},
+	"5940": {"Bicycle Shops - Sales and Service", CategoryShopping},
+	"5941": {"Sporting Goods Stores", CategoryShopping},
+	"5942": {"Book Stores", CategoryShopping},
+	"5943": {"Stationery, Office and School Supply Stores", CategoryShopping},
+	"5944": {"Jewelry, Watch, Clock and Silverware Stores", CategoryShopping},
+	"5945": {"Hobby, Toy and Game Shops", CategoryShopping},
+	"5946": {"Camera and Photographic Supply Stores", CategoryShopping},
+	"5947": {"Gift, Card, Novelty and Souvenir Shops", CategoryShopping},
+	"5948": {"Luggage and Leather Goods Stores", CategoryShopping},
+	"5949": {"Sewing, Needlework, Fabric and Piece Goods Stores", CategoryShopping},
+	"5950": {"Glassware and Crystal Stores", CategoryShopping},
+	"5960": {"Direct Marketing - Insurance Services", CategoryFinancial},
+	"5961": {"Mail Order Houses Including Catalog Order Stores", CategoryShopping},
+	"5962": {"Direct Marketing - Travel-Related Arrangement Services", CategoryTravel},
+	"5963": {"Door-To-Door Sales", CategoryShopping},
+	"5964": {"Direct Marketing - Catalog Merchants", CategoryShopping},
+	"5965": {"Direct Marketing - Combination Catalog and Retail Merchants", CategoryShopping},
+	"5966": {"Direct Marketing - Outbound Telemarketing Merchants", CategoryShopping},
+	"5967": {"Direct Marketing - Inbound Teleservices Merchants", CategoryEntertainment},
+	"5968": {"Direct Marketing - Continuity and Subscription Merchants", CategoryShopping},
+	"5969": {"Direct Marketing - Other Direct Marketers", CategoryShopping},
+	"5970": {"Artist's Supply and Craft Shops", CategoryShopping},
+	"5971": {"Art Dealers and Galleries", CategoryShopping},
+	"5972": {"Stamp and Coin Stores", CategoryShopping},
+	"5973": {"Religious Goods Stores", CategoryShopping},
+	"5975": {"Hearing Aids - Sales, Service and Supplies", CategoryHealthcare},
+	"5976": {"Orthopedic Goods and Prosthetic Devices", CategoryHealthcare},
+	"5977": {"Cosmetic Stores", CategoryShopping},
+	"5978": {"Typewriter Stores - Sales, Service and Rentals", CategoryShopping},
+	"5983": {"Fuel Dealers - Fuel Oil, Wood, Coal and Liquefied Petroleum", CategoryUtilities},
+	"5992": {"Florists", CategoryShopping},
+	"5993": {"Cigar Stores and Stands", CategoryShopping},
+	"5994": {"News Dealers and Newsstands", CategoryShopping},
+	"5995": {"Pet Shops, Pet Food and Supplies", CategoryShopping},
+	"5996": {"Swimming Pools - Sales, Supplies and Services", CategoryHome},
+	"5997": {"Electric Razor Stores - Sales and Service", CategoryShopping},
+	"5998": {"Tent and Awning Shops", CategoryShopping},
+	"5999": {"Miscellaneous and Specialty Retail Stores", CategoryShopping},
+
+	// Financial services
+	"6010": {"Financial Institutions - Manual Cash Disbursements", CategoryFinancial},
+	"6011": {"Financial Institutions - Automated Cash Disbursements", CategoryFinancial},
+	"6012": {"Financial Institutions - Merchandise, Services and Debt Repayment", CategoryFinancial},
+	"6050": {"Quasi Cash - Financial Institutions", CategoryFinancial},
+	"6051": {"Non-Financial Institutions - Foreign Currency, Money Orders, Travelers' Cheques and Quasi Cash", CategoryFinancial},
+	"6211": {"Security Brokers and Dealers", CategoryFinancial},
+	"6300": {"Insurance Sales, Underwriting and Premiums", CategoryFinancial},
+	"6381": {"Insurance Premiums", CategoryFinancial},
+	"6399": {"Insurance, Not Elsewhere Classified", CategoryFinancial},
+	"6513": {"Real Estate Agents and Managers - Rentals", CategoryHome},
+	"6529": {"Remote Stored Value Load - Financial Institution", CategoryFinancial},
+	"6530": {"Remote Stored Value Load - Merchant", CategoryFinancial},
+	"6532": {"Payment Transaction - Financial Institution", CategoryFinancial},
+	"6533": {"Payment Transaction - Merchant", CategoryFinancial},
+	"6534": {"Money Transfer - Financial Institution", CategoryFinancial},
+	"6535": {"Value Purchase - Financial Institution", CategoryFinancial},
+	"6536": {"MoneySend Intracountry", CategoryFinancial},
+	"6537": {"MoneySend Intercountry", CategoryFinancial},
+	"6538": {"MoneySend Funding", CategoryFinancial},
+	"6539": {"Funding Transaction (Excluding MoneySend)", CategoryFinancial},
+	"6540": {"Non-Financial Institutions - Stored Value Card Purchase and Load", CategoryFinancial},
+	"6611": {"Overpayments", CategoryFinancial},
+	"6760": {"Savings Bonds", CategoryFinancial},
+
+	// Lodging
+	"7011": {"Lodging - Hotels, Motels and Resorts", CategoryTravel},
+	"7012": {"Timeshares", CategoryTravel},
+	"7032": {"Sporting and Recreational Camps", CategoryTravel},
+	"7033": {"Trailer Parks and Campgrounds", CategoryTravel},
+
+	// Personal services
+	"7210": {"Laundry, Cleaning and Garment Services", CategoryServices},
+	"7211": {"Laundries - Family and Commercial", CategoryServices},
+	"7216": {"Dry Cleaners", CategoryServices},
+	"7217": {"Carpet and Upholstery Cleaning", CategoryServices},
+	"7221": {"Photographic Studios", CategoryServices},
+	"7230": {"Beauty and Barber Shops", CategoryServices},
+	"7251": {"Shoe Repair Shops, Shoe Shine Parlors and Hat Cleaning Shops", CategoryServices},
+	"7261": {"Funeral Services and Crematories", CategoryServices},
+	"7273": {"Dating and Escort Services", CategoryServices},
+	"7276": {"Tax Preparation Services", CategoryServices},
+	"7277": {"Counseling Services - Debt, Marriage and Personal", CategoryServices},
+	"7278": {"Buying and Shopping Services and Clubs", CategoryServices},
+	"7280": {"Hospital Patient-Personal Funds Withdrawal", CategoryHealthcare},
+	"7295": {"Babysitting Services", CategoryServices},
+	"7296": {"Clothing Rental - Costumes, Uniforms and Formal Wear", CategoryServices},
+	"7297": {"Massage Parlors", CategoryServices},
+	"7298": {"Health and Beauty Spas", CategoryServices},
+	"7299": {"Miscellaneous Personal Services", CategoryServices},
+
+	// Business services
+	"7311": {"Advertising Services", CategoryBusiness},
+	"7321": {"Consumer Credit Reporting Agencies", CategoryFinancial},
+	"7322": {"Debt Collection Agencies", CategoryFinancial},
+	"7332": {"Blueprinting and Photocopying Services", CategoryBusiness},
+	"7333": {"Commercial Photography, Art and Graphics", CategoryBusiness},
+	"7338": {"Quick Copy, Reproduction and Blueprinting Services", CategoryBusiness},
+	"7339": {"Stenographic and Secretarial Support Services", CategoryBusiness},
+	"7342": {"Exterminating and Disinfecting Services", CategoryHome},
+	"7349": {"Cleaning, Maintenance and Janitorial Services", CategoryHome},
+	"7361": {"Employment Agencies and Temporary Help Services", CategoryBusiness},
+	"7372": {"Computer Programming, Data Processing and Integrated Systems Design Services", CategoryBusiness},
+	"7375": {"Information Retrieval Services", CategoryBusiness},
+	"7379": {"Computer Maintenance, Repair and Services", CategoryServices},
+	"7392": {"Management, Consulting and Public Relations Services", CategoryBusiness},
+	"7393": {"Detective Agencies, Protective Agencies and Security Services", CategoryServices},
+	"7394": {"Equipment, Tool, Furniture and Appliance Rental and Leasing", CategoryServices},
+	"7395": {"Photofinishing Laboratories and Photo Developing", CategoryServices},
+	"7399": {"Business Services", CategoryBusiness},
+
+	// Automotive services
+	"7511": {"Truck Stop", CategoryGas},
+	"7512": {"Automobile Rental Agency", CategoryTravel},
+	"7513": {"Truck and Utility Trailer Rentals", CategoryTransport},
+	"7519": {"Motor Home and Recreational Vehicle Rentals", CategoryTravel},
+	"7523": {"Parking Lots and Garages", CategoryTransport},
+	"7524": {"Express Payment Service Merchants - Parking Lots and Garages", CategoryTransport},
+	"7531": {"Automotive Body Repair Shops", CategoryAutomotive},
+	"7534": {"Tire Retreading and Repair Shops", CategoryAutomotive},
+	"7535": {"Automotive Paint Shops", CategoryAutomotive},
+	"7538": {"Automotive Service Shops (Non-Dealer)", CategoryAutomotive},
+	"7542": {"Car Washes", CategoryAutomotive},
+	"7549": {"Towing Services", CategoryAutomotive},
+
+	// Repair services
+	"7622": {"Electronics Repair Shops", CategoryServices},
+	"7623": {"Air Conditioning and Refrigeration Repair Shops", CategoryServices},
+	"7629": {"Electrical and Small Appliance Repair Shops", CategoryServices},
+	"7631": {"Watch, Clock and Jewelry Repair Shops", CategoryServices},
+	"7641": {"Furniture - Reupholstery, Repair and Refinishing", CategoryServices},
+	"7692": {"Welding Services", CategoryServices},
+	"7699": {"Miscellaneous Repair Shops and Related Services", CategoryServices},
+
+	// Amusement and entertainment
+	"7800": {"Government-Owned Lotteries", CategoryGambling},
+	"7801": {"Government Licensed On-Line Casinos (On-Line Gambling)", CategoryGambling},
+	"7802": {"Government-Licensed Horse/Dog Racing", CategoryGambling},
+	"7829": {"Motion Picture and Video Tape Production and Distribution", CategoryEntertainment},
+	"7832": {"Motion Picture Theaters", CategoryEntertainment},
+	"7841": {"DVD/Video Tape Rental Stores", CategoryEntertainment},
+	"7911": {"Dance Halls, Studios and Schools", CategoryEntertainment},
+	"7922": {"Theatrical Producers (Except Motion Pictures) and Ticket Agencies", CategoryEntertainment},
+	"7929": {"Bands, Orchestras and Miscellaneous Entertainers", CategoryEntertainment},
+	"7932": {"Billiard and Pool Establishments", CategoryEntertainment},
+	"7933": {"Bowling Alleys", CategoryEntertainment},
+	"7941": {"Commercial Sports, Professional Sports Clubs, Athletic Fields and Sports Promoters", CategoryEntertainment},
+	"7991": {"Tourist Attractions and Exhibits", CategoryEntertainment},
+	"7992": {"Public Golf Courses", CategoryEntertainment},
+	"7993": {"Video Amusement Game Supplies", CategoryEntertainment},
+	"7994": {"Video Game Arcades and Establishments", CategoryEntertainment},
+	"7995": {"Betting, Including Lottery Tickets, Casino Gaming Chips, Off-Track Betting and Wagers at Race Tracks", CategoryGambling},
+	"7996": {"Amusement Parks, Circuses, Carnivals and Fortune Tellers", CategoryEntertainment},
+	"7997": {"Membership Clubs (Sports, Recreation, Athletic), Country Clubs and Private Golf Courses", CategoryEntertainment},
+	"7998": {"Aquariums, Seaquariums and Dolphinariums", CategoryEntertainment},
+	"7999": {"Recreation Services", CategoryEntertainment},
+
+	// Professional and medical services
+	"8011": {"Doctors and Physicians", CategoryHealthcare},
+	"8021": {"Dentists and Orthodontists", CategoryHealthcare},
+	"8031": {"Osteopaths", CategoryHealthcare},
+	"8041": {"Chiropractors", CategoryHealthcare},
+	"8042": {"Optometrists and Ophthalmologists", CategoryHealthcare},
+	"8043": {"Opticians, Optical Goods and Eyeglasses", CategoryHealthcare},
+	"8049": {"Podiatrists and Chiropodists", CategoryHealthcare},
+	"8050": {"Nursing and Personal Care Facilities", CategoryHealthcare},
+	"8062": {"Hospitals", CategoryHealthcare},
+	"8071": {"Medical and Dental Laboratories", CategoryHealthcare},
+	"8099": {"Medical Services and Health Practitioners", CategoryHealthcare},
+	"8111": {"Legal Services and Attorneys", CategoryServices},
+	"8211": {"Elementary and Secondary Schools", CategoryEducation},
+	"8220": {"Colleges, Universities, Professional Schools and Junior Colleges", CategoryEducation},
+	"8241": {"Correspondence Schools", CategoryEducation},
+	"8244": {"Business and Secretarial Schools", CategoryEducation},
+	"8249": {"Vocational and Trade Schools", CategoryEducation},
+	"8299": {"Schools and Educational Services", CategoryEducation},
+	"8351": {"Child Care Services", CategoryServices},
+	"8398": {"Charitable and Social Service Organizations - Fundraising", CategoryCharity},
+	"8641": {"Civic, Social and Fraternal Associations", CategoryCharity},
+	"8651": {"Political Organizations", CategoryCharity},
+	"8661": {"Religious Organizations", CategoryCharity},
+	"8675": {"Automobile Associations", CategoryAutomotive},
+	"8699": {"Membership Organizations", CategoryServices},
+	"8734": {"Testing Laboratories (Non-Medical Testing)", CategoryBusiness},
+	"8911": {"Architectural, Engineering and Surveying Services", CategoryBusiness},
+	"8931": {"Accounting, Auditing and Bookkeeping Services", CategoryBusiness},
+	"8999": {"Professional Services", CategoryServices},
+
+	// Government services
+	"9211": {"Court Costs, Including Alimony and Child Support", CategoryGovernment},
+	"9222": {"Fines", CategoryGovernment},
+	"9223": {"Bail and Bond Payments", CategoryGovernment},
+	"9311": {"Tax Payments", CategoryGovernment},
+	"9399": {"Government Services", CategoryGovernment},
+	"9402": {"Postal Services - Government Only", CategoryGovernment},
+	"9405": {"U.S. Federal Government Agencies or Departments", CategoryGovernment},
+	"9406": {"Government-Owned Lotteries (Non-U.S. Region)", CategoryGambling},
+	"9700": {"Automated Referral Service", CategoryBusiness},
+	"9701": {"Visa Credential Server", CategoryBusiness},
+	"9702": {"GCAS Emergency Services", CategoryBusiness},
+	"9751": {"U.K. Supermarkets, Electronic Hot File", CategoryGroceries},
+	"9752": {"U.K. Petrol Stations, Electronic Hot File", CategoryGas},
+	"9754": {"Gambling - Horse Racing, Dog Racing, State Lotteries", CategoryGambling},
+	"9950": {"Intra-Company Purchases", CategoryBusiness},
+}
+
+// LookupMCC returns the catalog entry for a merchant category code
+func LookupMCC(code string) (MCC, error) {
+	code = strings.TrimSpace(code)
+	if len(code) != 4 {
+		return MCC{}, fmt.Errorf("merchant category code must have 4 digits, got: %q", code)
+	}
+	n, err := strconv.Atoi(code)
+	if err != nil || n < 0 {
+		return MCC{}, fmt.Errorf("merchant category code must have 4 digits, got: %q", code)
+	}
+
+	if entry, ok := mccs[code]; ok {
+		return MCC{Code: code, Description: entry.description, Category: entry.category}, nil
+	}
+	for _, r := range mccRanges {
+		if n >= r.low && n <= r.high {
+			return MCC{Code: code, Description: r.description, Category: r.category}, nil
+		}
+	}
+	return MCC{}, fmt.Errorf("unknown merchant category code: %s", code)
+}
+
+// CategoryForMCC maps a merchant category code to a spending category, falling back to general
+func CategoryForMCC(code string) string {
+	mcc, err := LookupMCC(code)
+	if err != nil {
+		return CategoryGeneral
+	}
+	return mcc.Category
+}
+
+// MCCs returns the catalog in code order, including one entry per chain range
+func MCCs() []MCC {
+	catalog := make([]MCC, 0, len(mccs)+len(mccRanges))
+	for code, entry := range mccs {
+		catalog = append(catalog, MCC{Code: code, Description: entry.description, Category: entry.category})
+	}
+	for _, r := range mccRanges {
+		catalog = append(catalog, MCC{Code: fmt.Sprintf("%04d-%04d", r.low, r.high), Description: r.description, Category: r.category})
+	}
+	sort.Slice(catalog, func(i, j int) bool { return catalog[i].Code < catalog[j].Code })
+	return catalog
+}
+
+// Categories returns the built-in spending categories in name order
+func Categories() []string {
+	return []string{
+		CategoryAgriculture, CategoryAutomotive, CategoryBusiness, CategoryCharity, CategoryDining,
+		CategoryEducation, CategoryEntertainment, CategoryFinancial, CategoryGambling, CategoryGas,
+		CategoryGeneral, CategoryGovernment, CategoryGroceries, CategoryHealthcare, CategoryHome,
+		CategoryServices, CategoryShopping, CategoryTransport, CategoryTravel, CategoryUtilities,
+	}
+}
+
+// IsCategory reports whether name is a built-in spending category
+func IsCategory(name string) bool {
+	for _, category := range Categories() {
+		if category == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,97 @@
+package merchant
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// processorPrefixes are the payment facilitators whose name precedes the merchant's in
+// card statement descriptors, as in "SQ *COFFEE SHOP"
+var processorPrefixes = []string{
+	"SQ", "TST", "SP", "PP", "PAYPAL", "IZ", "IZETTLE", "ZTL", "SUMUP", "SMP",
+	"GOOGLE", "GGL", "APL", "PY", "WPY", "EB", "DNH", "FS", "CKO", "BT", "PAR",
+	"CLV", "TCB", "SPO", "IC", "WWW", "POS",
+}
+
+var (
+	prefixPattern = regexp.MustCompile(`^(?:` + strings.Join(processorPrefixes, "|") + `)\s*\*\s*`)
+
+	// Store numbers and references: "#123", "NO 45", "STORE 0042", numbers of two or more digits
+	// and references like "X4K2039". Single digits are kept for names such as "7-ELEVEN".
+	storeNumberPattern = regexp.MustCompile(`(?:#\s*\d+|\b(?:NO|STORE|STR|UNIT)\.?\s*\d+|\b\d{2,}\b|\b[A-Z\d]*\d{3,}[A-Z\d]*\b)`)
+
+	punctuationPattern = regexp.MustCompile(`[^A-Z0-9&'\- ]+`)
+)
+
+// droppedSuffixes are legal forms and domain endings dropped from the end of merchant names
+var droppedSuffixes = map[string]bool{
+	"INC": true, "LLC": true, "LTD": true, "CO": true, "CORP": true, "GMBH": true,
+	"PLC": true, "SA": true, "BV": true, "COM": true, "NET": true, "ORG": true,
+}
+
+// Normalize reduces a raw card descriptor to the key that identifies its merchant by removing
+// payment facilitator prefixes, store numbers, punctuation, legal suffixes and a trailing
+// city, region or country. "SQ *COFFEE SHOP 123" and "Coffee Shop #77" both normalize to "COFFEE SHOP".
+func Normalize(descriptor, city, country string) string {
+	s := strings.ToUpper(strings.TrimSpace(descriptor))
+	s = prefixPattern.ReplaceAllString(s, "")
+
+	// Descriptors from other facilitators keep a reference after the asterisk
+	if i := strings.Index(s, "*"); i > 0 {
+		s = s[:i]
+	}
+
+	s = storeNumberPattern.ReplaceAllString(s, " ")
+	s = punctuationPattern.ReplaceAllString(s, " ")
+
+	var words []string
+	for _, word := range strings.Fields(s) {
+		if word = strings.Trim(word, "-"); word != "" {
+			words = append(words, word)
+		}
+	}
+
+	location := strings.Fields(strings.ToUpper(city))
+	for len(words) > 1 {
+		last := words[len(words)-1]
+		switch {
+		case droppedSuffixes[last]:
+			words = words[:len(words)-1]
+		case country != "" && last == strings.ToUpper(country):
+			words = words[:len(words)-1]
+		case len(location) > 0 && len(words) > len(location) && hasSuffix(words, location):
+			words = words[:len(words)-len(location)]
+			location = nil
+		case len(location) > 0 && len(last) == 2 && len(words) > len(location)+1 && hasSuffix(words[:len(words)-1], location):
+			// City followed by a state or region code
+			words = words[:len(words)-1-len(location)]
+			location = nil
+		default:
+			return strings.Join(words, " ")
+		}
+	}
+
+	return strings.Join(words, " ")
+}
+
+// DisplayName formats a normalized key as a merchant name: "COFFEE SHOP" becomes "Coffee Shop"
+func DisplayName(key string) string {
+	runes := []rune(strings.ToLower(key))
+	for i, r := range runes {
+		if i == 0 || runes[i-1] == ' ' || runes[i-1] == '-' {
+			runes[i] = unicode.ToUpper(r)
+		}
+	}
+	return string(runes)
+}
+
+func hasSuffix(words, suffix []string) bool {
+	offset := len(words) - len(suffix)
+	for i, word := range suffix {
+		if words[offset+i] != word {
+			return false
+		}
+	}
+	return true
+}
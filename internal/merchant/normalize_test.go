@@ -0,0 +1,43 @@
+package merchant
+
+import "testing"
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		descriptor, city, country string
+		want                      string
+	}{
+		{"SQ *COFFEE SHOP 123", "", "", "COFFEE SHOP"},
+		{"Coffee Shop #77", "", "", "COFFEE SHOP"},
+		{"TST* THE DINER STORE 0042", "", "", "THE DINER"},
+		{"PAYPAL *NETFLIX", "", "", "NETFLIX"},
+		{"UBER *TRIP HELP.UBER.COM", "", "", "UBER"},
+		{"7-ELEVEN 38112", "", "", "7-ELEVEN"},
+		{"AMAZON.COM", "", "", "AMAZON"},
+		{"Joe's Pizza, Inc.", "", "", "JOE'S PIZZA"},
+		{"STARBUCKS SEATTLE WA", "Seattle", "US", "STARBUCKS"},
+		{"IKEA BERLIN DE", "Berlin", "DE", "IKEA"},
+		{"NEW YORK DELI NEW YORK NY", "New York", "US", "NEW YORK DELI"},
+		{"   ", "", "", ""},
+	}
+
+	for _, tt := range tests {
+		if got := Normalize(tt.descriptor, tt.city, tt.country); got != tt.want {
+			t.Errorf("Normalize(%q, %q, %q) = %q, want %q", tt.descriptor, tt.city, tt.country, got, tt.want)
+		}
+	}
+}
+
+func TestDisplayName(t *testing.T) {
+	tests := map[string]string{
+		"COFFEE SHOP": "Coffee Shop",
+		"7-ELEVEN":    "7-Eleven",
+		"":            "",
+	}
+
+	for key, want := range tests {
+		if got := DisplayName(key); got != want {
+			t.Errorf("DisplayName(%q) = %q, want %q", key, got, want)
+		}
+	}
+}
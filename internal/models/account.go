@@ -0,0 +1,63 @@
+package models
+
+import "time"
+
+// Account represents a double-entry ledger account
+type Account struct {
+	ID             string    `json:"id" db:"id"`
+	UserID         string    `json:"user_id,omitempty" db:"user_id"` // empty for system accounts
+	AccountType    string    `json:"account_type" db:"account_type"`
+	Name           string    `json:"name" db:"name"`
+	Currency       string    `json:"currency" db:"currency"`               // ISO 4217
+	LedgerBalance  int64     `json:"ledger_balance" db:"ledger_balance"`   // Credits minus debits, in minor units
+	HeldAmount     int64     `json:"held_amount" db:"held_amount"`         // Pending holds, in minor units
+	OverdraftLimit int64     `json:"overdraft_limit" db:"overdraft_limit"` // In minor units
+	CreatedAt      time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// AvailableBalance returns the ledger balance minus pending holds
+func (a *Account) AvailableBalance() int64 {
+	return a.LedgerBalance - a.HeldAmount
+}
+
+// CanSpend reports whether the account can absorb a debit without exceeding its overdraft limit
+func (a *Account) CanSpend(amount int64) bool {
+	return a.AvailableBalance()+a.OverdraftLimit >= amount
+}
+
+// AccountType constants
+const (
+	AccountTypeUserWallet       = "user_wallet"
+	AccountTypeMerchantClearing = "merchant_clearing"
+	AccountTypeFeeIncome        = "fee_income"
+	AccountTypeSuspense         = "suspense"
+)
+
+// Posting represents a single debit or credit leg of a ledger transaction
+type Posting struct {
+	ID            string    `json:"id" db:"id"`
+	TransactionID string    `json:"transaction_id" db:"transaction_id"`
+	AccountID     string    `json:"account_id" db:"account_id"`
+	Direction     string    `json:"direction" db:"direction"` // debit, credit
+	Amount        int64     `json:"amount" db:"amount"`       // Amount in minor units of the account currency
+	CreatedAt     time.Time `json:"created_at" db:"created_at"`
+}
+
+// PostingDirection constants
+const (
+	PostingDirectionDebit  = "debit"
+	PostingDirectionCredit = "credit"
+)
+
+// Balance represents the real-time balance of a user's wallet
+type Balance struct {
+	UserID           string    `json:"user_id"`
+	AccountID        string    `json:"account_id"`
+	Currency         string    `json:"currency"`
+	LedgerBalance    int64     `json:"ledger_balance"`    // Settled postings, in minor units
+	PendingAmount    int64     `json:"pending_amount"`    // Pending holds, in minor units
+	AvailableBalance int64     `json:"available_balance"` // Ledger balance minus pending holds
+	OverdraftLimit   int64     `json:"overdraft_limit"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
@@ -0,0 +1,67 @@
+package models
+
+import "time"
+
+// SpendingQuery selects a user's spend over a date range, bucketed by interval
+type SpendingQuery struct {
+	UserID   string    `json:"user_id"`
+	Interval string    `json:"interval"`           // day, week (from Monday) or month, in UTC
+	GroupBy  string    `json:"group_by,omitempty"` // category, merchant, card; empty for totals
+	Currency string    `json:"currency"`           // Only transactions billed in this currency count
+	From     time.Time `json:"from"`               // Start of the first bucket
+	To       time.Time `json:"to"`                 // Exclusive end of the last bucket
+}
+
+// SpendingSeries is a user's spend per bucket. Every group has a bucket for every interval in
+// the range, with zeros where nothing was spent.
+type SpendingSeries struct {
+	UserID   string            `json:"user_id"`
+	Interval string            `json:"interval"`
+	GroupBy  string            `json:"group_by,omitempty"`
+	Currency string            `json:"currency"`
+	From     time.Time         `json:"from"`
+	To       time.Time         `json:"to"`
+	Total    int64             `json:"total"`
+	Buckets  []*SpendingBucket `json:"buckets"`
+}
+
+// SpendingBucket is the net spend of one group in one interval. Change compares it with the
+// same group's previous interval, so monthly buckets carry month-over-month deltas.
+type SpendingBucket struct {
+	PeriodStart   time.Time `json:"period_start"`
+	Group         string    `json:"group,omitempty"` // Category, merchant name or card ID
+	Amount        int64     `json:"amount"`          // Accepted payments less settled refunds
+	Count         int       `json:"count"`           // Accepted payments
+	Previous      int64     `json:"previous"`        // Amount in the previous interval
+	Change        int64     `json:"change"`          // Amount - Previous
+	ChangePercent *float64  `json:"change_percent"`  // Nil unless Previous is positive
+}
+
+// SpendingRow is one group's net spend in one interval, as summed by a store
+type SpendingRow struct {
+	PeriodStart time.Time
+	Group       string
+	Amount      int64
+	Count       int
+}
+
+// SpendingTotal is one group's net spend over a date range, as summed by a store
+type SpendingTotal struct {
+	Group  string
+	Amount int64
+	Count  int
+}
+
+// SpendingInterval constants
+const (
+	SpendingIntervalDay   = "day"
+	SpendingIntervalWeek  = "week"
+	SpendingIntervalMonth = "month"
+)
+
+// SpendingGroup constants
+const (
+	SpendingGroupCategory = "category"
+	SpendingGroupMerchant = "merchant"
+	SpendingGroupCard     = "card"
+)
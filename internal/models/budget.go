@@ -0,0 +1,49 @@
+package models
+
+import "time"
+
+// Budget plans a user's spend in one category per calendar period. Amounts are in minor units
+// of Currency and only transactions billed in that currency count.
+type Budget struct {
+	ID        string    `json:"id" db:"id"`
+	UserID    string    `json:"user_id" db:"user_id"`
+	Category  string    `json:"category" db:"category"`
+	Period    string    `json:"period" db:"period"` // weekly (from Monday) or monthly, in UTC
+	Amount    int64     `json:"amount" db:"amount"`
+	Currency  string    `json:"currency" db:"currency"`
+	Rollover  bool      `json:"rollover" db:"rollover"` // Unspent amounts carry into the next period
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// BudgetProgress is a budget's spend in one period
+type BudgetProgress struct {
+	Budget      *Budget   `json:"budget"`
+	PeriodStart time.Time `json:"period_start"`
+	PeriodEnd   time.Time `json:"period_end"` // Exclusive
+	RolledOver  int64     `json:"rolled_over"`
+	Available   int64     `json:"available"` // Amount plus RolledOver
+	Spent       int64     `json:"spent"`     // Accepted payments less settled refunds
+	Remaining   int64     `json:"remaining"` // Negative when over budget
+	PercentUsed int       `json:"percent_used"`
+}
+
+// BudgetAlert records a budget passing a threshold. Each threshold alerts once per period.
+type BudgetAlert struct {
+	ID            string    `json:"id" db:"id"`
+	BudgetID      string    `json:"budget_id" db:"budget_id"`
+	UserID        string    `json:"user_id" db:"user_id"`
+	Category      string    `json:"category" db:"category"`
+	Threshold     int       `json:"threshold" db:"threshold"` // Percent of the available amount
+	PeriodStart   time.Time `json:"period_start" db:"period_start"`
+	Spent         int64     `json:"spent" db:"spent"`
+	Available     int64     `json:"available" db:"available"`
+	TransactionID string    `json:"transaction_id" db:"transaction_id"` // Transaction that crossed the threshold
+	CreatedAt     time.Time `json:"created_at" db:"created_at"`
+}
+
+// BudgetPeriod constants
+const (
+	BudgetPeriodWeekly  = "weekly"
+	BudgetPeriodMonthly = "monthly"
+)
@@ -0,0 +1,49 @@
+package models
+
+import "time"
+
+// Category is a spending category a user defined in addition to the built-in ones
+type Category struct {
+	ID        string    `json:"id,omitempty" db:"id"`           // Empty for built-in categories
+	UserID    string    `json:"user_id,omitempty" db:"user_id"` // Empty for built-in categories
+	Name      string    `json:"name" db:"name"`                 // Lowercase, stored on transactions
+	BuiltIn   bool      `json:"built_in" db:"-"`
+	CreatedAt time.Time `json:"created_at,omitempty" db:"created_at"`
+}
+
+// CategoryRule assigns a category to a user's transactions whose field matches a value.
+// Rules are evaluated in priority order, lowest first, and the first match wins.
+type CategoryRule struct {
+	ID        string    `json:"id" db:"id"`
+	UserID    string    `json:"user_id" db:"user_id"`
+	Field     string    `json:"field" db:"field"`       // merchant, mcc, merchant_city, merchant_country
+	Operator  string    `json:"operator" db:"operator"` // contains, equals, starts_with; case-insensitive
+	Value     string    `json:"value" db:"value"`
+	Category  string    `json:"category" db:"category"`
+	Priority  int       `json:"priority" db:"priority"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// RuleField constants. The merchant field matches the canonical name or the raw descriptor.
+const (
+	RuleFieldMerchant        = "merchant"
+	RuleFieldMCC             = "mcc"
+	RuleFieldMerchantCity    = "merchant_city"
+	RuleFieldMerchantCountry = "merchant_country"
+)
+
+// RuleOperator constants
+const (
+	RuleOperatorContains   = "contains"
+	RuleOperatorEquals     = "equals"
+	RuleOperatorStartsWith = "starts_with"
+)
+
+// CategorySource constants record how a transaction's category was chosen
+const (
+	CategorySourceMCC     = "mcc"     // Derived from the merchant category code
+	CategorySourcePayload = "payload" // Sent with the payment
+	CategorySourceRule    = "rule"    // Assigned by a category rule
+	CategorySourceUser    = "user"    // Set by the user; rules never override it
+)
@@ -0,0 +1,43 @@
+package models
+
+import "errors"
+
+// Store errors. Every store returns these, wrapped, so callers can use errors.Is whichever
+// store they run against.
+var (
+	// ErrNotFound is returned when a requested row does not exist
+	ErrNotFound = errors.New("not found")
+
+	// ErrNotInUnitOfWork is returned by store methods that only make sense inside a unit of work
+	ErrNotInUnitOfWork = errors.New("not in a unit of work")
+
+	// ErrInsufficientFunds is returned when a debit would take a wallet past its overdraft limit
+	ErrInsufficientFunds = errors.New("insufficient funds")
+
+	// ErrHoldNotActive is returned when a hold has already been captured, released or expired
+	ErrHoldNotActive = errors.New("hold is not active")
+
+	// ErrStatusConflict is returned when a transaction's status changed underneath a transition
+	ErrStatusConflict = errors.New("transaction status changed concurrently")
+
+	// ErrRefundLimitExceeded is returned when linked transactions would exceed the original amount
+	ErrRefundLimitExceeded = errors.New("refunds exceed original amount")
+
+	// ErrCardStatusConflict is returned when a card's status changed underneath a transition
+	ErrCardStatusConflict = errors.New("card status changed concurrently")
+
+	// ErrReviewClosed is returned when a review has already been decided or timed out
+	ErrReviewClosed = errors.New("review is closed")
+
+	// ErrReviewClaimed is returned when a review is claimed by another analyst
+	ErrReviewClaimed = errors.New("review is claimed by another analyst")
+
+	// ErrBudgetExists is returned when a user already budgets a category for the same period
+	ErrBudgetExists = errors.New("budget already exists")
+
+	// ErrCategoryExists is returned when a user already has a category with the same name
+	ErrCategoryExists = errors.New("category already exists")
+
+	// ErrCategoryInUse is returned when deleting a category that rules still assign
+	ErrCategoryInUse = errors.New("category is used by category rules")
+)
@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// Hold represents funds reserved on an account by a card authorization
+type Hold struct {
+	ID            string    `json:"id" db:"id"`
+	TransactionID string    `json:"transaction_id" db:"transaction_id"`
+	AccountID     string    `json:"account_id" db:"account_id"`
+	Amount        int64     `json:"amount" db:"amount"` // Amount in cents
+	Status        string    `json:"status" db:"status"` // active, captured, released, expired
+	ExpiresAt     time.Time `json:"expires_at" db:"expires_at"`
+	CreatedAt     time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// HoldStatus constants
+const (
+	HoldStatusActive   = "active"
+	HoldStatusCaptured = "captured"
+	HoldStatusReleased = "released"
+	HoldStatusExpired  = "expired"
+)
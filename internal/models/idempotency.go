@@ -0,0 +1,25 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// IdempotencyRecord stores the outcome of a request so retries can be answered without reprocessing
+type IdempotencyRecord struct {
+	Key           string          `json:"key" db:"key"`
+	Operation     string          `json:"operation" db:"operation"`
+	RequestHash   string          `json:"request_hash" db:"request_hash"`
+	Status        string          `json:"status" db:"status"` // in_progress, completed
+	TransactionID string          `json:"transaction_id,omitempty" db:"transaction_id"`
+	Response      json.RawMessage `json:"response,omitempty" db:"response"`
+	LockedAt      time.Time       `json:"locked_at" db:"locked_at"`
+	CreatedAt     time.Time       `json:"created_at" db:"created_at"`
+	CompletedAt   *time.Time      `json:"completed_at,omitempty" db:"completed_at"`
+}
+
+// IdempotencyStatus constants
+const (
+	IdempotencyStatusInProgress = "in_progress"
+	IdempotencyStatusCompleted  = "completed"
+)
@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// SpendingLimits caps what a card or user may spend. Amounts are in minor units of Currency and
+// zero means no limit. Windows are rolling and end at the transaction's timestamp.
+type SpendingLimits struct {
+	Scope             string        `json:"scope"`    // card or user
+	ScopeID           string        `json:"scope_id"` // Card or user ID
+	Currency          string        `json:"currency"` // Only transactions billed in this currency count
+	SingleTransaction int64         `json:"single_transaction"`
+	Daily             int64         `json:"daily"`   // Rolling 24 hours
+	Weekly            int64         `json:"weekly"`  // Rolling 7 days
+	Monthly           int64         `json:"monthly"` // Rolling 30 days
+	BlockedCategories []string      `json:"blocked_categories"`
+	CategoryCaps      []CategoryCap `json:"category_caps"`
+	UpdatedAt         time.Time     `json:"updated_at"`
+}
+
+// CategoryCap limits spend in one category over a rolling 30 days
+type CategoryCap struct {
+	Category string `json:"category"`
+	Amount   int64  `json:"amount"`
+}
+
+// LimitScope constants
+const (
+	LimitScopeCard = "card"
+	LimitScopeUser = "user"
+)
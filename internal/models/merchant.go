@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// Merchant is a canonical merchant that raw card descriptors are matched to
+type Merchant struct {
+	ID             string    `json:"id" db:"id"`
+	Name           string    `json:"name" db:"name"`
+	NormalizedName string    `json:"normalized_name" db:"normalized_name"` // Key that matching descriptors normalize to
+	MCC            string    `json:"mcc,omitempty" db:"mcc"`               // ISO 18245 merchant category code
+	City           string    `json:"city,omitempty" db:"city"`
+	Country        string    `json:"country,omitempty" db:"country"` // ISO 3166-1 alpha-2
+	LogoURL        string    `json:"logo_url,omitempty" db:"logo_url"`
+	CreatedAt      time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at" db:"updated_at"`
+}
@@ -0,0 +1,52 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// OutboxEvent is a domain event saved together with the change it announces, waiting to be
+// published to Kafka by the outbox relay
+type OutboxEvent struct {
+	ID            string          `json:"id" db:"id"`
+	Sequence      int64           `json:"sequence" db:"sequence"` // Assigned by the store; events are published in this order
+	AggregateType string          `json:"aggregate_type" db:"aggregate_type"`
+	AggregateID   string          `json:"aggregate_id" db:"aggregate_id"`
+	EventType     string          `json:"event_type" db:"event_type"`
+	Payload       json.RawMessage `json:"payload" db:"payload"`
+	Attempts      int             `json:"attempts" db:"attempts"` // Failed publish attempts
+	LastError     string          `json:"last_error,omitempty" db:"last_error"`
+	NextAttemptAt time.Time       `json:"next_attempt_at" db:"next_attempt_at"`
+	CreatedAt     time.Time       `json:"created_at" db:"created_at"`
+	SentAt        *time.Time      `json:"sent_at,omitempty" db:"sent_at"`
+}
+
+// AggregateType constants
+const (
+	AggregateTransaction = "transaction"
+	AggregateCard        = "card"
+)
+
+// EventType constants
+const (
+	EventTransactionCreated       = "TransactionCreated"
+	EventTransactionStatusChanged = "TransactionStatusChanged"
+	EventCardIssued               = "CardIssued"
+	EventCardFrozen               = "CardFrozen"
+	EventCardUnfrozen             = "CardUnfrozen"
+	EventCardReported             = "CardReported"
+	EventCardClosed               = "CardClosed"
+	EventCardExpired              = "CardExpired"
+)
+
+// TransactionEventPayload is the payload of transaction events
+type TransactionEventPayload struct {
+	Transaction *Transaction  `json:"transaction"`
+	Change      *StatusChange `json:"change,omitempty"` // Set for TransactionStatusChanged
+}
+
+// CardEventPayload is the payload of card events
+type CardEventPayload struct {
+	Card   *Card             `json:"card"`
+	Change *CardStatusChange `json:"change,omitempty"` // Set for every event but CardIssued
+}
@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// ReviewItem is a transaction parked for an analyst to approve or decline
+type ReviewItem struct {
+	ID            string     `json:"id" db:"id"`
+	TransactionID string     `json:"transaction_id" db:"transaction_id"`
+	Intent        string     `json:"intent" db:"intent"` // payment or authorization, decides what approval does
+	Status        string     `json:"status" db:"status"` // pending, claimed, approved, declined, expired
+	Reason        string     `json:"reason" db:"reason"` // Why the transaction was flagged
+	ClaimedBy     string     `json:"claimed_by,omitempty" db:"claimed_by"`
+	ClaimedAt     *time.Time `json:"claimed_at,omitempty" db:"claimed_at"`
+	DecidedBy     string     `json:"decided_by,omitempty" db:"decided_by"`
+	DecidedAt     *time.Time `json:"decided_at,omitempty" db:"decided_at"`
+	Notes         string     `json:"notes,omitempty" db:"notes"`
+	ExpiresAt     time.Time  `json:"expires_at" db:"expires_at"` // Auto-declined after this time
+	CreatedAt     time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// ReviewStatus constants
+const (
+	ReviewStatusPending  = "pending"
+	ReviewStatusClaimed  = "claimed"
+	ReviewStatusApproved = "approved"
+	ReviewStatusDeclined = "declined"
+	ReviewStatusExpired  = "expired"
+)
+
+// ReviewIntent constants
+const (
+	ReviewIntentPayment       = "payment"
+	ReviewIntentAuthorization = "authorization"
+)
@@ -6,32 +6,64 @@ import (
 
 // Transaction represents a financial transaction in the ledger
 type Transaction struct {
-	ID           string    `json:"id" db:"id"`
-	UserID       string    `json:"user_id" db:"user_id"`
-	CardID       string    `json:"card_id" db:"card_id"`
-	Amount       int64     `json:"amount" db:"amount"` // Amount in cents
-	MerchantName string    `json:"merchant_name" db:"merchant_name"`
-	Category     string    `json:"category" db:"category"`
-	Description  string    `json:"description" db:"description"`
-	Status       string    `json:"status" db:"status"` // pending, completed, failed
-	Timestamp    time.Time `json:"timestamp" db:"timestamp"`
-	CreatedAt    time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at" db:"updated_at"`
+	ID                  string    `json:"id" db:"id"`
+	UserID              string    `json:"user_id" db:"user_id"`
+	CardID              string    `json:"card_id" db:"card_id"`
+	ParentTransactionID string    `json:"parent_transaction_id,omitempty" db:"parent_transaction_id"` // Original transaction for refunds and reversals
+	Type                string    `json:"type" db:"type"`                                             // payment, refund, reversal
+	Amount              int64     `json:"amount" db:"amount"`                                         // Billing amount in minor units of Currency
+	AuthorizedAmount    int64     `json:"authorized_amount" db:"authorized_amount"`                   // Amount held by the authorization, in minor units of Currency
+	Currency            string    `json:"currency" db:"currency"`                                     // ISO 4217 billing currency
+	OriginalAmount      int64     `json:"original_amount" db:"original_amount"`                       // Amount charged by the merchant, in minor units of OriginalCurrency
+	OriginalCurrency    string    `json:"original_currency" db:"original_currency"`                   // ISO 4217 currency charged by the merchant
+	FXRate              string    `json:"fx_rate,omitempty" db:"fx_rate"`                             // Original -> billing rate, empty when no conversion was needed
+	MerchantID          string    `json:"merchant_id,omitempty" db:"merchant_id"`
+	MerchantName        string    `json:"merchant_name" db:"merchant_name"`                       // Canonical merchant name
+	MerchantDescriptor  string    `json:"merchant_descriptor,omitempty" db:"merchant_descriptor"` // Raw descriptor sent by the network
+	MCC                 string    `json:"mcc,omitempty" db:"mcc"`                                 // ISO 18245 merchant category code
+	MerchantCity        string    `json:"merchant_city,omitempty" db:"merchant_city"`
+	MerchantCountry     string    `json:"merchant_country,omitempty" db:"merchant_country"` // ISO 3166-1 alpha-2
+	Category            string    `json:"category" db:"category"`
+	CategorySource      string    `json:"category_source" db:"category_source"` // mcc, payload, rule, user
+	Description         string    `json:"description" db:"description"`
+	Status              string    `json:"status" db:"status"` // see ledger state machine
+	DeclineReason       string    `json:"decline_reason,omitempty" db:"decline_reason"`
+	FraudScore          int       `json:"fraud_score" db:"fraud_score"`
+	FraudDecision       string    `json:"fraud_decision,omitempty" db:"fraud_decision"` // approve, review, decline; empty when not screened
+	FraudReasons        []string  `json:"fraud_reasons,omitempty" db:"fraud_reasons"`
+	Timestamp           time.Time `json:"timestamp" db:"timestamp"`
+	CreatedAt           time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt           time.Time `json:"updated_at" db:"updated_at"`
 }
 
 // TransactionStatus constants
 const (
-	TransactionStatusPending   = "pending"
-	TransactionStatusCompleted = "completed"
-	TransactionStatusFailed    = "failed"
+	TransactionStatusPending           = "pending"
+	TransactionStatusInReview          = "in_review"
+	TransactionStatusAuthorized        = "authorized"
+	TransactionStatusSettled           = "settled"
+	TransactionStatusDeclined          = "declined"
+	TransactionStatusVoided            = "voided"
+	TransactionStatusExpired           = "expired"
+	TransactionStatusReversed          = "reversed"
+	TransactionStatusPartiallyRefunded = "partially_refunded"
+	TransactionStatusRefunded          = "refunded"
 )
 
-// TransactionSummary represents aggregated transaction data
-type TransactionSummary struct {
-	UserID      string `json:"user_id"`
-	TotalAmount int64  `json:"total_amount"`
-	TotalCount  int    `json:"total_count"`
-	AvgAmount   int64  `json:"avg_amount"`
-	TopCategory string `json:"top_category"`
-	TopMerchant string `json:"top_merchant"`
+// TransactionType constants
+const (
+	TransactionTypePayment  = "payment"
+	TransactionTypeRefund   = "refund"
+	TransactionTypeReversal = "reversal"
+)
+
+// StatusChange records a single transition of a transaction's status
+type StatusChange struct {
+	ID            string    `json:"id" db:"id"`
+	TransactionID string    `json:"transaction_id" db:"transaction_id"`
+	FromStatus    string    `json:"from_status" db:"from_status"`
+	ToStatus      string    `json:"to_status" db:"to_status"`
+	Actor         string    `json:"actor" db:"actor"`
+	Reason        string    `json:"reason,omitempty" db:"reason"`
+	CreatedAt     time.Time `json:"created_at" db:"created_at"`
 }
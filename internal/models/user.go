@@ -13,19 +13,62 @@ type User struct {
 
 // Card represents a user's payment card
 type Card struct {
+	ID             string    `json:"id" db:"id"`
+	UserID         string    `json:"user_id" db:"user_id"`
+	Token          string    `json:"token" db:"token"`   // Opaque reference used in place of the card number
+	CardNumber     string    `json:"card_number" db:"-"` // Masked for display: **** 9012
+	Last4          string    `json:"last4" db:"last4"`
+	CardType       string    `json:"card_type" db:"card_type"` // visa, mastercard, etc
+	Issuer         string    `json:"issuer,omitempty" db:"issuer"`
+	IssuerCountry  string    `json:"issuer_country,omitempty" db:"issuer_country"` // ISO 3166-1 alpha-2
+	Funding        string    `json:"funding,omitempty" db:"funding"`               // credit, debit or prepaid
+	Currency       string    `json:"currency" db:"currency"`                       // ISO 4217 billing currency
+	Status         string    `json:"status" db:"status"`                           // active, frozen, lost, stolen, closed, expired
+	IsActive       bool      `json:"is_active" db:"is_active"`                     // Status is active
+	ExpiresAt      time.Time `json:"expires_at" db:"expires_at"`
+	ReplacesCardID string    `json:"replaces_card_id,omitempty" db:"replaces_card_id"` // Card this one was issued to replace
+	CreatedAt      time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at" db:"updated_at"`
+
+	// The card number is only stored encrypted, and is never serialized
+	PANHash       string `json:"-" db:"pan_hash"`       // Keyed lookup hash of the card number
+	PANKeyID      string `json:"-" db:"pan_key_id"`     // Vault key that wrapped the data key
+	PANDataKey    []byte `json:"-" db:"pan_data_key"`   // Wrapped data key
+	PANCiphertext []byte `json:"-" db:"pan_ciphertext"` // Card number encrypted under the data key
+}
+
+// CardStatus constants
+const (
+	CardStatusActive  = "active"
+	CardStatusFrozen  = "frozen"
+	CardStatusLost    = "lost"
+	CardStatusStolen  = "stolen"
+	CardStatusClosed  = "closed"
+	CardStatusExpired = "expired"
+)
+
+// CardStatusChange records a single transition of a card's status
+type CardStatusChange struct {
 	ID         string    `json:"id" db:"id"`
-	UserID     string    `json:"user_id" db:"user_id"`
-	CardNumber string    `json:"card_number" db:"card_number"`
-	CardType   string    `json:"card_type" db:"card_type"` // visa, mastercard, etc
-	IsActive   bool      `json:"is_active" db:"is_active"`
+	CardID     string    `json:"card_id" db:"card_id"`
+	FromStatus string    `json:"from_status" db:"from_status"`
+	ToStatus   string    `json:"to_status" db:"to_status"`
+	Actor      string    `json:"actor" db:"actor"`
+	Reason     string    `json:"reason,omitempty" db:"reason"`
 	CreatedAt  time.Time `json:"created_at" db:"created_at"`
 }
 
 // CardPayload represents incoming card transaction data
 type CardPayload struct {
-	CardNumber   string `json:"card_number"`
-	Amount       int64  `json:"amount"` // Amount in cents
-	MerchantName string `json:"merchant_name"`
-	Category     string `json:"category"`  // groceries, gas, etc
-	Timestamp    string `json:"timestamp"` // ISO 8601 format
+	CardNumber       string `json:"card_number,omitempty"`
+	CardToken        string `json:"card_token,omitempty"` // Used instead of CardNumber once a payload has been tokenized
+	Amount           int64  `json:"amount"`               // Amount in minor units of Currency
+	Currency         string `json:"currency,omitempty"`   // ISO 4217, defaults to the card's billing currency
+	MerchantName     string `json:"merchant_name"`        // Raw descriptor, normalized to a canonical merchant
+	MerchantCity     string `json:"merchant_city,omitempty"`
+	MerchantCountry  string `json:"merchant_country,omitempty"`  // ISO 3166-1 alpha-2
+	MCC              string `json:"mcc,omitempty"`               // ISO 18245 merchant category code
+	Category         string `json:"category,omitempty"`          // groceries, gas, etc; derived from the MCC when empty
+	Timestamp        string `json:"timestamp"`                   // ISO 8601 format
+	NetworkReference string `json:"network_reference,omitempty"` // Card network's unique reference for the message
 }
@@ -0,0 +1,164 @@
+package money
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+	"time"
+)
+
+// ErrNoRate is returned when no exchange rate is effective for a currency pair at a given time
+var ErrNoRate = errors.New("no exchange rate available")
+
+// Rate converts one major unit of From into To, effective from a point in time
+type Rate struct {
+	From          string    `json:"from"`
+	To            string    `json:"to"`
+	Rate          string    `json:"rate"` // Decimal string to avoid floating point error
+	EffectiveFrom time.Time `json:"effective_from"`
+
+	value *big.Rat
+}
+
+// Value returns the rate as an exact rational number
+func (r Rate) Value() *big.Rat {
+	return new(big.Rat).Set(r.value)
+}
+
+// RateTable holds exchange rates with effective dates
+type RateTable struct {
+	rates map[string][]Rate // keyed by "FROM/TO", sorted by EffectiveFrom ascending
+}
+
+// NewRateTable builds a table from a list of rates, validating currencies and values
+func NewRateTable(rates []Rate) (*RateTable, error) {
+	table := &RateTable{rates: make(map[string][]Rate)}
+
+	for _, rate := range rates {
+		from, err := NormalizeCurrency(rate.From)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rate: %w", err)
+		}
+		to, err := NormalizeCurrency(rate.To)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rate: %w", err)
+		}
+
+		value, ok := new(big.Rat).SetString(rate.Rate)
+		if !ok || value.Sign() <= 0 {
+			return nil, fmt.Errorf("invalid rate for %s/%s: %q", from, to, rate.Rate)
+		}
+
+		rate.From, rate.To, rate.value = from, to, value
+		key := pairKey(from, to)
+		table.rates[key] = append(table.rates[key], rate)
+	}
+
+	for _, pair := range table.rates {
+		sort.Slice(pair, func(i, j int) bool {
+			return pair[i].EffectiveFrom.Before(pair[j].EffectiveFrom)
+		})
+	}
+
+	return table, nil
+}
+
+// LoadRates reads a JSON rate file of the form {"rates": [{"from", "to", "rate", "effective_from"}]}
+func LoadRates(path string) (*RateTable, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rate file: %w", err)
+	}
+
+	var file struct {
+		Rates []Rate `json:"rates"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse rate file: %w", err)
+	}
+
+	return NewRateTable(file.Rates)
+}
+
+// Lookup returns the rate from one currency to another effective at the given time.
+// If only the opposite pair is known its inverse is used.
+func (t *RateTable) Lookup(from, to string, at time.Time) (Rate, error) {
+	if from == to {
+		return Rate{From: from, To: to, Rate: "1", value: big.NewRat(1, 1)}, nil
+	}
+
+	if rate, ok := effective(t.rates[pairKey(from, to)], at); ok {
+		return rate, nil
+	}
+
+	if inverse, ok := effective(t.rates[pairKey(to, from)], at); ok {
+		value := new(big.Rat).Inv(inverse.value)
+		return Rate{
+			From:          from,
+			To:            to,
+			Rate:          value.FloatString(10),
+			EffectiveFrom: inverse.EffectiveFrom,
+			value:         value,
+		}, nil
+	}
+
+	return Rate{}, fmt.Errorf("%w: %s/%s at %s", ErrNoRate, from, to, at.Format(time.RFC3339))
+}
+
+// Convert converts an amount into another currency using the rate effective at the given time,
+// rounding half away from zero to the target currency's minor unit
+func (t *RateTable) Convert(m Money, to string, at time.Time) (Money, Rate, error) {
+	source, err := LookupCurrency(m.Currency)
+	if err != nil {
+		return Money{}, Rate{}, err
+	}
+	target, err := LookupCurrency(to)
+	if err != nil {
+		return Money{}, Rate{}, err
+	}
+
+	rate, err := t.Lookup(source.Code, target.Code, at)
+	if err != nil {
+		return Money{}, Rate{}, err
+	}
+
+	// minor_to = minor_from * rate * 10^(exp_to - exp_from)
+	value := new(big.Rat).SetInt64(m.Amount)
+	value.Mul(value, rate.value)
+	value.Mul(value, new(big.Rat).SetInt64(pow10(target.Exponent)))
+	value.Quo(value, new(big.Rat).SetInt64(pow10(source.Exponent)))
+
+	return Money{Amount: roundHalfAwayFromZero(value), Currency: target.Code}, rate, nil
+}
+
+// effective returns the latest rate whose EffectiveFrom is not after at
+func effective(rates []Rate, at time.Time) (Rate, bool) {
+	for i := len(rates) - 1; i >= 0; i-- {
+		if !rates[i].EffectiveFrom.After(at) {
+			return rates[i], true
+		}
+	}
+	return Rate{}, false
+}
+
+func roundHalfAwayFromZero(value *big.Rat) int64 {
+	num := new(big.Int).Abs(value.Num())
+	den := value.Denom()
+
+	quotient, remainder := new(big.Int).QuoRem(num, den, new(big.Int))
+	if new(big.Int).Mul(remainder, big.NewInt(2)).Cmp(den) >= 0 {
+		quotient.Add(quotient, big.NewInt(1))
+	}
+
+	if value.Sign() < 0 {
+		quotient.Neg(quotient)
+	}
+	return quotient.Int64()
+}
+
+func pairKey(from, to string) string {
+	return from + "/" + to
+}
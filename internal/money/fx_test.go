@@ -0,0 +1,88 @@
+package money
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestConvert(t *testing.T) {
+	jan := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	jun := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	table, err := NewRateTable([]Rate{
+		{From: "USD", To: "JPY", Rate: "150.5", EffectiveFrom: jan},
+		{From: "usd", To: "jpy", Rate: "160", EffectiveFrom: jun},
+		{From: "EUR", To: "USD", Rate: "1.5", EffectiveFrom: jan},
+		{From: "USD", To: "KWD", Rate: "0.3075", EffectiveFrom: jan},
+	})
+	if err != nil {
+		t.Fatalf("failed to build rate table: %v", err)
+	}
+
+	march := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		amount int64
+		from   string
+		to     string
+		at     time.Time
+		want   int64
+	}{
+		{"same currency", 1234, "USD", "USD", march, 1234},
+		{"rounds half up", 3, "EUR", "USD", march, 5},
+		{"rounds below half down", 1, "USD", "KWD", march, 3},
+		{"rounds half away from zero", -3, "EUR", "USD", march, -5},
+		{"exact", 2, "EUR", "USD", march, 3},
+		{"to zero exponent", 100, "USD", "JPY", march, 151},
+		{"from zero exponent", 301, "JPY", "USD", march, 200},
+		{"inverse rate", 1, "USD", "EUR", march, 1},
+		{"to three digit exponent", 100, "USD", "KWD", march, 308},
+		{"negative to three digit exponent", -100, "USD", "KWD", march, -308},
+		{"later rate", 100, "USD", "JPY", jun.Add(time.Hour), 160},
+		{"rate effective at its start", 100, "USD", "JPY", jun, 160},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _, err := table.Convert(Money{Amount: tt.amount, Currency: tt.from}, tt.to, tt.at)
+			if err != nil {
+				t.Fatalf("Convert() = %v", err)
+			}
+			if got.Amount != tt.want || got.Currency != tt.to {
+				t.Errorf("Convert(%d %s) = %d %s, want %d %s", tt.amount, tt.from, got.Amount, got.Currency, tt.want, tt.to)
+			}
+		})
+	}
+}
+
+func TestConvertWithoutRate(t *testing.T) {
+	jan := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	table, err := NewRateTable([]Rate{{From: "USD", To: "JPY", Rate: "150", EffectiveFrom: jan}})
+	if err != nil {
+		t.Fatalf("failed to build rate table: %v", err)
+	}
+
+	if _, _, err := table.Convert(Money{Amount: 100, Currency: "USD"}, "JPY", jan.Add(-time.Second)); !errors.Is(err, ErrNoRate) {
+		t.Errorf("converting before the first rate: err=%v, want %v", err, ErrNoRate)
+	}
+	if _, _, err := table.Convert(Money{Amount: 100, Currency: "USD"}, "GBP", jan); !errors.Is(err, ErrNoRate) {
+		t.Errorf("converting without a rate: err=%v, want %v", err, ErrNoRate)
+	}
+	if _, _, err := table.Convert(Money{Amount: 100, Currency: "USD"}, "XYZ", jan); err == nil {
+		t.Error("converting to an unknown currency succeeded")
+	}
+}
+
+func TestNewRateTableRejectsInvalidRates(t *testing.T) {
+	for _, rate := range []Rate{
+		{From: "USD", To: "JPY", Rate: "0"},
+		{From: "USD", To: "JPY", Rate: "-1"},
+		{From: "USD", To: "JPY", Rate: "abc"},
+		{From: "USD", To: "XYZ", Rate: "1"},
+	} {
+		if _, err := NewRateTable([]Rate{rate}); err == nil {
+			t.Errorf("NewRateTable(%+v) succeeded", rate)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package money
+
+import "fmt"
+
+// Money is an amount in the minor units of a currency
+type Money struct {
+	Amount   int64  `json:"amount"`
+	Currency string `json:"currency"`
+}
+
+// New creates a Money value, validating the currency code
+func New(amount int64, code string) (Money, error) {
+	currency, err := LookupCurrency(code)
+	if err != nil {
+		return Money{}, err
+	}
+	return Money{Amount: amount, Currency: currency.Code}, nil
+}
+
+// String formats the amount in major units, e.g. "12.34 USD" or "1500 JPY"
+func (m Money) String() string {
+	currency, err := LookupCurrency(m.Currency)
+	if err != nil || currency.Exponent == 0 {
+		return fmt.Sprintf("%d %s", m.Amount, m.Currency)
+	}
+
+	sign := ""
+	amount := m.Amount
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+
+	scale := pow10(currency.Exponent)
+	return fmt.Sprintf("%s%d.%0*d %s", sign, amount/scale, currency.Exponent, amount%scale, m.Currency)
+}
+
+func pow10(n int) int64 {
+	result := int64(1)
+	for i := 0; i < n; i++ {
+		result *= 10
+	}
+	return result
+}
@@ -0,0 +1,242 @@
+package vault
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// TokenPrefix starts every card token so tokens are never mistaken for card numbers
+const TokenPrefix = "tok_"
+
+const keySize = 32 // AES-256
+
+var (
+	// ErrInvalidCardNumber is returned when a card number has no digits to tokenize
+	ErrInvalidCardNumber = errors.New("invalid card number")
+
+	// ErrUnknownKey is returned when a sealed card number was encrypted under a key the vault does not hold
+	ErrUnknownKey = errors.New("unknown vault key")
+)
+
+// Sealed is a card number encrypted under its own data key, which is itself encrypted
+// under the vault's key-encryption key
+type Sealed struct {
+	KeyID      string // Key-encryption key that wrapped DataKey
+	DataKey    []byte // Wrapped data key: nonce followed by ciphertext
+	Ciphertext []byte // Encrypted card number: nonce followed by ciphertext
+}
+
+// Vault tokenizes and encrypts card numbers with keys from a local keyfile
+type Vault struct {
+	keyID     string
+	kek       cipher.AEAD
+	lookupKey []byte
+}
+
+// New creates a vault from a 32 byte master key. The key-encryption and lookup keys are
+// derived from it so that neither can be used in place of the other.
+func New(keyID string, masterKey []byte) (*Vault, error) {
+	if keyID == "" {
+		return nil, fmt.Errorf("vault key ID cannot be empty")
+	}
+	if len(masterKey) != keySize {
+		return nil, fmt.Errorf("vault key must be %d bytes, got: %d", keySize, len(masterKey))
+	}
+
+	kek, err := newAEAD(deriveKey(masterKey, "ledgertime/vault/kek"))
+	if err != nil {
+		return nil, err
+	}
+
+	return &Vault{
+		keyID:     keyID,
+		kek:       kek,
+		lookupKey: deriveKey(masterKey, "ledgertime/vault/lookup"),
+	}, nil
+}
+
+// LoadKeyfile reads a JSON keyfile of the form {"key_id", "key"} with a base64 encoded 32 byte key.
+// There is no default keyfile, so an empty path is an error.
+func LoadKeyfile(path string) (*Vault, error) {
+	if path == "" {
+		return nil, fmt.Errorf("vault keyfile is not set (VAULT_KEYFILE)")
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read vault keyfile: %w", err)
+	}
+
+	var file struct {
+		KeyID string `json:"key_id"`
+		Key   string `json:"key"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse vault keyfile: %w", err)
+	}
+
+	key, err := base64.StdEncoding.DecodeString(file.Key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode vault key: %w", err)
+	}
+
+	return New(file.KeyID, key)
+}
+
+// Normalize strips spaces and dashes from a card number and checks that only digits remain
+func Normalize(cardNumber string) (string, error) {
+	var b strings.Builder
+	for _, r := range cardNumber {
+		switch {
+		case r >= '0' && r <= '9':
+			b.WriteRune(r)
+		case r == ' ' || r == '-':
+		default:
+			return "", fmt.Errorf("%w: unexpected character %q", ErrInvalidCardNumber, r)
+		}
+	}
+
+	if b.Len() < 4 {
+		return "", fmt.Errorf("%w: too short", ErrInvalidCardNumber)
+	}
+	return b.String(), nil
+}
+
+// LookupHash returns a deterministic keyed hash of a normalized card number, used to find
+// cards by number without storing it in plaintext
+func (v *Vault) LookupHash(cardNumber string) string {
+	mac := hmac.New(sha256.New, v.lookupKey)
+	mac.Write([]byte(cardNumber))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Seal encrypts a normalized card number under a fresh data key. The associated data
+// (typically the card's token) must be passed again to Open.
+func (v *Vault) Seal(cardNumber, associatedData string) (*Sealed, error) {
+	dataKey := make([]byte, keySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, fmt.Errorf("failed to generate data key: %w", err)
+	}
+
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	ciphertext, err := seal(aead, []byte(cardNumber), []byte(associatedData))
+	if err != nil {
+		return nil, err
+	}
+
+	wrapped, err := seal(v.kek, dataKey, []byte(v.keyID))
+	if err != nil {
+		return nil, err
+	}
+
+	return &Sealed{KeyID: v.keyID, DataKey: wrapped, Ciphertext: ciphertext}, nil
+}
+
+// Open decrypts a sealed card number
+func (v *Vault) Open(sealed *Sealed, associatedData string) (string, error) {
+	if sealed.KeyID != v.keyID {
+		return "", fmt.Errorf("%w: %s", ErrUnknownKey, sealed.KeyID)
+	}
+
+	dataKey, err := open(v.kek, sealed.DataKey, []byte(sealed.KeyID))
+	if err != nil {
+		return "", fmt.Errorf("failed to unwrap data key: %w", err)
+	}
+
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return "", err
+	}
+
+	cardNumber, err := open(aead, sealed.Ciphertext, []byte(associatedData))
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt card number: %w", err)
+	}
+	return string(cardNumber), nil
+}
+
+// NewToken returns a random opaque card token
+func NewToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	return TokenPrefix + hex.EncodeToString(b), nil
+}
+
+// IsToken reports whether a card reference is a token rather than a card number
+func IsToken(ref string) bool {
+	return strings.HasPrefix(ref, TokenPrefix)
+}
+
+// Last4 returns the last four digits of a card number, ignoring separators
+func Last4(cardNumber string) string {
+	digits := make([]byte, 0, 4)
+	for i := len(cardNumber) - 1; i >= 0 && len(digits) < 4; i-- {
+		if c := cardNumber[i]; c >= '0' && c <= '9' {
+			digits = append(digits, c)
+		}
+	}
+	for i, j := 0, len(digits)-1; i < j; i, j = i+1, j-1 {
+		digits[i], digits[j] = digits[j], digits[i]
+	}
+	return string(digits)
+}
+
+// Mask returns a card number for display, keeping only its last four digits: **** 9012
+func Mask(cardNumber string) string {
+	return MaskLast4(Last4(cardNumber))
+}
+
+// MaskLast4 formats the last four digits of a card number for display
+func MaskLast4(last4 string) string {
+	return "**** " + last4
+}
+
+func deriveKey(masterKey []byte, purpose string) []byte {
+	mac := hmac.New(sha256.New, masterKey)
+	mac.Write([]byte(purpose))
+	return mac.Sum(nil)
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	return aead, nil
+}
+
+func seal(aead cipher.AEAD, plaintext, associatedData []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return aead.Seal(nonce, nonce, plaintext, associatedData), nil
+}
+
+func open(aead cipher.AEAD, sealed, associatedData []byte) ([]byte, error) {
+	if len(sealed) < aead.NonceSize() {
+		return nil, fmt.Errorf("ciphertext too short")
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	return aead.Open(nil, nonce, ciphertext, associatedData)
+}
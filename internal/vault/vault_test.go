@@ -0,0 +1,207 @@
+package vault
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+)
+
+const testNumber = "4012888888881881"
+
+func newTestVault(t *testing.T, keyID string, fill byte) *Vault {
+	t.Helper()
+	v, err := New(keyID, bytes.Repeat([]byte{fill}, keySize))
+	if err != nil {
+		t.Fatalf("failed to create vault: %v", err)
+	}
+	return v
+}
+
+func TestSealOpen(t *testing.T) {
+	v := newTestVault(t, "test-1", 1)
+
+	sealed, err := v.Seal(testNumber, "tok_a")
+	if err != nil {
+		t.Fatalf("Seal() = %v", err)
+	}
+	if sealed.KeyID != "test-1" {
+		t.Errorf("KeyID = %q, want test-1", sealed.KeyID)
+	}
+	if bytes.Contains(sealed.Ciphertext, []byte(testNumber)) {
+		t.Error("ciphertext contains the card number")
+	}
+
+	got, err := v.Open(sealed, "tok_a")
+	if err != nil {
+		t.Fatalf("Open() = %v", err)
+	}
+	if got != testNumber {
+		t.Errorf("Open() = %q, want %q", got, testNumber)
+	}
+
+	// Every seal uses a fresh data key and nonce
+	again, err := v.Seal(testNumber, "tok_a")
+	if err != nil {
+		t.Fatalf("Seal() = %v", err)
+	}
+	if bytes.Equal(again.Ciphertext, sealed.Ciphertext) || bytes.Equal(again.DataKey, sealed.DataKey) {
+		t.Error("sealing the same number twice gave the same ciphertext")
+	}
+}
+
+func TestOpenFails(t *testing.T) {
+	v := newTestVault(t, "test-1", 1)
+
+	tamper := func(b []byte) []byte {
+		b = append([]byte(nil), b...)
+		b[len(b)-1] ^= 0xff
+		return b
+	}
+
+	tests := []struct {
+		name           string
+		vault          *Vault
+		change         func(*Sealed)
+		associatedData string
+		wantErr        error
+	}{
+		{"wrong associated data", v, func(*Sealed) {}, "tok_b", nil},
+		{"wrong key ID", v, func(s *Sealed) { s.KeyID = "test-2" }, "tok_a", ErrUnknownKey},
+		{"other vault key ID", newTestVault(t, "test-2", 1), func(*Sealed) {}, "tok_a", ErrUnknownKey},
+		{"other master key", newTestVault(t, "test-1", 2), func(*Sealed) {}, "tok_a", nil},
+		{"tampered ciphertext", v, func(s *Sealed) { s.Ciphertext = tamper(s.Ciphertext) }, "tok_a", nil},
+		{"tampered data key", v, func(s *Sealed) { s.DataKey = tamper(s.DataKey) }, "tok_a", nil},
+		{"truncated ciphertext", v, func(s *Sealed) { s.Ciphertext = s.Ciphertext[:4] }, "tok_a", nil},
+	}
+
+	for _, tt := range tests {
+		sealed, err := v.Seal(testNumber, "tok_a")
+		if err != nil {
+			t.Fatalf("Seal() = %v", err)
+		}
+		tt.change(sealed)
+
+		got, err := tt.vault.Open(sealed, tt.associatedData)
+		if err == nil {
+			t.Errorf("%s: Open() = %q, want an error", tt.name, got)
+			continue
+		}
+		if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: Open() = %v, want %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestLookupHash(t *testing.T) {
+	v := newTestVault(t, "test-1", 1)
+
+	hash := v.LookupHash(testNumber)
+	if hash != v.LookupHash(testNumber) {
+		t.Error("LookupHash() is not deterministic")
+	}
+	if len(hash) != 64 {
+		t.Errorf("LookupHash() = %q, want 64 hex characters", hash)
+	}
+	if hash == v.LookupHash("4111111111111111") {
+		t.Error("different numbers have the same lookup hash")
+	}
+
+	// The hash is keyed: the same key ID with another master key gives another hash
+	if hash == newTestVault(t, "test-1", 2).LookupHash(testNumber) {
+		t.Error("different master keys give the same lookup hash")
+	}
+
+	// Numbers are normalized before hashing, so formatting doesn't change the hash
+	for _, formatted := range []string{"4012 8888 8888 1881", "4012-8888-8888-1881", " 4012888888881881 "} {
+		normalized, err := Normalize(formatted)
+		if err != nil {
+			t.Fatalf("Normalize(%q) = %v", formatted, err)
+		}
+		if got := v.LookupHash(normalized); got != hash {
+			t.Errorf("LookupHash(Normalize(%q)) = %s, want %s", formatted, got, hash)
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		number  string
+		want    string
+		wantErr bool
+	}{
+		{"4012888888881881", "4012888888881881", false},
+		{"4012 8888 8888 1881", "4012888888881881", false},
+		{"4012-8888-8888-1881", "4012888888881881", false},
+		{"4012.8888.8888.1881", "", true},
+		{"tok_0123", "", true},
+		{"12 3", "", true},
+		{"", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := Normalize(tt.number)
+		if tt.wantErr {
+			if !errors.Is(err, ErrInvalidCardNumber) {
+				t.Errorf("Normalize(%q) = %q, %v, want ErrInvalidCardNumber", tt.number, got, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("Normalize(%q) = %q, %v, want %q", tt.number, got, err, tt.want)
+		}
+	}
+}
+
+func TestNewToken(t *testing.T) {
+	token, err := NewToken()
+	if err != nil {
+		t.Fatalf("NewToken() = %v", err)
+	}
+
+	if !strings.HasPrefix(token, TokenPrefix) || !IsToken(token) {
+		t.Errorf("NewToken() = %q, want the %s prefix", token, TokenPrefix)
+	}
+	if _, err := hex.DecodeString(strings.TrimPrefix(token, TokenPrefix)); err != nil || len(token) != 36 {
+		t.Errorf("NewToken() = %q, want %s followed by 32 hex characters", token, TokenPrefix)
+	}
+	if IsToken(testNumber) {
+		t.Errorf("IsToken(%q) = true", testNumber)
+	}
+
+	other, err := NewToken()
+	if err != nil {
+		t.Fatalf("NewToken() = %v", err)
+	}
+	if other == token {
+		t.Errorf("NewToken() returned %q twice", token)
+	}
+}
+
+func TestMask(t *testing.T) {
+	tests := map[string]string{
+		"4012888888881881":    "**** 1881",
+		"4012 8888 8888 1881": "**** 1881",
+		"3782-822463-10005":   "**** 0005",
+		"12":                  "**** 12",
+	}
+
+	for number, want := range tests {
+		if got := Mask(number); got != want {
+			t.Errorf("Mask(%q) = %q, want %q", number, got, want)
+		}
+	}
+	if got := MaskLast4("0366"); got != "**** 0366" {
+		t.Errorf("MaskLast4() = %q, want **** 0366", got)
+	}
+}
+
+func TestNew(t *testing.T) {
+	if _, err := New("", make([]byte, keySize)); err == nil {
+		t.Error("New() accepted an empty key ID")
+	}
+	if _, err := New("test-1", make([]byte, 16)); err == nil {
+		t.Error("New() accepted a 16 byte key")
+	}
+}
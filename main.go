@@ -2,7 +2,7 @@ package main
 
 import (
 	"context"
-	"encoding/json"
+	"flag"
 	"log"
 	"net/http"
 	"os"
@@ -12,8 +12,12 @@ import (
 
 	"github.com/araesf/ledgertime/internal/config"
 	"github.com/araesf/ledgertime/internal/db"
+	"github.com/araesf/ledgertime/internal/fraud"
 	gql "github.com/araesf/ledgertime/internal/graphql"
+	"github.com/araesf/ledgertime/internal/kafka"
 	"github.com/araesf/ledgertime/internal/ledger"
+	"github.com/araesf/ledgertime/internal/memory"
+	"github.com/araesf/ledgertime/internal/vault"
 	"github.com/araesf/ledgertime/pkg/logger"
 	"github.com/gin-gonic/gin"
 	"github.com/graphql-go/graphql"
@@ -22,13 +26,20 @@ import (
 
 // Global services
 var (
-	database      *db.DB
+	store         ledger.Store
 	ledgerService *ledger.Service
 	gqlSchema     graphql.Schema
-	logger        *logger.Logger
+	appLogger     *logger.Logger
+
+	// Deadline for each GraphQL request, zero for none
+	requestTimeout time.Duration
 )
 
 func main() {
+	storage := flag.String("storage", "postgres", "storage backend: postgres or memory")
+	relayOutbox := flag.Bool("relay", false, "publish the outbox to Kafka from this process (the consumer does by default)")
+	flag.Parse()
+
 	// Initialize configuration
 	cfg, err := config.Load()
 	if err != nil {
@@ -36,19 +47,56 @@ func main() {
 	}
 
 	// Initialize logger
-	logger = logger.New(cfg.Log.Level)
-
-	// Initialize database
-	database, err = db.New(cfg.Database.DSN, logger)
-	if err != nil {
-		logger.Fatal("Failed to connect to database", "error", err)
+	appLogger = logger.NewLoggerWithLevel(cfg.Logger.Level)
+	requestTimeout = cfg.Server.RequestTimeout
+
+	// Initialize storage
+	switch *storage {
+	case "postgres":
+		database, err := db.Connect(cfg.Database, appLogger)
+		if err != nil {
+			appLogger.Fatal("Failed to connect to database", "error", err)
+		}
+		defer database.Close()
+		store = database
+	case "memory":
+		appLogger.Info("Using in-memory storage, data is lost on exit")
+		store = memory.NewStore()
+	default:
+		appLogger.Fatal("Unknown storage backend", "storage", *storage)
 	}
-	defer database.Close()
 
 	// Initialize services
-	ledgerService = ledger.NewService(database, logger)
+	rates, err := ledger.LoadRates(cfg.Ledger)
+	if err != nil {
+		appLogger.Fatal("Failed to load FX rates", "error", err)
+	}
+	fraudEngine := fraud.NewEngine(cfg.Fraud, store)
+	processor, err := ledger.NewProcessor(cfg.Processor, rates, ledger.NewFraudProcessor(fraudEngine, store, appLogger))
+	if err != nil {
+		appLogger.Fatal("Failed to create transaction processor", "error", err)
+	}
+	cardVault, err := vault.LoadKeyfile(cfg.Vault.KeyFile)
+	if err != nil {
+		appLogger.Fatal("Failed to load card vault", "error", err)
+	}
+	ledgerService, err = ledger.NewService(cfg.Ledger, store, processor, cardVault, appLogger)
+	if err != nil {
+		appLogger.Fatal("Failed to create ledger service", "error", err)
+	}
 	initGraphQL()
 
+	// Expire authorizations, reviews and cards in the background
+	workers, stopWorkers := context.WithCancel(context.Background())
+	ledgerService.StartSweepers(workers)
+
+	// Publish ledger events from the outbox
+	var relay *kafka.Relay
+	if *relayOutbox {
+		relay = kafka.NewRelay(cfg.Kafka, store, appLogger)
+		go relay.Run(workers)
+	}
+
 	// Initialize Gin router
 	gin.SetMode(gin.ReleaseMode)
 	r := gin.Default()
@@ -65,9 +113,9 @@ func main() {
 
 	// Graceful shutdown
 	go func() {
-		logger.Info("Starting Ledgertime GraphQL API on :8080")
+		appLogger.Info("Starting Ledgertime GraphQL API on :8080")
 		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			logger.Fatal("Failed to start server", "error", err)
+			appLogger.Fatal("Failed to start server", "error", err)
 		}
 	}()
 
@@ -76,22 +124,28 @@ func main() {
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 
-	logger.Info("Shutting down server...")
+	appLogger.Info("Shutting down server...")
+	stopWorkers()
+	if relay != nil {
+		if err := relay.Close(); err != nil {
+			appLogger.Error("Error closing outbox relay", "error", err)
+		}
+	}
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
 	if err := srv.Shutdown(ctx); err != nil {
-		logger.Fatal("Server forced to shutdown", "error", err)
+		appLogger.Fatal("Server forced to shutdown", "error", err)
 	}
-	logger.Info("Server shutdown complete")
+	appLogger.Info("Server shutdown complete")
 }
 
 
 func initGraphQL() {
-	resolver := gql.NewResolver(database, ledgerService, logger)
+	resolver := gql.NewResolver(store, ledgerService, appLogger)
 	schema, err := resolver.BuildSchema()
 	if err != nil {
-		logger.Fatal("Failed to build GraphQL schema", "error", err)
+		appLogger.Fatal("Failed to build GraphQL schema", "error", err)
 	}
 	gqlSchema = schema
 }
@@ -109,15 +163,25 @@ func graphqlHandler(c *gin.Context) {
 		return
 	}
 
+	// Resolvers pass the request's context to the ledger and database, so the deadline or a
+	// client disconnect cancels their queries
+	ctx := c.Request.Context()
+	if requestTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, requestTimeout)
+		defer cancel()
+	}
+
 	result := graphql.Do(graphql.Params{
 		Schema:         gqlSchema,
+		Context:        ctx,
 		RequestString:  req.Query,
 		VariableValues: req.Variables,
 		OperationName:  req.OperationName,
 	})
 
 	if result.HasErrors() {
-		logger.Error("GraphQL errors", "errors", result.Errors)
+		appLogger.Error("GraphQL errors", "errors", result.Errors)
 	}
 
 	c.JSON(http.StatusOK, result)